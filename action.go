@@ -0,0 +1,105 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// actionKey is the document key choosing the bulk action a document is sent
+// with, when it is not index.
+const actionKey = "_action"
+
+// Bulk actions a document can be sent with besides index.
+const (
+	actionCreate = "create" // Fails if a document with the _id exists
+	actionUpdate = "update" // Merges the fields into the existing document with the _id
+	actionDelete = "delete" // Deletes the document with the _id, leaving a tombstone
+)
+
+// documentActions are the values accepted for _action.
+var documentActions = []string{"index", actionCreate, actionUpdate, actionDelete}
+
+// parseDocumentAction checks the _action of a document, returning it as
+// stored in Document, where index is the empty default.
+func parseDocumentAction(value string) (string, error) {
+	if !slices.Contains(documentActions, value) {
+		return "", fmt.Errorf("%s must be one of index, create, update, or delete, got %q", actionKey, value)
+	}
+	if value == "index" {
+		return "", nil
+	}
+	return value, nil
+}
+
+// checkDocumentAction checks that doc, with the given _action, can be sent
+// with it: update and delete name an existing document by _id, and a delete
+// has no fields to send.
+func checkDocumentAction(doc Document) error {
+	switch doc.action {
+	case actionUpdate, actionDelete:
+		if doc.ID == "" {
+			return fmt.Errorf("%s %s requires an _id", actionKey, doc.action)
+		}
+	}
+	if doc.action == actionDelete && string(doc.Source) != "{}" {
+		return fmt.Errorf("a document with %s delete takes no fields besides _id and %s", actionKey, routingKey)
+	}
+	return nil
+}
+
+// Action returns the bulk action the document is sent with: index, create,
+// update, or delete, as set by its _action.
+func (d Document) Action() string {
+	if d.action == "" {
+		return "index"
+	}
+	return d.action
+}
+
+// partial reports whether d is sent as an update or a delete, which carry
+// only some or none of the fields of the document they apply to.
+func (d Document) partial() bool {
+	return d.action == actionUpdate || d.action == actionDelete
+}
+
+// updateBody is the body of an update action merging source into the
+// existing document.
+func updateBody(source json.RawMessage) []byte {
+	body, _ := json.Marshal(map[string]json.RawMessage{"doc": source})
+	return body
+}
+
+// bulkItem returns the bulk action doc is sent with and the body following
+// its action line, which is nil for a delete.
+func bulkItem(doc Document) (action string, body []byte) {
+	switch doc.action {
+	case actionCreate:
+		return "create", doc.Source
+	case actionUpdate:
+		return "update", updateBody(doc.Source)
+	case actionDelete:
+		return "delete", nil
+	}
+	return "index", doc.Source
+}
+
+// checkDocumentActions rejects update and delete actions under DedupeByID,
+// which would drop the document they apply to.
+func (l *Loader) checkDocumentActions() error {
+	if !l.dedupe {
+		return nil
+	}
+
+	var errs []error
+	for _, f := range l.fixtures {
+		for _, doc := range f.documents {
+			if doc.partial() {
+				errs = append(errs, fmt.Errorf("index %q: %s: %s %s cannot be used with DedupeByID, which keeps only the last document of each _id", f.name, doc.Location(), actionKey, doc.action))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseFixtures_DocumentActions(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": `- _id: "1"
+  _action: create
+  name: Alice
+- _id: "2"
+  _action: index
+  name: Bob
+- _id: "1"
+  _action: update
+  age: 30
+- _id: "2"
+  _action: delete
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	want := []struct{ action, source string }{
+		{actionCreate, `{"name":"Alice"}`},
+		{"", `{"name":"Bob"}`},
+		{actionUpdate, `{"age":30}`},
+		{actionDelete, `{}`},
+	}
+	docs := loader.fixture("users").documents
+	if len(docs) != len(want) {
+		t.Fatalf("expected %d documents, got %d", len(want), len(docs))
+	}
+	for i, w := range want {
+		if docs[i].action != w.action || string(docs[i].Source) != w.source {
+			t.Errorf("document %d: expected %q %s, got %q %s", i, w.action, w.source, docs[i].action, docs[i].Source)
+		}
+	}
+}
+
+func TestParseFixtures_DocumentActionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "unknown", data: "- _id: \"1\"\n  _action: upsert\n", want: `line 2: _action must be one of index, create, update, or delete, got "upsert"`},
+		{name: "not a scalar", data: "- _id: \"1\"\n  _action: [create]\n", want: "line 2: _action must be a scalar"},
+		{name: "update without id", data: "- _action: update\n  n: 1\n", want: "line 1: _action update requires an _id"},
+		{name: "delete without id", data: "- _action: delete\n", want: "line 1: _action delete requires an _id"},
+		{name: "delete with fields", data: "- _id: \"1\"\n  _action: delete\n  n: 1\n", want: "line 1: a document with _action delete takes no fields besides _id and _routing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": tt.data})
+			_, err := New(newOfflineClient(t), Directory(dir))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_DocumentActions(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_settings.json": `{"index":{"sort.field":"name"}}`,
+		"users/_mapping.json":  `{"properties":{"name":{"type":"keyword"}}}`,
+		"users/documents.yml": `- _id: "1"
+  _action: create
+  name: Alice
+- _id: "1"
+  _action: update
+  age: 30
+- _id: "2"
+  _routing: eu
+  _action: delete
+`,
+	})
+
+	var body string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			return jsonResponse(200, `{"errors":false,"items":[{"create":{"_id":"1","status":201}},{"update":{"_id":"1","status":200}},{"delete":{"_id":"2","status":404}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	// A created timestamp is not added to the partial update.
+	loader, err := New(client, Directory(dir), WithTimestampField("created_at"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 bulk lines, got:\n%s", body)
+	}
+	for i, want := range []string{
+		`{"create":{"_id":"1"}}`,
+		`{"name":"Alice","created_at":`,
+		`{"update":{"_id":"1"}}`,
+		`{"doc":{"age":30}}`,
+		`{"delete":{"_id":"2","routing":"eu"}}`,
+	} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("line %d: expected %s, got %s", i+1, want, lines[i])
+		}
+	}
+}
+
+func TestNew_DocumentActionsWithDedupe(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: \"1\"\n  n: 1\n- _id: \"1\"\n  _action: update\n  n: 2\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir), DedupeByID())
+	if err == nil || !strings.Contains(err.Error(), `index "users": users/documents.yml:3: _action update cannot be used with DedupeByID`) {
+		t.Errorf("expected a DedupeByID conflict, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"gopkg.in/yaml.v3"
+)
+
+// aliasConfig is the alias section of _config.yml. A directory with one is an
+// alias fixture: instead of an index of its own, it is created as an alias,
+// named after the directory, over other fixture indices:
+//
+//	alias:
+//	  indices: [orders]
+//	  filter: {term: {tenant_id: acme}}
+//	  routing: acme
+type aliasConfig struct {
+	Indices    []string  `yaml:"indices"`     // Fixture indices the alias points to
+	Filter     yaml.Node `yaml:"filter"`      // Query limiting the documents visible through the alias (optional)
+	Routing    string    `yaml:"routing"`     // Routing applied to searches through the alias (optional)
+	WriteIndex string    `yaml:"write_index"` // Index of Indices that receives writes through the alias (optional)
+}
+
+// isAlias reports whether f is an alias fixture rather than an index.
+func (f *indexFixture) isAlias() bool {
+	return f.config.Alias != nil
+}
+
+// parseAliasDir completes an alias fixture. Its directory may hold only
+// _config.yml and _expectations/, since documents and schema belong to the
+// indices it points to.
+func parseAliasDir(fsys fs.FS, dir string, f *indexFixture) (*indexFixture, error) {
+	alias := f.config.Alias
+	if len(alias.Indices) == 0 {
+		return nil, fmt.Errorf("%s: alias must list at least one index", configFile)
+	}
+	if f.config.InheritCommon {
+		return nil, fmt.Errorf("%s: an alias cannot set inherit_common", configFile)
+	}
+	if f.config.State != "" {
+		return nil, fmt.Errorf("%s: an alias cannot set state", configFile)
+	}
+	if alias.WriteIndex != "" && !slices.Contains(alias.Indices, alias.WriteIndex) {
+		return nil, fmt.Errorf("%s: write_index %q is not one of the alias indices", configFile, alias.WriteIndex)
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != configFile && name != expectationsDir {
+			return nil, fmt.Errorf("alias directory may only contain %s and %s/, found %q", configFile, expectationsDir, name)
+		}
+	}
+
+	body := make(map[string]any)
+	if alias.Filter.Kind != 0 {
+		filter, err := yamlToJSON(&alias.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("%s: encoding alias filter as JSON: %w", configFile, err)
+		}
+		body["filter"] = filter
+	}
+	if alias.Routing != "" {
+		body["routing"] = alias.Routing
+	}
+	if len(body) > 0 {
+		if f.aliasBody, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("%s: encoding alias: %w", configFile, err)
+		}
+	}
+	if alias.WriteIndex != "" {
+		body["is_write_index"] = true
+		if f.writeAliasBody, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("%s: encoding alias: %w", configFile, err)
+		}
+	}
+
+	expectations, err := parseExpectations(fsys, path.Join(dir, expectationsDir))
+	if err != nil {
+		return nil, err
+	}
+	f.expectations = expectations
+
+	return f, nil
+}
+
+// resolveAliases checks that every alias fixture points to index fixtures
+// managed by the Loader, and moves alias fixtures after all indices so that
+// their targets exist by the time Load creates them.
+func (l *Loader) resolveAliases() error {
+	for _, f := range l.fixtures {
+		if !f.isAlias() {
+			continue
+		}
+		if len(f.providers) > 0 {
+			return fmt.Errorf("index %q is an alias fixture and cannot have providers", f.name)
+		}
+		for _, target := range f.config.Alias.Indices {
+			t := l.fixture(target)
+			if t == nil {
+				return fmt.Errorf("alias %q points to %q, which is not a fixture index", f.name, target)
+			}
+			if t.isAlias() {
+				return fmt.Errorf("alias %q points to %q, which is itself an alias", f.name, target)
+			}
+		}
+	}
+
+	slices.SortStableFunc(l.fixtures, func(a, b *indexFixture) int {
+		switch {
+		case a.isAlias() == b.isAlias():
+			return 0
+		case a.isAlias():
+			return 1
+		}
+		return -1
+	})
+
+	return nil
+}
+
+// putFixtureAlias creates the alias of an alias fixture over its target
+// indices, with the index named by write_index as its write index. Under
+// WithUniqueIndices the alias is claimed, failing if another loader uses it.
+func (l *Loader) putFixtureAlias(ctx context.Context, f *indexFixture) error {
+	var targets, write []string
+	for _, target := range f.config.Alias.Indices {
+		if target == f.config.Alias.WriteIndex {
+			write = append(write, l.IndexName(target))
+			continue
+		}
+		targets = append(targets, l.IndexName(target))
+	}
+
+	put := putAlias
+	if l.uniqueSuffix != "" {
+		put = claimAlias
+	}
+	name := l.aliasName(f.name)
+	if len(targets) == 0 {
+		return put(ctx, l.client, write, name, f.writeAliasBody)
+	}
+	if err := put(ctx, l.client, targets, name, f.aliasBody); err != nil {
+		return err
+	}
+	if len(write) > 0 {
+		return putAlias(ctx, l.client, write, name, f.writeAliasBody)
+	}
+
+	return nil
+}
+
+// putAlias creates an alias over the given indices, with body holding its
+// filter and routing (may be nil).
+func putAlias(ctx context.Context, client *elasticsearch.Client, indices []string, name string, body json.RawMessage) error {
+	opts := []func(*esapi.IndicesPutAliasRequest){client.Indices.PutAlias.WithContext(ctx)}
+	if body != nil {
+		opts = append(opts, client.Indices.PutAlias.WithBody(bytes.NewReader(body)))
+	}
+
+	res, err := client.Indices.PutAlias(indices, name, opts...)
+	if err != nil {
+		return fmt.Errorf("creating alias %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating alias %q: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseFixtures_AliasFixture(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml": "- _id: 1\n  tenant_id: acme\n",
+		"acme_orders/_config.yml": `alias:
+  indices: [orders]
+  filter:
+    term: {tenant_id: acme}
+  routing: acme
+`,
+		"acme_orders/_expectations/all.yml": "all:\n  query: {match_all: {}}\n  ids: [\"1\"]\n",
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	var alias *indexFixture
+	for _, f := range fixtures {
+		if f.name == "acme_orders" {
+			alias = f
+		}
+	}
+	if alias == nil || !alias.isAlias() {
+		t.Fatalf("expected acme_orders to be an alias fixture, got %+v", fixtures)
+	}
+	if want := `{"filter":{"term":{"tenant_id":"acme"}},"routing":"acme"}`; string(alias.aliasBody) != want {
+		t.Errorf("aliasBody = %s, want %s", alias.aliasBody, want)
+	}
+	if len(alias.expectations) != 1 {
+		t.Errorf("expected the alias to keep its expectations, got %d", len(alias.expectations))
+	}
+}
+
+func TestParseFixtures_AliasFixtureRejectsDocuments(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml":      "- _id: 1\n",
+		"acme_orders/_config.yml":   "alias:\n  indices: [orders]\n",
+		"acme_orders/documents.yml": "- _id: 2\n",
+	})
+
+	_, err := parseFixtures(os.DirFS(dir), ".")
+	if err == nil || !strings.Contains(err.Error(), `found "documents.yml"`) {
+		t.Fatalf("expected an error for documents in an alias directory, got %v", err)
+	}
+}
+
+func TestNew_AliasUnknownIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml":    "- _id: 1\n",
+		"acme_orders/_config.yml": "alias:\n  indices: [invoices]\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil || !strings.Contains(err.Error(), `"invoices", which is not a fixture index`) {
+		t.Fatalf("expected an error for an alias over an unknown index, got %v", err)
+	}
+}
+
+func TestLoad_AliasFixture(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"acme_orders/_config.yml": "alias:\n  indices: [orders]\n  filter: {term: {tenant_id: acme}}\n",
+		"orders/documents.yml":    "- _id: 1\n  tenant_id: acme\n",
+	})
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		line := req.Method + " " + req.URL.Path
+		if req.Method == http.MethodPut && strings.HasSuffix(req.URL.Path, "/acme_orders") && req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			line += " " + string(body)
+		}
+		mu.Lock()
+		requests = append(requests, line)
+		mu.Unlock()
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	want := `PUT /orders/_aliases/acme_orders {"filter":{"term":{"tenant_id":"acme"}}}`
+	if last := requests[len(requests)-1]; last != want {
+		t.Errorf("expected the alias to be created last, got requests:\n%s", strings.Join(requests, "\n"))
+	}
+	for _, r := range requests {
+		if r == "DELETE /acme_orders" {
+			t.Errorf("expected the alias not to be deleted as an index")
+		}
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if len(requests) != 1 || requests[0] != "DELETE /orders" {
+		t.Errorf("expected Clean to delete only the orders index, got %v", requests)
+	}
+}
+
+func TestNew_AliasWriteIndexNotTarget(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders_2023/documents.yml": "- _id: 1\n",
+		"orders/_config.yml":        "alias:\n  indices: [orders_2023]\n  write_index: orders_2024\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil || !strings.Contains(err.Error(), `write_index "orders_2024" is not one of the alias indices`) {
+		t.Fatalf("expected an error for a write index outside the alias, got %v", err)
+	}
+}
+
+func TestLoad_AliasFixtureWriteIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_config.yml":        "alias:\n  indices: [orders_2023, orders_2024]\n  routing: acme\n  write_index: orders_2024\n",
+		"orders_2023/documents.yml": "- _id: 1\n",
+		"orders_2024/documents.yml": "- _id: 2\n",
+	})
+
+	var (
+		mu      sync.Mutex
+		aliases []string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/_aliases/") {
+			line := req.Method + " " + req.URL.Path
+			if req.Body != nil {
+				body, _ := io.ReadAll(req.Body)
+				line += " " + string(body)
+			}
+			mu.Lock()
+			aliases = append(aliases, line)
+			mu.Unlock()
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	want := []string{
+		`PUT /orders_2023/_aliases/orders {"routing":"acme"}`,
+		`PUT /orders_2024/_aliases/orders {"is_write_index":true,"routing":"acme"}`,
+	}
+	if strings.Join(aliases, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected alias requests\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(aliases, "\n"))
+	}
+}
@@ -0,0 +1,74 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// allocationSettingsPrefix is the settings namespace holding shard allocation
+// filters, including the _tier_preference and box_type attributes copied from
+// production hot/warm clusters.
+const allocationSettingsPrefix = "index.routing.allocation."
+
+// withoutAllocationSettings removes every index.routing.allocation.* setting
+// from settings, whether written nested, dotted, or without the "index."
+// prefix. Objects left empty by the removal are removed as well.
+func withoutAllocationSettings(settings json.RawMessage) (json.RawMessage, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	return removeSettings(settings, "")
+}
+
+func removeSettings(obj json.RawMessage, prefix string) (json.RawMessage, error) {
+	fields, err := decodeObject(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := fields[:0]
+	for _, f := range fields {
+		path := f.key
+		if prefix != "" {
+			path = prefix + "." + f.key
+		}
+		if isAllocationSetting(path) {
+			continue
+		}
+
+		if nested, err := decodeObject(f.value); err == nil && len(nested) > 0 {
+			value, err := removeSettings(f.value, path)
+			if err != nil {
+				return nil, err
+			}
+			if string(value) == "{}" {
+				continue
+			}
+			f.value = value
+		}
+		kept = append(kept, f)
+	}
+
+	return encodeObject(kept), nil
+}
+
+// isAllocationSetting reports whether the dotted settings path names an
+// allocation setting or the object holding them.
+func isAllocationSetting(path string) bool {
+	if !strings.HasPrefix(path, "index.") {
+		path = "index." + path
+	}
+	return path+"." == allocationSettingsPrefix || strings.HasPrefix(path, allocationSettingsPrefix)
+}
+
+// stripAllocationSettings removes allocation settings from every fixture.
+func (l *Loader) stripAllocationSettings() error {
+	for _, f := range l.fixtures {
+		settings, err := withoutAllocationSettings(f.settings)
+		if err != nil {
+			return err
+		}
+		f.settings = settings
+	}
+	return nil
+}
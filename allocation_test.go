@@ -0,0 +1,62 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWithoutAllocationSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     string
+	}{
+		{
+			name:     "nested",
+			settings: `{"index":{"number_of_shards":1,"routing":{"allocation":{"include":{"_tier_preference":"data_hot"}}}}}`,
+			want:     `{"index":{"number_of_shards":1}}`,
+		},
+		{
+			name:     "dotted",
+			settings: `{"index.routing.allocation.require.box_type":"hot","index.number_of_replicas":0}`,
+			want:     `{"index.number_of_replicas":0}`,
+		},
+		{
+			name:     "without index prefix",
+			settings: `{"routing.allocation.include._tier_preference":"data_warm","routing":{"rebalance":{"enable":"all"}}}`,
+			want:     `{"routing":{"rebalance":{"enable":"all"}}}`,
+		},
+		{
+			name:     "mixed nesting",
+			settings: `{"index":{"routing.allocation":{"exclude":{"_name":"node-1"}}}}`,
+			want:     `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withoutAllocationSettings(json.RawMessage(tt.settings))
+			if err != nil {
+				t.Fatalf("withoutAllocationSettings() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripAllocationSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"logs/_settings.json": `{"number_of_shards":1,"index.routing.allocation.include._tier_preference":"data_hot"}`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir), StripAllocationSettings())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if got := string(loader.fixtures[0].settings); got != `{"number_of_shards":1}` {
+		t.Errorf("settings = %s", got)
+	}
+}
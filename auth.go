@@ -0,0 +1,127 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// credentialsEarlyRefresh is how long before their Expires time credentials
+// are replaced, so requests in flight do not race their expiry.
+const credentialsEarlyRefresh = time.Minute
+
+// Credentials authenticate the requests of a Loader. Set APIKey,
+// BearerToken, or Username and Password.
+type Credentials struct {
+	APIKey      string    // Encoded API key, sent as "ApiKey <key>"
+	BearerToken string    // OAuth2 access token or service account token
+	Username    string    // User of basic authentication
+	Password    string    // Password of basic authentication
+	Expires     time.Time // When the credentials stop working, or zero if unknown
+}
+
+// authorization returns the Authorization header value for c.
+func (c Credentials) authorization() (string, error) {
+	switch {
+	case c.APIKey != "":
+		return "ApiKey " + c.APIKey, nil
+	case c.BearerToken != "":
+		return "Bearer " + c.BearerToken, nil
+	case c.Username != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)), nil
+	}
+	return "", errors.New("credentials hold no API key, token, or username")
+}
+
+// CredentialProvider returns credentials for the requests of a Loader, such
+// as a short-lived API key or token from a secrets manager. It is called
+// with the context of the request that needs them.
+type CredentialProvider func(ctx context.Context) (Credentials, error)
+
+// credentialsTransport sets the Authorization header of each request from
+// a CredentialProvider, refreshing the credentials before they expire and
+// when Elasticsearch rejects them.
+type credentialsTransport struct {
+	next     esapi.Transport
+	provider CredentialProvider
+
+	mu      sync.Mutex
+	header  string    // Authorization header of the current credentials
+	expires time.Time // Expires of the current credentials
+	version int       // Incremented each time the credentials are replaced
+}
+
+func newCredentialsTransport(next esapi.Transport, provider CredentialProvider) *credentialsTransport {
+	return &credentialsTransport{next: next, provider: provider}
+}
+
+func (t *credentialsTransport) Perform(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		body = data
+	}
+
+	header, version, err := t.current(req.Context(), -1)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		req.Header.Set("Authorization", header)
+
+		res, err := t.next.Perform(req)
+		if err != nil || res.StatusCode != http.StatusUnauthorized || attempt == 1 {
+			return res, err
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+
+		// Rejected credentials are replaced once, by whichever request
+		// sees the rejection first.
+		if header, version, err = t.current(req.Context(), version); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// current returns the Authorization header to send and the version of the
+// credentials it comes from, asking the provider for new credentials if
+// there are none yet, if they are about to expire, or if they are still the
+// rejected version.
+func (t *credentialsTransport) current(ctx context.Context, rejected int) (string, int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	expiring := !t.expires.IsZero() && time.Until(t.expires) < credentialsEarlyRefresh
+	if t.header != "" && !expiring && t.version != rejected {
+		return t.header, t.version, nil
+	}
+
+	creds, err := t.provider(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("getting credentials: %w", err)
+	}
+	header, err := creds.authorization()
+	if err != nil {
+		return "", 0, fmt.Errorf("getting credentials: %w", err)
+	}
+	t.header, t.expires = header, creds.Expires
+	t.version++
+
+	return t.header, t.version, nil
+}
@@ -0,0 +1,165 @@
+package testfixtures
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCredentialsTransport_SetsAuthorization(t *testing.T) {
+	tests := []struct {
+		creds Credentials
+		want  string
+	}{
+		{Credentials{APIKey: "a2V5"}, "ApiKey a2V5"},
+		{Credentials{BearerToken: "tok"}, "Bearer tok"},
+		{Credentials{Username: "elastic", Password: "changeme"}, "Basic ZWxhc3RpYzpjaGFuZ2VtZQ=="},
+	}
+	for _, tt := range tests {
+		var got string
+		next := performFunc(func(req *http.Request) (*http.Response, error) {
+			got = req.Header.Get("Authorization")
+			return jsonResponse(200, `{}`), nil
+		})
+		tr := newCredentialsTransport(next, func(context.Context) (Credentials, error) { return tt.creds, nil })
+
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "ApiKey static")
+		if _, err := tr.Perform(req); err != nil {
+			t.Fatalf("Perform() error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Authorization = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCredentialsTransport_RefreshesOnUnauthorized(t *testing.T) {
+	var keys []string
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		if string(data) != `{"index":{}}` {
+			t.Errorf("body = %q, want it resent", data)
+		}
+		keys = append(keys, req.Header.Get("Authorization"))
+		if req.Header.Get("Authorization") == "ApiKey old" {
+			return jsonResponse(401, `{"error":{"type":"security_exception","reason":"api key is expired"},"status":401}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	})
+	var calls int
+	tr := newCredentialsTransport(next, func(context.Context) (Credentials, error) {
+		calls++
+		if calls == 1 {
+			return Credentials{APIKey: "old"}, nil
+		}
+		return Credentials{APIKey: "new"}, nil
+	})
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodPost, "/_bulk", strings.NewReader(`{"index":{}}`))
+		res, err := tr.Perform(req)
+		if err != nil {
+			t.Fatalf("Perform() error: %v", err)
+		}
+		if res.StatusCode != 200 {
+			t.Errorf("status = %d, want 200", res.StatusCode)
+		}
+	}
+	if want := []string{"ApiKey old", "ApiKey new", "ApiKey new"}; strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("sent %q, want %q", keys, want)
+	}
+	if calls != 2 {
+		t.Errorf("provider called %d times, want 2", calls)
+	}
+}
+
+func TestCredentialsTransport_RetriesOnce(t *testing.T) {
+	var sent int
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return jsonResponse(401, `{}`), nil
+	})
+	tr := newCredentialsTransport(next, func(context.Context) (Credentials, error) { return Credentials{APIKey: "bad"}, nil })
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	res, err := tr.Perform(req)
+	if err != nil {
+		t.Fatalf("Perform() error: %v", err)
+	}
+	if res.StatusCode != 401 || sent != 2 {
+		t.Errorf("got status %d after %d requests, want 401 after 2", res.StatusCode, sent)
+	}
+}
+
+func TestCredentialsTransport_RefreshesBeforeExpiry(t *testing.T) {
+	next := performFunc(func(req *http.Request) (*http.Response, error) { return jsonResponse(200, `{}`), nil })
+	var calls int
+	tr := newCredentialsTransport(next, func(context.Context) (Credentials, error) {
+		calls++
+		if calls == 1 {
+			return Credentials{BearerToken: "short", Expires: time.Now().Add(30 * time.Second)}, nil
+		}
+		return Credentials{BearerToken: "long", Expires: time.Now().Add(time.Hour)}, nil
+	})
+
+	for range 3 {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if _, err := tr.Perform(req); err != nil {
+			t.Fatalf("Perform() error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("provider called %d times, want 2", calls)
+	}
+}
+
+func TestCredentialsTransport_ProviderError(t *testing.T) {
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		t.Error("request sent without credentials")
+		return jsonResponse(200, `{}`), nil
+	})
+	tr := newCredentialsTransport(next, func(context.Context) (Credentials, error) { return Credentials{}, errors.New("vault sealed") })
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if _, err := tr.Perform(req); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("Perform() error = %v, want the provider error", err)
+	}
+
+	tr = newCredentialsTransport(next, func(context.Context) (Credentials, error) { return Credentials{}, nil })
+	if _, err := tr.Perform(req); err == nil {
+		t.Error("expected an error for empty credentials")
+	}
+}
+
+func TestWithCredentials(t *testing.T) {
+	var got string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("Authorization")
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": "- name: alice\n"})
+
+	l, err := New(client, Directory(dir), WithCredentials(func(context.Context) (Credentials, error) {
+		return Credentials{APIKey: "cm90YXRlZA=="}, nil
+	}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if _, err := l.client.Info(); err != nil {
+		t.Fatalf("Info() error: %v", err)
+	}
+	if got != "ApiKey cm90YXRlZA==" {
+		t.Errorf("Authorization = %q, want the provided API key", got)
+	}
+
+	if _, err := New(client, Directory(dir), WithCredentials(nil)); err == nil {
+		t.Error("expected an error for a nil provider")
+	}
+}
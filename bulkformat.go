@@ -0,0 +1,86 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// bulkMetaFields are the keys an action line of the bulk format may hold.
+// Only _id and the routing are honored; the rest, such as the _index of the
+// cluster the file was exported from, are ignored.
+var bulkMetaFields = map[string]bool{
+	"_index": true, "_id": true, "_type": true, "routing": true, "_routing": true,
+	"pipeline": true, "require_alias": true, "dynamic_templates": true,
+	"version": true, "version_type": true, "if_seq_no": true, "if_primary_term": true,
+}
+
+// bulkAction is the action line preceding a document in a bulk-format
+// NDJSON file, such as {"index":{"_id":"1"}} or {"create":{"_id":"2"}}.
+type bulkAction struct {
+	action  string // actionCreate, or empty for index
+	id      string
+	routing string
+}
+
+// isBulkAction reports whether an NDJSON line is a bulk action line rather
+// than a document: an object with a single action key whose value holds
+// nothing but bulk metadata. The first line of a file decides its format.
+func isBulkAction(line []byte) bool {
+	var action map[string]json.RawMessage
+	if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+		return false
+	}
+	for name, value := range action {
+		switch name {
+		case "index", "create", "update", "delete":
+		default:
+			return false
+		}
+		var meta map[string]json.RawMessage
+		if err := json.Unmarshal(value, &meta); err != nil || meta == nil {
+			return false
+		}
+		for key := range meta {
+			if !bulkMetaFields[key] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseBulkAction parses an action line of a bulk-format NDJSON file. Only
+// index and create actions are accepted, since every action of a fixture
+// file must be followed by a document.
+func parseBulkAction(line []byte) (bulkAction, error) {
+	var action map[string]json.RawMessage
+	if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+		return bulkAction{}, errors.New(`expected a bulk action line such as {"index":{}}`)
+	}
+
+	var a bulkAction
+	for name, value := range action {
+		switch name {
+		case "index":
+		case "create":
+			a.action = actionCreate
+		default:
+			return bulkAction{}, fmt.Errorf("bulk action %q is not supported in fixtures; use index or create", name)
+		}
+
+		var meta struct {
+			ID         string `json:"_id"`
+			Routing    string `json:"routing"`
+			OldRouting string `json:"_routing"` // Bulk files written before Elasticsearch 7
+		}
+		if err := json.Unmarshal(value, &meta); err != nil {
+			return bulkAction{}, fmt.Errorf("parsing %s action: %w", name, err)
+		}
+		a.id, a.routing = meta.ID, meta.Routing
+		if a.routing == "" {
+			a.routing = meta.OldRouting
+		}
+	}
+	return a, nil
+}
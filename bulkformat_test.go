@@ -0,0 +1,121 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFeedNDJSONFile_BulkFormat(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users/export.ndjson": {Data: []byte(`{"index":{"_index":"prod-users","_id":"1"}}
+{"name":"Alice"}
+
+{"create":{"_id":"2","routing":"eu"}}
+{"name":"Bob"}
+{"index":{}}
+{"name":"Carol"}
+`)},
+	}
+
+	var docs []Document
+	err := feedNDJSONFile(fsys, "users/export.ndjson", func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("feedNDJSONFile() error: %v", err)
+	}
+
+	want := []struct {
+		id, routing, source, location string
+		action                        string
+	}{
+		{id: "1", source: `{"name":"Alice"}`, location: "users/export.ndjson:1"},
+		{id: "2", routing: "eu", source: `{"name":"Bob"}`, location: "users/export.ndjson:4", action: actionCreate},
+		{source: `{"name":"Carol"}`, location: "users/export.ndjson:6"},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("expected %d documents, got %d", len(want), len(docs))
+	}
+	for i, w := range want {
+		d := docs[i]
+		if d.ID != w.id || d.Routing != w.routing || string(d.Source) != w.source || d.Location() != w.location || d.action != w.action {
+			t.Errorf("document %d: got %+v at %s, want %+v", i, d, d.Location(), w)
+		}
+	}
+}
+
+func TestFeedNDJSONFile_BulkFormatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "delete", data: "{\"index\":{}}\n{}\n{\"delete\":{\"_id\":\"1\"}}\n", want: `users/export.ndjson:3: bulk action "delete" is not supported`},
+		{name: "missing document", data: "{\"index\":{}}\n{}\n{\"create\":{}}\n", want: "users/export.ndjson:3: bulk action has no document line"},
+		{name: "not an action", data: "{\"index\":{}}\n{}\n{\"name\":\"x\"}\n{}\n", want: "users/export.ndjson:3: bulk action \"name\" is not supported"},
+		{name: "bad id", data: "{\"index\":{\"_id\":1}}\n{}\n", want: "users/export.ndjson:1: parsing index action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"users/export.ndjson": {Data: []byte(tt.data)}}
+			err := feedNDJSONFile(fsys, "users/export.ndjson", func(Document) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFeedNDJSONFile_PlainDocumentsNamedLikeActions(t *testing.T) {
+	// A document with a single field named like an action is not mistaken
+	// for a bulk action line.
+	fsys := fstest.MapFS{"users/events.ndjson": {Data: []byte("{\"index\":{\"name\":\"a\"}}\n{\"index\":{\"name\":\"b\"}}\n")}}
+
+	var n int
+	err := feedNDJSONFile(fsys, "users/events.ndjson", func(doc Document) error {
+		n++
+		if doc.ID != "" || doc.action != "" {
+			t.Errorf("expected a plain document, got %+v", doc)
+		}
+		return nil
+	})
+	if err != nil || n != 2 {
+		t.Errorf("expected 2 documents and no error, got %d and %v", n, err)
+	}
+}
+
+func TestLoad_BulkFormatActions(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/export.ndjson": "{\"create\":{\"_id\":\"1\",\"_routing\":\"eu\"}}\n{\"n\":1}\n{\"index\":{\"_id\":\"2\"}}\n{\"n\":2}\n",
+	})
+
+	var body string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			return jsonResponse(200, `{"errors":false,"items":[{"create":{"_id":"1","status":201}},{"index":{"_id":"2","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	for _, want := range []string{`{"create":{"_id":"1","routing":"eu"}}`, `{"index":{"_id":"2"}}`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the bulk request, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+const (
+	// defaultMaxContentLength is the default of http.max_content_length,
+	// used when the cluster setting cannot be read.
+	defaultMaxContentLength = 100 << 20
+
+	// defaultFlushBytes is the BulkIndexer's default flush threshold.
+	defaultFlushBytes = 5e6
+
+	// bulkMetaOverhead approximates the size of a bulk action line beyond
+	// the document ID.
+	bulkMetaOverhead = 64
+)
+
+// bulkConfig bounds the size of bulk requests and observes their outcome.
+type bulkConfig struct {
+	flushBytes      int // Buffered bytes at which a bulk request is sent
+	maxRequestBytes int // Largest request the cluster accepts (zero for no check)
+
+	onFlush   func(succeeded, failed int) // Called after each bulk request (may be nil)
+	onIndexed func(Document)              // Called for each document the cluster accepted (may be nil)
+
+	skip    func(Document) bool // Reports streamed documents to leave out (may be nil)
+	onQueue func(Document)      // Called for each streamed document before it is queued (may be nil)
+}
+
+// newBulkConfig returns a configuration for a cluster accepting requests of up to
+// maxRequestBytes. The flush threshold is kept at half the maximum or below,
+// so a buffered request plus one document smaller than the threshold always
+// fits; larger documents are sent in requests of their own.
+func newBulkConfig(maxRequestBytes int) bulkConfig {
+	return bulkConfig{
+		flushBytes:      min(defaultFlushBytes, maxRequestBytes/2),
+		maxRequestBytes: maxRequestBytes,
+	}
+}
+
+// bulkPayloadSize estimates the bytes doc adds to a bulk request.
+func bulkPayloadSize(doc Document) int {
+	return len(doc.Source) + len(doc.ID) + bulkMetaOverhead
+}
+
+// documentTooLarge describes a document that cannot fit in any bulk request.
+func documentTooLarge(doc Document, size, limit int) error {
+	var b strings.Builder
+	if loc := doc.Location(); loc != "" {
+		b.WriteString(loc)
+		b.WriteString(": ")
+	}
+	if doc.ID != "" {
+		fmt.Fprintf(&b, "document %q: ", doc.ID)
+	}
+	fmt.Fprintf(&b, "document is about %d bytes, larger than the %d-byte request limit (http.max_content_length); shrink the document or raise the limit", size, limit)
+
+	return fmt.Errorf("%s", b.String())
+}
+
+// maxContentLength returns the cluster's http.max_content_length in bytes.
+func maxContentLength(ctx context.Context, client *elasticsearch.Client) (int, error) {
+	res, err := client.Cluster.GetSettings(
+		client.Cluster.GetSettings.WithContext(ctx),
+		client.Cluster.GetSettings.WithIncludeDefaults(true),
+		client.Cluster.GetSettings.WithFlatSettings(true),
+		client.Cluster.GetSettings.WithFilterPath("*.http.max_content_length"),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("reading cluster settings: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return 0, fmt.Errorf("reading cluster settings: %w", err)
+	}
+
+	var settings map[string]map[string]string
+	if err := json.NewDecoder(res.Body).Decode(&settings); err != nil {
+		return 0, fmt.Errorf("decoding cluster settings: %w", err)
+	}
+
+	// Explicit settings take precedence over defaults
+	for _, scope := range []string{"transient", "persistent", "defaults"} {
+		if v, ok := settings[scope]["http.max_content_length"]; ok {
+			return parseByteSize(v)
+		}
+	}
+
+	return defaultMaxContentLength, nil
+}
+
+// parseByteSize parses an Elasticsearch byte size value such as "100mb".
+func parseByteSize(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	units := []struct {
+		suffix string
+		scale  int
+	}{
+		{"pb", 1 << 50}, {"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"b", 1},
+	}
+	scale := 1
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, scale = strings.TrimSuffix(s, u.suffix), u.scale
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	return int(n * float64(scale)), nil
+}
@@ -0,0 +1,111 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int{
+		"100mb":  100 << 20,
+		"1GB":    1 << 30,
+		"512kb":  512 << 10,
+		"1.5kb":  1536,
+		"2048b":  2048,
+		"123456": 123456,
+	}
+	for in, want := range tests {
+		got, err := parseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	if _, err := parseByteSize("lots"); err == nil {
+		t.Error("expected error for invalid byte size")
+	}
+}
+
+func TestMaxContentLength(t *testing.T) {
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"persistent":{"http.max_content_length":"10mb"},"defaults":{"http.max_content_length":"100mb"}}`), nil
+	}))
+
+	n, err := maxContentLength(context.Background(), client)
+	if err != nil || n != 10<<20 {
+		t.Errorf("maxContentLength() = %d, %v, want %d", n, err, 10<<20)
+	}
+}
+
+func TestBulkInsertPartition_SplitsOversizedDocuments(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests [][]string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+			var meta struct {
+				Index struct {
+					ID string `json:"_id"`
+				} `json:"index"`
+			}
+			if json.Unmarshal([]byte(line), &meta) == nil && meta.Index.ID != "" {
+				ids = append(ids, meta.Index.ID)
+			}
+		}
+		mu.Lock()
+		requests = append(requests, ids)
+		mu.Unlock()
+		return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+	}))
+
+	big := `{"text":"` + strings.Repeat("x", 300) + `"}`
+	docs := []Document{
+		{ID: "1", Source: json.RawMessage(`{"n":1}`)},
+		{ID: "big", Source: json.RawMessage(big)},
+		{ID: "2", Source: json.RawMessage(`{"n":2}`)},
+	}
+
+	cfg := bulkConfig{flushBytes: 200, maxRequestBytes: 1000}
+	if err := bulkInsertDocuments(context.Background(), client, "users", cfg, docs, 1, nil); err != nil {
+		t.Fatalf("bulkInsertDocuments() error: %v", err)
+	}
+
+	var sawBig bool
+	for _, ids := range requests {
+		for _, id := range ids {
+			if id == "big" {
+				sawBig = true
+				if len(ids) != 1 {
+					t.Errorf("expected the oversized document in a request of its own, got %v", ids)
+				}
+			}
+		}
+	}
+	if !sawBig {
+		t.Errorf("oversized document was not sent: %v", requests)
+	}
+}
+
+func TestBulkInsertPartition_DocumentTooLarge(t *testing.T) {
+	client := newFakeClient(t, roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+	}))
+
+	docs := []Document{{ID: "huge", Source: json.RawMessage(`{"text":"` + strings.Repeat("x", 2000) + `"}`), file: "logs/documents.yml", line: 3}}
+	err := bulkInsertDocuments(context.Background(), client, "logs", bulkConfig{flushBytes: 200, maxRequestBytes: 1000}, docs, 1, nil)
+	if err == nil {
+		t.Fatal("expected error for document larger than the request limit")
+	}
+	for _, want := range []string{"logs/documents.yml:3", `"huge"`, "http.max_content_length"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package testfixtures
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// CatIndex is a row of the cat indices API for an index managed by the
+// Loader. Counts and sizes are zero for closed indices.
+type CatIndex struct {
+	Index        string // Name of the index in the cluster
+	Health       string // green, yellow, or red
+	Status       string // open or close
+	Primaries    int    // Number of primary shards
+	Replicas     int    // Number of replicas per primary shard
+	DocsCount    int64  // Live documents, including nested ones
+	DocsDeleted  int64  // Deleted documents not yet merged away
+	StoreBytes   int64  // Size of all shard copies on disk
+	PrimaryBytes int64  // Size of the primary shards on disk
+}
+
+// CatAlias is a row of the cat aliases API for an alias of an index
+// managed by the Loader.
+type CatAlias struct {
+	Alias         string // Name of the alias
+	Index         string // Index the alias points to
+	Filter        bool   // Whether the alias filters documents
+	RoutingIndex  string // Routing of writes through the alias (empty if none)
+	RoutingSearch string // Routing of searches through the alias (empty if none)
+	IsWriteIndex  bool   // Whether Index is the write index of the alias
+}
+
+// CatShard is a row of the cat shards API for a shard copy of an index
+// managed by the Loader.
+type CatShard struct {
+	Index      string // Name of the index
+	Shard      int    // Shard number
+	Primary    bool   // Whether this copy is the primary
+	State      string // STARTED, RELOCATING, INITIALIZING, or UNASSIGNED
+	Docs       int64  // Documents in the copy (zero until it is started)
+	StoreBytes int64  // Size of the copy on disk
+	Node       string // Node holding the copy (empty if unassigned)
+}
+
+// managedIndices returns the names of the indices the Loader manages: those
+// of its index fixtures, and those made by ShrinkIndex, SplitIndex, and
+// CloneIndex.
+func (l *Loader) managedIndices() map[string]bool {
+	names := make(map[string]bool, len(l.fixtures)+len(l.derived))
+	for _, f := range l.fixtures {
+		if !f.isAlias() {
+			names[l.IndexName(f.name)] = true
+		}
+	}
+	for _, name := range l.derived {
+		names[name] = true
+	}
+	return names
+}
+
+// CatIndices returns the cat indices rows of the indices managed by the
+// Loader, ordered by name, leaving out every other index in the cluster.
+// Indices not created yet are missing from the result.
+func (l *Loader) CatIndices(ctx context.Context) ([]CatIndex, error) {
+	var rows []struct {
+		Index        string  `json:"index"`
+		Health       string  `json:"health"`
+		Status       string  `json:"status"`
+		Pri          string  `json:"pri"`
+		Rep          string  `json:"rep"`
+		DocsCount    *string `json:"docs.count"`
+		DocsDeleted  *string `json:"docs.deleted"`
+		StoreSize    *string `json:"store.size"`
+		PriStoreSize *string `json:"pri.store.size"`
+	}
+	err := l.cat("indices", &rows, func() (*esapi.Response, error) {
+		cat := l.client.Cat
+		return cat.Indices(
+			cat.Indices.WithContext(ctx),
+			cat.Indices.WithFormat("json"),
+			cat.Indices.WithBytes("b"),
+			cat.Indices.WithExpandWildcards("all"),
+			cat.Indices.WithH("index", "health", "status", "pri", "rep", "docs.count", "docs.deleted", "store.size", "pri.store.size"),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	managed := l.managedIndices()
+	var indices []CatIndex
+	for _, r := range rows {
+		if !managed[r.Index] {
+			continue
+		}
+		indices = append(indices, CatIndex{
+			Index:        r.Index,
+			Health:       r.Health,
+			Status:       r.Status,
+			Primaries:    int(catNumber(&r.Pri)),
+			Replicas:     int(catNumber(&r.Rep)),
+			DocsCount:    catNumber(r.DocsCount),
+			DocsDeleted:  catNumber(r.DocsDeleted),
+			StoreBytes:   catNumber(r.StoreSize),
+			PrimaryBytes: catNumber(r.PriStoreSize),
+		})
+	}
+	slices.SortFunc(indices, func(a, b CatIndex) int { return cmp.Compare(a.Index, b.Index) })
+
+	return indices, nil
+}
+
+// CatAliases returns the cat aliases rows of the aliases pointing to the
+// indices managed by the Loader, ordered by alias and index name. They
+// include the aliases of alias fixtures, _aliases.json, WithUniqueIndices,
+// and WithTenantAliases.
+func (l *Loader) CatAliases(ctx context.Context) ([]CatAlias, error) {
+	var rows []struct {
+		Alias         string `json:"alias"`
+		Index         string `json:"index"`
+		Filter        string `json:"filter"`
+		RoutingIndex  string `json:"routing.index"`
+		RoutingSearch string `json:"routing.search"`
+		IsWriteIndex  string `json:"is_write_index"`
+	}
+	err := l.cat("aliases", &rows, func() (*esapi.Response, error) {
+		cat := l.client.Cat
+		return cat.Aliases(
+			cat.Aliases.WithContext(ctx),
+			cat.Aliases.WithFormat("json"),
+			cat.Aliases.WithExpandWildcards("all"),
+			cat.Aliases.WithH("alias", "index", "filter", "routing.index", "routing.search", "is_write_index"),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	managed := l.managedIndices()
+	var aliases []CatAlias
+	for _, r := range rows {
+		if !managed[r.Index] {
+			continue
+		}
+		aliases = append(aliases, CatAlias{
+			Alias:         r.Alias,
+			Index:         r.Index,
+			Filter:        r.Filter == "*",
+			RoutingIndex:  catText(r.RoutingIndex),
+			RoutingSearch: catText(r.RoutingSearch),
+			IsWriteIndex:  r.IsWriteIndex == "true",
+		})
+	}
+	slices.SortFunc(aliases, func(a, b CatAlias) int {
+		return cmp.Or(cmp.Compare(a.Alias, b.Alias), cmp.Compare(a.Index, b.Index))
+	})
+
+	return aliases, nil
+}
+
+// CatShards returns the cat shards rows of the indices managed by the
+// Loader, ordered by index and shard number, with the primary before its
+// replicas.
+func (l *Loader) CatShards(ctx context.Context) ([]CatShard, error) {
+	var rows []struct {
+		Index  string  `json:"index"`
+		Shard  string  `json:"shard"`
+		PriRep string  `json:"prirep"`
+		State  string  `json:"state"`
+		Docs   *string `json:"docs"`
+		Store  *string `json:"store"`
+		Node   *string `json:"node"`
+	}
+	err := l.cat("shards", &rows, func() (*esapi.Response, error) {
+		cat := l.client.Cat
+		return cat.Shards(
+			cat.Shards.WithContext(ctx),
+			cat.Shards.WithFormat("json"),
+			cat.Shards.WithBytes("b"),
+			cat.Shards.WithH("index", "shard", "prirep", "state", "docs", "store", "node"),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	managed := l.managedIndices()
+	var shards []CatShard
+	for _, r := range rows {
+		if !managed[r.Index] {
+			continue
+		}
+		var node string
+		if r.Node != nil {
+			node = *r.Node
+		}
+		shards = append(shards, CatShard{
+			Index:      r.Index,
+			Shard:      int(catNumber(&r.Shard)),
+			Primary:    r.PriRep == "p",
+			State:      r.State,
+			Docs:       catNumber(r.Docs),
+			StoreBytes: catNumber(r.Store),
+			Node:       node,
+		})
+	}
+	slices.SortFunc(shards, func(a, b CatShard) int {
+		primary := func(s CatShard) int {
+			if s.Primary {
+				return 0
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.Index, b.Index), cmp.Compare(a.Shard, b.Shard), cmp.Compare(primary(a), primary(b)), cmp.Compare(a.Node, b.Node))
+	})
+
+	return shards, nil
+}
+
+// cat sends a cat API request and decodes its JSON rows into v. api names
+// the API in errors.
+func (l *Loader) cat(api string, v any, do func() (*esapi.Response, error)) error {
+	res, err := do()
+	if err != nil {
+		return fmt.Errorf("testfixtures: cat %s: %w", api, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("testfixtures: cat %s: %w", api, err)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("testfixtures: decoding cat %s: %w", api, err)
+	}
+
+	return nil
+}
+
+// catNumber parses a numeric cat column, which is null or empty where the
+// value is unknown, such as the document count of a closed index.
+func catNumber(s *string) int64 {
+	if s == nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(*s, 10, 64)
+	return n
+}
+
+// catText returns a text cat column, which is "-" where it has no value.
+func catText(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
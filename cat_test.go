@@ -0,0 +1,100 @@
+package testfixtures
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCat(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml":  "- _id: \"1\"\n",
+		"orders/documents.yml": "- _id: \"1\"\n",
+	})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if q := req.URL.Query(); q.Get("format") != "json" {
+			t.Errorf("%s: expected format=json, got %s", req.URL.Path, req.URL.RawQuery)
+		}
+		switch req.URL.Path {
+		case "/_cat/indices":
+			return jsonResponse(200, `[
+				{"index":"ci_users","health":"yellow","status":"open","pri":"1","rep":"1","docs.count":"3","docs.deleted":"1","store.size":"2048","pri.store.size":"1024"},
+				{"index":"ci_orders","health":"green","status":"close","pri":"2","rep":"0","docs.count":null,"docs.deleted":null,"store.size":null,"pri.store.size":null},
+				{"index":"other","health":"green","status":"open","pri":"1","rep":"0","docs.count":"9","docs.deleted":"0","store.size":"1","pri.store.size":"1"}
+			]`), nil
+		case "/_cat/aliases":
+			return jsonResponse(200, `[
+				{"alias":"ci_users_read","index":"ci_users","filter":"*","routing.index":"-","routing.search":"1","is_write_index":"-"},
+				{"alias":"ci_all","index":"ci_users","filter":"-","routing.index":"-","routing.search":"-","is_write_index":"true"},
+				{"alias":"other_read","index":"other","filter":"-","routing.index":"-","routing.search":"-","is_write_index":"-"}
+			]`), nil
+		case "/_cat/shards":
+			return jsonResponse(200, `[
+				{"index":"ci_users","shard":"0","prirep":"r","state":"UNASSIGNED","docs":null,"store":null,"node":null},
+				{"index":"ci_users","shard":"0","prirep":"p","state":"STARTED","docs":"3","store":"1024","node":"es01"},
+				{"index":"other","shard":"0","prirep":"p","state":"STARTED","docs":"9","store":"1","node":"es01"}
+			]`), nil
+		}
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+		return jsonResponse(404, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	ctx := context.Background()
+
+	indices, err := loader.CatIndices(ctx)
+	if err != nil {
+		t.Fatalf("CatIndices() error: %v", err)
+	}
+	wantIndices := []CatIndex{
+		{Index: "ci_orders", Health: "green", Status: "close", Primaries: 2},
+		{Index: "ci_users", Health: "yellow", Status: "open", Primaries: 1, Replicas: 1, DocsCount: 3, DocsDeleted: 1, StoreBytes: 2048, PrimaryBytes: 1024},
+	}
+	if len(indices) != len(wantIndices) {
+		t.Fatalf("expected %d indices, got %+v", len(wantIndices), indices)
+	}
+	for i, want := range wantIndices {
+		if indices[i] != want {
+			t.Errorf("index %d: expected %+v, got %+v", i, want, indices[i])
+		}
+	}
+
+	aliases, err := loader.CatAliases(ctx)
+	if err != nil {
+		t.Fatalf("CatAliases() error: %v", err)
+	}
+	wantAliases := []CatAlias{
+		{Alias: "ci_all", Index: "ci_users", IsWriteIndex: true},
+		{Alias: "ci_users_read", Index: "ci_users", Filter: true, RoutingSearch: "1"},
+	}
+	if len(aliases) != len(wantAliases) {
+		t.Fatalf("expected %d aliases, got %+v", len(wantAliases), aliases)
+	}
+	for i, want := range wantAliases {
+		if aliases[i] != want {
+			t.Errorf("alias %d: expected %+v, got %+v", i, want, aliases[i])
+		}
+	}
+
+	shards, err := loader.CatShards(ctx)
+	if err != nil {
+		t.Fatalf("CatShards() error: %v", err)
+	}
+	wantShards := []CatShard{
+		{Index: "ci_users", Primary: true, State: "STARTED", Docs: 3, StoreBytes: 1024, Node: "es01"},
+		{Index: "ci_users", State: "UNASSIGNED"},
+	}
+	if len(shards) != len(wantShards) {
+		t.Fatalf("expected %d shards, got %+v", len(wantShards), shards)
+	}
+	for i, want := range wantShards {
+		if shards[i] != want {
+			t.Errorf("shard %d: expected %+v, got %+v", i, want, shards[i])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LoadOption configures a single call to Load or LoadContext.
+type LoadOption func(*loadConfig)
+
+// loadConfig holds the settings of the LoadOptions given to one Load.
+type loadConfig struct {
+	resume bool
+}
+
+// Resume makes Load continue the load recorded in the checkpoint file of
+// WithCheckpoint instead of starting over: indices the interrupted Load
+// completed are kept as they are, and documents it had indexed into the
+// index it was working on are not sent again. Documents from providers are
+// always sent again. Without a checkpoint file, Load starts from scratch.
+func Resume() LoadOption {
+	return func(c *loadConfig) {
+		c.resume = true
+	}
+}
+
+// checkpoint is the progress of a Load, as written to the checkpoint file.
+type checkpoint struct {
+	Indices map[string]*indexCheckpoint `json:"indices"` // By fixture name
+}
+
+// indexCheckpoint is the progress of a single fixture index.
+type indexCheckpoint struct {
+	Index     string         `json:"index"`               // Name of the index in the cluster
+	Complete  bool           `json:"complete,omitempty"`  // Whether the index finished loading
+	Documents int            `json:"documents,omitempty"` // Documents in a complete index
+	Files     map[string]int `json:"files,omitempty"`     // Position in each file up to which every document was indexed
+}
+
+// indexed reports whether doc was indexed before the checkpoint was written.
+func (c *indexCheckpoint) indexed(doc Document) bool {
+	return doc.file != "" && doc.pos <= c.Files[doc.file]
+}
+
+// readCheckpoint reads a checkpoint file. A missing file is an empty
+// checkpoint.
+func readCheckpoint(path string) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("parsing checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// fileProgress tracks which documents of a file have been indexed, to find
+// the position up to which all of them have.
+type fileProgress struct {
+	sent []int        // Positions of the documents sent, in order
+	done map[int]bool // Positions that were indexed
+	next int          // Index in sent of the first document not yet indexed
+}
+
+// checkpointWriter records the progress of a Load in the checkpoint file as
+// bulk requests complete. Bulk requests finish on indexer goroutines, so its
+// methods may be called concurrently.
+type checkpointWriter struct {
+	path string
+
+	mu      sync.Mutex
+	state   checkpoint
+	current *indexCheckpoint         // Index being loaded
+	files   map[string]*fileProgress // Progress of current, by file
+	err     error                    // First error writing the file
+}
+
+// reset starts recording a new Load, continuing from cp.
+func (w *checkpointWriter) reset(cp checkpoint) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = checkpoint{Indices: map[string]*indexCheckpoint{}}
+	for name, ic := range cp.Indices {
+		if ic.Complete {
+			w.state.Indices[name] = ic
+		}
+	}
+	w.current, w.files, w.err = nil, nil, nil
+}
+
+// begin starts recording the progress of a fixture index, continuing from
+// resume if it is not nil.
+func (w *checkpointWriter) begin(fixture, index string, resume *indexCheckpoint) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.current = &indexCheckpoint{Index: index, Files: map[string]int{}}
+	if resume != nil {
+		for file, pos := range resume.Files {
+			w.current.Files[file] = pos
+		}
+	}
+	w.state.Indices[fixture] = w.current
+	w.files = map[string]*fileProgress{}
+}
+
+// expect registers documents about to be sent. Documents of a file must be
+// registered in order, but may be indexed in any order.
+func (w *checkpointWriter) expect(docs ...Document) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, doc := range docs {
+		if doc.file == "" {
+			continue
+		}
+		p := w.files[doc.file]
+		if p == nil {
+			p = &fileProgress{done: map[int]bool{}}
+			w.files[doc.file] = p
+		}
+		p.sent = append(p.sent, doc.pos)
+	}
+}
+
+// indexed records a document acknowledged by the cluster.
+func (w *checkpointWriter) indexed(doc Document) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p := w.files[doc.file]
+	if p == nil {
+		return
+	}
+	p.done[doc.pos] = true
+	for p.next < len(p.sent) && p.done[p.sent[p.next]] {
+		p.next++
+	}
+	if p.next > 0 {
+		w.current.Files[doc.file] = max(w.current.Files[doc.file], p.sent[p.next-1])
+	}
+}
+
+// flushed writes the checkpoint after a bulk request. A failure is kept
+// and reported by complete or failed.
+func (w *checkpointWriter) flushed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.save(); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// complete records that the current index finished loading.
+func (w *checkpointWriter) complete(docs int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	*w.current = indexCheckpoint{Index: w.current.Index, Complete: true, Documents: docs}
+	return w.save()
+}
+
+// failed writes the last progress of a Load that stopped with an error.
+func (w *checkpointWriter) failed() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.save()
+}
+
+// remove deletes the checkpoint file after a successful Load.
+func (w *checkpointWriter) remove() error {
+	if err := os.Remove(w.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// save writes the checkpoint file, replacing it atomically so an
+// interruption never leaves a partial file. The caller holds w.mu.
+func (w *checkpointWriter) save() error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithCheckpoint_Resume(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml": "- _id: 1\n  n: 1\n- _id: 2\n  n: 2\n",
+		"users/documents.yml":  "- _id: 1\n  n: 1\n- _id: 2\n  n: 2\n- _id: 3\n  n: 3\n",
+	})
+	path := filepath.Join(t.TempDir(), "load.checkpoint")
+
+	var (
+		failUsers bool
+		requests  []string
+		bulk      string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case req.URL.Path == "/orders/_bulk":
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":201}}]}`), nil
+		case req.URL.Path == "/users/_bulk":
+			body, _ := io.ReadAll(req.Body)
+			bulk = string(body)
+			if failUsers {
+				return jsonResponse(200, `{"errors":true,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}},{"index":{"_id":"3","status":201}}]}`), nil
+			}
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"2","status":201}},{"index":{"_id":"3","status":201}}]}`), nil
+		case req.Method == http.MethodHead:
+			return jsonResponse(200, ``), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithCheckpoint(path))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	failUsers = true
+	if err := loader.Load(); err == nil {
+		t.Fatal("expected the first Load to fail")
+	}
+
+	cp, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatalf("readCheckpoint() error: %v", err)
+	}
+	if orders := cp.Indices["orders"]; orders == nil || !orders.Complete || orders.Documents != 2 {
+		t.Errorf("expected orders to be recorded as complete, got %+v", orders)
+	}
+	// Document 3 was indexed, but document 2 before it was not.
+	if users := cp.Indices["users"]; users == nil || users.Complete || users.Files["users/documents.yml"] != 1 {
+		t.Errorf("expected users to be recorded up to document 1, got %+v", users)
+	}
+
+	failUsers = false
+	requests = nil
+	if err := loader.Load(Resume()); err != nil {
+		t.Fatalf("Load(Resume()) error: %v", err)
+	}
+	for _, unwanted := range []string{"DELETE /orders", "PUT /orders", "POST /orders/_bulk", "DELETE /users", "PUT /users"} {
+		if slices.Contains(requests, unwanted) {
+			t.Errorf("expected no request %q when resuming, got %v", unwanted, requests)
+		}
+	}
+	if strings.Contains(bulk, `"n":1`) || !strings.Contains(bulk, `"n":2`) || !strings.Contains(bulk, `"n":3`) {
+		t.Errorf("expected only documents 2 and 3 to be sent again, got %s", bulk)
+	}
+
+	results := loader.Results()
+	if len(results) != 2 || results[0].Skipped != 2 || results[0].Documents != 0 {
+		t.Errorf("expected orders to be skipped entirely, got %+v", results)
+	}
+	if len(results) == 2 && (results[1].Skipped != 1 || results[1].Documents != 2) {
+		t.Errorf("expected users to skip 1 document and send 2, got %+v", results[1])
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the checkpoint to be removed after a successful Load, got %v", err)
+	}
+}
+
+func TestWithCheckpoint_IndexNameChanged(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  n: 1\n",
+	})
+	path := filepath.Join(t.TempDir(), "load.checkpoint")
+	data, _ := json.Marshal(checkpoint{Indices: map[string]*indexCheckpoint{
+		"users": {Index: "users", Files: map[string]int{"users/documents.yml": 1}},
+	}})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := New(newOfflineClient(t), Directory(dir), WithCheckpoint(path), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(Resume()); err == nil || !strings.Contains(err.Error(), `"ci_users"`) {
+		t.Errorf("expected an error for a checkpoint of another index, got %v", err)
+	}
+}
+
+func TestResume_RequiresCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  n: 1\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(Resume()); err == nil {
+		t.Error("expected Resume without WithCheckpoint to be rejected")
+	}
+	if _, err := New(newOfflineClient(t), Directory(dir), WithCheckpoint("")); err == nil {
+		t.Error("expected an empty checkpoint path to be rejected")
+	}
+}
@@ -0,0 +1,578 @@
+// Package cli implements the esfixtures command, which loads Elasticsearch
+// test fixtures from the command line for provisioning local development
+// clusters with the same data the tests use. Run exposes the commands so
+// they can be embedded in other tools without shelling out to esfixtures.
+//
+// Usage:
+//
+//	esfixtures load [-config FILE] [-profile NAME] [-url URL] [-dir DIR] [-v | -q] [-no-color]
+//	esfixtures clean [-config FILE] [-profile NAME] [-url URL] [-dir DIR] [-v | -q] [-no-color]
+//	esfixtures fmt [-config FILE] [-profile NAME] [-dir DIR] [-l] [-q] [-no-color]
+//	esfixtures history [-config FILE] [-profile NAME] [-url URL] [-dir DIR] [-no-color]
+//	esfixtures cat indices|aliases|shards [-config FILE] [-profile NAME] [-url URL] [-dir DIR] [-no-color]
+//	esfixtures pack -o FILE -name NAME -version VERSION [-es RANGE] [-config FILE] [-profile NAME] [-dir DIR] [-q] [-no-color]
+//	esfixtures unpack [-config FILE] [-profile NAME] [-dir DIR] [-q] [-no-color] FILE
+//	esfixtures dump [-query JSON] [-include FIELDS] [-exclude FIELDS] [-max-docs N] [-docs-per-file N] [-config FILE] [-profile NAME] [-url URL] [-dir DIR] [-q] [-no-color] INDEX...
+//	esfixtures shell [-config FILE] [-profile NAME] [-url URL] [-dir DIR] [-v | -q] [-no-color]
+//
+// Connection settings and the fixtures directory may also be given in an
+// esfixtures.yml config file, with named profiles selected by -profile.
+// Flags take precedence over the config file.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	testfixtures "github.com/kurakura967/go-elasticsearch-testfixtures"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK    = 0 // The command succeeded
+	ExitError = 1 // The command failed
+	ExitUsage = 2 // The command line was invalid
+)
+
+// Defaults used when neither a flag nor the config file sets a value.
+const (
+	defaultURL = "http://localhost:9200"
+	defaultDir = "testdata/fixtures"
+)
+
+const usage = `Usage: esfixtures <command> [flags]
+
+Commands:
+  load    Recreate the fixture indices and load their documents
+  clean   Delete the fixture indices
+  fmt     Rewrite the fixture files in canonical form
+  history List recent loads into the cluster
+  cat     Show the indices, aliases, or shards of the fixtures in the cluster
+  pack    Bundle the fixtures into a versioned pack file for sharing
+  unpack  Extract a pack file into the fixtures directory
+  dump    Write indices of the cluster into the fixtures directory
+  shell   Run load, clean, count, diff, and cat interactively
+
+Run 'esfixtures <command> -h' for the flags of a command.
+`
+
+// IO holds the streams a command reads from and writes to.
+type IO struct {
+	Stdin  io.Reader // Commands of the shell command
+	Stdout io.Writer // Results and progress
+	Stderr io.Writer // Errors and, with -v, requests as curl commands
+}
+
+// Run executes a command line, such as []string{"load", "-profile", "ci"},
+// and returns the exit code. args does not include the program name.
+// Elasticsearch requests are made with ctx, so cancelling it stops a load.
+func Run(ctx context.Context, args []string, streams IO) int {
+	stdin, stdout, stderr := streams.Stdin, streams.Stdout, streams.Stderr
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	if len(args) == 0 {
+		_, _ = io.WriteString(stderr, usage)
+		return ExitUsage
+	}
+
+	var (
+		cmd      func(*testfixtures.Loader, *printer) error
+		loadOpts []testfixtures.LoadOption
+		flagArgs = args[1:]
+	)
+	switch args[0] {
+	case "load":
+		cmd = func(loader *testfixtures.Loader, p *printer) error { return load(loader, p, nil, loadOpts...) }
+	case "clean":
+		cmd = func(loader *testfixtures.Loader, p *printer) error { return clean(loader, p, nil) }
+	case "history":
+		cmd = func(loader *testfixtures.Loader, p *printer) error { return history(ctx, loader, p) }
+	case "cat":
+		var view string
+		if len(flagArgs) > 0 && !strings.HasPrefix(flagArgs[0], "-") {
+			view, flagArgs = flagArgs[0], flagArgs[1:]
+		}
+		if !isCatView(view) {
+			fmt.Fprintf(stderr, "esfixtures: cat needs one of indices, aliases, or shards\n\n%s", usage)
+			return ExitUsage
+		}
+		cmd = func(loader *testfixtures.Loader, p *printer) error { return catView(ctx, loader, p, view) }
+	case "fmt", "pack", "unpack":
+		// Need no cluster; run once the flags are parsed
+	case "dump":
+		// Needs no Loader; run once the client is created
+	case "shell":
+		// Makes a Loader per command; run once the client is created
+	case "-h", "-help", "--help", "help":
+		_, _ = io.WriteString(stdout, usage)
+		return ExitOK
+	default:
+		fmt.Fprintf(stderr, "esfixtures: unknown command %q\n\n%s", args[0], usage)
+		return ExitUsage
+	}
+
+	fs := flag.NewFlagSet("esfixtures "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", defaultConfigFile, "config file")
+	profile := fs.String("profile", "", "config profile to use")
+	url := fs.String("url", "", "Elasticsearch URL (default from config, $ELASTICSEARCH_URL, or "+defaultURL+")")
+	dir := fs.String("dir", "", "fixtures directory (default from config, or "+defaultDir+")")
+	prefix := fs.String("prefix", "", "prefix for the names of loaded or cleaned indices (default from config)")
+	verbose := fs.Bool("v", false, "verbose: also print every request as a curl command")
+	quiet := fs.Bool("q", false, "quiet: print errors only")
+	noColor := fs.Bool("no-color", false, "disable colored output (also set by $NO_COLOR)")
+	var list *bool
+	if args[0] == "fmt" {
+		list = fs.Bool("l", false, "list files whose formatting differs instead of rewriting them")
+	}
+	var output, packName, packVersion, packRange *string
+	if args[0] == "pack" {
+		output = fs.String("o", "", "pack file to write")
+		packName = fs.String("name", "", "name of the dataset")
+		packVersion = fs.String("version", "", "version of the dataset")
+		packRange = fs.String("es", "", `compatible Elasticsearch versions, such as ">=8.12 <9"`)
+	}
+	var query, include, exclude *string
+	var maxDocs, docsPerFile *int
+	if args[0] == "dump" {
+		query = fs.String("query", "", `query clause selecting the documents to dump, such as '{"term":{"active":true}}'`)
+		include = fs.String("include", "", "comma-separated _source fields to keep, with dotted paths and wildcards")
+		exclude = fs.String("exclude", "", "comma-separated _source fields to leave out, with dotted paths and wildcards")
+		maxDocs = fs.Int("max-docs", 0, "dump at most this many documents of each index (default all)")
+		docsPerFile = fs.Int("docs-per-file", 0, "split the documents of an index across files of at most this many (default one file)")
+	}
+	var checkpoint *string
+	var resume, noHistory *bool
+	if args[0] == "load" {
+		checkpoint = fs.String("checkpoint", "", "file recording the progress of the load, for -resume")
+		resume = fs.Bool("resume", false, "continue the interrupted load recorded in the -checkpoint file")
+		noHistory = fs.Bool("no-history", false, "do not record the load in the cluster's load history")
+	}
+	if err := fs.Parse(flagArgs); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if *verbose && *quiet {
+		fmt.Fprintln(stderr, "esfixtures: -v and -q are mutually exclusive")
+		return ExitUsage
+	}
+	switch {
+	case args[0] == "pack" && (*output == "" || *packName == "" || *packVersion == ""):
+		fmt.Fprintln(stderr, "esfixtures: pack requires -o, -name, and -version")
+		return ExitUsage
+	case args[0] == "unpack" && fs.NArg() != 1:
+		fmt.Fprintln(stderr, "esfixtures: unpack needs the pack file to extract")
+		return ExitUsage
+	case args[0] == "dump" && fs.NArg() == 0:
+		fmt.Fprintln(stderr, "esfixtures: dump needs the indices to dump")
+		return ExitUsage
+	}
+	if resume != nil && *resume {
+		if *checkpoint == "" {
+			fmt.Fprintln(stderr, "esfixtures: -resume requires -checkpoint")
+			return ExitUsage
+		}
+		loadOpts = append(loadOpts, testfixtures.Resume())
+	}
+
+	p := newPrinter(stdout, stderr, !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(stdout))
+	switch {
+	case *verbose:
+		p.level = levelVerbose
+	case *quiet:
+		p.level = levelQuiet
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	cfg, err := readConfig(*configPath, explicit["config"])
+	if err != nil {
+		p.errorf("%v", err)
+		return ExitError
+	}
+	conn, err := cfg.resolve(*profile)
+	if err != nil {
+		p.errorf("%v", err)
+		return ExitError
+	}
+	conn = conn.merge(connection{URL: *url, Dir: *dir, Prefix: *prefix})
+	if conn.URL == "" {
+		conn.URL = os.Getenv("ELASTICSEARCH_URL")
+	}
+	conn = connection{URL: defaultURL, Dir: defaultDir}.merge(conn)
+
+	if cmd == nil && args[0] != "shell" && args[0] != "dump" {
+		var err error
+		switch args[0] {
+		case "fmt":
+			err = format(conn.Dir, *list, p)
+		case "pack":
+			err = pack(conn.Dir, *output, testfixtures.PackManifest{Name: *packName, Version: *packVersion, Elasticsearch: *packRange}, p)
+		case "unpack":
+			err = unpack(fs.Arg(0), conn.Dir, p)
+		}
+		if err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{conn.URL},
+		Username:  conn.Username,
+		Password:  conn.Password,
+		APIKey:    conn.APIKey,
+	})
+	if err != nil {
+		p.errorf("creating client: %v", err)
+		return ExitError
+	}
+	if args[0] == "dump" {
+		var dumpOpts []testfixtures.DumpOption
+		if *query != "" {
+			dumpOpts = append(dumpOpts, testfixtures.DumpQuery(*query))
+		}
+		if *include != "" {
+			dumpOpts = append(dumpOpts, testfixtures.DumpSourceIncludes(splitList(*include)...))
+		}
+		if *exclude != "" {
+			dumpOpts = append(dumpOpts, testfixtures.DumpSourceExcludes(splitList(*exclude)...))
+		}
+		dumpOpts = append(dumpOpts, testfixtures.DumpMaxDocs(*maxDocs))
+		if err := dump(ctx, client, conn, fs.Args(), dumpOpts, *docsPerFile, p); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	opts := []testfixtures.Option{testfixtures.Directory(conn.Dir), testfixtures.WithContext(ctx)}
+	if conn.Prefix != "" {
+		opts = append(opts, testfixtures.WithIndexPrefix(conn.Prefix))
+	}
+	if conn.Strategy != "" {
+		opts = append(opts, testfixtures.WithStrategy(testfixtures.Strategy(conn.Strategy)))
+	}
+	if checkpoint != nil && *checkpoint != "" {
+		opts = append(opts, testfixtures.WithCheckpoint(*checkpoint))
+	}
+	if noHistory != nil && !*noHistory {
+		opts = append(opts, testfixtures.RecordHistory())
+	}
+	if conn.APIKeyCommand != "" {
+		opts = append(opts, testfixtures.WithCredentials(commandCredentials(conn.APIKeyCommand)))
+	}
+	if p.level == levelVerbose {
+		opts = append(opts, testfixtures.WithDebugRequests(stderr))
+	}
+	if args[0] == "load" || args[0] == "shell" {
+		// An interrupted load deletes the indices it created, or keeps
+		// them for -resume with -checkpoint
+		opts = append(opts, testfixtures.HandleSignals())
+	}
+	if args[0] == "shell" {
+		newLoader := func() (*testfixtures.Loader, error) { return testfixtures.New(client, opts...) }
+		if err := shell(ctx, stdin, newLoader, p); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	loader, err := testfixtures.New(client, opts...)
+	if err != nil {
+		p.errorf("%v", err)
+		return ExitError
+	}
+	for _, w := range loader.Warnings() {
+		p.warnf("%s", w)
+	}
+
+	if err := cmd(loader, p); err != nil {
+		return ExitError
+	}
+	return ExitOK
+}
+
+// load runs Loader.Load with opts, or Loader.LoadIndices if indices are
+// named, and reports the outcome of each index.
+func load(loader *testfixtures.Loader, p *printer, indices []string, opts ...testfixtures.LoadOption) error {
+	start := time.Now()
+	warned := len(loader.Warnings())
+	var err error
+	if len(indices) > 0 {
+		err = loader.LoadIndices(indices...)
+	} else {
+		err = loader.Load(opts...)
+	}
+	results := loader.Results()
+
+	// Components of _optional.yml the cluster cannot run are found by Load
+	for _, w := range loader.Warnings()[warned:] {
+		p.warnf("%s", w)
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			p.failure(r.Index, r.Err)
+			continue
+		}
+		detail := fmt.Sprintf("%d docs in %s", r.Documents, formatDuration(r.Duration))
+		if r.Skipped > 0 {
+			detail += fmt.Sprintf(", %d already loaded", r.Skipped)
+		}
+		p.success(r.Index, detail)
+	}
+	if err != nil {
+		// The failing index is reported above, but not the rollback of an
+		// interrupted load
+		if len(results) == 0 || results[len(results)-1].Err == nil || errors.Is(err, testfixtures.ErrInterrupted) {
+			p.errorf("%v", err)
+		}
+		return err
+	}
+
+	p.summary(results, time.Since(start))
+	return nil
+}
+
+// format runs Format, or CheckFormat if list is set, printing the files
+// that were (or would be) changed. Listing files that need formatting is
+// reported as a failure, so the command can gate CI.
+func format(dir string, list bool, p *printer) error {
+	fn := testfixtures.Format
+	if list {
+		fn = testfixtures.CheckFormat
+	}
+
+	changed, err := fn(dir)
+	if err != nil {
+		p.errorf("%v", err)
+		return err
+	}
+	for _, path := range changed {
+		p.infof("%s", path)
+	}
+	if list && len(changed) > 0 {
+		return fmt.Errorf("%d files need formatting", len(changed))
+	}
+	return nil
+}
+
+// pack runs Pack, writing the pack of the fixtures in dir to the file
+// output, and prints its name, version, and number of files.
+func pack(dir, output string, manifest testfixtures.PackManifest, p *printer) error {
+	f, err := os.Create(output)
+	if err != nil {
+		p.errorf("%v", err)
+		return err
+	}
+	manifest, err = testfixtures.Pack(f, dir, manifest)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(output)
+		p.errorf("%v", err)
+		return err
+	}
+	p.infof("Packed %s %s (%d files) into %s", manifest.Name, manifest.Version, len(manifest.Files), output)
+	return nil
+}
+
+// unpack runs Unpack, extracting the pack file input into dir, and prints
+// what it extracted.
+func unpack(input, dir string, p *printer) error {
+	f, err := os.Open(input)
+	if err != nil {
+		p.errorf("%v", err)
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	manifest, err := testfixtures.Unpack(f, dir)
+	if err != nil {
+		p.errorf("%v", err)
+		return err
+	}
+	detail := fmt.Sprintf("%d files", len(manifest.Files))
+	if manifest.Elasticsearch != "" {
+		detail += ", Elasticsearch " + manifest.Elasticsearch
+	}
+	p.infof("Unpacked %s %s (%s) into %s", manifest.Name, manifest.Version, detail, dir)
+	return nil
+}
+
+// dump runs Dumper.Dump for each of indices, writing them into the fixtures
+// directory of conn, and prints the progress and outcome of each. An
+// api_key_command is run once for the API key of the dump.
+func dump(ctx context.Context, client *elasticsearch.Client, conn connection, indices []string, opts []testfixtures.DumpOption, docsPerFile int, p *printer) error {
+	if conn.APIKeyCommand != "" {
+		creds, err := commandCredentials(conn.APIKeyCommand)(ctx)
+		if err != nil {
+			p.errorf("%v", err)
+			return err
+		}
+		if client, err = elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{conn.URL}, APIKey: creds.APIKey}); err != nil {
+			p.errorf("creating client: %v", err)
+			return err
+		}
+	}
+
+	var failed error
+	for _, index := range indices {
+		start := time.Now()
+		progress := testfixtures.DumpProgress(func(dumped, total int) {
+			p.infof("%s: %d/%d docs", index, dumped, total)
+		})
+		d := testfixtures.NewDumper(client, append(slices.Clone(opts), progress)...)
+		if err := d.Dump(ctx, index, conn.Dir, testfixtures.DocsPerFile(docsPerFile)); err != nil {
+			p.failure(index, err)
+			failed = err
+			continue
+		}
+		p.success(index, fmt.Sprintf("dumped into %s in %s", filepath.Join(conn.Dir, index), formatDuration(time.Since(start))))
+	}
+	return failed
+}
+
+// splitList splits a comma-separated flag value, dropping the spaces around
+// each item and empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// clean runs Loader.Clean, or Loader.CleanIndices if indices are named.
+func clean(loader *testfixtures.Loader, p *printer, indices []string) error {
+	var err error
+	if len(indices) > 0 {
+		err = loader.CleanIndices(indices...)
+	} else {
+		err = loader.Clean()
+	}
+	if err != nil {
+		p.errorf("%v", err)
+		return err
+	}
+	p.infof("Deleted fixture indices")
+	return nil
+}
+
+// count prints the number of documents searches of each of indices see.
+func count(ctx context.Context, loader *testfixtures.Loader, p *printer, indices []string) error {
+	var errs []error
+	for _, index := range indices {
+		n, err := loader.Count(ctx, index)
+		if err != nil {
+			p.errorf("%v", err)
+			errs = append(errs, err)
+			continue
+		}
+		p.infof("%s: %d documents", index, n)
+	}
+	return errors.Join(errs...)
+}
+
+// diff prints how the documents in the cluster differ from the fixtures,
+// for each of indices or, if none are named, every fixture index.
+func diff(ctx context.Context, loader *testfixtures.Loader, p *printer, indices []string) error {
+	if len(indices) == 0 {
+		indices = loader.Indices()
+	}
+	var errs []error
+	for _, index := range indices {
+		d, err := loader.Diff(ctx, index)
+		if err != nil {
+			p.errorf("%v", err)
+			errs = append(errs, err)
+			continue
+		}
+		p.diff(d)
+	}
+	return errors.Join(errs...)
+}
+
+// history prints the loads recorded in the cluster, newest first.
+func history(ctx context.Context, loader *testfixtures.Loader, p *printer) error {
+	records, err := loader.History(ctx)
+	if err != nil {
+		p.errorf("%v", err)
+		return err
+	}
+	if len(records) == 0 {
+		p.infof("No loads recorded in %s", testfixtures.HistoryIndex)
+		return nil
+	}
+	p.history(records)
+	return nil
+}
+
+// catView prints one of the cat views of the fixture indices: indices,
+// aliases, or shards.
+func catView(ctx context.Context, loader *testfixtures.Loader, p *printer, view string) error {
+	var err error
+	switch view {
+	case "indices":
+		var indices []testfixtures.CatIndex
+		if indices, err = loader.CatIndices(ctx); err == nil {
+			p.catIndices(indices)
+		}
+	case "aliases":
+		var aliases []testfixtures.CatAlias
+		if aliases, err = loader.CatAliases(ctx); err == nil {
+			p.catAliases(aliases)
+		}
+	case "shards":
+		var shards []testfixtures.CatShard
+		if shards, err = loader.CatShards(ctx); err == nil {
+			p.catShards(shards)
+		}
+	}
+	if err != nil {
+		p.errorf("%v", err)
+	}
+	return err
+}
+
+// isCatView reports whether view is one of the views of the cat command.
+func isCatView(view string) bool {
+	switch view {
+	case "indices", "aliases", "shards":
+		return true
+	}
+	return false
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,383 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	testfixtures "github.com/kurakura967/go-elasticsearch-testfixtures"
+)
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "no command", args: nil, want: ExitUsage},
+		{name: "unknown command", args: []string{"seed"}, want: ExitUsage},
+		{name: "verbose and quiet", args: []string{"load", "-v", "-q"}, want: ExitUsage},
+		{name: "help", args: []string{"help"}, want: ExitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := Run(context.Background(), tt.args, IO{Stdout: &stdout, Stderr: &stderr}); got != tt.want {
+				t.Errorf("Run(%v) = %d, want %d (stderr: %s)", tt.args, got, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestPrinter_Summary(t *testing.T) {
+	var out, errOut bytes.Buffer
+	p := newPrinter(&out, &errOut, false)
+
+	p.success("users", "2 docs in 5ms")
+	p.failure("orders", errors.New("bad mapping"))
+	p.summary([]testfixtures.IndexResult{
+		{Index: "products", Documents: 3, Duration: 12 * time.Millisecond},
+		{Index: "users", Documents: 2, Duration: 5 * time.Millisecond},
+	}, 20*time.Millisecond)
+
+	want := "✓ users (2 docs in 5ms)\n" +
+		"\n" +
+		"INDEX     DOCS  DURATION\n" +
+		"products  3     12ms\n" +
+		"users     2     5ms\n" +
+		"\n" +
+		"Loaded 2 indices (5 documents) in 20ms\n"
+	if out.String() != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out.String(), want)
+	}
+	if errOut.String() != "✗ orders: bad mapping\n" {
+		t.Errorf("stderr = %q", errOut.String())
+	}
+}
+
+func TestPrinter_QuietAndColor(t *testing.T) {
+	var out, errOut bytes.Buffer
+	p := newPrinter(&out, &errOut, true)
+	p.level = levelQuiet
+
+	p.success("users", "2 docs")
+	p.infof("done")
+	p.warnf("flattened")
+	p.errorf("boom")
+
+	if out.Len() != 0 {
+		t.Errorf("expected no stdout in quiet mode, got %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), colorRed+"error:"+colorReset+" boom") {
+		t.Errorf("expected colored error, got %q", errOut.String())
+	}
+	if strings.Contains(errOut.String(), "flattened") {
+		t.Errorf("expected no warnings in quiet mode, got %q", errOut.String())
+	}
+}
+
+func TestRun_LoadFailureIsReported(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"load", "-url", "http://127.0.0.1:1", "-dir", "../testdata/fixtures", "-config", writeConfig(t, ""), "-q"}
+
+	if got := Run(context.Background(), args, IO{Stdout: &stdout, Stderr: &stderr}); got != ExitError {
+		t.Fatalf("Run() = %d, want %d", got, ExitError)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no stdout with -q, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "products") {
+		t.Errorf("expected the failing index in stderr, got %q", stderr.String())
+	}
+}
+
+func TestRun_UnknownStrategy(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"load", "-url", "http://127.0.0.1:1", "-dir", "../testdata/fixtures", "-config", writeConfig(t, "strategy: reuse\n")}
+
+	if got := Run(context.Background(), args, IO{Stdout: &stdout, Stderr: &stderr}); got != ExitError {
+		t.Fatalf("Run() = %d, want %d", got, ExitError)
+	}
+	if !strings.Contains(stderr.String(), `unknown strategy "reuse"`) {
+		t.Errorf("expected the unknown strategy in stderr, got %q", stderr.String())
+	}
+}
+
+func TestRun_LoadInterruptedRollsBack(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending SIGINT to the test process needs a Unix-like system")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a_users/documents.yml":  "- _id: \"1\"\n",
+		"b_orders/documents.yml": "- _id: \"1\"\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu          sync.Mutex
+		interrupted bool
+		deleted     []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+
+		mu.Lock()
+		wasInterrupted := interrupted
+		if wasInterrupted && r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		if r.URL.Path == "/b_orders/_refresh" {
+			interrupted = true
+		}
+		mu.Unlock()
+
+		switch {
+		case !wasInterrupted && r.URL.Path == "/b_orders/_refresh":
+			// Interrupt the load once both indices are created, and fail
+			// the refresh as the cancelled request it becomes
+			if err := syscallInterrupt(); err != nil {
+				t.Error(err)
+			}
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+				t.Error("expected the signal to cancel the refresh")
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"error":{"type":"task_cancelled_exception"}}`)
+		case strings.HasSuffix(r.URL.Path, "/_bulk"):
+			_, _ = io.WriteString(w, `{"errors":false,"items":[]}`)
+		default:
+			_, _ = io.WriteString(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"load", "-url", srv.URL, "-dir", dir, "-config", writeConfig(t, ""), "-no-history"}
+	if got := Run(context.Background(), args, IO{Stdout: &stdout, Stderr: &stderr}); got != ExitError {
+		t.Fatalf("Run() = %d, want %d", got, ExitError)
+	}
+	if !strings.Contains(stderr.String(), testfixtures.ErrInterrupted.Error()) {
+		t.Errorf("expected ErrInterrupted in stderr, got %q", stderr.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"/a_users", "/b_orders"}; !slices.Equal(deleted, want) {
+		t.Errorf("expected the created indices %v to be deleted, got %v", want, deleted)
+	}
+}
+
+// syscallInterrupt sends SIGINT to the test process.
+func syscallInterrupt() error {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return p.Signal(os.Interrupt)
+}
+
+func TestRun_Fmt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users", "documents.yml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("- {name: Bob, _id: \"2\"}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := writeConfig(t, "dir: "+dir+"\n")
+
+	var stdout, stderr bytes.Buffer
+	streams := IO{Stdout: &stdout, Stderr: &stderr}
+
+	if got := Run(context.Background(), []string{"fmt", "-config", config, "-l"}, streams); got != ExitError {
+		t.Errorf("fmt -l on unformatted fixtures = %d, want %d", got, ExitError)
+	}
+	if !strings.Contains(stdout.String(), path) {
+		t.Errorf("expected %s to be listed, got %q", path, stdout.String())
+	}
+
+	if got := Run(context.Background(), []string{"fmt", "-config", config}, streams); got != ExitOK {
+		t.Fatalf("fmt = %d, want %d (stderr: %s)", got, ExitOK, stderr.String())
+	}
+	if got := Run(context.Background(), []string{"fmt", "-config", config, "-l"}, streams); got != ExitOK {
+		t.Errorf("fmt -l after fmt = %d, want %d", got, ExitOK)
+	}
+}
+
+func TestRun_PackUnpack(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users", "documents.yml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("- _id: \"1\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "users.tgz")
+	dest := filepath.Join(t.TempDir(), "fixtures")
+
+	var stdout, stderr bytes.Buffer
+	streams := IO{Stdout: &stdout, Stderr: &stderr}
+
+	if got := Run(context.Background(), []string{"pack", "-dir", dir, "-o", output}, streams); got != ExitUsage {
+		t.Errorf("pack without -name and -version = %d, want %d", got, ExitUsage)
+	}
+	if got := Run(context.Background(), []string{"pack", "-dir", dir, "-o", output, "-name", "users", "-version", "1.0.0", "-es", ">=8.12"}, streams); got != ExitOK {
+		t.Fatalf("pack = %d, want %d (stderr: %s)", got, ExitOK, stderr.String())
+	}
+	if got := Run(context.Background(), []string{"unpack", "-dir", dest}, streams); got != ExitUsage {
+		t.Errorf("unpack without a file = %d, want %d", got, ExitUsage)
+	}
+	if got := Run(context.Background(), []string{"unpack", "-dir", dest, output}, streams); got != ExitOK {
+		t.Fatalf("unpack = %d, want %d (stderr: %s)", got, ExitOK, stderr.String())
+	}
+
+	if !strings.Contains(stdout.String(), "Unpacked users 1.0.0 (1 files, Elasticsearch >=8.12)") {
+		t.Errorf("expected the unpacked pack to be described, got %q", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(dest, "users", "documents.yml")); err != nil {
+		t.Errorf("expected the documents to be unpacked: %v", err)
+	}
+}
+
+func TestRun_Dump(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		searches []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+
+		switch r.URL.Path {
+		case "/users/_mapping":
+			_, _ = io.WriteString(w, `{"users":{"mappings":{"properties":{"name":{"type":"keyword"}}}}}`)
+		case "/users/_settings":
+			_, _ = io.WriteString(w, `{"users":{"settings":{"index":{"number_of_shards":"1"}}}}`)
+		case "/users/_pit":
+			_, _ = io.WriteString(w, `{"id":"p1"}`)
+		case "/_search":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			searches = append(searches, string(body))
+			first := len(searches) == 1
+			mu.Unlock()
+			if first {
+				_, _ = io.WriteString(w, `{"pit_id":"p1","hits":{"total":{"value":1},"hits":[{"_id":"1","_source":{"name":"Alice"},"sort":[0]}]}}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"pit_id":"p1","hits":{"hits":[]}}`)
+		default:
+			_, _ = io.WriteString(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	streams := IO{Stdout: &stdout, Stderr: &stderr}
+
+	if got := Run(context.Background(), []string{"dump", "-url", srv.URL, "-dir", dir}, streams); got != ExitUsage {
+		t.Errorf("dump without indices = %d, want %d", got, ExitUsage)
+	}
+	args := []string{"dump", "-url", srv.URL, "-dir", dir, "-config", writeConfig(t, ""), "-query", `{"term":{"active":true}}`, "-exclude", "email, password", "users"}
+	if got := Run(context.Background(), args, streams); got != ExitOK {
+		t.Fatalf("dump = %d, want %d (stderr: %s)", got, ExitOK, stderr.String())
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "users", "documents.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "- _id: \"1\"\n  name: Alice\n"; string(got) != want {
+		t.Errorf("documents.yml: expected\n%s\ngot\n%s", want, got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(searches) == 0 || !strings.Contains(searches[0], `"query":{"term":{"active":true}}`) || !strings.Contains(searches[0], `"excludes":["email","password"]`) {
+		t.Errorf("expected the search to carry -query and -exclude, got %v", searches)
+	}
+	if !strings.Contains(stdout.String(), "users") {
+		t.Errorf("expected the dumped index to be reported, got %q", stdout.String())
+	}
+}
+
+func TestPrinter_History(t *testing.T) {
+	var out bytes.Buffer
+	p := newPrinter(&out, io.Discard, false)
+
+	p.history([]testfixtures.LoadRecord{{
+		User: "ci", Host: "runner-1", Time: time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local),
+		Source: "testdata/fixtures", FixtureHash: "0123456789abcdef", Indices: []string{"products", "users"},
+		Documents: 5, Duration: 1500 * time.Millisecond, Err: "boom",
+	}})
+
+	want := "TIME                 BY           FIXTURES                        INDICES  DOCS  DURATION  RESULT\n" +
+		"2026-10-14 09:00:00  ci@runner-1  testdata/fixtures 0123456789ab  2        5     1.5s      failed\n"
+	if out.String() != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRun_CatNeedsView(t *testing.T) {
+	for _, args := range [][]string{{"cat"}, {"cat", "-url", "http://127.0.0.1:1"}, {"cat", "nodes"}} {
+		var stderr bytes.Buffer
+		if got := Run(context.Background(), args, IO{Stderr: &stderr}); got != ExitUsage {
+			t.Errorf("Run(%q) = %d, want %d", args, got, ExitUsage)
+		}
+		if !strings.Contains(stderr.String(), "cat needs one of indices, aliases, or shards") {
+			t.Errorf("Run(%q): expected the cat views in stderr, got %q", args, stderr.String())
+		}
+	}
+}
+
+func TestPrinter_Cat(t *testing.T) {
+	var out bytes.Buffer
+	p := newPrinter(&out, io.Discard, false)
+
+	p.catIndices([]testfixtures.CatIndex{
+		{Index: "users", Health: "yellow", Status: "open", Primaries: 1, Replicas: 1, DocsCount: 3, StoreBytes: 5632},
+	})
+	p.catAliases([]testfixtures.CatAlias{
+		{Alias: "users_read", Index: "users", Filter: true, RoutingSearch: "1"},
+	})
+	p.catShards([]testfixtures.CatShard{
+		{Index: "users", Primary: true, State: "STARTED", Docs: 3, StoreBytes: 512, Node: "es01"},
+		{Index: "users", State: "UNASSIGNED"},
+	})
+
+	want := "INDEX  HEALTH  STATUS  PRI  REP  DOCS  DELETED  SIZE\n" +
+		"users  yellow  open    1    1    3     0        5.5kb\n" +
+		"ALIAS       INDEX  FILTER  ROUTING.INDEX  ROUTING.SEARCH  WRITE\n" +
+		"users_read  users  yes     -              1               no\n" +
+		"INDEX  SHARD  PRIREP  STATE       DOCS  SIZE  NODE\n" +
+		"users  0      p       STARTED     3     512b  es01\n" +
+		"users  0      r       UNASSIGNED  0     0b    -\n"
+	if out.String() != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	testfixtures "github.com/kurakura967/go-elasticsearch-testfixtures"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read from the working directory when -config is not given.
+const defaultConfigFile = "esfixtures.yml"
+
+// config is the contents of esfixtures.yml. Values may reference environment
+// variables as $VAR or ${VAR}, so secrets need not be written to the file.
+type config struct {
+	connection `yaml:",inline"`
+
+	// Profiles are named sets of values that override the top-level ones
+	// when selected with -profile.
+	Profiles map[string]connection `yaml:"profiles"`
+}
+
+// connection holds the values a config file or profile can set.
+type connection struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	APIKey   string `yaml:"api_key"`
+	Dir      string `yaml:"dir"`
+	Prefix   string `yaml:"prefix"`
+	Strategy string `yaml:"strategy"`
+
+	// APIKeyCommand is a shell command printing an encoded API key, run
+	// before the first request and again whenever Elasticsearch rejects
+	// the key, for keys that expire during long loads. It is not expanded,
+	// as the shell expands its variables.
+	APIKeyCommand string `yaml:"api_key_command"`
+}
+
+// readConfig reads a config file. A missing file is not an error unless
+// required is set.
+func readConfig(path string, required bool) (config, error) {
+	var cfg config
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("reading config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// resolve returns the top-level values overridden by the named profile, with
+// environment variables expanded. An empty profile selects the top level.
+func (c config) resolve(profile string) (connection, error) {
+	conn := c.connection
+	if profile != "" {
+		p, ok := c.Profiles[profile]
+		if !ok {
+			return connection{}, fmt.Errorf("unknown profile %q", profile)
+		}
+		conn = conn.merge(p)
+	}
+
+	conn = connection{
+		URL:      os.ExpandEnv(conn.URL),
+		Username: os.ExpandEnv(conn.Username),
+		Password: os.ExpandEnv(conn.Password),
+		APIKey:   os.ExpandEnv(conn.APIKey),
+		Dir:      os.ExpandEnv(conn.Dir),
+		Prefix:   os.ExpandEnv(conn.Prefix),
+		Strategy: os.ExpandEnv(conn.Strategy),
+
+		APIKeyCommand: conn.APIKeyCommand,
+	}
+
+	return conn, nil
+}
+
+// merge returns c with every non-empty value of o applied over it.
+func (c connection) merge(o connection) connection {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&c.URL, o.URL)
+	set(&c.Username, o.Username)
+	set(&c.Password, o.Password)
+	set(&c.APIKey, o.APIKey)
+	set(&c.Dir, o.Dir)
+	set(&c.Prefix, o.Prefix)
+	set(&c.Strategy, o.Strategy)
+	set(&c.APIKeyCommand, o.APIKeyCommand)
+	return c
+}
+
+// commandCredentials returns a credential provider running command with sh
+// and using its output, without surrounding whitespace, as the API key.
+func commandCredentials(command string) testfixtures.CredentialProvider {
+	return func(ctx context.Context) (testfixtures.Credentials, error) {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			return testfixtures.Credentials{}, fmt.Errorf("running api_key_command: %w", err)
+		}
+		key := strings.TrimSpace(string(out))
+		if key == "" {
+			return testfixtures.Credentials{}, errors.New("api_key_command printed no API key")
+		}
+		return testfixtures.Credentials{APIKey: key}, nil
+	}
+}
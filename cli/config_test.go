@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), defaultConfigFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_Profiles(t *testing.T) {
+	t.Setenv("CI_ES_API_KEY", "secret")
+	t.Setenv("CI_JOB_ID", "42")
+	path := writeConfig(t, `url: http://localhost:9200
+dir: testdata/fixtures
+profiles:
+  ci:
+    url: http://es-ci:9200
+    api_key: ${CI_ES_API_KEY}
+    prefix: ${CI_JOB_ID}_
+    strategy: truncate
+`)
+
+	cfg, err := readConfig(path, true)
+	if err != nil {
+		t.Fatalf("readConfig() error: %v", err)
+	}
+
+	base, err := cfg.resolve("")
+	if err != nil {
+		t.Fatalf("resolve() error: %v", err)
+	}
+	if base.URL != "http://localhost:9200" || base.APIKey != "" {
+		t.Errorf("unexpected top-level values: %+v", base)
+	}
+
+	ci, err := cfg.resolve("ci")
+	if err != nil {
+		t.Fatalf("resolve(ci) error: %v", err)
+	}
+	want := connection{URL: "http://es-ci:9200", APIKey: "secret", Dir: "testdata/fixtures", Prefix: "42_", Strategy: "truncate"}
+	if ci != want {
+		t.Errorf("resolve(ci) = %+v, want %+v", ci, want)
+	}
+
+	if _, err := cfg.resolve("staging"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestReadConfig_Errors(t *testing.T) {
+	if _, err := readConfig(filepath.Join(t.TempDir(), "missing.yml"), false); err != nil {
+		t.Errorf("expected missing optional config to be ignored, got %v", err)
+	}
+	if _, err := readConfig(filepath.Join(t.TempDir(), "missing.yml"), true); err == nil {
+		t.Error("expected error for missing required config")
+	}
+	if _, err := readConfig(writeConfig(t, "urll: http://localhost:9200\n"), true); err == nil {
+		t.Error("expected error for unknown config key")
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	creds, err := commandCredentials("printf ' a2V5\\n'")(context.Background())
+	if err != nil {
+		t.Fatalf("provider error: %v", err)
+	}
+	if creds.APIKey != "a2V5" {
+		t.Errorf("APIKey = %q, want %q", creds.APIKey, "a2V5")
+	}
+
+	if _, err := commandCredentials("true")(context.Background()); err == nil {
+		t.Error("expected an error for a command printing nothing")
+	}
+	_, err = commandCredentials("echo denied >&2; exit 1")(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("error = %v, want the command's stderr", err)
+	}
+}
@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	testfixtures "github.com/kurakura967/go-elasticsearch-testfixtures"
+)
+
+// level controls how much the CLI prints.
+type level int
+
+const (
+	levelQuiet   level = iota // Errors only
+	levelNormal               // Per-index results and a summary table
+	levelVerbose              // Also every request, as curl commands on stderr
+)
+
+// ANSI escape sequences used when color is enabled.
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBold   = "\x1b[1m"
+)
+
+// printer writes CLI output at the configured level.
+type printer struct {
+	out   io.Writer
+	err   io.Writer
+	color bool
+	level level
+}
+
+func newPrinter(out, err io.Writer, color bool) *printer {
+	return &printer{out: out, err: err, color: color, level: levelNormal}
+}
+
+// paint wraps s in the given color when color output is enabled.
+func (p *printer) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (p *printer) infof(format string, args ...any) {
+	if p.level < levelNormal {
+		return
+	}
+	fmt.Fprintf(p.out, format+"\n", args...)
+}
+
+// errorf prints an error regardless of level.
+func (p *printer) errorf(format string, args ...any) {
+	fmt.Fprintf(p.err, "%s %s\n", p.paint(colorRed, "error:"), fmt.Sprintf(format, args...))
+}
+
+// warnf prints a warning on stderr unless output is quiet.
+func (p *printer) warnf(format string, args ...any) {
+	if p.level < levelNormal {
+		return
+	}
+	fmt.Fprintf(p.err, "%s %s\n", p.paint(colorYellow, "warning:"), fmt.Sprintf(format, args...))
+}
+
+// success reports an index that loaded.
+func (p *printer) success(index, detail string) {
+	if p.level < levelNormal {
+		return
+	}
+	fmt.Fprintf(p.out, "%s %s (%s)\n", p.paint(colorGreen, "✓"), index, detail)
+}
+
+// failure reports an index that failed to load, regardless of level.
+func (p *printer) failure(index string, err error) {
+	fmt.Fprintf(p.err, "%s %s: %v\n", p.paint(colorRed, "✗"), index, err)
+}
+
+// summary prints a table of loaded indices followed by the totals.
+func (p *printer) summary(results []testfixtures.IndexResult, elapsed time.Duration) {
+	if p.level < levelNormal {
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	tw := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, p.paint(colorBold, "INDEX")+"\t"+p.paint(colorBold, "DOCS")+"\t"+p.paint(colorBold, "DURATION"))
+	total := 0
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", r.Index, r.Documents, formatDuration(r.Duration))
+		total += r.Documents
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(p.out, "\nLoaded %d indices (%d documents) in %s\n", len(results), total, formatDuration(elapsed))
+}
+
+// history prints a table of recorded loads. Failed loads are marked in red.
+func (p *printer) history(records []testfixtures.LoadRecord) {
+	if p.level < levelNormal {
+		return
+	}
+
+	tw := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, p.paint(colorBold, "TIME")+"\t"+p.paint(colorBold, "BY")+"\t"+p.paint(colorBold, "FIXTURES")+"\t"+
+		p.paint(colorBold, "INDICES")+"\t"+p.paint(colorBold, "DOCS")+"\t"+p.paint(colorBold, "DURATION")+"\t"+p.paint(colorBold, "RESULT"))
+	for _, r := range records {
+		hash := r.FixtureHash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		result := "ok"
+		if r.Err != "" {
+			result = p.paint(colorRed, "failed")
+		}
+		fmt.Fprintf(tw, "%s\t%s@%s\t%s %s\t%d\t%d\t%s\t%s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.User, r.Host, r.Source, hash,
+			len(r.Indices), r.Documents, formatDuration(r.Duration), result)
+	}
+	_ = tw.Flush()
+}
+
+// catIndices prints a table of fixture indices. Indices that are not
+// green are marked in yellow or red.
+func (p *printer) catIndices(indices []testfixtures.CatIndex) {
+	if p.level < levelNormal {
+		return
+	}
+
+	tw := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, p.paint(colorBold, "INDEX")+"\t"+p.paint(colorBold, "HEALTH")+"\t"+p.paint(colorBold, "STATUS")+"\t"+
+		p.paint(colorBold, "PRI")+"\t"+p.paint(colorBold, "REP")+"\t"+p.paint(colorBold, "DOCS")+"\t"+p.paint(colorBold, "DELETED")+"\t"+p.paint(colorBold, "SIZE"))
+	for _, i := range indices {
+		health := i.Health
+		switch health {
+		case "yellow":
+			health = p.paint(colorYellow, health)
+		case "red":
+			health = p.paint(colorRed, health)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			i.Index, health, i.Status, i.Primaries, i.Replicas, i.DocsCount, i.DocsDeleted, formatBytes(i.StoreBytes))
+	}
+	_ = tw.Flush()
+}
+
+// catAliases prints a table of the aliases of fixture indices.
+func (p *printer) catAliases(aliases []testfixtures.CatAlias) {
+	if p.level < levelNormal {
+		return
+	}
+
+	tw := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, p.paint(colorBold, "ALIAS")+"\t"+p.paint(colorBold, "INDEX")+"\t"+p.paint(colorBold, "FILTER")+"\t"+
+		p.paint(colorBold, "ROUTING.INDEX")+"\t"+p.paint(colorBold, "ROUTING.SEARCH")+"\t"+p.paint(colorBold, "WRITE"))
+	for _, a := range aliases {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Alias, a.Index, yesNo(a.Filter), dash(a.RoutingIndex), dash(a.RoutingSearch), yesNo(a.IsWriteIndex))
+	}
+	_ = tw.Flush()
+}
+
+// catShards prints a table of the shard copies of fixture indices.
+// Unassigned copies are marked in yellow.
+func (p *printer) catShards(shards []testfixtures.CatShard) {
+	if p.level < levelNormal {
+		return
+	}
+
+	tw := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, p.paint(colorBold, "INDEX")+"\t"+p.paint(colorBold, "SHARD")+"\t"+p.paint(colorBold, "PRIREP")+"\t"+
+		p.paint(colorBold, "STATE")+"\t"+p.paint(colorBold, "DOCS")+"\t"+p.paint(colorBold, "SIZE")+"\t"+p.paint(colorBold, "NODE"))
+	for _, s := range shards {
+		prirep := "r"
+		if s.Primary {
+			prirep = "p"
+		}
+		state := s.State
+		if state == "UNASSIGNED" {
+			state = p.paint(colorYellow, state)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t%s\t%s\n", s.Index, s.Shard, prirep, state, s.Docs, formatBytes(s.StoreBytes), dash(s.Node))
+	}
+	_ = tw.Flush()
+}
+
+// diff prints the differences between the documents of a fixture index in
+// the cluster and its fixtures: missing documents in red, changed ones in
+// yellow, and extra ones in green.
+func (p *printer) diff(d testfixtures.IndexDiff) {
+	if p.level < levelNormal {
+		return
+	}
+	if d.Empty() {
+		fmt.Fprintf(p.out, "%s %s: in sync\n", p.paint(colorGreen, "✓"), d.Index)
+		return
+	}
+
+	fmt.Fprintf(p.out, "%s %s: %d missing, %d changed, %d extra\n", p.paint(colorYellow, "≠"), d.Index,
+		len(d.Missing)+d.MissingUnkeyed, len(d.Changed), len(d.Extra))
+	for _, id := range d.Missing {
+		fmt.Fprintf(p.out, "  %s %s\n", p.paint(colorRed, "-"), id)
+	}
+	if d.MissingUnkeyed > 0 {
+		fmt.Fprintf(p.out, "  %s %d documents without _id\n", p.paint(colorRed, "-"), d.MissingUnkeyed)
+	}
+	for _, id := range d.Changed {
+		fmt.Fprintf(p.out, "  %s %s\n", p.paint(colorYellow, "~"), id)
+	}
+	for _, id := range d.Extra {
+		fmt.Fprintf(p.out, "  %s %s\n", p.paint(colorGreen, "+"), id)
+	}
+}
+
+// prompt prints the prompt of the shell, with no newline, regardless of
+// level.
+func (p *printer) prompt(s string) {
+	fmt.Fprint(p.out, p.paint(colorBold, s))
+}
+
+// yesNo formats a flag for a table column.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// dash formats an empty table cell.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatBytes formats a size in bytes with a binary unit for display.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%db", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cb", float64(n)/float64(div), "kmgtpe"[exp])
+}
+
+// formatDuration rounds d for display.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	testfixtures "github.com/kurakura967/go-elasticsearch-testfixtures"
+)
+
+// shellPrompt is printed before each command the shell reads.
+const shellPrompt = "esfixtures> "
+
+const shellHelp = `Commands:
+  load [INDEX...]              Recreate the fixture indices, or only the named ones, and load them
+  clean [INDEX...]             Delete the fixture indices, or only the named ones
+  count INDEX...               Count the documents searches of the indices or aliases see
+  diff [INDEX...]              Compare the documents in the cluster with the fixtures
+  cat indices|aliases|shards   Show the indices, aliases, or shards of the fixtures
+  help                         Show this help
+  exit                         Leave the shell (also Ctrl-D)
+`
+
+// shell reads commands from in until it ends, the exit command, or ctx is
+// done. The fixtures are read again for every command with newLoader, so
+// edits to the fixture files take effect without restarting the shell. A
+// failing command is reported and the shell goes on.
+func shell(ctx context.Context, in io.Reader, newLoader func() (*testfixtures.Loader, error), p *printer) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		p.prompt(shellPrompt)
+		var line string
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case l, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			line = l
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch name, args := args[0], args[1:]; name {
+		case "exit", "quit":
+			return nil
+		case "help":
+			p.infof("%s", strings.TrimSuffix(shellHelp, "\n"))
+		case "load", "clean", "count", "diff", "cat":
+			shellCommand(ctx, name, args, newLoader, p)
+		default:
+			p.errorf("unknown command %q (type help for the commands)", name)
+		}
+	}
+}
+
+// shellCommand runs one of the shell commands that use the cluster on a
+// Loader made for it. Its errors are printed by the command.
+func shellCommand(ctx context.Context, name string, args []string, newLoader func() (*testfixtures.Loader, error), p *printer) {
+	switch {
+	case name == "count" && len(args) == 0:
+		p.errorf("count needs a fixture index or alias")
+		return
+	case name == "cat" && (len(args) != 1 || !isCatView(args[0])):
+		p.errorf("cat needs one of indices, aliases, or shards")
+		return
+	}
+
+	loader, err := newLoader()
+	if err != nil {
+		p.errorf("%v", err)
+		return
+	}
+	if name == "load" {
+		for _, w := range loader.Warnings() {
+			p.warnf("%s", w)
+		}
+	}
+
+	switch name {
+	case "load":
+		_ = load(loader, p, args)
+	case "clean":
+		_ = clean(loader, p, args)
+	case "count":
+		_ = count(ctx, loader, p, args)
+	case "diff":
+		_ = diff(ctx, loader, p, args)
+	default:
+		_ = catView(ctx, loader, p, args[0])
+	}
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testfixtures "github.com/kurakura967/go-elasticsearch-testfixtures"
+)
+
+func TestRun_Shell(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users", "documents.yml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("- _id: \"1\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_count"):
+			_, _ = w.Write([]byte(`{"count":3}`))
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			_, _ = w.Write([]byte(`{"hits":{"hits":[{"_id":"1","_source":{}},{"_id":"2","_source":{}}]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	// The fixtures are read again by every command, so the second count
+	// sees the index added after the shell started.
+	var stdout, stderr bytes.Buffer
+	stdin := &shellInput{lines: []string{"help", "", "count users", "bogus", "count", "cat nodes", "diff", "count products", "exit", "count users"}, onRead: func(n int) {
+		if n == 7 {
+			products := filepath.Join(dir, "products", "documents.yml")
+			if err := os.MkdirAll(filepath.Dir(products), 0o755); err != nil {
+				t.Error(err)
+			}
+			if err := os.WriteFile(products, []byte("- _id: p1\n"), 0o644); err != nil {
+				t.Error(err)
+			}
+		}
+	}}
+	args := []string{"shell", "-url", server.URL, "-dir", dir, "-config", writeConfig(t, "")}
+	if got := Run(context.Background(), args, IO{Stdin: stdin, Stdout: &stdout, Stderr: &stderr}); got != ExitOK {
+		t.Fatalf("Run() = %d, want %d (stderr: %s)", got, ExitOK, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"esfixtures> Commands:", "users: 3 documents", "≠ users: 0 missing, 0 changed, 1 extra\n  + 2\n", "products: 3 documents"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in stdout, got %q", want, out)
+		}
+	}
+	if n := strings.Count(out, "users: 3 documents"); n != 1 {
+		t.Errorf("expected the shell to stop at exit, got %d counts of users", n)
+	}
+	for _, want := range []string{`unknown command "bogus"`, "count needs a fixture index or alias", "cat needs one of indices, aliases, or shards"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("expected %q in stderr, got %q", want, stderr.String())
+		}
+	}
+}
+
+// shellInput feeds lines to the shell one read at a time, calling onRead
+// with the number of the line about to be read.
+type shellInput struct {
+	lines  []string
+	read   int
+	onRead func(n int)
+}
+
+func (s *shellInput) Read(p []byte) (int, error) {
+	if s.read == len(s.lines) {
+		return 0, io.EOF
+	}
+	s.onRead(s.read)
+	n := copy(p, s.lines[s.read]+"\n")
+	s.read++
+	return n, nil
+}
+
+func TestPrinter_Diff(t *testing.T) {
+	var out bytes.Buffer
+	p := newPrinter(&out, io.Discard, false)
+
+	p.diff(testfixtures.IndexDiff{Index: "products"})
+	p.diff(testfixtures.IndexDiff{Index: "users", Missing: []string{"2"}, MissingUnkeyed: 1, Changed: []string{"3"}, Extra: []string{"9"}})
+
+	want := "✓ products: in sync\n" +
+		"≠ users: 2 missing, 1 changed, 1 extra\n" +
+		"  - 2\n" +
+		"  - 1 documents without _id\n" +
+		"  ~ 3\n" +
+		"  + 9\n"
+	if out.String() != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,67 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithClock(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": `- _id: "1"
+  expires_at: "{{ now "-1h" }}"
+- _id: "2"
+  created_at: "2020-01-01T00:00:00Z"
+`,
+	})
+
+	var bodies []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			data, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(data))
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	loader, err := New(client, Directory(dir),
+		WithTemplates(nil),
+		WithTimestampField("created_at"),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	for _, want := range []string{
+		`{"expires_at":"2024-06-01T02:00:00Z","created_at":"2024-06-01T03:00:00Z"}`,
+		`{"created_at":"2020-01-01T00:00:00Z"}`,
+	} {
+		if !strings.Contains(bodies[0], want) {
+			t.Errorf("expected %s in the bulk request, got:\n%s", want, bodies[0])
+		}
+	}
+
+	// Timestamp fields read the clock on each Load; templates were rendered by New.
+	now = now.Add(time.Minute)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if want := `{"expires_at":"2024-06-01T02:00:00Z","created_at":"2024-06-01T03:01:00Z"}`; !strings.Contains(bodies[1], want) {
+		t.Errorf("expected %s in the second bulk request, got:\n%s", want, bodies[1])
+	}
+}
+
+func TestWithClock_Nil(t *testing.T) {
+	if _, err := New(newOfflineClient(t), Directory(t.TempDir()), WithClock(nil)); err == nil || !strings.Contains(err.Error(), "clock must not be nil") {
+		t.Errorf("expected a nil clock error, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// clusterSettingsFile is the top-level file holding cluster settings that
+// Load applies as transient settings and Clean restores.
+const clusterSettingsFile = "_cluster_settings.json"
+
+// readClusterSettings reads _cluster_settings.json, whose settings may be
+// nested objects or dotted names, and returns them by dotted name. A missing
+// file means there are none.
+func readClusterSettings(fsys fs.FS, dir string) (map[string]json.RawMessage, error) {
+	body, err := readJSONFile(fsys, path.Join(dir, clusterSettingsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", clusterSettingsFile, err)
+	}
+	if !isJSONObject(body) {
+		return nil, fmt.Errorf("%s: settings must be an object", clusterSettingsFile)
+	}
+
+	settings := make(map[string]json.RawMessage)
+	if err := flattenSettings(body, "", settings); err != nil {
+		return nil, fmt.Errorf("%s: %w", clusterSettingsFile, err)
+	}
+	return settings, nil
+}
+
+// flattenSettings adds the settings of the JSON object obj to flat by dotted
+// name, with prefix before each name.
+func flattenSettings(obj json.RawMessage, prefix string, flat map[string]json.RawMessage) error {
+	fields, err := decodeObject(obj)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		name := prefix + f.key
+		if isJSONObject(f.value) {
+			if err := flattenSettings(f.value, name+".", flat); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := flat[name]; ok {
+			return fmt.Errorf("setting %q is set twice", name)
+		}
+		flat[name] = f.value
+	}
+	return nil
+}
+
+// applyClusterSettings sets the settings of _cluster_settings.json as
+// transient cluster settings. The first time, it records their previous
+// transient values for restoreClusterSettings, so later loads do not take
+// the fixture values for the cluster's own.
+func (l *Loader) applyClusterSettings(ctx context.Context) error {
+	if len(l.clusterSettings) == 0 {
+		return nil
+	}
+
+	if l.clusterRestore == nil {
+		current, err := transientClusterSettings(ctx, l.client)
+		if err != nil {
+			return err
+		}
+		restore := make(map[string]json.RawMessage, len(l.clusterSettings))
+		for name := range l.clusterSettings {
+			restore[name] = json.RawMessage("null")
+			if v, ok := current[name]; ok {
+				restore[name] = v
+			}
+		}
+		l.clusterRestore = restore
+	}
+
+	return putTransientClusterSettings(ctx, l.client, l.clusterSettings)
+}
+
+// restoreClusterSettings gives the settings of _cluster_settings.json their
+// transient values from before the first Load again, resetting those that
+// had none.
+func (l *Loader) restoreClusterSettings(ctx context.Context) error {
+	if l.clusterRestore == nil {
+		return nil
+	}
+	if err := putTransientClusterSettings(ctx, l.client, l.clusterRestore); err != nil {
+		return err
+	}
+	l.clusterRestore = nil
+	return nil
+}
+
+// transientClusterSettings returns the transient cluster settings by dotted
+// name.
+func transientClusterSettings(ctx context.Context, client *elasticsearch.Client) (map[string]json.RawMessage, error) {
+	res, err := client.Cluster.GetSettings(
+		client.Cluster.GetSettings.WithContext(ctx),
+		client.Cluster.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster settings: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, fmt.Errorf("reading cluster settings: %w", err)
+	}
+
+	var settings struct {
+		Transient map[string]json.RawMessage `json:"transient"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&settings); err != nil {
+		return nil, fmt.Errorf("decoding cluster settings: %w", err)
+	}
+
+	return settings.Transient, nil
+}
+
+// putTransientClusterSettings sets the given transient cluster settings; a
+// null value resets a setting.
+func putTransientClusterSettings(ctx context.Context, client *elasticsearch.Client, settings map[string]json.RawMessage) error {
+	fields := make([]jsonField, 0, len(settings))
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		fields = append(fields, jsonField{key: name, value: settings[name]})
+	}
+	body := encodeObject([]jsonField{{key: "transient", value: encodeObject(fields)}})
+
+	res, err := client.Cluster.PutSettings(bytes.NewReader(body), client.Cluster.PutSettings.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("updating cluster settings: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("updating cluster settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadClusterSettings(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_cluster_settings.json": {Data: []byte(`{"search": {"max_buckets": 20000}, "cluster.routing.allocation.disk.watermark.low": "95%", "indices": {"breaker": {"total.limit": "80%"}}}`)},
+	}
+
+	settings, err := readClusterSettings(fsys, ".")
+	if err != nil {
+		t.Fatalf("readClusterSettings() error: %v", err)
+	}
+	want := map[string]string{
+		"search.max_buckets":                            `20000`,
+		"cluster.routing.allocation.disk.watermark.low": `"95%"`,
+		"indices.breaker.total.limit":                   `"80%"`,
+	}
+	if len(settings) != len(want) {
+		t.Fatalf("expected %d settings, got %v", len(want), settings)
+	}
+	for name, v := range want {
+		if got := string(settings[name]); got != v {
+			t.Errorf("%s = %s, want %s", name, got, v)
+		}
+	}
+
+	if settings, err := readClusterSettings(fstest.MapFS{}, "."); settings != nil || err != nil {
+		t.Errorf("expected no settings for a missing file, got %v, %v", settings, err)
+	}
+}
+
+func TestReadClusterSettings_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not an object", data: `["search.max_buckets"]`, want: "_cluster_settings.json: settings must be an object"},
+		{name: "set twice", data: `{"search.max_buckets": 1, "search": {"max_buckets": 2}}`, want: `_cluster_settings.json: setting "search.max_buckets" is set twice`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"_cluster_settings.json": {Data: []byte(tt.data)}}
+			_, err := readClusterSettings(fsys, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ClusterSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_cluster_settings.json": `{"search": {"max_buckets": 20000}, "cluster.routing.allocation.disk.threshold_enabled": false}`,
+		"users/documents.yml":    "- _id: \"1\"\n",
+	})
+
+	var (
+		puts []string
+		gets int
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/_cluster/settings" {
+			switch req.Method {
+			case http.MethodGet:
+				if req.URL.Query().Get("include_defaults") == "true" {
+					return jsonResponse(200, `{"defaults":{}}`), nil
+				}
+				gets++
+				return jsonResponse(200, `{"persistent":{},"transient":{"search.max_buckets":"65536"}}`), nil
+			case http.MethodPut:
+				body, _ := io.ReadAll(req.Body)
+				puts = append(puts, string(body))
+			}
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	for range 2 {
+		if err := loader.Load(); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+	}
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+
+	apply := `{"transient":{"cluster.routing.allocation.disk.threshold_enabled":false,"search.max_buckets":20000}}`
+	restore := `{"transient":{"cluster.routing.allocation.disk.threshold_enabled":null,"search.max_buckets":"65536"}}`
+	if want := []string{apply, apply, restore}; strings.Join(puts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected the settings applied twice and restored once, got:\n%s", strings.Join(puts, "\n"))
+	}
+	if gets != 1 {
+		t.Errorf("expected only the first Load to read the values to restore, got %d reads", gets)
+	}
+
+	puts = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("second Clean() error: %v", err)
+	}
+	if len(puts) != 0 {
+		t.Errorf("expected nothing to restore after Clean, got %v", puts)
+	}
+}
@@ -0,0 +1,17 @@
+// Command esfixtures loads Elasticsearch test fixtures from the command line.
+// See package cli for the commands and flags.
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/kurakura967/go-elasticsearch-testfixtures/cli"
+)
+
+// main leaves SIGINT and SIGTERM to the Loader, which traps them during a
+// load to roll back the indices it created; cancelling the context given to
+// Run instead would skip the rollback.
+func main() {
+	os.Exit(cli.Run(context.Background(), os.Args[1:], cli.IO{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}))
+}
@@ -0,0 +1,154 @@
+package testfixtures
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"sort"
+)
+
+// unionFS layers the fixture file systems combined by Compose. A file in a
+// later layer shadows the file at the same path in earlier ones, and
+// directories list the files of every layer.
+type unionFS []fs.FS
+
+// Open opens the file name of the latest layer that has it. A directory
+// lists the entries of all layers.
+func (u unionFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	i, info, err := u.find(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if !info.IsDir() {
+		return u[i].Open(name)
+	}
+
+	entries, err := u.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &unionDir{info: info, entries: entries}, nil
+}
+
+// ReadFile reads the file name of the latest layer that has it.
+func (u unionFS) ReadFile(name string) ([]byte, error) {
+	i, _, err := u.find(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return fs.ReadFile(u[i], name)
+}
+
+// Stat describes the file name of the latest layer that has it.
+func (u unionFS) Stat(name string) (fs.FileInfo, error) {
+	_, info, err := u.find(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// ReadDir lists the entries of the directory name in every layer, sorted by
+// name. An entry of a later layer replaces one of the same name.
+func (u unionFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		entries []fs.DirEntry
+		seen    = make(map[string]bool)
+		found   bool
+	)
+	for i := len(u) - 1; i >= 0; i-- {
+		layer, err := fs.ReadDir(u[i], name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range layer {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// find returns the latest layer holding name, and its description there.
+func (u unionFS) find(name string) (int, fs.FileInfo, error) {
+	for i := len(u) - 1; i >= 0; i-- {
+		info, err := fs.Stat(u[i], name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		return i, info, nil
+	}
+	return 0, nil, fs.ErrNotExist
+}
+
+// unionDir is a directory opened from a unionFS.
+type unionDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *unionDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *unionDir) Close() error               { return nil }
+
+func (d *unionDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir returns the next n entries of the directory, or all remaining ones
+// if n <= 0, as fs.ReadDirFile requires.
+func (d *unionDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	d.offset += len(rest)
+	return slices.Clone(rest), nil
+}
+
+// orderByLayer reorders the file documents of each fixture by the layer of
+// Compose their file comes from, keeping the order within a layer, so that a
+// document of a later source is written after, and so replaces, one with
+// the same _id from an earlier source.
+func (l *Loader) orderByLayer(u unionFS) {
+	layer := make(map[string]int)
+	for _, f := range l.fixtures {
+		sort.SliceStable(f.documents, func(i, j int) bool {
+			return layerOf(u, layer, f.documents[i].file) < layerOf(u, layer, f.documents[j].file)
+		})
+	}
+}
+
+// layerOf returns the layer of u that the fixture file name is read from,
+// caching results in cache.
+func layerOf(u unionFS, cache map[string]int, name string) int {
+	if i, ok := cache[name]; ok {
+		return i
+	}
+	i, _, _ := u.find(name)
+	cache[name] = i
+	return i
+}
@@ -0,0 +1,199 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUnionFS(t *testing.T) {
+	u := unionFS{
+		fstest.MapFS{
+			"users/_mapping.json":  {Data: []byte(`{"base":true}`)},
+			"users/documents.yml":  {Data: []byte("base")},
+			"orders/documents.yml": {Data: []byte("orders")},
+		},
+		fstest.MapFS{
+			"users/_mapping.json": {Data: []byte(`{"extra":true}`)},
+			"users/extra.yml":     {Data: []byte("extra")},
+		},
+	}
+
+	if err := fstest.TestFS(u, "users/_mapping.json", "users/documents.yml", "users/extra.yml", "orders/documents.yml"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(u, "users/_mapping.json")
+	if err != nil || string(data) != `{"extra":true}` {
+		t.Errorf("expected the later layer's file, got %q, %v", data, err)
+	}
+
+	entries, err := fs.ReadDir(u, "users")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"_mapping.json", "documents.yml", "extra.yml"}; !slices.Equal(names, want) {
+		t.Errorf("expected entries %v, got %v", want, names)
+	}
+
+	if _, err := fs.Stat(u, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	base := t.TempDir()
+	writeFixtureFiles(t, base, map[string]string{
+		"users/_mapping.json": `{"properties":{"name":{"type":"text"}}}`,
+		"users/documents.yml": "- _id: \"1\"\n  name: base\n- _id: \"2\"\n  name: base\n",
+	})
+	override := fstest.MapFS{
+		"fixtures/users/_mapping.json": {Data: []byte(`{"properties":{"name":{"type":"keyword"}}}`)},
+		"fixtures/users/a.yml":         {Data: []byte("- _id: \"2\"\n  name: override\n")},
+	}
+	extra := NewDataset().
+		Settings("orders", map[string]any{"number_of_shards": 1}).
+		Add("orders", "o1", map[string]any{"user": "1"}).
+		Add("users", "3", struct {
+			Name string `json:"name"`
+		}{Name: "built"})
+
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] += string(data)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	provider := DocumentProviderFunc(func(context.Context, string) (iter.Seq2[Document, error], error) {
+		return func(yield func(Document, error) bool) {
+			yield(Document{ID: "2", Source: json.RawMessage(`{"name":"provider"}`)}, nil)
+		}, nil
+	})
+	loader, err := New(client, Compose(
+		Directory(base),
+		FS(override, "fixtures"),
+		Builder(extra),
+		WithProvider("users", provider),
+	))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if !strings.Contains(bodies["/users"], `"keyword"`) {
+		t.Errorf("expected the mapping of the later source, got %s", bodies["/users"])
+	}
+	if !strings.Contains(bodies["/orders"], `"number_of_shards":1`) {
+		t.Errorf("expected the dataset's settings, got %s", bodies["/orders"])
+	}
+	if want := `{"user":"1"}`; !strings.Contains(bodies["/orders/_bulk"], want) {
+		t.Errorf("expected %s in the orders bulk request, got:\n%s", want, bodies["/orders/_bulk"])
+	}
+
+	// Documents are written in source order, whatever their file names.
+	users := bodies["/users/_bulk"]
+	var order []string
+	for _, name := range []string{`"name":"base"}`, `"name":"override"`, `"name":"built"`, `"name":"provider"`} {
+		order = append(order, name)
+		if !strings.Contains(users, name) {
+			t.Fatalf("expected %s in the users bulk request, got:\n%s", name, users)
+		}
+	}
+	if !slices.IsSortedFunc(order, func(a, b string) int {
+		return strings.Index(users, a) - strings.Index(users, b)
+	}) {
+		t.Errorf("expected documents in source order, got:\n%s", users)
+	}
+}
+
+func TestCompose_DedupeByIDKeepsLaterSource(t *testing.T) {
+	base := fstest.MapFS{"users/z.yml": {Data: []byte("- _id: \"1\"\n  name: base\n")}}
+	extra := NewDataset().Add("users", "1", map[string]string{"name": "extra"})
+
+	var body string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Compose(FS(base, "."), Builder(extra)), DedupeByID())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !strings.Contains(body, `"name":"extra"`) || strings.Contains(body, `"name":"base"`) {
+		t.Errorf("expected only the later source's document, got:\n%s", body)
+	}
+}
+
+func TestCompose_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{name: "other option", opt: Compose(Directory(t.TempDir()), DedupeByID()), want: "source 1: Compose accepts only Directory, FS, Builder, and WithProvider"},
+		{name: "failing source", opt: Compose(FS(nil, ".")), want: "source 0: fixtures file system must not be nil"},
+		{name: "nil source", opt: Compose(nil), want: "source 0 must not be nil"},
+		{name: "nil dataset", opt: Builder(nil), want: "dataset must not be nil"},
+		{name: "empty dataset", opt: Builder(NewDataset()), want: "dataset: dataset has no indices"},
+		{name: "bad index", opt: Builder(NewDataset().Add("_users", "1", map[string]any{})), want: `dataset: invalid index name "_users"`},
+		{name: "not an object", opt: Builder(NewDataset().Add("users", "1", []int{1})), want: `dataset: index "users": document "1": must be a JSON object, got [1]`},
+		{name: "marshal error", opt: Builder(NewDataset().Mapping("users", func() {})), want: `dataset: index "users": _mapping.json: json: unsupported type`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(newOfflineClient(t), tt.opt)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestBuilder_DocumentLocations(t *testing.T) {
+	loader, err := New(newOfflineClient(t), Builder(NewDataset().
+		Add("users", "1", map[string]any{"_routing": "eu", "name": "a"}).
+		Add("users", "", map[string]any{})))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	docs := loader.fixture("users").documents
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+	if d := docs[0]; d.ID != "1" || d.Routing != "eu" || string(d.Source) != `{"name":"a"}` || d.Location() != "users/dataset.json:2" {
+		t.Errorf("unexpected first document %+v at %s", d, d.Location())
+	}
+	if d := docs[1]; d.ID != "" || string(d.Source) != `{}` || d.Location() != "users/dataset.json:3" {
+		t.Errorf("unexpected second document %+v at %s", d, d.Location())
+	}
+}
@@ -0,0 +1,69 @@
+package testfixtures
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the optional per-index configuration file.
+const configFile = "_config.yml"
+
+// indexConfig is the contents of an index's _config.yml.
+type indexConfig struct {
+	// References maps document fields to the fixture index whose _id values
+	// they refer to, e.g. {author_id: users}. Checked by Validate.
+	References map[string]string `yaml:"references"`
+
+	// InheritCommon deep-merges _common/_mapping.json and _common/_settings.json
+	// under this index's own files, which override them key by key.
+	InheritCommon bool `yaml:"inherit_common"`
+
+	// Alias makes the directory an alias over other fixture indices instead
+	// of an index with documents of its own.
+	Alias *aliasConfig `yaml:"alias"`
+
+	// State is the state the index is left in after Load: open (the
+	// default), closed, or read_only.
+	State string `yaml:"state"`
+
+	// Strategy overrides the Strategy of WithStrategy for the index:
+	// recreate, truncate, or sync.
+	Strategy string `yaml:"strategy"`
+
+	// GeoJSON configures the documents read from *.geojson files.
+	GeoJSON geojsonConfig `yaml:"geojson"`
+}
+
+// readIndexConfig reads an index's _config.yml. Unknown keys are rejected so
+// that typos do not silently disable a setting. A missing file yields the
+// zero configuration.
+func readIndexConfig(fsys fs.FS, name string) (indexConfig, error) {
+	var cfg indexConfig
+
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("reading %s: %w", configFile, err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("parsing %s: %w", configFile, err)
+	}
+	if err := checkIndexState(cfg.State); err != nil {
+		return cfg, err
+	}
+	if err := checkIndexStrategy(cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,142 @@
+package testfixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+// datasetFile is the document file a Dataset writes the documents added to
+// an index to.
+const datasetFile = "dataset.json"
+
+// Dataset builds fixtures in Go code, for the cases where writing the files
+// is more awkward than constructing the values, such as documents derived
+// from test constants. It is loaded with Builder, on its own or composed with
+// fixture directories by Compose, and reads like a fixture directory: a
+// mapping, settings, and documents per index.
+//
+//	users := testfixtures.NewDataset().
+//		Mapping("users", mapping).
+//		Add("users", "1", User{Name: "Alice"})
+//
+// Values are marshaled with encoding/json when they are given, so later
+// changes to them do not reach the Dataset. The first marshaling error is
+// returned by Builder.
+type Dataset struct {
+	indices []string                   // Index names in the order first used
+	files   map[string]json.RawMessage // _mapping.json and _settings.json by path
+	docs    map[string][][]byte        // Document lines by index
+	err     error
+}
+
+// NewDataset returns an empty Dataset.
+func NewDataset() *Dataset {
+	return &Dataset{
+		files: make(map[string]json.RawMessage),
+		docs:  make(map[string][][]byte),
+	}
+}
+
+// Mapping sets the mapping of index, as in its _mapping.json file.
+func (d *Dataset) Mapping(index string, mapping any) *Dataset {
+	return d.setFile(index, "_mapping.json", mapping)
+}
+
+// Settings sets the settings of index, as in its _settings.json file.
+func (d *Dataset) Settings(index string, settings any) *Dataset {
+	return d.setFile(index, "_settings.json", settings)
+}
+
+// Add adds a document with the given _id to index. source must marshal to a
+// JSON object; it may use the _routing and _traits keys of document files.
+// An empty id lets Elasticsearch assign one.
+func (d *Dataset) Add(index, id string, source any) *Dataset {
+	if !d.use(index) {
+		return d
+	}
+	doc, err := json.Marshal(source)
+	if err != nil {
+		d.fail(fmt.Errorf("index %q: document %q: %w", index, id, err))
+		return d
+	}
+	doc = bytes.TrimSpace(doc)
+	if len(doc) == 0 || doc[0] != '{' {
+		d.fail(fmt.Errorf("index %q: document %q: must be a JSON object, got %s", index, id, doc))
+		return d
+	}
+	if id != "" {
+		key, _ := json.Marshal(id)
+		rest := doc[1:]
+		if string(bytes.TrimSpace(rest)) != "}" {
+			rest = append([]byte{','}, rest...)
+		}
+		doc = append(append([]byte(`{"_id":`), key...), rest...)
+	}
+	d.docs[index] = append(d.docs[index], doc)
+	return d
+}
+
+// setFile marshals v as the file name of index.
+func (d *Dataset) setFile(index, name string, v any) *Dataset {
+	if !d.use(index) {
+		return d
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		d.fail(fmt.Errorf("index %q: %s: %w", index, name, err))
+		return d
+	}
+	d.files[path.Join(index, name)] = data
+	return d
+}
+
+// use records index as part of the dataset, reporting whether it is a valid
+// fixture directory name.
+func (d *Dataset) use(index string) bool {
+	if index == "" || strings.ContainsAny(index, "/\\") || strings.HasPrefix(index, "_") || index == "." || index == ".." {
+		d.fail(fmt.Errorf("invalid index name %q", index))
+		return false
+	}
+	if _, ok := d.docs[index]; !ok {
+		d.indices = append(d.indices, index)
+		d.docs[index] = nil
+	}
+	return true
+}
+
+// fail records the first error of the dataset.
+func (d *Dataset) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// mapFS returns the fixture directory the dataset stands for.
+func (d *Dataset) mapFS() (fstest.MapFS, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.indices) == 0 {
+		return nil, errors.New("dataset has no indices")
+	}
+
+	fsys := make(fstest.MapFS)
+	for name, data := range d.files {
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	for _, index := range d.indices {
+		fsys[index] = &fstest.MapFile{Mode: fs.ModeDir | 0o755}
+		if docs := d.docs[index]; len(docs) > 0 {
+			// One document per line, so error locations tell them apart.
+			data := append([]byte("[\n"), bytes.Join(docs, []byte(",\n"))...)
+			fsys[path.Join(index, datasetFile)] = &fstest.MapFile{Data: append(data, "\n]\n"...)}
+		}
+	}
+	return fsys, nil
+}
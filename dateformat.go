@@ -0,0 +1,437 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDateFormat is the format of date fields whose mapping sets none.
+const defaultDateFormat = "strict_date_optional_time||epoch_millis"
+
+// dateFormat is the format of a date field, compiled to the Go layouts its
+// alternatives (separated by ||) accept.
+type dateFormat struct {
+	text         string
+	alternatives []dateAlternative
+}
+
+// dateAlternative is a single format of a dateFormat.
+type dateAlternative struct {
+	name    string   // Name or pattern, as written in the mapping
+	epoch   string   // "millis" or "second" for the epoch formats
+	layouts []string // Go layouts accepting the same values
+	unknown bool     // Whether the format cannot be checked, so any value passes
+}
+
+// epochValue matches the numbers accepted by epoch_millis and epoch_second.
+var epochValue = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// withZones returns layout followed by each form of time zone the ISO 8601
+// formats accept: Z, +09:00, +0900, and +09.
+func withZones(layout string) []string {
+	return []string{layout + "Z07:00", layout + "Z0700", layout + "Z07"}
+}
+
+// optionalZone returns layout with and without a time zone.
+func optionalZone(layout string) []string {
+	return append([]string{layout}, withZones(layout)...)
+}
+
+// isoDateOptionalTime holds the layouts of date_optional_time: a date of
+// year, month, and day, each part after the year optional, then an
+// optional time of day whose minutes, seconds, fraction, and zone are
+// optional too.
+var isoDateOptionalTime = slices.Concat(
+	[]string{"2006", "2006-01", "2006-01-02"},
+	optionalZone("2006-01-02T15"),
+	optionalZone("2006-01-02T15:04"),
+	optionalZone("2006-01-02T15:04:05"),
+)
+
+// builtinDateFormats maps the built-in date format names of Elasticsearch,
+// without their strict_ prefix, to Go layouts in their strict form. Formats
+// missing here, such as the week-based ones, are not checked.
+var builtinDateFormats = map[string][]string{
+	"date_optional_time":       isoDateOptionalTime,
+	"date_optional_time_nanos": isoDateOptionalTime,
+	"iso8601":                  isoDateOptionalTime,
+
+	"date":                              {"2006-01-02"},
+	"year_month_day":                    {"2006-01-02"},
+	"year_month":                        {"2006-01"},
+	"year":                              {"2006"},
+	"date_hour":                         {"2006-01-02T15"},
+	"date_hour_minute":                  {"2006-01-02T15:04"},
+	"date_hour_minute_second":           {"2006-01-02T15:04:05"},
+	"date_hour_minute_second_fraction":  {"2006-01-02T15:04:05"},
+	"date_hour_minute_second_millis":    {"2006-01-02T15:04:05"},
+	"date_time":                         withZones("2006-01-02T15:04:05"),
+	"date_time_no_millis":               withZones("2006-01-02T15:04:05"),
+	"ordinal_date":                      {"2006-002"},
+	"ordinal_date_time":                 withZones("2006-002T15:04:05"),
+	"ordinal_date_time_no_millis":       withZones("2006-002T15:04:05"),
+	"hour":                              {"15"},
+	"hour_minute":                       {"15:04"},
+	"hour_minute_second":                {"15:04:05"},
+	"hour_minute_second_fraction":       {"15:04:05"},
+	"hour_minute_second_millis":         {"15:04:05"},
+	"time":                              withZones("15:04:05"),
+	"time_no_millis":                    withZones("15:04:05"),
+	"t_time":                            withZones("T15:04:05"),
+	"t_time_no_millis":                  withZones("T15:04:05"),
+	"basic_date":                        {"20060102"},
+	"basic_date_time":                   withZones("20060102T150405.000"),
+	"basic_date_time_no_millis":         withZones("20060102T150405"),
+	"basic_ordinal_date":                {"2006002"},
+	"basic_ordinal_date_time":           withZones("2006002T150405.000"),
+	"basic_ordinal_date_time_no_millis": withZones("2006002T150405"),
+	"basic_time":                        withZones("150405.000"),
+	"basic_time_no_millis":              withZones("150405"),
+	"basic_t_time":                      withZones("T150405.000"),
+	"basic_t_time_no_millis":            withZones("T150405"),
+}
+
+// lenientLayout relaxes a strict layout to accept the single-digit months,
+// days, minutes, and seconds that formats without the strict_ prefix allow.
+var lenientLayout = strings.NewReplacer("-01", "-1", "-02", "-2", ":04", ":4", ":05", ":5")
+
+// builtinFormatName matches names that look like built-in formats rather
+// than patterns, such as strict_weekyear_week.
+var builtinFormatName = regexp.MustCompile(`^[a-z]+(_[a-z]+)+$`)
+
+// parseDateFormat compiles the format of a date field. locale is the
+// mapping's locale, which decides the month and day names patterns accept.
+func parseDateFormat(format, locale string) dateFormat {
+	df := dateFormat{text: format}
+	for _, name := range strings.Split(format, "||") {
+		name = strings.TrimSpace(name)
+		alt := dateAlternative{name: name}
+		base := strings.TrimPrefix(name, "strict_")
+		switch layouts, ok := builtinDateFormats[base]; {
+		case base == "epoch_millis" || base == "epoch_second":
+			alt.epoch = strings.TrimPrefix(base, "epoch_")
+		case ok:
+			if base == name && !strings.HasPrefix(base, "basic_") {
+				relaxed := make([]string, len(layouts))
+				for i, l := range layouts {
+					relaxed[i] = lenientLayout.Replace(l)
+				}
+				layouts = relaxed
+			}
+			alt.layouts = layouts
+		case builtinFormatName.MatchString(name):
+			alt.unknown = true
+		default:
+			alt.layouts, ok = javaLayouts(name, englishLocale(locale))
+			alt.unknown = !ok
+		}
+		df.alternatives = append(df.alternatives, alt)
+	}
+	return df
+}
+
+// englishLocale reports whether a mapping locale names months and days in
+// English, as the default root locale does.
+func englishLocale(locale string) bool {
+	locale = strings.ToLower(locale)
+	return locale == "" || locale == "root" || locale == "en" || strings.HasPrefix(locale, "en_") || strings.HasPrefix(locale, "en-")
+}
+
+// match returns the first alternative of df that accepts value, as
+// Elasticsearch tries them in order. The result is nil but true when an
+// alternative that cannot be checked might accept it.
+func (df dateFormat) match(value string) (*dateAlternative, bool) {
+	for i := range df.alternatives {
+		alt := &df.alternatives[i]
+		switch {
+		case alt.unknown:
+			return nil, true
+		case alt.epoch != "":
+			if epochValue.MatchString(value) {
+				return alt, true
+			}
+		default:
+			for _, layout := range alt.layouts {
+				if _, err := time.Parse(layout, value); err == nil {
+					return alt, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// epochWarning describes an epoch value that is probably in the other unit:
+// seconds since 2001 read as epoch_millis fall in the first years after
+// 1970, and milliseconds read as epoch_second fall after the year 5000.
+func (alt *dateAlternative) epochWarning(value string) string {
+	if alt == nil || alt.epoch == "" {
+		return ""
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return ""
+	}
+
+	asMillis := time.UnixMilli(int64(n)).UTC().Format(time.RFC3339)
+	switch abs := math.Abs(n); {
+	case alt.epoch == "millis" && abs >= 1e9 && abs < 1e11:
+		return fmt.Sprintf("%s is read as epoch_millis, which is %s; if it counts seconds, use epoch_second", value, asMillis)
+	case alt.epoch == "second" && abs >= 1e11:
+		return fmt.Sprintf("%s is read as epoch_second, which is after the year 5000; if it counts milliseconds (%s), use epoch_millis", value, asMillis)
+	}
+	return ""
+}
+
+// javaLayouts converts a Java date pattern, such as "yyyy-MM-dd HH:mm:ss",
+// to the Go layouts accepting the same values, one for each combination of
+// its optional [sections]. It reports false for patterns using letters with
+// no Go equivalent, and for month and day names outside english locales.
+func javaLayouts(pattern string, english bool) ([]string, bool) {
+	p := &javaPattern{src: pattern, english: english}
+	layouts := p.sequence()
+	if p.err || p.pos < len(p.src) || len(layouts) == 0 {
+		return nil, false
+	}
+	return layouts, true
+}
+
+// javaPattern is the state of converting a Java date pattern.
+type javaPattern struct {
+	src     string
+	pos     int
+	english bool
+	err     bool
+}
+
+// javaLetters maps pattern letters and their repeat counts to Go layout
+// elements. A count of 0 stands for any count not listed.
+var javaLetters = map[byte]map[int]string{
+	'y': {2: "06", 0: "2006"},
+	'u': {2: "06", 0: "2006"},
+	'M': {1: "1", 2: "01", 3: "Jan", 0: "January"},
+	'L': {1: "1", 2: "01", 3: "Jan", 0: "January"},
+	'd': {1: "2", 2: "02"},
+	'D': {3: "002"},
+	'H': {1: "15", 2: "15"},
+	'h': {1: "3", 2: "03"},
+	'm': {1: "4", 2: "04"},
+	's': {1: "5", 2: "05"},
+	'a': {1: "PM"},
+	'E': {1: "Mon", 2: "Mon", 3: "Mon", 4: "Monday"},
+	'X': {1: "Z07", 2: "Z0700", 3: "Z07:00"},
+	'x': {1: "-07", 2: "-0700", 3: "-07:00"},
+	'Z': {1: "-0700", 2: "-0700", 3: "-0700", 5: "-07:00"},
+	'z': {1: "MST", 2: "MST", 3: "MST"},
+}
+
+// sequence converts the pattern up to the end of the current optional
+// section, returning its layouts.
+func (p *javaPattern) sequence() []string {
+	layouts := []string{""}
+	appendAll := func(parts ...string) {
+		next := make([]string, 0, len(layouts)*len(parts))
+		for _, l := range layouts {
+			for _, part := range parts {
+				next = append(next, l+part)
+			}
+		}
+		layouts = next
+		if len(layouts) > 64 {
+			p.err = true
+		}
+	}
+
+	for p.pos < len(p.src) && !p.err {
+		c := p.src[p.pos]
+		switch {
+		case c == ']':
+			return layouts
+		case c == '[':
+			p.pos++
+			inner := p.sequence()
+			if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+				p.err = true
+				return nil
+			}
+			p.pos++
+			appendAll(append([]string{""}, inner...)...)
+		case c == '\'':
+			lit, ok := p.quoted()
+			if !ok {
+				p.err = true
+				return nil
+			}
+			appendAll(lit)
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			n := 1
+			for p.pos+n < len(p.src) && p.src[p.pos+n] == c {
+				n++
+			}
+			p.pos += n
+			elem, ok := p.letter(c, n, layouts)
+			if !ok {
+				p.err = true
+				return nil
+			}
+			appendAll(elem)
+		default:
+			if (c >= '0' && c <= '9') || c == '_' {
+				// Digits and underscores would be read as Go layout elements.
+				p.err = true
+				return nil
+			}
+			p.pos++
+			appendAll(string(c))
+		}
+	}
+	return layouts
+}
+
+// letter converts n repeats of the pattern letter c. Fractions of a second
+// need the separator before them, which Go layouts spell together with the
+// digits.
+func (p *javaPattern) letter(c byte, n int, layouts []string) (string, bool) {
+	if c == 'S' {
+		for _, l := range layouts {
+			if !strings.HasSuffix(l, ".") && !strings.HasSuffix(l, ",") {
+				return "", false
+			}
+		}
+		return strings.Repeat("0", n), n <= 9
+	}
+	if names := c == 'a' || c == 'E' || ((c == 'M' || c == 'L') && n >= 3); names && !p.english {
+		return "", false
+	}
+
+	elems, ok := javaLetters[c]
+	if !ok {
+		return "", false
+	}
+	if elem, ok := elems[n]; ok {
+		return elem, true
+	}
+	elem, ok := elems[0]
+	return elem, ok
+}
+
+// quoted reads a quoted literal, in which ” stands for a quote. Go layouts
+// read some letter sequences as elements, so only T and characters that are
+// not letters or digits are accepted in literals.
+func (p *javaPattern) quoted() (string, bool) {
+	p.pos++
+	if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+		p.pos++
+		return "'", true
+	}
+
+	var lit strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		if c == '\'' {
+			if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+				lit.WriteByte('\'')
+				p.pos++
+				continue
+			}
+			return lit.String(), true
+		}
+		if ((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_') && c != 'T' {
+			return "", false
+		}
+		lit.WriteByte(c)
+	}
+	return "", false
+}
+
+// dateField is a date or date_nanos field of a mapping whose values are
+// checked against its format.
+type dateField struct {
+	path   string // Dotted path of the field, including a multi-field's name
+	source string // Dotted path holding its value in documents
+	format dateFormat
+}
+
+// checkedDateFields returns the date and date_nanos fields of a mapping,
+// leaving out those with ignore_malformed, for which Elasticsearch skips
+// values it cannot parse instead of failing.
+func checkedDateFields(mapping json.RawMessage) []dateField {
+	var fields []dateField
+	walkMappedFields(mapping, func(path, source string, def json.RawMessage) {
+		var field struct {
+			Type            string `json:"type"`
+			Format          string `json:"format"`
+			Locale          string `json:"locale"`
+			IgnoreMalformed bool   `json:"ignore_malformed"`
+		}
+		if json.Unmarshal(def, &field) != nil || (field.Type != "date" && field.Type != "date_nanos") || field.IgnoreMalformed {
+			return
+		}
+		if field.Format == "" {
+			field.Format = defaultDateFormat
+		}
+		fields = append(fields, dateField{path: path, source: source, format: parseDateFormat(field.Format, field.Locale)})
+	})
+
+	return fields
+}
+
+// checkDateFormats checks the values of date fields in parsed documents
+// against the formats of their mappings, since Elasticsearch only reports a
+// mismatch as a failed bulk item. The check does not depend on the local
+// time zone or locale: patterns without a zone accept only values without
+// one, and month and day names are English unless the mapping sets another
+// locale, in which case they are not checked. Epoch values that are likely
+// in the wrong unit are recorded as warnings.
+func (l *Loader) checkDateFormats() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		if v, ok, _ := indexSetting(f.settings, "index.mapping.ignore_malformed"); ok && (string(v) == "true" || string(v) == `"true"`) {
+			continue
+		}
+		for _, field := range checkedDateFields(f.mapping) {
+			for _, doc := range f.documents {
+				values, err := lookupField(doc.Source, strings.Split(field.source, "."))
+				if err != nil {
+					continue
+				}
+				for _, v := range values {
+					var text string
+					switch value, _ := decodeValue(v); value := value.(type) {
+					case nil:
+						continue
+					case string:
+						text = value
+					case json.Number:
+						text = value.String()
+					default:
+						errs = append(errs, fmt.Errorf("index %q: %s: date field %q holds %s, which is not a date", f.name, doc.Location(), field.path, v))
+						continue
+					}
+					if text == "" {
+						continue
+					}
+
+					alt, ok := field.format.match(text)
+					if !ok {
+						errs = append(errs, fmt.Errorf("index %q: %s: date field %q holds %s, which does not match its format %q", f.name, doc.Location(), field.path, v, field.format.text))
+						continue
+					}
+					if w := alt.epochWarning(text); w != "" {
+						l.warnings = append(l.warnings, fmt.Sprintf("index %q: %s: date field %q: %s", f.name, doc.Location(), field.path, w))
+					}
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package testfixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDateFormat_Match(t *testing.T) {
+	tests := []struct {
+		format string
+		value  string
+		want   bool
+	}{
+		{defaultDateFormat, "2024-03-01", true},
+		{defaultDateFormat, "2024-03-01T10:15:30Z", true},
+		{defaultDateFormat, "2024-03-01T10:15:30.123456789+09:00", true},
+		{defaultDateFormat, "2024-03", true},
+		{defaultDateFormat, "1709287200000", true},
+		{defaultDateFormat, "2024-3-1", false},
+		{defaultDateFormat, "2024-02-30", false},
+		{defaultDateFormat, "2024/03/01", false},
+		{defaultDateFormat, "March 1, 2024", false},
+		{"date_optional_time", "2024-3-1T9:05", true},
+		{"strict_date", "2024-03-01T00:00:00Z", false},
+		{"date_time", "2024-03-01T10:15:30.000Z", true},
+		{"date_time", "2024-03-01T10:15:30", false},
+		{"basic_date", "20240301", true},
+		{"epoch_second", "1709287200", true},
+		{"epoch_second", "1709287200.5", true},
+		{"epoch_second", "2024-03-01", false},
+		{"yyyy-MM-dd HH:mm:ss", "2024-03-01 10:15:30", true},
+		{"yyyy-MM-dd HH:mm:ss", "2024-03-01T10:15:30", false},
+		{"yyyy-MM-dd HH:mm:ss", "2024-03-01 10:15:30+09:00", false},
+		{"yyyy-MM-dd HH:mm:ss", "2024-03-01 25:15:30", false},
+		{"yyyy-MM-dd'T'HH:mm:ss.SSSXXX", "2024-03-01T10:15:30.123+09:00", true},
+		{"yyyy-MM-dd'T'HH:mm:ss.SSSXXX", "2024-03-01T10:15:30.123", false},
+		{"yyyy-MM-dd[ HH:mm[:ss]]", "2024-03-01 10:15", true},
+		{"yyyy-MM-dd[ HH:mm[:ss]]", "2024-03-01", true},
+		{"dd MMM yyyy", "01 Mar 2024", true},
+		{"dd MMM yyyy", "01 mär 2024", false},
+		{"dd/MM/yyyy||epoch_millis", "01/03/2024", true},
+		{"dd/MM/yyyy||epoch_millis", "2024-03-01", false},
+		{"week_date", "anything", true},
+		{"yyyy-ww", "anything", true},
+	}
+
+	for _, tt := range tests {
+		df := parseDateFormat(tt.format, "")
+		if _, got := df.match(tt.value); got != tt.want {
+			t.Errorf("format %q, value %q: expected match %v, got %v", tt.format, tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestDateFormat_Locale(t *testing.T) {
+	if _, ok := parseDateFormat("dd MMM yyyy", "en_US").match("01 Mar 2024"); !ok {
+		t.Error("expected an English month name to match in the en_US locale")
+	}
+	if _, ok := parseDateFormat("dd MMM yyyy", "de_DE").match("01 Mär. 2024"); !ok {
+		t.Error("expected month names outside English locales to be left unchecked")
+	}
+	if _, ok := parseDateFormat("dd.MM.yyyy", "de_DE").match("2024-03-01"); ok {
+		t.Error("expected numeric patterns to be checked in any locale")
+	}
+}
+
+func TestJavaLayouts(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"yyyy-MM-dd HH:mm:ss", []string{"2006-01-02 15:04:05"}},
+		{"yyyyMMdd'T'HHmmss.SSSZ", []string{"20060102T150405.000-0700"}},
+		{"yyyy-MM-dd[ HH:mm]", []string{"2006-01-02", "2006-01-02 15:04"}},
+		{"EEE, dd MMM yyyy HH:mm:ss z", []string{"Mon, 02 Jan 2006 15:04:05 MST"}},
+		{"h:mm a", []string{"3:04 PM"}},
+		{"HH 'o''clock'", nil},
+		{"yyyy-MM-dd'_'HH", nil},
+		{"yyyy-MM-dd HHmmssSSS", nil},
+		{"yyyy-MM-dd[ HH", nil},
+		{"GGGG yyyy", nil},
+	}
+
+	for _, tt := range tests {
+		got, ok := javaLayouts(tt.pattern, true)
+		if ok != (tt.want != nil) || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("javaLayouts(%q) = %q, %v; want %q", tt.pattern, got, ok, tt.want)
+		}
+	}
+}
+
+func TestNew_DateFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"events/_mapping.json": `{"properties": {
+			"created_at": {"type": "date", "format": "yyyy-MM-dd HH:mm:ss"},
+			"logged_at": {"type": "date"},
+			"sent_at": {"type": "date", "format": "epoch_second"},
+			"raw": {"type": "date", "format": "yyyy-MM-dd", "ignore_malformed": true}
+		}}`,
+		"events/documents.yml": "- _id: \"1\"\n  created_at: \"2024-03-01 10:15:30\"\n  logged_at: 2024-03-01T10:15:30Z\n" +
+			"- _id: \"2\"\n  created_at: \"2024-03-01T10:15:30Z\"\n  raw: yesterday\n" +
+			"- _id: \"3\"\n  logged_at: [\"2024-03-01\", \"01/03/2024\"]\n  sent_at: true\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil {
+		t.Fatal("expected New to fail on date values not matching their format")
+	}
+	for _, want := range []string{
+		`index "events": events/documents.yml:4: date field "created_at" holds "2024-03-01T10:15:30Z", which does not match its format "yyyy-MM-dd HH:mm:ss"`,
+		`date field "logged_at" holds "01/03/2024", which does not match its format "strict_date_optional_time||epoch_millis"`,
+		`date field "sent_at" holds true, which is not a date`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "raw") {
+		t.Errorf("expected ignore_malformed fields to be skipped, got %v", err)
+	}
+}
+
+func TestNew_EpochUnitWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"events/_mapping.json": `{"properties": {
+			"logged_at": {"type": "date", "format": "epoch_millis"},
+			"sent_at": {"type": "date", "format": "epoch_second"}
+		}}`,
+		"events/documents.yml": "- _id: \"1\"\n  logged_at: 1709287200\n  sent_at: 1709287200000\n" +
+			"- _id: \"2\"\n  logged_at: 1709287200000\n  sent_at: 1709287200\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	want := []string{
+		`index "events": events/documents.yml:1: date field "logged_at": 1709287200 is read as epoch_millis, which is 1970-01-20T18:48:07Z; if it counts seconds, use epoch_second`,
+		`index "events": events/documents.yml:1: date field "sent_at": 1709287200000 is read as epoch_second, which is after the year 5000; if it counts milliseconds (2024-03-01T10:00:00Z), use epoch_millis`,
+	}
+	got := loader.Warnings()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected warnings\n%q\ngot\n%q", want, got)
+	}
+}
@@ -0,0 +1,93 @@
+package testfixtures
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// debugBodyLimit is the number of request body bytes shown in a debug
+// command; longer bodies (typically bulk requests) are truncated.
+const debugBodyLimit = 2048
+
+// debugTransport writes each request as an equivalent curl command before
+// performing it.
+type debugTransport struct {
+	next    esapi.Transport
+	baseURL string
+
+	mu sync.Mutex // Serializes writes from concurrent bulk indexers
+	w  io.Writer
+}
+
+// newDebugTransport returns a debugTransport for requests sent by client.
+// Commands use client's first node URL, or $ELASTICSEARCH_URL if the client
+// does not expose its nodes.
+func newDebugTransport(client *elasticsearch.Client, next esapi.Transport, w io.Writer) *debugTransport {
+	baseURL := "$ELASTICSEARCH_URL"
+	if nodes, ok := client.Transport.(interface{ URLs() []*url.URL }); ok {
+		if urls := nodes.URLs(); len(urls) > 0 {
+			baseURL = strings.TrimSuffix(urls[0].String(), "/")
+		}
+	}
+
+	return &debugTransport{next: next, baseURL: baseURL, w: w}
+}
+
+func (t *debugTransport) Perform(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		body = data
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	t.mu.Lock()
+	_, _ = io.WriteString(t.w, curlCommand(t.baseURL, req, body)+"\n")
+	t.mu.Unlock()
+
+	return t.next.Perform(req)
+}
+
+// curlCommand formats a request as a curl command line. Bodies longer than
+// debugBodyLimit are truncated, with a note of how much was left out.
+func curlCommand(baseURL string, req *http.Request, body []byte) string {
+	target := baseURL + req.URL.Path
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "curl -X %s %s", req.Method, shellQuote(target))
+	if ct := req.Header.Get("Content-Type"); ct != "" && body != nil {
+		fmt.Fprintf(&b, " -H %s", shellQuote("Content-Type: "+ct))
+	}
+	if body != nil {
+		shown := string(body)
+		if len(body) > debugBodyLimit {
+			shown = fmt.Sprintf("%s... (%d more bytes)", body[:debugBodyLimit], len(body)-debugBodyLimit)
+		}
+		fmt.Fprintf(&b, " --data-binary %s", shellQuote(shown))
+	}
+
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,71 @@
+package testfixtures
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+func TestWithDebugRequests(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		headers []string
+	)
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		headers = append(headers, req.Header.Get(elasticsearch.HeaderClientMeta))
+		mu.Unlock()
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	})
+
+	var buf bytes.Buffer
+	loader, err := New(newFakeClient(t, fake), Directory("testdata/fixtures"), WithDebugRequests(&buf))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`curl -X DELETE 'http://es.test:9200/users?ignore_unavailable=true'`,
+		`curl -X PUT 'http://es.test:9200/users' -H 'Content-Type: application/json' --data-binary '{"mappings":`,
+		`curl -X POST 'http://es.test:9200/users/_bulk' -H 'Content-Type: application/json' --data-binary '{"index":{"_id":"1"}}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug output missing %q:\n%s", want, out)
+		}
+	}
+
+	for _, h := range headers {
+		if strings.Count(h, "es=") != 1 {
+			t.Errorf("expected a single client meta header value, got %q", h)
+		}
+	}
+}
+
+func TestCurlCommand_TruncatesAndQuotes(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/users/_search", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	body := []byte(`{"query":{"match":{"name":"O'Brien"}}}` + strings.Repeat(" ", debugBodyLimit))
+	got := curlCommand("http://localhost:9200", req, body)
+
+	if !strings.Contains(got, `O'\''Brien`) {
+		t.Errorf("expected single quotes to be escaped, got %s", got)
+	}
+	if !strings.Contains(got, "... (38 more bytes)") {
+		t.Errorf("expected body to be truncated, got %s", got)
+	}
+}
@@ -0,0 +1,114 @@
+package testfixtures
+
+import (
+	"context"
+	"fmt"
+)
+
+// DuplicateDocument reports an _id that appeared more than once in the
+// sources of an index and was deduplicated by DedupeByID.
+type DuplicateDocument struct {
+	ID      string   // Document ID
+	Kept    string   // Source of the document that was indexed
+	Dropped []string // Sources of the documents that were not, in load order
+}
+
+// docPosition locates a document among the sources of an index: group 0 holds
+// the fixture file documents and group i+1 those of provider i.
+type docPosition struct {
+	group, index int
+}
+
+// dedupeByID removes documents whose ID reappears later in groups, so that
+// the last occurrence in load order wins, as it would if each write were
+// applied in turn. Documents without an ID are kept. The survivors keep their
+// relative order, and each deduplicated ID is reported in order of first
+// appearance.
+func dedupeByID(groups [][]Document) ([][]Document, []DuplicateDocument) {
+	var (
+		ids         []string
+		occurrences = make(map[string][]docPosition)
+	)
+	for g, docs := range groups {
+		for i, doc := range docs {
+			if doc.ID == "" {
+				continue
+			}
+			if _, ok := occurrences[doc.ID]; !ok {
+				ids = append(ids, doc.ID)
+			}
+			occurrences[doc.ID] = append(occurrences[doc.ID], docPosition{g, i})
+		}
+	}
+
+	var duplicates []DuplicateDocument
+	for _, id := range ids {
+		positions := occurrences[id]
+		if len(positions) < 2 {
+			continue
+		}
+		last := positions[len(positions)-1]
+		dup := DuplicateDocument{ID: id, Kept: documentSource(groups, last)}
+		for _, p := range positions[:len(positions)-1] {
+			dup.Dropped = append(dup.Dropped, documentSource(groups, p))
+		}
+		duplicates = append(duplicates, dup)
+	}
+	if len(duplicates) == 0 {
+		return groups, nil
+	}
+
+	kept := make([][]Document, len(groups))
+	for g, docs := range groups {
+		for i, doc := range docs {
+			if doc.ID != "" {
+				positions := occurrences[doc.ID]
+				if positions[len(positions)-1] != (docPosition{g, i}) {
+					continue
+				}
+			}
+			kept[g] = append(kept[g], doc)
+		}
+	}
+
+	return kept, duplicates
+}
+
+// documentSource describes where the document at p came from: its fixture
+// file location, or the provider that supplied it.
+func documentSource(groups [][]Document, p docPosition) string {
+	if loc := groups[p.group][p.index].Location(); loc != "" {
+		return loc
+	}
+	if p.group == 0 {
+		return "fixture files"
+	}
+	return fmt.Sprintf("provider %d", p.group-1)
+}
+
+// collectProviderDocuments reads the documents of each provider into memory,
+// applying transform to each, so they can be deduplicated before indexing.
+func collectProviderDocuments(ctx context.Context, indexName string, providers []DocumentProvider, transform func(Document) (Document, error)) ([][]Document, error) {
+	groups := make([][]Document, len(providers))
+	for i, p := range providers {
+		docs, err := p.Documents(ctx, indexName)
+		if err != nil {
+			return nil, fmt.Errorf("provider %d for %q: %w", i, indexName, err)
+		}
+
+		add := withTransform(func(doc Document) error {
+			groups[i] = append(groups[i], doc)
+			return nil
+		}, transform)
+		for doc, err := range docs {
+			if err != nil {
+				return nil, fmt.Errorf("provider %d for %q: %w", i, indexName, err)
+			}
+			if err := add(doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return groups, nil
+}
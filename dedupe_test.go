@@ -0,0 +1,100 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDedupeByID(t *testing.T) {
+	groups := [][]Document{
+		{
+			{ID: "1", Source: json.RawMessage(`{"v":"a"}`), file: "users/a.yml", line: 1},
+			{Source: json.RawMessage(`{"v":"no id"}`), file: "users/a.yml", line: 3},
+			{ID: "2", Source: json.RawMessage(`{"v":"b"}`), file: "users/a.yml", line: 5},
+			{ID: "1", Source: json.RawMessage(`{"v":"c"}`), file: "users/b.yml", line: 1},
+		},
+		{
+			{ID: "2", Source: json.RawMessage(`{"v":"d"}`)},
+		},
+	}
+
+	kept, duplicates := dedupeByID(groups)
+
+	var got [][]string
+	for _, docs := range kept {
+		var sources []string
+		for _, doc := range docs {
+			sources = append(sources, string(doc.Source))
+		}
+		got = append(got, sources)
+	}
+	want := [][]string{{`{"v":"no id"}`, `{"v":"c"}`}, {`{"v":"d"}`}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept documents = %v, want %v", got, want)
+	}
+
+	wantDups := []DuplicateDocument{
+		{ID: "1", Kept: "users/b.yml:1", Dropped: []string{"users/a.yml:1"}},
+		{ID: "2", Kept: "provider 0", Dropped: []string{"users/a.yml:5"}},
+	}
+	if !reflect.DeepEqual(duplicates, wantDups) {
+		t.Errorf("duplicates = %+v, want %+v", duplicates, wantDups)
+	}
+}
+
+func TestLoad_DedupeByID(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n- _id: 2\n  name: Bob\n",
+	})
+
+	var (
+		mu    sync.Mutex
+		bulks []string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			body, _ := io.ReadAll(req.Body)
+			mu.Lock()
+			bulks = append(bulks, string(body))
+			mu.Unlock()
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	p := DocumentProviderFunc(func(context.Context, string) (iter.Seq2[Document, error], error) {
+		return func(yield func(Document, error) bool) {
+			yield(Document{ID: "2", Source: json.RawMessage(`{"name":"Robert"}`)}, nil)
+		}, nil
+	})
+
+	loader, err := New(client, Directory(dir), WithProvider("users", p), DedupeByID())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	sent := strings.Join(bulks, "")
+	if strings.Contains(sent, "Bob") || !strings.Contains(sent, "Robert") || !strings.Contains(sent, "Alice") {
+		t.Errorf("expected the provider's document 2 to replace the fixture's, sent:\n%s", sent)
+	}
+
+	results := loader.Results()
+	if len(results) != 1 || results[0].Documents != 2 {
+		t.Fatalf("expected two documents sent to users, got %+v", results)
+	}
+	want := []DuplicateDocument{{ID: "2", Kept: "provider 0", Dropped: []string{"users/documents.yml:3"}}}
+	if !reflect.DeepEqual(results[0].Duplicates, want) {
+		t.Errorf("Duplicates = %+v, want %+v", results[0].Duplicates, want)
+	}
+}
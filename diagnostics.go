@@ -0,0 +1,173 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+var (
+	// failed to parse field [age] of type [integer] in document with id '1'
+	parseFieldReason = regexp.MustCompile(`failed to parse field \[([^\]]+)\](?: of type \[([^\]]+)\])?`)
+	// failed to parse date field [2024/01/01] with format [strict_date_optional_time||epoch_millis]
+	dateFormatReason = regexp.MustCompile(`failed to parse date field \[([^\]]*)\] with format \[([^\]]+)\]`)
+	// mapping set to strict, dynamic introduction of [nickname] within [_doc] is not allowed
+	strictDynamicReason = regexp.MustCompile(`dynamic introduction of \[([^\]]+)\] within \[([^\]]+)\]`)
+)
+
+// circuitBreakerHint is suggested when Elasticsearch rejects requests because
+// it is short of memory or its write queue is full.
+const circuitBreakerHint = "the cluster is rejecting requests under memory pressure; limit concurrent bulk requests with WithMaxInFlightBytes or give Elasticsearch a larger heap"
+
+// describeFlushError renders an error returned for a whole bulk request,
+// adding a hint when the cluster rejected it under memory pressure.
+func describeFlushError(err error) string {
+	msg := err.Error()
+	if strings.Contains(msg, "circuit_breaking_exception") || strings.Contains(msg, "429 Too Many Requests") {
+		return msg + "; hint: " + circuitBreakerHint
+	}
+	return msg
+}
+
+// describeBulkFailure renders a failed bulk item as an actionable message
+// naming the fixture file, the document, the offending field when known,
+// and a suggested fix for common mapping errors.
+func describeBulkFailure(doc Document, res esutil.BulkIndexerResponseItem) string {
+	var b strings.Builder
+
+	if loc := doc.Location(); loc != "" {
+		b.WriteString(loc)
+		b.WriteString(": ")
+	}
+	if doc.ID != "" {
+		fmt.Fprintf(&b, "document %q: ", doc.ID)
+	}
+
+	field, hint := diagnoseBulkError(res.Error.Type, res.Error.Reason, res.Error.Cause.Type, res.Error.Cause.Reason)
+	if field != "" {
+		fmt.Fprintf(&b, "field %q: ", field)
+	}
+
+	fmt.Fprintf(&b, "[%d] %s: %s", res.Status, res.Error.Type, res.Error.Reason)
+	if res.Error.Cause.Reason != "" {
+		fmt.Fprintf(&b, " (caused by %s: %s)", res.Error.Cause.Type, res.Error.Cause.Reason)
+	}
+	if hint != "" {
+		fmt.Fprintf(&b, "; hint: %s", hint)
+	}
+
+	return b.String()
+}
+
+// diagnoseBulkError extracts the failing field and a suggested fix from an
+// Elasticsearch error. Both are empty for errors it does not recognize.
+func diagnoseBulkError(errType, reason, causeType, causeReason string) (field, hint string) {
+	switch errType {
+	case "mapper_parsing_exception", "document_parsing_exception":
+		if m := parseFieldReason.FindStringSubmatch(reason); m != nil {
+			field = m[1]
+		}
+
+		if m := dateFormatReason.FindStringSubmatch(causeReason); m != nil {
+			return field, fmt.Sprintf("value %q does not match the date format [%s]; change the value or set a \"format\" for the field in %s", m[1], m[2], mappingFile)
+		}
+		if field != "" {
+			typ := "its mapped type"
+			if m := parseFieldReason.FindStringSubmatch(reason); m != nil && m[2] != "" {
+				typ = fmt.Sprintf("type [%s]", m[2])
+			}
+			return field, fmt.Sprintf("the value cannot be indexed as %s; fix the value or the field's type in %s", typ, mappingFile)
+		}
+		if causeType == "json_parse_exception" || causeType == "x_content_parse_exception" {
+			return "", "the document body is not valid JSON"
+		}
+
+	case "strict_dynamic_mapping_exception":
+		if m := strictDynamicReason.FindStringSubmatch(reason); m != nil {
+			return m[1], fmt.Sprintf("the mapping is strict; add %q to %s or remove it from the document", m[1], mappingFile)
+		}
+		return "", fmt.Sprintf("the mapping is strict; add the field to %s or remove it from the document", mappingFile)
+
+	case "circuit_breaking_exception", "es_rejected_execution_exception":
+		return "", circuitBreakerHint
+
+	case "version_conflict_engine_exception":
+		return "", "a document with this _id already exists; check for duplicate _id values across fixture files"
+
+	case "illegal_argument_exception":
+		if m := dateFormatReason.FindStringSubmatch(reason); m != nil {
+			return "", fmt.Sprintf("value %q does not match the date format [%s]", m[1], m[2])
+		}
+		if strings.Contains(reason, "Limit of total fields") {
+			return "", "the document introduces too many fields; raise index.mapping.total_fields.limit in " + settingsFile + " or map the object as flattened"
+		}
+	}
+
+	return "", ""
+}
+
+// esErrorCause is an Elasticsearch error object, as found under "error" in
+// an error response and nested under "caused_by".
+type esErrorCause struct {
+	Type      string          `json:"type"`
+	Reason    string          `json:"reason"`
+	RootCause []esErrorCause  `json:"root_cause"`
+	CausedBy  *esErrorCause   `json:"caused_by"`
+	Failed    []esShardFailed `json:"failed_shards"`
+}
+
+// esShardFailed is an entry of an error's failed_shards list.
+type esShardFailed struct {
+	Shard  int           `json:"shard"`
+	Index  string        `json:"index"`
+	Node   string        `json:"node"`
+	Reason *esErrorCause `json:"reason"`
+}
+
+// formatErrorBody renders an Elasticsearch error response body as an
+// indented summary: the error, its caused_by chain, root causes that are not
+// already part of the chain, and failed shards. Bodies that are not in the
+// structured error format are returned as is.
+func formatErrorBody(body []byte) string {
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil || len(parsed.Error) == 0 {
+		return strings.TrimSpace(string(body))
+	}
+
+	var cause esErrorCause
+	if err := json.Unmarshal(parsed.Error, &cause); err != nil || cause.Type == "" {
+		// Some APIs report errors as a plain string
+		return strings.TrimSpace(string(body))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", cause.Type, cause.Reason)
+
+	seen := map[string]bool{cause.Type + ": " + cause.Reason: true}
+	for c := cause.CausedBy; c != nil; c = c.CausedBy {
+		fmt.Fprintf(&b, "\n  caused by: %s: %s", c.Type, c.Reason)
+		seen[c.Type+": "+c.Reason] = true
+	}
+	for _, rc := range cause.RootCause {
+		if seen[rc.Type+": "+rc.Reason] {
+			continue
+		}
+		fmt.Fprintf(&b, "\n  root cause: %s: %s", rc.Type, rc.Reason)
+	}
+	for _, f := range cause.Failed {
+		fmt.Fprintf(&b, "\n  failed shard %d of %q", f.Shard, f.Index)
+		if f.Node != "" {
+			fmt.Fprintf(&b, " on node %s", f.Node)
+		}
+		if f.Reason != nil {
+			fmt.Fprintf(&b, ": %s: %s", f.Reason.Type, f.Reason.Reason)
+		}
+	}
+
+	return b.String()
+}
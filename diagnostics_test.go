@@ -0,0 +1,99 @@
+package testfixtures
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+func TestDescribeBulkFailure(t *testing.T) {
+	doc := Document{ID: "1", file: "users/documents.yml", line: 7}
+
+	tests := []struct {
+		name      string
+		errType   string
+		reason    string
+		causeType string
+		cause     string
+		want      []string
+	}{
+		{
+			name:    "type mismatch",
+			errType: "document_parsing_exception",
+			reason:  "[1:30] failed to parse field [age] of type [integer] in document with id '1'. Preview of field's value: 'thirty'",
+			want:    []string{"users/documents.yml:7", `document "1"`, `field "age"`, "type [integer]", "_mapping.json"},
+		},
+		{
+			name:      "date format",
+			errType:   "mapper_parsing_exception",
+			reason:    "failed to parse field [joined] of type [date] in document with id '1'",
+			causeType: "illegal_argument_exception",
+			cause:     "failed to parse date field [2024/01/01] with format [strict_date_optional_time||epoch_millis]",
+			want:      []string{`field "joined"`, `"2024/01/01"`, "[strict_date_optional_time||epoch_millis]", `"format"`},
+		},
+		{
+			name:    "strict mapping",
+			errType: "strict_dynamic_mapping_exception",
+			reason:  "[1:2] mapping set to strict, dynamic introduction of [nickname] within [_doc] is not allowed",
+			want:    []string{`field "nickname"`, `add "nickname" to _mapping.json`},
+		},
+		{
+			name:    "unknown error",
+			errType: "some_other_exception",
+			reason:  "something happened",
+			want:    []string{"[400] some_other_exception: something happened"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res esutil.BulkIndexerResponseItem
+			res.Status = 400
+			res.Error.Type = tt.errType
+			res.Error.Reason = tt.reason
+			res.Error.Cause.Type = tt.causeType
+			res.Error.Cause.Reason = tt.cause
+
+			got := describeBulkFailure(doc, res)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected message to contain %q, got: %s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatErrorBody(t *testing.T) {
+	body := `{"error":{"root_cause":[{"type":"mapper_parsing_exception","reason":"unknown parameter [analyser] on mapper [name] of type [text]"}],"type":"mapper_parsing_exception","reason":"Failed to parse mapping: unknown parameter [analyser] on mapper [name] of type [text]","caused_by":{"type":"mapper_parsing_exception","reason":"unknown parameter [analyser] on mapper [name] of type [text]"}},"status":400}`
+
+	want := "mapper_parsing_exception: Failed to parse mapping: unknown parameter [analyser] on mapper [name] of type [text]\n" +
+		"  caused by: mapper_parsing_exception: unknown parameter [analyser] on mapper [name] of type [text]"
+	if got := formatErrorBody([]byte(body)); got != want {
+		t.Errorf("formatErrorBody() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatErrorBody_FailedShards(t *testing.T) {
+	body := `{"error":{"root_cause":[{"type":"query_shard_exception","reason":"No mapping found for [age] in order to sort on"}],"type":"search_phase_execution_exception","reason":"all shards failed","failed_shards":[{"shard":0,"index":"users","node":"n1","reason":{"type":"query_shard_exception","reason":"No mapping found for [age] in order to sort on"}}]},"status":400}`
+
+	got := formatErrorBody([]byte(body))
+	for _, want := range []string{
+		"search_phase_execution_exception: all shards failed",
+		"\n  root cause: query_shard_exception: No mapping found for [age]",
+		"\n  failed shard 0 of \"users\" on node n1: query_shard_exception:",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatErrorBody_Unstructured(t *testing.T) {
+	for _, body := range []string{`{"error":"Incorrect HTTP method","status":405}`, "not json\n"} {
+		if got := formatErrorBody([]byte(body)); got != strings.TrimSpace(body) {
+			t.Errorf("formatErrorBody(%q) = %q, want body unchanged", body, got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// IndexDiff is the difference between the documents Load sends for a
+// fixture index and the documents stored in the cluster, matched by _id.
+type IndexDiff struct {
+	Index   string   // Fixture index
+	Missing []string // _ids of fixture documents the cluster does not hold
+	Extra   []string // _ids of documents in the cluster the fixtures do not hold
+	Changed []string // _ids whose _source or routing differs in the cluster
+
+	// MissingUnkeyed counts the fixture documents without _id that no
+	// document in the cluster matches by _source. Cluster documents that
+	// match one are not reported as Extra.
+	MissingUnkeyed int
+}
+
+// Empty reports whether the cluster holds exactly the fixture documents.
+func (d IndexDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Changed) == 0 && d.MissingUnkeyed == 0
+}
+
+// Diff compares the documents of a fixture index in the cluster with those
+// Load sends for it, to tell whether the index still holds the fixtures or
+// what a test or an edit of the fixture files changed. The fixture side is
+// the state the documents' _action values leave, after DedupeByID and every
+// transform; _source is compared as JSON values, so key order and number
+// formatting do not matter. Fields that ingest pipelines or load-time
+// generators fill in with new values make documents Changed.
+func (l *Loader) Diff(ctx context.Context, index string) (IndexDiff, error) {
+	f := l.fixture(index)
+	if f == nil || f.isAlias() {
+		return IndexDiff{}, fmt.Errorf("testfixtures: %q is not a fixture index", index)
+	}
+
+	want, unkeyed, err := l.expectedDocuments(f)
+	if err != nil {
+		return IndexDiff{}, fmt.Errorf("testfixtures: diffing %q: %w", index, err)
+	}
+	stored, err := fetchDocuments(ctx, l.client, l.IndexName(index))
+	if err != nil {
+		return IndexDiff{}, fmt.Errorf("testfixtures: diffing %q: fetching documents: %w", index, err)
+	}
+
+	diff := IndexDiff{Index: index}
+	seen := make(map[string]bool, len(stored))
+	for _, doc := range stored {
+		seen[doc.ID] = true
+		if expected, ok := want[doc.ID]; ok {
+			if expected.Routing != doc.Routing || !goldenEqual(expected.Source, doc.Source) {
+				diff.Changed = append(diff.Changed, doc.ID)
+			}
+			continue
+		}
+		if key := canonicalJSON(doc.Source); len(unkeyed[key]) > 0 {
+			unkeyed[key] = unkeyed[key][1:]
+			continue
+		}
+		diff.Extra = append(diff.Extra, doc.ID)
+	}
+	for id := range want {
+		if !seen[id] {
+			diff.Missing = append(diff.Missing, id)
+		}
+	}
+	for _, docs := range unkeyed {
+		diff.MissingUnkeyed += len(docs)
+	}
+	slices.Sort(diff.Missing)
+
+	return diff, nil
+}
+
+// expectedDocuments returns the documents f leaves in its index once loaded,
+// by _id, and the documents without _id by canonical _source.
+func (l *Loader) expectedDocuments(f *indexFixture) (map[string]Document, map[string][]Document, error) {
+	keyed := make(map[string]Document)
+	unkeyed := make(map[string][]Document)
+	err := l.feedIndexDocuments(f, func(doc Document) error {
+		prev, exists := keyed[doc.ID]
+		switch {
+		case doc.ID == "":
+			key := canonicalJSON(doc.Source)
+			unkeyed[key] = append(unkeyed[key], Document{Routing: doc.Routing, Source: doc.Source})
+		case doc.action == actionDelete:
+			delete(keyed, doc.ID)
+		case doc.action == actionUpdate && exists:
+			merged, err := mergeSource(prev.Source, doc.Source)
+			if err != nil {
+				return fmt.Errorf("document %q: %w", doc.ID, err)
+			}
+			prev.Source = merged
+			keyed[doc.ID] = prev
+		case doc.action == actionUpdate, doc.action == actionCreate && exists:
+			// Elasticsearch rejects these, leaving the index as it was
+		default:
+			keyed[doc.ID] = Document{ID: doc.ID, Routing: doc.Routing, Source: doc.Source}
+		}
+		return nil
+	})
+	return keyed, unkeyed, err
+}
+
+// mergeSource merges the fields of patch into base, as a partial update
+// does: objects present in both are merged recursively, and other values of
+// patch replace those of base.
+func mergeSource(base, patch json.RawMessage) (json.RawMessage, error) {
+	fields, err := decodeObject(base)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decodeObject(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		i := slices.IndexFunc(fields, func(f jsonField) bool { return f.key == change.key })
+		switch {
+		case i < 0:
+			fields = append(fields, change)
+		case isJSONObject(fields[i].value) && isJSONObject(change.value):
+			merged, err := mergeSource(fields[i].value, change.value)
+			if err != nil {
+				return nil, err
+			}
+			fields[i].value = merged
+		default:
+			fields[i].value = change.value
+		}
+	}
+	return encodeObject(fields), nil
+}
+
+// canonicalJSON returns data re-encoded with sorted object keys, so equal
+// JSON values have equal encodings. Invalid JSON is returned as is.
+func canonicalJSON(data json.RawMessage) string {
+	v, err := decodeValue(data)
+	if err != nil {
+		return string(data)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
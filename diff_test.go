@@ -0,0 +1,66 @@
+package testfixtures
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": `- _id: "1"
+  name: Alice
+  address: {city: Tokyo, zip: "100"}
+- _id: "2"
+  name: Bob
+- _id: "3"
+  name: Carol
+- _id: "4"
+  name: Dave
+- _id: "1"
+  _action: update
+  address: {city: Osaka}
+- _id: "4"
+  _action: delete
+- name: Anonymous
+- name: Ghost
+`,
+	})
+
+	var path string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		path = req.URL.Path
+		return jsonResponse(200, `{"hits":{"hits":[
+			{"_id":"1","_source":{"address":{"zip":"100","city":"Osaka"},"name":"Alice"}},
+			{"_id":"3","_source":{"name":"Caroline"}},
+			{"_id":"9","_source":{"name":"Eve"}},
+			{"_id":"x7Yz","_source":{"name":"Anonymous"}}
+		]}}`), nil
+	}))
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	diff, err := loader.Diff(t.Context(), "users")
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if path != "/users/_search" {
+		t.Errorf("expected a search of /users, got %s", path)
+	}
+	if !slices.Equal(diff.Missing, []string{"2"}) || !slices.Equal(diff.Changed, []string{"3"}) || !slices.Equal(diff.Extra, []string{"9"}) || diff.MissingUnkeyed != 1 {
+		t.Errorf("expected 2 missing, 3 changed, 9 extra, and 1 unkeyed missing, got %+v", diff)
+	}
+	if diff.Empty() {
+		t.Error("expected the diff not to be empty")
+	}
+	if !(IndexDiff{Index: "users"}).Empty() {
+		t.Error("expected a diff without differences to be empty")
+	}
+
+	if _, err := loader.Diff(t.Context(), "customers"); err == nil {
+		t.Error("expected an error for an index that is not a fixture")
+	}
+}
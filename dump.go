@@ -0,0 +1,485 @@
+package testfixtures
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// generatedSettings are the index settings Elasticsearch sets on its own,
+// which cannot be given when an index is created and so are not dumped.
+var generatedSettings = []string{"uuid", "creation_date", "provided_name", "version", "history", "resize"}
+
+// dumpKeepAlive is how long the point in time of a dump is kept between
+// requests.
+const dumpKeepAlive = "1m"
+
+// Dumper writes indices of a live cluster out as fixture files, to
+// bootstrap fixtures from staging data instead of writing them by hand:
+//
+//	d := testfixtures.NewDumper(client)
+//	err := d.Dump(ctx, "products", "testdata/fixtures")
+type Dumper struct {
+	client *elasticsearch.Client
+
+	query    string   // Query clause selecting the documents to dump (empty for all)
+	includes []string // _source fields to keep (empty for all)
+	excludes []string // _source fields to leave out
+	maxDocs  int      // Most documents dumped per index (0 for all)
+
+	progress   func(dumped, total int)               // Called after each page of documents read (may be nil)
+	transforms []func(map[string]any) map[string]any // Applied to the _source of each document, in order
+}
+
+// DumpOption configures a Dumper.
+type DumpOption func(*Dumper)
+
+// DumpQuery dumps only the documents matching query, a query clause of the
+// Elasticsearch query DSL, so a handful of relevant documents can be taken
+// from a large index:
+//
+//	d := testfixtures.NewDumper(client, testfixtures.DumpQuery(`{"term":{"status":"active"}}`))
+func DumpQuery(query string) DumpOption {
+	return func(d *Dumper) {
+		d.query = query
+	}
+}
+
+// DumpSourceIncludes keeps only the given _source fields of the dumped
+// documents. Fields are dotted paths and may use wildcards, as in the
+// _source includes of a search.
+func DumpSourceIncludes(fields ...string) DumpOption {
+	return func(d *Dumper) {
+		d.includes = append(d.includes, fields...)
+	}
+}
+
+// DumpSourceExcludes leaves the given _source fields out of the dumped
+// documents, such as large blobs or personal data. Fields are dotted paths
+// and may use wildcards, as in the _source excludes of a search.
+func DumpSourceExcludes(fields ...string) DumpOption {
+	return func(d *Dumper) {
+		d.excludes = append(d.excludes, fields...)
+	}
+}
+
+// DumpMaxDocs dumps at most n documents of each index, so a sample of a large
+// index can be taken. Which documents are dumped is up to the cluster; with
+// DumpQuery, they are among the matching ones. Zero or less dumps them all.
+func DumpMaxDocs(n int) DumpOption {
+	return func(d *Dumper) {
+		d.maxDocs = max(n, 0)
+	}
+}
+
+// DumpProgress calls fn after each page of documents read from an index,
+// with the number read so far and the number the dump will hold, to report
+// the progress of dumping a large index.
+func DumpProgress(fn func(dumped, total int)) DumpOption {
+	return func(d *Dumper) {
+		d.progress = fn
+	}
+}
+
+// DumpTransform rewrites the _source of each dumped document with fn before
+// it is written, so personal data can be masked, hashed, or replaced with
+// fake values on its way from production data into committed fixtures:
+//
+//	d := testfixtures.NewDumper(client, testfixtures.DumpTransform(func(doc map[string]any) map[string]any {
+//		doc["email"] = "user@example.com"
+//		return doc
+//	}))
+//
+// Numbers are json.Number values, so they are written back unchanged, and
+// fields keep the order they had in _source, with fields a transform adds
+// written after them in sorted order. A nil result leaves the document out
+// of the dump. Several transforms are applied in the order given.
+func DumpTransform(fn func(doc map[string]any) map[string]any) DumpOption {
+	return func(d *Dumper) {
+		d.transforms = append(d.transforms, fn)
+	}
+}
+
+// NewDumper returns a Dumper that reads indices with client.
+func NewDumper(client *elasticsearch.Client, opts ...DumpOption) *Dumper {
+	d := &Dumper{client: client}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Dump writes the index of the cluster, or the single index an alias
+// points to, into destDir as a fixture directory named after index: its
+// mapping as _mapping.json, its settings as _settings.json, and its
+// documents, ordered by _id and with their routing, as documents.yml or the
+// numbered files of DocsPerFile. Settings Elasticsearch generates, such as
+// the uuid and creation date, and shard allocation filters are left out, so
+// the fixture can be loaded into any cluster. With DumpQuery and the
+// _source options, only the matching documents and the selected fields are
+// written; the mapping is written whole. Documents are written as the
+// transforms of DumpTransform leave them. Files of an earlier dump of the
+// index are replaced.
+//
+// Documents are read in pages with a point in time and search_after, so the
+// dump is a consistent view of the index however many documents it has.
+// This needs Elasticsearch 7.12 or later.
+func (d *Dumper) Dump(ctx context.Context, index, destDir string, opts ...WriteOption) error {
+	var cfg writeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	search, err := d.searchFields()
+	if err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: %w", index, err)
+	}
+	mapping, err := d.indexMapping(ctx, index)
+	if err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: reading mapping: %w", index, err)
+	}
+	settings, err := d.indexSettings(ctx, index)
+	if err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: reading settings: %w", index, err)
+	}
+	docs, err := d.fetchDocuments(ctx, index, search)
+	if err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: fetching documents: %w", index, err)
+	}
+	if docs, err = d.transformDocuments(docs); err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: %w", index, err)
+	}
+
+	dir := filepath.Join(destDir, index)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: %w", index, err)
+	}
+	if err := writeJSONFile(filepath.Join(dir, mappingFile), mapping); err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: %w", index, err)
+	}
+	if err := writeJSONFile(filepath.Join(dir, settingsFile), settings); err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: %w", index, err)
+	}
+	if err := writeDocumentFiles(dir, docs, cfg); err != nil {
+		return fmt.Errorf("testfixtures: dumping %q: %w", index, err)
+	}
+
+	return nil
+}
+
+// searchFields returns the query and _source fields of the searches finding
+// the documents and fields to dump, none to dump whole documents.
+func (d *Dumper) searchFields() ([]jsonField, error) {
+	var fields []jsonField
+	if d.query != "" {
+		query := json.RawMessage(d.query)
+		if !isJSONObject(query) || !json.Valid(query) {
+			return nil, fmt.Errorf("query must be a JSON object, got %s", d.query)
+		}
+		fields = append(fields, jsonField{key: "query", value: query})
+	}
+	if len(d.includes) > 0 || len(d.excludes) > 0 {
+		source, err := json.Marshal(struct {
+			Includes []string `json:"includes,omitempty"`
+			Excludes []string `json:"excludes,omitempty"`
+		}{d.includes, d.excludes})
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: "_source", value: source})
+	}
+	return fields, nil
+}
+
+// fetchDocuments reads the documents of index to dump, ordered by _id. The
+// fields of search select them, and pages of them are read with
+// search_after in a point in time, which is closed once they are read.
+func (d *Dumper) fetchDocuments(ctx context.Context, index string, search []jsonField) ([]Document, error) {
+	pit, err := openPointInTime(ctx, d.client, index)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { closePointInTime(d.client, pit) }()
+
+	var (
+		docs  []Document
+		after json.RawMessage
+		total = -1
+	)
+	for {
+		size := fetchBatchSize
+		if d.maxDocs > 0 {
+			size = min(size, d.maxDocs-len(docs))
+		}
+		body, err := pitSearchBody(search, pit, size, after)
+		if err != nil {
+			return nil, err
+		}
+		res, err := d.client.Search(
+			d.client.Search.WithContext(ctx),
+			d.client.Search.WithBody(bytes.NewReader(body)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		page, err := decodePITPage(res)
+		if err != nil {
+			return nil, err
+		}
+		if page.PitID != "" {
+			pit = page.PitID
+		}
+		if total < 0 {
+			total = page.Hits.Total.Value
+			if d.maxDocs > 0 {
+				total = min(total, d.maxDocs)
+			}
+		}
+
+		for _, hit := range page.Hits.Hits {
+			docs = append(docs, Document{ID: hit.ID, Routing: hit.Routing, Source: hit.Source})
+		}
+		if d.progress != nil {
+			d.progress(len(docs), max(total, len(docs)))
+		}
+		if len(page.Hits.Hits) < size || len(docs) == d.maxDocs {
+			break
+		}
+		after = page.Hits.Hits[len(page.Hits.Hits)-1].Sort
+	}
+
+	slices.SortFunc(docs, func(a, b Document) int { return cmp.Compare(a.ID, b.ID) })
+	return docs, nil
+}
+
+// transformDocuments applies the transforms of DumpTransform to docs,
+// leaving out the documents a transform returns nil for.
+func (d *Dumper) transformDocuments(docs []Document) ([]Document, error) {
+	if len(d.transforms) == 0 {
+		return docs, nil
+	}
+
+	kept := docs[:0]
+	for _, doc := range docs {
+		source := map[string]any{}
+		if len(doc.Source) > 0 {
+			v, err := decodeValue(doc.Source)
+			if err != nil {
+				return nil, fmt.Errorf("transforming document %q: %w", doc.ID, err)
+			}
+			if m, ok := v.(map[string]any); ok {
+				source = m
+			}
+		}
+		for _, fn := range d.transforms {
+			if source = fn(source); source == nil {
+				break
+			}
+		}
+		if source == nil {
+			continue
+		}
+		body, err := encodeInOrder(doc.Source, source)
+		if err != nil {
+			return nil, fmt.Errorf("transforming document %q: %w", doc.ID, err)
+		}
+		doc.Source = body
+		kept = append(kept, doc)
+	}
+	return kept, nil
+}
+
+// pitSearchBody returns the body of the search for a page of size documents
+// in the point in time pit, following the document whose sort values are
+// after, or starting with the first if after is nil. The first page also
+// counts the documents found.
+func pitSearchBody(search []jsonField, pit string, size int, after json.RawMessage) (json.RawMessage, error) {
+	point, err := json.Marshal(struct {
+		ID        string `json:"id"`
+		KeepAlive string `json:"keep_alive"`
+	}{pit, dumpKeepAlive})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := append(slices.Clone(search),
+		jsonField{key: "pit", value: point},
+		jsonField{key: "size", value: json.RawMessage(strconv.Itoa(size))},
+		jsonField{key: "sort", value: json.RawMessage(`[{"_shard_doc":"asc"}]`)},
+	)
+	if after == nil {
+		fields = append(fields, jsonField{key: "track_total_hits", value: json.RawMessage(`true`)})
+	} else {
+		fields = append(fields, jsonField{key: "search_after", value: after})
+	}
+	return encodeObject(fields), nil
+}
+
+// pitPage is a page of search results in a point in time.
+type pitPage struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			ID      string          `json:"_id"`
+			Routing string          `json:"_routing"`
+			Source  json.RawMessage `json:"_source"`
+			Sort    json.RawMessage `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// decodePITPage reads and closes a search response in a point in time.
+func decodePITPage(res *esapi.Response) (pitPage, error) {
+	defer func() { _ = res.Body.Close() }()
+
+	var page pitPage
+	if err := checkResponse(res); err != nil {
+		return page, err
+	}
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		return page, fmt.Errorf("decoding search results: %w", err)
+	}
+	return page, nil
+}
+
+// openPointInTime opens a point in time over index, returning its id.
+func openPointInTime(ctx context.Context, client *elasticsearch.Client, index string) (string, error) {
+	res, err := client.OpenPointInTime([]string{index}, dumpKeepAlive, client.OpenPointInTime.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("opening point in time: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return "", fmt.Errorf("opening point in time: %w", err)
+	}
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return "", fmt.Errorf("decoding point in time: %w", err)
+	}
+	return pit.ID, nil
+}
+
+// closePointInTime releases a point in time; errors are ignored since it
+// expires on its own.
+func closePointInTime(client *elasticsearch.Client, id string) {
+	body, err := json.Marshal(struct {
+		ID string `json:"id"`
+	}{id})
+	if err != nil {
+		return
+	}
+	res, err := client.ClosePointInTime(client.ClosePointInTime.WithBody(bytes.NewReader(body)))
+	if err == nil {
+		_ = res.Body.Close()
+	}
+}
+
+// indexMapping returns the mapping of index.
+func (d *Dumper) indexMapping(ctx context.Context, index string) (json.RawMessage, error) {
+	res, err := d.client.Indices.GetMapping(
+		d.client.Indices.GetMapping.WithContext(ctx),
+		d.client.Indices.GetMapping.WithIndex(index),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+	var indices map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("decoding mapping response: %w", err)
+	}
+
+	m, err := singleIndex(index, indices)
+	if err != nil {
+		return nil, err
+	}
+	if m.Mappings == nil {
+		return json.RawMessage(`{}`), nil
+	}
+	return m.Mappings, nil
+}
+
+// indexSettings returns the index settings of index that a fixture can set,
+// without the "index." prefix.
+func (d *Dumper) indexSettings(ctx context.Context, index string) (json.RawMessage, error) {
+	res, err := d.client.Indices.GetSettings(
+		d.client.Indices.GetSettings.WithContext(ctx),
+		d.client.Indices.GetSettings.WithIndex(index),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+	var indices map[string]struct {
+		Settings struct {
+			Index json.RawMessage `json:"index"`
+		} `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("decoding settings response: %w", err)
+	}
+
+	s, err := singleIndex(index, indices)
+	if err != nil {
+		return nil, err
+	}
+	if s.Settings.Index == nil {
+		return json.RawMessage(`{}`), nil
+	}
+	fields, err := decodeObject(s.Settings.Index)
+	if err != nil {
+		return nil, err
+	}
+	fields = slices.DeleteFunc(fields, func(f jsonField) bool { return slices.Contains(generatedSettings, f.key) })
+	return withoutAllocationSettings(encodeObject(fields))
+}
+
+// singleIndex returns the only entry of a response keyed by index name,
+// which a dumped alias must resolve to.
+func singleIndex[T any](index string, indices map[string]T) (T, error) {
+	var zero T
+	switch len(indices) {
+	case 0:
+		return zero, fmt.Errorf("index %q not found", index)
+	case 1:
+		for _, v := range indices {
+			return v, nil
+		}
+	}
+	return zero, fmt.Errorf("%q resolves to %d indices; dump them one at a time", index, len(indices))
+}
+
+// writeJSONFile writes body to path as indented JSON, keeping its key order.
+func writeJSONFile(path string, body json.RawMessage) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err != nil {
+		return fmt.Errorf("formatting %s: %w", filepath.Base(path), err)
+	}
+	buf.WriteByte('\n')
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,294 @@
+package testfixtures
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDumper_Dump(t *testing.T) {
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/products/_mapping":
+			return jsonResponse(200, `{"products-v2":{"mappings":{"properties":{"name":{"type":"text"},"price":{"type":"double"}}}}}`), nil
+		case "/products/_settings":
+			return jsonResponse(200, `{"products-v2":{"settings":{"index":{
+				"number_of_shards":"2","number_of_replicas":"1",
+				"uuid":"AbC","creation_date":"1700000000000","provided_name":"products-v2",
+				"version":{"created":"8190299"},
+				"routing":{"allocation":{"include":{"_tier_preference":"data_content"}}},
+				"analysis":{"analyzer":{"folded":{"type":"custom","tokenizer":"standard"}}}
+			}}}}`), nil
+		case "/products/_pit":
+			return jsonResponse(200, `{"id":"p1"}`), nil
+		case "/_search":
+			return jsonResponse(200, `{"pit_id":"p1","hits":{"total":{"value":2},"hits":[
+				{"_id":"2","_source":{"name":"Pen","price":1.5},"sort":[0]},
+				{"_id":"1","_routing":"eu","_source":{"name":"Book","price":12},"sort":[1]}
+			]}}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"products/documents-01.yml": "- _id: old\n"})
+	if err := NewDumper(client).Dump(t.Context(), "products", dir); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	want := map[string]string{
+		"_mapping.json": `{
+  "properties": {
+    "name": {
+      "type": "text"
+    },
+    "price": {
+      "type": "double"
+    }
+  }
+}
+`,
+		"_settings.json": `{
+  "number_of_shards": "2",
+  "number_of_replicas": "1",
+  "analysis": {
+    "analyzer": {
+      "folded": {
+        "type": "custom",
+        "tokenizer": "standard"
+      }
+    }
+  }
+}
+`,
+		"documents.yml": `- _id: "1"
+  _routing: "eu"
+  name: Book
+  price: 12
+- _id: "2"
+  name: Pen
+  price: 1.5
+`,
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, "products", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, content, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "products", "documents-01.yml")); !os.IsNotExist(err) {
+		t.Errorf("expected the document file of an earlier write to be removed, got %v", err)
+	}
+
+	// The dump loads back as a fixture with the same documents
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if docs := loader.fixture("products").documents; len(docs) != 2 || docs[0].Routing != "eu" {
+		t.Errorf("expected the dumped documents to load back, got %+v", docs)
+	}
+}
+
+func TestDumper_DumpAliasOfSeveralIndices(t *testing.T) {
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"logs-1":{"mappings":{}},"logs-2":{"mappings":{}}}`), nil
+	}))
+
+	err := NewDumper(client).Dump(t.Context(), "logs", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), `"logs" resolves to 2 indices; dump them one at a time`) {
+		t.Errorf("expected an error for an alias of several indices, got %v", err)
+	}
+}
+
+func TestDumper_DumpQueryAndSource(t *testing.T) {
+	var search string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/orders/_mapping":
+			return jsonResponse(200, `{"orders":{"mappings":{}}}`), nil
+		case "/orders/_settings":
+			return jsonResponse(200, `{"orders":{"settings":{"index":{"number_of_shards":"1"}}}}`), nil
+		case "/orders/_pit":
+			return jsonResponse(200, `{"id":"p1"}`), nil
+		case "/_search":
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			search = string(body)
+			return jsonResponse(200, `{"hits":{"total":{"value":1},"hits":[{"_id":"1","_source":{"status":"active"},"sort":[0]}]}}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	dir := t.TempDir()
+	d := NewDumper(client,
+		DumpQuery(`{"term":{"status":"active"}}`),
+		DumpSourceIncludes("status", "customer.*"),
+		DumpSourceExcludes("customer.email"),
+	)
+	if err := d.Dump(t.Context(), "orders", dir); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	want := `{"query":{"term":{"status":"active"}},"_source":{"includes":["status","customer.*"],"excludes":["customer.email"]},` +
+		`"pit":{"id":"p1","keep_alive":"1m"},"size":1000,"sort":[{"_shard_doc":"asc"}],"track_total_hits":true}`
+	if search != want {
+		t.Errorf("expected search body\n%s\ngot\n%s", want, search)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "orders", documentsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "- _id: \"1\"\n  status: active\n" {
+		t.Errorf("unexpected documents:\n%s", got)
+	}
+
+	err = NewDumper(client, DumpQuery(`term: {status: active}`)).Dump(t.Context(), "orders", dir)
+	if err == nil || !strings.Contains(err.Error(), "query must be a JSON object") {
+		t.Errorf("expected an error for a query that is not JSON, got %v", err)
+	}
+}
+
+func TestDumper_DumpPages(t *testing.T) {
+	var (
+		searches []string
+		closed   string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/events/_mapping":
+			return jsonResponse(200, `{"events":{"mappings":{}}}`), nil
+		case "/events/_settings":
+			return jsonResponse(200, `{"events":{"settings":{"index":{}}}}`), nil
+		case "/events/_pit":
+			if got := req.URL.Query().Get("keep_alive"); got != "1m" {
+				t.Errorf("expected keep_alive 1m, got %q", got)
+			}
+			return jsonResponse(200, `{"id":"p1"}`), nil
+		case "/_pit":
+			body, _ := io.ReadAll(req.Body)
+			closed = string(body)
+			return jsonResponse(200, `{"succeeded":true}`), nil
+		case "/_search":
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			searches = append(searches, string(body))
+			if len(searches) == 1 {
+				hits := make([]string, fetchBatchSize)
+				for i := range hits {
+					hits[i] = fmt.Sprintf(`{"_id":"%04d","_source":{},"sort":[%d]}`, i, i)
+				}
+				return jsonResponse(200, `{"pit_id":"p2","hits":{"total":{"value":5000},"hits":[`+strings.Join(hits, ",")+`]}}`), nil
+			}
+			return jsonResponse(200, `{"pit_id":"p3","hits":{"total":{"value":5000},"hits":[{"_id":"1000","_source":{},"sort":[1000]},{"_id":"1001","_source":{},"sort":[1001]}]}}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	var progress [][2]int
+	d := NewDumper(client,
+		DumpMaxDocs(fetchBatchSize+2),
+		DumpProgress(func(dumped, total int) { progress = append(progress, [2]int{dumped, total}) }),
+	)
+	dir := t.TempDir()
+	if err := d.Dump(t.Context(), "events", dir); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	if len(searches) != 2 {
+		t.Fatalf("expected 2 searches, got %d", len(searches))
+	}
+	if want := `{"pit":{"id":"p2","keep_alive":"1m"},"size":2,"sort":[{"_shard_doc":"asc"}],"search_after":[999]}`; searches[1] != want {
+		t.Errorf("expected the second search\n%s\ngot\n%s", want, searches[1])
+	}
+	if closed != `{"id":"p3"}` {
+		t.Errorf("expected the last point in time to be closed, got %q", closed)
+	}
+	if want := [][2]int{{1000, 1002}, {1002, 1002}}; !slices.Equal(progress, want) {
+		t.Errorf("expected progress %v, got %v", want, progress)
+	}
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if docs := loader.fixture("events").documents; len(docs) != fetchBatchSize+2 || docs[0].ID != "0000" || docs[len(docs)-1].ID != "1001" {
+		t.Errorf("expected %d dumped documents ordered by _id, got %d", fetchBatchSize+2, len(docs))
+	}
+}
+
+func TestDumper_DumpTransform(t *testing.T) {
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/users/_mapping":
+			return jsonResponse(200, `{"users":{"mappings":{}}}`), nil
+		case "/users/_settings":
+			return jsonResponse(200, `{"users":{"settings":{"index":{}}}}`), nil
+		case "/users/_pit":
+			return jsonResponse(200, `{"id":"p1"}`), nil
+		case "/_search":
+			return jsonResponse(200, `{"hits":{"total":{"value":3},"hits":[
+				{"_id":"1","_source":{"name":"Ann","email":"ann@example.org","id":12345678901234567890,"address":{"zip":"100","city":"Tokyo"}},"sort":[0]},
+				{"_id":"2","_source":{"name":"Bob","email":"bob@example.org","test":true},"sort":[1]},
+				{"_id":"3","_source":{"name":"Cy"},"sort":[2]}
+			]}}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	d := NewDumper(client,
+		DumpTransform(func(doc map[string]any) map[string]any {
+			if doc["test"] == true {
+				return nil
+			}
+			return doc
+		}),
+		DumpTransform(func(doc map[string]any) map[string]any {
+			if _, ok := doc["email"]; ok {
+				doc["email"] = "user@example.com"
+				doc["masked"] = true
+			}
+			if address, ok := doc["address"].(map[string]any); ok {
+				address["city"] = "Osaka"
+				delete(address, "zip")
+				address["country"] = "JP"
+			}
+			return doc
+		}),
+	)
+	dir := t.TempDir()
+	if err := d.Dump(t.Context(), "users", dir); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "users", documentsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- _id: "1"
+  name: Ann
+  email: user@example.com
+  id: 12345678901234567890
+  address:
+    city: Osaka
+    country: JP
+  masked: true
+- _id: "3"
+  name: Cy
+`
+	if string(got) != want {
+		t.Errorf("expected documents\n%s\ngot\n%s", want, got)
+	}
+}
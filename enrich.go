@@ -0,0 +1,362 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// enrichDir is the top-level directory holding enrich policies, one
+// <name>.json file per policy in the format of the Create Enrich Policy API.
+// Load creates and executes each policy once its source indices are loaded.
+const enrichDir = "_enrich"
+
+// enrichPolicyTypes are the keys of an enrich policy naming its type.
+var enrichPolicyTypes = []string{"match", "geo_match", "range"}
+
+// enrichPolicy is a policy of the _enrich directory.
+type enrichPolicy struct {
+	name    string
+	kind    string          // One of enrichPolicyTypes
+	config  json.RawMessage // The object under kind
+	indices []string        // Source fixture indices
+}
+
+// readEnrichPolicies reads the policies of the _enrich directory, ordered by
+// name. A missing directory means there are none.
+func readEnrichPolicies(fsys fs.FS, dir string) ([]enrichPolicy, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, enrichDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", enrichDir, err)
+	}
+
+	var policies []enrichPolicy
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		file := enrichDir + "/" + name
+		body, err := readJSONFile(fsys, path.Join(dir, enrichDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		p, err := parseEnrichPolicy(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		p.name = strings.TrimSuffix(name, ".json")
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// parseEnrichPolicy parses an enrich policy definition, which holds a
+// single policy type whose indices are a source index or a list of them.
+func parseEnrichPolicy(body json.RawMessage) (enrichPolicy, error) {
+	var fields []jsonField
+	if isJSONObject(body) {
+		fields, _ = decodeObject(body)
+	}
+	if len(fields) != 1 || !slices.Contains(enrichPolicyTypes, fields[0].key) || !isJSONObject(fields[0].value) {
+		return enrichPolicy{}, errors.New(`policy must be an object with a single "match", "geo_match", or "range" object`)
+	}
+
+	var def struct {
+		Indices json.RawMessage `json:"indices"`
+	}
+	if err := json.Unmarshal(fields[0].value, &def); err != nil {
+		return enrichPolicy{}, err
+	}
+	var indices []string
+	if err := json.Unmarshal(def.Indices, &indices); err != nil {
+		var index string
+		if json.Unmarshal(def.Indices, &index) != nil {
+			return enrichPolicy{}, errors.New("indices must be a source index or a list of them")
+		}
+		indices = []string{index}
+	}
+	if len(indices) == 0 {
+		return enrichPolicy{}, errors.New("indices must not be empty")
+	}
+
+	return enrichPolicy{kind: fields[0].key, config: fields[0].value, indices: indices}, nil
+}
+
+// checkEnrichPolicies checks that the source indices of every enrich policy
+// are fixture indices, which Load fills before executing the policy.
+func (l *Loader) checkEnrichPolicies() error {
+	var errs []error
+	for _, p := range l.enrichPolicies {
+		for _, index := range p.indices {
+			if f := l.fixture(index); f == nil || f.isAlias() {
+				errs = append(errs, fmt.Errorf("%s/%s.json: source index %q is not a fixture index", enrichDir, p.name, index))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// orderEnrichSources moves the source indices of enrich policies ahead of
+// the other index fixtures, keeping alias fixtures last, so each policy is
+// executed before the indices whose pipelines look up its data are loaded.
+func (l *Loader) orderEnrichSources() {
+	source := make(map[string]bool)
+	for _, p := range l.enrichPolicies {
+		for _, index := range p.indices {
+			source[index] = true
+		}
+	}
+	if len(source) == 0 {
+		return
+	}
+
+	rank := func(f *indexFixture) int {
+		switch {
+		case f.isAlias():
+			return 2
+		case source[f.name]:
+			return 0
+		}
+		return 1
+	}
+	slices.SortStableFunc(l.fixtures, func(a, b *indexFixture) int { return rank(a) - rank(b) })
+}
+
+// enrichPolicyBody returns the Create Enrich Policy body of p, with its
+// source indices named as they are in the cluster.
+func (l *Loader) enrichPolicyBody(p enrichPolicy) (json.RawMessage, error) {
+	indices := make([]string, len(p.indices))
+	for i, index := range p.indices {
+		indices[i] = l.IndexName(index)
+	}
+	value, err := json.Marshal(indices)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := decodeObject(p.config)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range fields {
+		if f.key == "indices" {
+			fields[i].value = value
+		}
+	}
+	return encodeObject([]jsonField{{key: p.kind, value: encodeObject(fields)}}), nil
+}
+
+// enrichReferences returns the enrich policies a pipeline definition looks
+// up with enrich processors, wherever they appear in it.
+func enrichReferences(body json.RawMessage) []string {
+	v, err := decodeValue(body)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		names []string
+		walk  func(any)
+	)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if p, ok := v["enrich"].(map[string]any); ok {
+				if name, ok := p["policy_name"].(string); ok && !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(v)
+
+	slices.Sort(names)
+	return names
+}
+
+// executeEnrichPolicies creates and executes the enrich policies whose last
+// source index among fixtures is f, now that their source data is loaded.
+// Policies cannot be replaced, so each is deleted first, together with the
+// pipelines of CreatePipelines that use it; those are removed from put so
+// they are created again, and added to dropped.
+func (l *Loader) executeEnrichPolicies(ctx context.Context, f *indexFixture, fixtures []*indexFixture, put, dropped map[string]bool) error {
+	for _, p := range l.enrichPolicies {
+		if lastSource(p.indices, fixtures) != f {
+			continue
+		}
+
+		for _, name := range l.enrichPipelines(p.name) {
+			if err := deletePipeline(ctx, l.client, name); err != nil {
+				return err
+			}
+			delete(put, name)
+			dropped[name] = true
+		}
+		if err := deleteEnrichPolicy(ctx, l.client, p.name); err != nil {
+			return err
+		}
+
+		body, err := l.enrichPolicyBody(p)
+		if err != nil {
+			return fmt.Errorf("enrich policy %q: %w", p.name, err)
+		}
+		if err := putEnrichPolicy(ctx, l.client, p.name, body); err != nil {
+			return err
+		}
+		if err := executeEnrichPolicy(ctx, l.client, p.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreEnrichPipelines creates again the pipelines that
+// executeEnrichPolicies deleted and no index of the Load has recreated, as
+// indices left out of LoadIndices may still use them.
+func (l *Loader) restoreEnrichPipelines(ctx context.Context, put, dropped map[string]bool) error {
+	for _, name := range slices.Sorted(maps.Keys(dropped)) {
+		if put[name] {
+			continue
+		}
+		if err := putPipeline(ctx, l.client, name, l.pipelines[name]); err != nil {
+			return err
+		}
+		put[name] = true
+	}
+	return nil
+}
+
+// enrichPipelines returns the pipelines of _pipelines that look up the
+// enrich policy name, if CreatePipelines is set. Other pipelines are not
+// the Loader's to delete.
+func (l *Loader) enrichPipelines(name string) []string {
+	if !l.createPipelines {
+		return nil
+	}
+	var names []string
+	for _, pipeline := range slices.Sorted(maps.Keys(l.pipelines)) {
+		if slices.Contains(enrichReferences(l.pipelines[pipeline]), name) {
+			names = append(names, pipeline)
+		}
+	}
+	return names
+}
+
+// deleteEnrichPolicies removes the enrich policies of _enrich, and with them
+// their .enrich-* indices, after the pipelines of CreatePipelines that use
+// them. It returns the errors of the deletions that failed.
+func (l *Loader) deleteEnrichPolicies(ctx context.Context) []error {
+	var errs []error
+	for _, p := range l.enrichPolicies {
+		var err error
+		for _, name := range l.enrichPipelines(p.name) {
+			if err = deletePipeline(ctx, l.client, name); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = deleteEnrichPolicy(ctx, l.client, p.name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// putEnrichPolicy creates the enrich policy name.
+func putEnrichPolicy(ctx context.Context, client *elasticsearch.Client, name string, body json.RawMessage) error {
+	res, err := client.EnrichPutPolicy(name, bytes.NewReader(body), client.EnrichPutPolicy.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating enrich policy %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating enrich policy %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// executeEnrichPolicy builds the enrich index of the policy name from its
+// source indices, waiting until it is done.
+func executeEnrichPolicy(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.EnrichExecutePolicy(name,
+		client.EnrichExecutePolicy.WithWaitForCompletion(true),
+		client.EnrichExecutePolicy.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("executing enrich policy %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("executing enrich policy %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// deleteEnrichPolicy deletes the enrich policy name and its enrich indices
+// if it exists.
+func deleteEnrichPolicy(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.EnrichDeletePolicy(name, client.EnrichDeletePolicy.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting enrich policy %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The policy may already be gone, which is fine
+	if res.StatusCode == 404 {
+		return nil
+	}
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting enrich policy %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// deletePipeline deletes the ingest pipeline name if it exists.
+func deletePipeline(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.Ingest.DeletePipeline(name, client.Ingest.DeletePipeline.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting pipeline %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The pipeline may already be gone, which is fine
+	if res.StatusCode == 404 {
+		return nil
+	}
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting pipeline %q: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadEnrichPolicies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_enrich/users.json":  {Data: []byte(`{"match": {"indices": "users", "match_field": "email", "enrich_fields": ["name"]}}`)},
+		"_enrich/cities.json": {Data: []byte(`{"geo_match": {"indices": ["cities", "towns"], "match_field": "area", "enrich_fields": ["name"]}}`)},
+		"_enrich/README.md":   {Data: []byte("notes")},
+	}
+
+	policies, err := readEnrichPolicies(fsys, ".")
+	if err != nil {
+		t.Fatalf("readEnrichPolicies() error: %v", err)
+	}
+	if len(policies) != 2 {
+		t.Fatalf("expected 2 policies, got %d", len(policies))
+	}
+	if p := policies[0]; p.name != "cities" || p.kind != "geo_match" || !slices.Equal(p.indices, []string{"cities", "towns"}) {
+		t.Errorf("unexpected first policy: %+v", p)
+	}
+	if p := policies[1]; p.name != "users" || p.kind != "match" || !slices.Equal(p.indices, []string{"users"}) {
+		t.Errorf("unexpected second policy: %+v", p)
+	}
+
+	if policies, err := readEnrichPolicies(fstest.MapFS{}, "."); policies != nil || err != nil {
+		t.Errorf("expected no policies for a missing directory, got %v, %v", policies, err)
+	}
+}
+
+func TestReadEnrichPolicies_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "unknown type", data: `{"lookup": {"indices": "users"}}`, want: `_enrich/users.json: policy must be an object with a single "match", "geo_match", or "range" object`},
+		{name: "two types", data: `{"match": {"indices": "users"}, "range": {"indices": "users"}}`, want: "policy must be an object with a single"},
+		{name: "no indices", data: `{"match": {"match_field": "email"}}`, want: "_enrich/users.json: indices must be a source index or a list of them"},
+		{name: "empty indices", data: `{"match": {"indices": []}}`, want: "_enrich/users.json: indices must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"_enrich/users.json": {Data: []byte(tt.data)}}
+			_, err := readEnrichPolicies(fsys, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNew_EnrichSourceNotAFixture(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_enrich/users.json":   `{"match": {"indices": ["users", "buyers"], "match_field": "email", "enrich_fields": ["name"]}}`,
+		"buyers/_config.yml":   "alias:\n  indices: [orders]\n",
+		"orders/documents.yml": "- _id: \"1\"\n  email: a@example.com\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	for _, want := range []string{`_enrich/users.json: source index "users" is not a fixture index`, `source index "buyers" is not a fixture index`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestEnrichReferences(t *testing.T) {
+	body := `{"processors": [{"enrich": {"policy_name": "users", "field": "email", "target_field": "user"}}, {"foreach": {"field": "items", "processor": {"enrich": {"policy_name": "products", "field": "_ingest._value.sku", "target_field": "_ingest._value.product"}}}}]}`
+	if got := enrichReferences([]byte(body)); !slices.Equal(got, []string{"products", "users"}) {
+		t.Errorf("enrichReferences() = %v", got)
+	}
+}
+
+func TestLoad_EnrichPolicies(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_enrich/user-lookup.json":      `{"match": {"indices": "users", "match_field": "email", "enrich_fields": ["name"]}}`,
+		"_pipelines/orders-enrich.json": `{"processors": [{"enrich": {"policy_name": "user-lookup", "field": "email", "target_field": "user"}}]}`,
+		"orders/_settings.json":         `{"index": {"default_pipeline": "orders-enrich"}}`,
+		"orders/documents.yml":          "- _id: \"1\"\n  email: alice@example.com\n",
+		"users/documents.yml":           "- _id: \"1\"\n  email: alice@example.com\n  name: Alice\n",
+	})
+
+	var requests []string
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		if req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/_enrich/") {
+			return jsonResponse(404, `{}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), CreatePipelines(), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	order := []string{
+		"POST /ci_users/_bulk",
+		"DELETE /_ingest/pipeline/orders-enrich",
+		"DELETE /_enrich/policy/user-lookup",
+		"PUT /_enrich/policy/user-lookup",
+		"PUT /_enrich/policy/user-lookup/_execute",
+		"PUT /_ingest/pipeline/orders-enrich",
+		"PUT /ci_orders",
+	}
+	last := -1
+	for _, want := range order {
+		i := slices.Index(requests, want)
+		if i <= last {
+			t.Fatalf("expected requests in the order %v, got %v", order, requests)
+		}
+		last = i
+	}
+	want := `{"match":{"indices":["ci_users"],"match_field":"email","enrich_fields":["name"]}}`
+	if got := bodies["/_enrich/policy/user-lookup"]; got != want {
+		t.Errorf("expected policy body %s, got %s", want, got)
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	del := slices.Index(requests, "DELETE /ci_users")
+	pipeline := slices.Index(requests, "DELETE /_ingest/pipeline/orders-enrich")
+	policy := slices.Index(requests, "DELETE /_enrich/policy/user-lookup")
+	if del < 0 || pipeline < del || policy < pipeline {
+		t.Errorf("expected Clean to delete the indices, then the pipeline, then the policy, got %v", requests)
+	}
+}
+
+func TestLoadIndices_RestoresEnrichPipelines(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_enrich/user-lookup.json":      `{"match": {"indices": "users", "match_field": "email", "enrich_fields": ["name"]}}`,
+		"_pipelines/orders-enrich.json": `{"processors": [{"enrich": {"policy_name": "user-lookup", "field": "email", "target_field": "user"}}]}`,
+		"orders/_settings.json":         `{"index": {"default_pipeline": "orders-enrich"}}`,
+		"orders/documents.yml":          "- _id: \"1\"\n  email: alice@example.com\n",
+		"users/documents.yml":           "- _id: \"1\"\n  email: alice@example.com\n  name: Alice\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), CreatePipelines())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.LoadIndices("users"); err != nil {
+		t.Fatalf("LoadIndices() error: %v", err)
+	}
+
+	execute := slices.Index(requests, "PUT /_enrich/policy/user-lookup/_execute")
+	restore := slices.Index(requests, "PUT /_ingest/pipeline/orders-enrich")
+	if execute < 0 || restore < execute || slices.Contains(requests, "PUT /orders") {
+		t.Errorf("expected the pipeline of the untouched orders index to be created again after the policy, got %v", requests)
+	}
+}
@@ -0,0 +1,68 @@
+package testfixtures
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReference matches ${VAR} and ${VAR:-default} in fixture files.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// envFS expands environment variable references in the mapping, settings,
+// and YAML document files of the fixtures directory root when they are read
+// with fs.ReadFile. Other files are read as they are.
+type envFS struct {
+	fs.FS
+	root string
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (e envFS) ReadFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(e.FS, name)
+	if err != nil || !(isSchemaFile(e.root, name) || isDocumentFile(e.root, name)) {
+		return data, err
+	}
+	return expandEnv(data)
+}
+
+// expandEnv replaces each ${VAR} in data with the value of the environment
+// variable VAR, and each ${VAR:-default} with default if VAR is unset or
+// empty. An unset variable without a default is an error giving the line
+// of the reference.
+func expandEnv(data []byte) ([]byte, error) {
+	var err error
+	expanded := envReference.ReplaceAllFunc(data, func(ref []byte) []byte {
+		m := envReference.FindSubmatch(ref)
+		value, ok := os.LookupEnv(string(m[1]))
+		if hasDefault := bytes.Contains(ref, []byte(":-")); hasDefault && value == "" {
+			return m[2]
+		}
+		if !ok && err == nil {
+			line := 1 + bytes.Count(data[:bytes.Index(data, ref)], []byte("\n"))
+			err = fmt.Errorf("line %d: environment variable %s is not set", line, m[1])
+		}
+		return []byte(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+// isSchemaFile reports whether name is the mapping or settings file of an
+// index directory, or of _common, in the fixtures directory root.
+func isSchemaFile(root, name string) bool {
+	rel, ok := fixturePath(root, name)
+	if !ok {
+		return false
+	}
+	dir, base, ok := strings.Cut(rel, "/")
+	if !ok || strings.Contains(base, "/") || (strings.HasPrefix(dir, "_") && dir != commonDir) {
+		return false
+	}
+	return base == mappingFile || base == settingsFile || base == settingsYAMLFile
+}
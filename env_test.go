@@ -0,0 +1,62 @@
+package testfixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("FIXTURE_ANALYZER", "english")
+	t.Setenv("FIXTURE_REGION", "eu-west-1")
+	t.Setenv("FIXTURE_EMPTY", "")
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_common/_settings.json": `{"number_of_replicas": ${FIXTURE_REPLICAS:-0}}`,
+		"_traits.yml":            "eu:\n  zone: ${FIXTURE_REGION}\n",
+		"users/_config.yml":      "inherit_common: true\n",
+		"users/_mapping.json":    `{"properties":{"name":{"type":"text","analyzer":"${FIXTURE_ANALYZER}"}}}`,
+		"users/documents.yml":    "- _id: 1\n  region: ${FIXTURE_REGION}\n  tier: ${FIXTURE_EMPTY:-free}\n  price: $PRICE\n  _traits: eu\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir), ExpandEnv())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	f := loader.fixtures[0]
+	if !strings.Contains(string(f.mapping), `"analyzer":"english"`) {
+		t.Errorf("expected the analyzer to be expanded, got %s", f.mapping)
+	}
+	if !strings.Contains(string(f.settings), `"number_of_replicas":0`) {
+		t.Errorf("expected the default replica count, got %s", f.settings)
+	}
+	want := `{"zone":"${FIXTURE_REGION}","region":"eu-west-1","tier":"free","price":"$PRICE"}`
+	if got := string(f.documents[0].Source); got != want {
+		t.Errorf("document = %s, want %s", got, want)
+	}
+}
+
+func TestExpandEnv_Unset(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_settings.json": "{\n  \"number_of_replicas\": ${FIXTURE_UNSET_REPLICAS}\n}\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir), ExpandEnv())
+	if err == nil || !strings.Contains(err.Error(), "_settings.json: line 2: environment variable FIXTURE_UNSET_REPLICAS is not set") {
+		t.Errorf("expected an error naming the unset variable, got %v", err)
+	}
+
+	// Without ExpandEnv, references are kept as written.
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_settings.json": `{"index":{"default_pipeline":"${FIXTURE_UNSET_PIPELINE}"}}`,
+	})
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if !strings.Contains(string(loader.fixtures[0].settings), "${FIXTURE_UNSET_PIPELINE}") {
+		t.Errorf("expected the settings as written, got %s", loader.fixtures[0].settings)
+	}
+}
@@ -0,0 +1,84 @@
+package testfixtures
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is something observable that happened while the Loader worked with
+// the cluster, as passed to handlers registered with WithEventHandler. It is
+// one of IndexDeleted, IndexCreated, IndexTruncated, IndexSynced,
+// BulkFlushed, or LoadFinished.
+type Event interface {
+	isEvent()
+}
+
+// IndexDeleted is emitted after a managed index is deleted, by Load before
+// recreating it, by Clean, or when an interrupted Load rolls back.
+type IndexDeleted struct {
+	Index string // Index name
+}
+
+// IndexCreated is emitted after Load creates an index with its mapping and
+// settings, before any documents are inserted.
+type IndexCreated struct {
+	Index string // Index name
+}
+
+// IndexTruncated is emitted after Load deletes the documents of an existing
+// index under StrategyTruncate, before any documents are inserted.
+type IndexTruncated struct {
+	Index   string // Index name
+	Deleted int    // Documents deleted
+}
+
+// IndexSynced is emitted after Load compares the documents of an existing
+// index with the fixture under StrategySync, before it sends the actions
+// that bring the index in line.
+type IndexSynced struct {
+	Index     string // Index name
+	Indexed   int    // Documents to index, new or changed
+	Deleted   int    // Documents to delete, gone from the fixture or changed in routing
+	Unchanged int    // Documents left as they are
+}
+
+// BulkFlushed is emitted after each bulk request sent to an index.
+type BulkFlushed struct {
+	Index     string // Index name
+	Succeeded int    // Documents indexed by the request
+	Failed    int    // Documents rejected by the request, or lost with it
+}
+
+// LoadFinished is emitted when Load returns, whether or not it succeeded.
+type LoadFinished struct {
+	Results  []IndexResult // Per-index outcome, as returned by Results
+	Duration time.Duration // Time taken by the whole load
+	Err      error         // Error returned by Load, if any
+}
+
+func (IndexDeleted) isEvent()   {}
+func (IndexCreated) isEvent()   {}
+func (IndexTruncated) isEvent() {}
+func (IndexSynced) isEvent()    {}
+func (BulkFlushed) isEvent()    {}
+func (LoadFinished) isEvent()   {}
+
+// eventBus delivers events to the registered handlers. Bulk requests complete
+// on indexer goroutines, so delivery is serialized: a handler never runs
+// concurrently with itself or with another handler.
+type eventBus struct {
+	mu       sync.Mutex
+	handlers []func(Event)
+}
+
+// emit passes e to every handler, in registration order.
+func (b *eventBus) emit(e Event) {
+	if len(b.handlers) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,285 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"gopkg.in/yaml.v3"
+)
+
+// expectFile is the optional per-index file declaring the number of
+// documents the index holds once loaded, checked by every Load.
+const expectFile = "_expect.yml"
+
+// countExpectations is the contents of an index's _expect.yml:
+//
+//	count: 3
+//	routing:
+//	  tenant-a: 2
+//	  tenant-b: 1
+//	queries:
+//	  adults:
+//	    query: {range: {age: {gte: 30}}}
+//	    count: 2
+type countExpectations struct {
+	count   *int           // Documents in the index; nil if not declared
+	routing []routingCount // In file order
+	queries []queryCount   // In file order
+}
+
+// routingCount is the number of documents of a custom routing value.
+type routingCount struct {
+	value string
+	count int
+}
+
+// queryCount is the number of documents a named query should match.
+type queryCount struct {
+	name  string
+	query json.RawMessage // Query clause, sent as {"query": ...}
+	count int
+}
+
+// readCountExpectations reads an index's _expect.yml. Unknown keys are
+// rejected so that typos do not silently disable a check. A missing file
+// means the index has no expected counts.
+func readCountExpectations(fsys fs.FS, name string) (*countExpectations, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", expectFile, err)
+	}
+
+	var raw struct {
+		Count   *int      `yaml:"count"`
+		Routing yaml.Node `yaml:"routing"`
+		Queries yaml.Node `yaml:"queries"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("parsing %s: %w", expectFile, err)
+	}
+	if raw.Count != nil && *raw.Count < 0 {
+		return nil, fmt.Errorf("parsing %s: count must not be negative, got %d", expectFile, *raw.Count)
+	}
+
+	e := &countExpectations{count: raw.Count}
+	if raw.Routing.Kind != 0 {
+		if raw.Routing.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("parsing %s: line %d: routing must be a mapping of routing values to counts", expectFile, raw.Routing.Line)
+		}
+		pairs, err := mappingPairs(&raw.Routing)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", expectFile, err)
+		}
+		for _, p := range pairs {
+			var n int
+			if err := p.value.Decode(&n); err != nil || n < 0 {
+				return nil, fmt.Errorf("parsing %s: routing %q: count must be a non-negative integer, got %q", expectFile, p.key, p.value.Value)
+			}
+			e.routing = append(e.routing, routingCount{value: p.key, count: n})
+		}
+	}
+	if raw.Queries.Kind == 0 {
+		return e, nil
+	}
+	if raw.Queries.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parsing %s: line %d: queries must be a mapping of query names", expectFile, raw.Queries.Line)
+	}
+	pairs, err := mappingPairs(&raw.Queries)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", expectFile, err)
+	}
+	for _, p := range pairs {
+		var q struct {
+			Query yaml.Node `yaml:"query"`
+			Count *int      `yaml:"count"`
+		}
+		if err := p.value.Decode(&q); err != nil {
+			return nil, fmt.Errorf("parsing %s: query %q: %w", expectFile, p.key, err)
+		}
+		if q.Query.Kind == 0 || q.Count == nil {
+			return nil, fmt.Errorf("parsing %s: query %q: query and count are required", expectFile, p.key)
+		}
+		if *q.Count < 0 {
+			return nil, fmt.Errorf("parsing %s: query %q: count must not be negative, got %d", expectFile, p.key, *q.Count)
+		}
+		query, err := yamlToJSON(&q.Query)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: query %q: encoding query: %w", expectFile, p.key, err)
+		}
+		e.queries = append(e.queries, queryCount{name: p.key, query: query, count: *q.Count})
+	}
+
+	return e, nil
+}
+
+// checkCounts compares the documents in index, after its refresh, with the
+// counts of its _expect.yml, reporting every count that differs.
+func checkCounts(ctx context.Context, client *elasticsearch.Client, index string, e *countExpectations) error {
+	if e == nil {
+		return nil
+	}
+
+	var errs []error
+	if e.count != nil {
+		n, err := countDocs(ctx, client, index, nil)
+		if err != nil {
+			return err
+		}
+		if n != *e.count {
+			errs = append(errs, fmt.Errorf("%s: expected %d documents, found %d", expectFile, *e.count, n))
+		}
+	}
+	if len(e.routing) > 0 {
+		counts, err := countByRouting(ctx, client, index, e.routing)
+		if err != nil {
+			return fmt.Errorf("%s: routing: %w", expectFile, err)
+		}
+		for _, r := range e.routing {
+			if n := counts[r.value]; n != r.count {
+				errs = append(errs, fmt.Errorf("%s: routing %q: expected %d documents, found %d", expectFile, r.value, r.count, n))
+			}
+		}
+	}
+	for _, q := range e.queries {
+		n, err := countDocs(ctx, client, index, q.query)
+		if err != nil {
+			return fmt.Errorf("%s: query %q: %w", expectFile, q.name, err)
+		}
+		if n != q.count {
+			errs = append(errs, fmt.Errorf("%s: query %q: expected %d documents, found %d", expectFile, q.name, q.count, n))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// countByRouting returns the number of documents in index for each of the
+// routing values of expected. They are read with a terms aggregation on
+// _routing where the cluster allows it, and otherwise counted one value at
+// a time, since the _routing field has no doc values in most versions.
+func countByRouting(ctx context.Context, client *elasticsearch.Client, index string, expected []routingCount) (map[string]int, error) {
+	values := make([]string, len(expected))
+	for i, r := range expected {
+		values[i] = r.value
+	}
+	filter, err := json.Marshal(map[string]any{"terms": map[string]any{"_routing": values}})
+	if err != nil {
+		return nil, fmt.Errorf("building routing query: %w", err)
+	}
+
+	counts, ok, err := aggregateRouting(ctx, client, index, filter, len(values))
+	if err != nil || ok {
+		return counts, err
+	}
+
+	counts = make(map[string]int, len(values))
+	for _, v := range values {
+		query, err := json.Marshal(map[string]any{"term": map[string]any{"_routing": v}})
+		if err != nil {
+			return nil, fmt.Errorf("building routing query: %w", err)
+		}
+		if counts[v], err = countDocs(ctx, client, index, query); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// aggregateRouting counts the documents of index matching filter per
+// _routing value with a terms aggregation of up to size buckets. The second
+// result is false if the cluster rejects aggregating on _routing.
+func aggregateRouting(ctx context.Context, client *elasticsearch.Client, index string, filter json.RawMessage, size int) (map[string]int, bool, error) {
+	body, err := json.Marshal(map[string]any{
+		"size":  0,
+		"query": filter,
+		"aggs":  map[string]any{"routing": map[string]any{"terms": map[string]any{"field": "_routing", "size": size}}},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("building routing aggregation: %w", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("aggregating routing values: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// Fielddata is not supported on _routing, which is a bad request
+	if res.StatusCode == 400 {
+		return nil, false, nil
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, false, fmt.Errorf("aggregating routing values: %w", err)
+	}
+
+	var result struct {
+		Aggregations struct {
+			Routing struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int    `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"routing"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, false, fmt.Errorf("decoding routing aggregation: %w", err)
+	}
+
+	counts := make(map[string]int, len(result.Aggregations.Routing.Buckets))
+	for _, b := range result.Aggregations.Routing.Buckets {
+		counts[b.Key] = b.DocCount
+	}
+	return counts, true, nil
+}
+
+// countDocs returns the number of documents in index matching query, or all
+// of them if query is nil.
+func countDocs(ctx context.Context, client *elasticsearch.Client, index string, query json.RawMessage) (int, error) {
+	opts := []func(*esapi.CountRequest){client.Count.WithContext(ctx), client.Count.WithIndex(index)}
+	if query != nil {
+		body, err := json.Marshal(map[string]json.RawMessage{"query": query})
+		if err != nil {
+			return 0, fmt.Errorf("building count body: %w", err)
+		}
+		opts = append(opts, client.Count.WithBody(bytes.NewReader(body)))
+	}
+
+	res, err := client.Count(opts...)
+	if err != nil {
+		return 0, fmt.Errorf("counting documents: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return 0, fmt.Errorf("counting documents: %w", err)
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decoding count response: %w", err)
+	}
+
+	return result.Count, nil
+}
@@ -0,0 +1,217 @@
+package testfixtures
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadCountExpectations(t *testing.T) {
+	fsys := fstest.MapFS{"users/_expect.yml": {Data: []byte(`count: 3
+routing:
+  tenant-b: 1
+  tenant-a: 2
+queries:
+  adults:
+    query: {range: {age: {gte: 30}}}
+    count: 2
+  none:
+    query: {term: {name: nobody}}
+    count: 0
+`)}}
+
+	e, err := readCountExpectations(fsys, "users/_expect.yml")
+	if err != nil {
+		t.Fatalf("readCountExpectations() error: %v", err)
+	}
+	if e.count == nil || *e.count != 3 {
+		t.Errorf("expected count 3, got %v", e.count)
+	}
+	if len(e.routing) != 2 || e.routing[0] != (routingCount{value: "tenant-b", count: 1}) || e.routing[1] != (routingCount{value: "tenant-a", count: 2}) {
+		t.Errorf("expected the routing counts in file order, got %+v", e.routing)
+	}
+	if len(e.queries) != 2 {
+		t.Fatalf("expected 2 queries, got %d", len(e.queries))
+	}
+	if q := e.queries[0]; q.name != "adults" || q.count != 2 || string(q.query) != `{"range":{"age":{"gte":30}}}` {
+		t.Errorf("unexpected first query %+v (%s)", q, q.query)
+	}
+	if q := e.queries[1]; q.name != "none" || q.count != 0 {
+		t.Errorf("unexpected second query %+v", q)
+	}
+
+	if e, err := readCountExpectations(fsys, "orders/_expect.yml"); e != nil || err != nil {
+		t.Errorf("expected no expectations for a missing file, got %+v, %v", e, err)
+	}
+}
+
+func TestReadCountExpectations_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "unknown key", data: "cont: 3\n", want: "field cont not found"},
+		{name: "negative count", data: "count: -1\n", want: "count must not be negative, got -1"},
+		{name: "queries list", data: "queries: [a]\n", want: "queries must be a mapping of query names"},
+		{name: "missing count", data: "queries:\n  adults:\n    query: {match_all: {}}\n", want: `query "adults": query and count are required`},
+		{name: "unknown query key", data: "queries:\n  adults:\n    q: {}\n    count: 1\n", want: `query "adults"`},
+		{name: "routing list", data: "routing: [tenant-a]\n", want: "routing must be a mapping of routing values to counts"},
+		{name: "negative routing count", data: "routing:\n  tenant-a: -2\n", want: `routing "tenant-a": count must be a non-negative integer, got "-2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"users/_expect.yml": {Data: []byte(tt.data)}}
+			_, err := readCountExpectations(fsys, "users/_expect.yml")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ExpectCounts(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: \"1\"\n  age: 25\n- _id: \"2\"\n  age: 35\n",
+		"users/_expect.yml":   "count: 2\nqueries:\n  adults:\n    query: {range: {age: {gte: 30}}}\n    count: 1\n",
+	})
+
+	newLoader := func(total, adults string) (*[]string, *Loader) {
+		var counts []string
+		client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/_bulk"):
+				return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":201}}]}`), nil
+			case strings.HasSuffix(req.URL.Path, "/_count"):
+				var body string
+				if req.Body != nil {
+					data, _ := io.ReadAll(req.Body)
+					body = string(data)
+				}
+				counts = append(counts, body)
+				if body == "" {
+					return jsonResponse(200, `{"count":`+total+`}`), nil
+				}
+				return jsonResponse(200, `{"count":`+adults+`}`), nil
+			}
+			return jsonResponse(200, `{}`), nil
+		}))
+		loader, err := New(client, Directory(dir))
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		return &counts, loader
+	}
+
+	counts, loader := newLoader("2", "1")
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(*counts) != 2 || (*counts)[1] != `{"query":{"range":{"age":{"gte":30}}}}` {
+		t.Errorf("expected a total and a query count, got %q", *counts)
+	}
+
+	_, loader = newLoader("1", "0")
+	var loadErr *LoadError
+	err := loader.Load()
+	if !errors.As(err, &loadErr) || loadErr.Stage != StageExpect {
+		t.Fatalf("expected an expect LoadError, got %v", err)
+	}
+	for _, want := range []string{
+		"_expect.yml: expected 2 documents, found 1",
+		`_expect.yml: query "adults": expected 1 documents, found 0`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoad_ExpectRoutingCounts(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml": "- _id: \"1\"\n  _routing: tenant-a\n- _id: \"2\"\n  _routing: tenant-a\n- _id: \"3\"\n  _routing: tenant-b\n",
+		"orders/_expect.yml":   "routing:\n  tenant-a: 2\n  tenant-b: 1\n",
+	})
+
+	newLoader := func(search func() *http.Response, counts map[string]string) (*[]string, *Loader) {
+		var requests []string
+		client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			var body string
+			if req.Body != nil {
+				data, _ := io.ReadAll(req.Body)
+				body = string(data)
+			}
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/_bulk"):
+				return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+			case strings.HasSuffix(req.URL.Path, "/_search"):
+				requests = append(requests, "search "+body)
+				return search(), nil
+			case strings.HasSuffix(req.URL.Path, "/_count"):
+				requests = append(requests, "count "+body)
+				for value, n := range counts {
+					if strings.Contains(body, `"`+value+`"`) {
+						return jsonResponse(200, `{"count":`+n+`}`), nil
+					}
+				}
+				return jsonResponse(200, `{"count":0}`), nil
+			}
+			return jsonResponse(200, `{}`), nil
+		}))
+		loader, err := New(client, Directory(dir))
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		return &requests, loader
+	}
+
+	// The aggregation answers in one request
+	requests, loader := newLoader(func() *http.Response {
+		return jsonResponse(200, `{"aggregations":{"routing":{"buckets":[{"key":"tenant-a","doc_count":2},{"key":"tenant-b","doc_count":1}]}}}`)
+	}, nil)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := `search {"aggs":{"routing":{"terms":{"field":"_routing","size":2}}},"query":{"terms":{"_routing":["tenant-a","tenant-b"]}},"size":0}`
+	if len(*requests) != 1 || (*requests)[0] != want {
+		t.Errorf("expected a single routing aggregation, got %q", *requests)
+	}
+
+	// Without fielddata on _routing, each value is counted
+	unsupported := func() *http.Response {
+		return jsonResponse(400, `{"error":{"type":"illegal_argument_exception","reason":"Fielddata is not supported on field [_routing] of type [_routing]"}}`)
+	}
+	requests, loader = newLoader(unsupported, map[string]string{"tenant-a": "2", "tenant-b": "1"})
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(*requests) != 3 || (*requests)[1] != `count {"query":{"term":{"_routing":"tenant-a"}}}` {
+		t.Errorf("expected a count per routing value after the aggregation failed, got %q", *requests)
+	}
+
+	_, loader = newLoader(unsupported, map[string]string{"tenant-a": "1", "tenant-b": "1"})
+	var loadErr *LoadError
+	err := loader.Load()
+	if !errors.As(err, &loadErr) || loadErr.Stage != StageExpect || !strings.Contains(err.Error(), `_expect.yml: routing "tenant-a": expected 2 documents, found 1`) {
+		t.Errorf("expected a routing count mismatch, got %v", err)
+	}
+}
+
+func TestNew_ExpectFileInAlias(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: \"1\"\n",
+		"people/_config.yml":  "alias:\n  indices: [users]\n",
+		"people/_expect.yml":  "count: 1\n",
+	})
+
+	if _, err := New(newOfflineClient(t), Directory(dir)); err == nil || !strings.Contains(err.Error(), `found "_expect.yml"`) {
+		t.Errorf("expected _expect.yml to be rejected in an alias directory, got %v", err)
+	}
+}
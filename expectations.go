@@ -0,0 +1,207 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"gopkg.in/yaml.v3"
+)
+
+// expectationsDir is the per-index directory holding expected search results.
+const expectationsDir = "_expectations"
+
+// expectationSize is the number of hits requested when checking an
+// expectation; it matches the default index.max_result_window.
+const expectationSize = 10000
+
+// expectation pairs a named query with the exact set of document IDs it
+// should return once fixtures are loaded.
+type expectation struct {
+	name  string
+	file  string
+	query json.RawMessage // Query clause, sent as {"query": ...}
+	ids   []string
+}
+
+// parseExpectations reads all YAML files in an index's _expectations
+// directory. Each file maps expectation names to a query and expected IDs:
+//
+//	adults:
+//	  query: {range: {age: {gte: 30}}}
+//	  ids: ["1", "3"]
+//
+// A missing directory means the index has no expectations.
+func parseExpectations(fsys fs.FS, dir string) ([]expectation, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", expectationsDir, err)
+	}
+
+	var expectations []expectation
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml")) {
+			continue
+		}
+
+		parsed, err := parseExpectationFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s/%s: %w", expectationsDir, name, err)
+		}
+		expectations = append(expectations, parsed...)
+	}
+
+	return expectations, nil
+}
+
+func parseExpectationFile(fsys fs.FS, name string) ([]expectation, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("unmarshaling YAML: %w", err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("expected a mapping of expectation names")
+	}
+
+	pairs, err := mappingPairs(root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+
+	expectations := make([]expectation, 0, len(pairs))
+	for _, p := range pairs {
+		var raw struct {
+			Query yaml.Node `yaml:"query"`
+			IDs   []string  `yaml:"ids"`
+		}
+		if err := p.value.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("expectation %q: %w", p.key, err)
+		}
+		if raw.Query.Kind == 0 {
+			return nil, fmt.Errorf("expectation %q: query is required", p.key)
+		}
+
+		query, err := yamlToJSON(&raw.Query)
+		if err != nil {
+			return nil, fmt.Errorf("expectation %q: encoding query: %w", p.key, err)
+		}
+
+		expectations = append(expectations, expectation{
+			name:  p.key,
+			file:  path.Base(name),
+			query: query,
+			ids:   raw.IDs,
+		})
+	}
+
+	return expectations, nil
+}
+
+// Verify runs every expectation declared in the fixtures' _expectations
+// directories against the cluster and reports any query whose hits differ
+// from its expected document IDs. Call it after Load.
+func (l *Loader) Verify() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		for _, e := range f.expectations {
+			if err := checkExpectation(l.ctx, l.client, l.IndexName(f.name), e); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("testfixtures: verifying expectations: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// checkExpectation runs a single expectation and compares ID sets.
+func checkExpectation(ctx context.Context, client *elasticsearch.Client, index string, e expectation) error {
+	ids, err := searchIDs(ctx, client, index, e.query)
+	if err != nil {
+		return fmt.Errorf("index %q: expectation %q: %w", index, e.name, err)
+	}
+
+	var missing, unexpected []string
+	for _, id := range e.ids {
+		if !slices.Contains(ids, id) {
+			missing = append(missing, id)
+		}
+	}
+	for _, id := range ids {
+		if !slices.Contains(e.ids, id) {
+			unexpected = append(unexpected, id)
+		}
+	}
+
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("index %q: expectation %q (%s): missing %v, unexpected %v", index, e.name, e.file, missing, unexpected)
+}
+
+// searchIDs returns the IDs of the documents in index matching query.
+func searchIDs(ctx context.Context, client *elasticsearch.Client, index string, query json.RawMessage) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":   query,
+		"size":    expectationSize,
+		"_source": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building search body: %w", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding search response: %w", err)
+	}
+
+	ids := make([]string, 0, len(result.Hits.Hits))
+	for _, h := range result.Hits.Hits {
+		ids = append(ids, h.ID)
+	}
+
+	return ids, nil
+}
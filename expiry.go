@@ -0,0 +1,116 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// checkExpiryField checks that the field set by WithExpiryField holds dates
+// in every fixture index that maps it, so ApplyExpiry can compare it with
+// the current time.
+func (l *Loader) checkExpiryField() error {
+	if l.expiryField == "" {
+		return nil
+	}
+
+	var errs []error
+	for _, f := range l.fixtures {
+		if f.isAlias() {
+			continue
+		}
+		if typ, _, ok := mappedField(f.mapping, l.expiryField); ok && typ != "date" && typ != "date_nanos" {
+			errs = append(errs, fmt.Errorf("index %q: expiry field %q is mapped as %s, not date", f.name, l.expiryField, typ))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ApplyExpiry deletes, from every fixture index, the documents whose field
+// set by WithExpiryField holds a time at or before the current time of the
+// Loader's clock (see WithClock), as a retention job would once they
+// expire. Documents without the field are kept. The indices are refreshed
+// so searches see the result. It returns the number of documents deleted,
+// including those deleted before an error.
+//
+// Together with WithClock, fixtures can be aged step by step:
+//
+//	now = now.Add(30 * 24 * time.Hour)
+//	deleted, err := loader.ApplyExpiry(ctx)
+func (l *Loader) ApplyExpiry(ctx context.Context) (int, error) {
+	if l.expiryField == "" {
+		return 0, errors.New("testfixtures: ApplyExpiry requires WithExpiryField")
+	}
+
+	now := l.now().UTC().Format(time.RFC3339Nano)
+	var deleted int
+	for _, f := range l.fixtures {
+		if f.isAlias() {
+			continue
+		}
+		n, err := deleteExpired(ctx, l.client, l.IndexName(f.name), l.expiryField, now)
+		deleted += n
+		if err != nil {
+			return deleted, fmt.Errorf("testfixtures: applying expiry to %q: %w", f.name, err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// expiryDateFormat is the date format of the RFC 3339 time ApplyExpiry
+// compares expiry fields with.
+const expiryDateFormat = "strict_date_optional_time_nanos"
+
+// deleteExpired deletes the documents of index whose field is at or before
+// now, returning how many were deleted. The range names the format of now,
+// which Elasticsearch would otherwise parse with the mapped format of field,
+// such as epoch_millis.
+func deleteExpired(ctx context.Context, client *elasticsearch.Client, index, field, now string) (int, error) {
+	body, err := json.Marshal(map[string]any{
+		"query": map[string]any{"range": map[string]any{field: map[string]any{"lte": now, "format": expiryDateFormat}}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("building delete by query body: %w", err)
+	}
+
+	res, err := client.DeleteByQuery(
+		[]string{index},
+		bytes.NewReader(body),
+		client.DeleteByQuery.WithContext(ctx),
+		client.DeleteByQuery.WithConflicts("proceed"),
+		client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired documents: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return 0, fmt.Errorf("deleting expired documents: %w", err)
+	}
+
+	var result struct {
+		Deleted  int               `json:"deleted"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decoding delete by query response: %w", err)
+	}
+	if len(result.Failures) > 0 {
+		failures := make([]string, len(result.Failures))
+		for i, f := range result.Failures {
+			failures[i] = string(f)
+		}
+		return result.Deleted, fmt.Errorf("deleting expired documents: %d failures: %s", len(result.Failures), strings.Join(failures, "; "))
+	}
+
+	return result.Deleted, nil
+}
@@ -0,0 +1,108 @@
+package testfixtures
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyExpiry(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"sessions/_mapping.json": `{"properties": {"expires_at": {"type": "date", "format": "epoch_millis"}}}`,
+		"sessions/documents.yml": "- _id: \"1\"\n  expires_at: 1704067200000\n",
+		"tokens/documents.yml":   "- _id: \"1\"\n",
+	})
+
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_delete_by_query") {
+			if q := req.URL.Query(); q.Get("refresh") != "true" || q.Get("conflicts") != "proceed" {
+				t.Errorf("%s: expected refresh=true and conflicts=proceed, got %s", req.URL.Path, req.URL.RawQuery)
+			}
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+			if strings.HasPrefix(req.URL.Path, "/ci_sessions") {
+				return jsonResponse(200, `{"deleted":2,"failures":[]}`), nil
+			}
+			return jsonResponse(200, `{"deleted":0,"failures":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"), WithExpiryField("expires_at"),
+		WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	deleted, err := loader.ApplyExpiry(context.Background())
+	if err != nil {
+		t.Fatalf("ApplyExpiry() error: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 documents deleted, got %d", deleted)
+	}
+	// The format of the bound is given, as the field is mapped as epoch_millis
+	want := `{"query":{"range":{"expires_at":{"format":"strict_date_optional_time_nanos","lte":"2024-03-01T03:00:00Z"}}}}`
+	for _, path := range []string{"/ci_sessions/_delete_by_query", "/ci_tokens/_delete_by_query"} {
+		if bodies[path] != want {
+			t.Errorf("%s: expected body %s, got %s", path, want, bodies[path])
+		}
+	}
+}
+
+func TestApplyExpiry_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"sessions/documents.yml": "- _id: \"1\"\n",
+	})
+
+	t.Run("without WithExpiryField", func(t *testing.T) {
+		loader, err := New(newOfflineClient(t), Directory(dir))
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		if _, err := loader.ApplyExpiry(context.Background()); err == nil || !strings.Contains(err.Error(), "requires WithExpiryField") {
+			t.Errorf("expected an error about WithExpiryField, got %v", err)
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(200, `{"deleted":1,"failures":[{"id":"2","status":500}]}`), nil
+		}))
+		loader, err := New(client, Directory(dir), WithExpiryField("expires_at"))
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		deleted, err := loader.ApplyExpiry(context.Background())
+		if err == nil || !strings.Contains(err.Error(), `applying expiry to "sessions"`) || !strings.Contains(err.Error(), "1 failures") {
+			t.Errorf("expected an error reporting the failure, got %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("expected the 1 deleted document to be counted, got %d", deleted)
+		}
+	})
+}
+
+func TestNew_ExpiryFieldMapping(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"sessions/_mapping.json": `{"properties": {"expires_at": {"type": "keyword"}}}`,
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir), WithExpiryField("expires_at"))
+	want := `index "sessions": expiry field "expires_at" is mapped as keyword, not date`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected an error containing %q, got %v", want, err)
+	}
+
+	if _, err := New(newOfflineClient(t), Directory(dir), WithExpiryField("")); err == nil {
+		t.Error("expected an error for an empty expiry field")
+	}
+}
@@ -0,0 +1,106 @@
+package testfixtures
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// ExportBulk writes to w the NDJSON bulk payload Load sends for the named
+// fixture index: an action line for each document, followed by its body
+// unless it is a delete, as sent to the _bulk endpoint of the index. The
+// documents come in the order Load sends them, after DedupeByID and every
+// transform, as a single payload rather than split into requests. It does
+// not contact the cluster, but calls the index's DocumentProviders with the
+// context set by WithContext.
+func (l *Loader) ExportBulk(w io.Writer, index string) error {
+	f := l.fixture(index)
+	if f == nil || f.isAlias() {
+		return fmt.Errorf("testfixtures: %q is not a fixture index", index)
+	}
+
+	bw := bufio.NewWriter(w)
+	err := l.feedIndexDocuments(f, func(doc Document) error {
+		// A bufio.Writer keeps its first error, which Flush reports.
+		writeBulkItem(bw, doc)
+		return nil
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("testfixtures: exporting %q: %w", index, err)
+	}
+	return nil
+}
+
+// feedIndexDocuments passes to add the documents Load sends for the fixture
+// index f, in the order it sends them, after DedupeByID and every transform.
+func (l *Loader) feedIndexDocuments(f *indexFixture, add func(Document) error) error {
+	indexName := l.IndexName(f.name)
+
+	documents := f.documents
+	var provided []Document
+	if l.dedupe {
+		groups, err := collectProviderDocuments(l.ctx, indexName, f.providers, l.streamTransform())
+		if err != nil {
+			return err
+		}
+		groups, _ = dedupeByID(append([][]Document{f.documents}, groups...))
+		documents, provided = groups[0], slices.Concat(groups[1:]...)
+	}
+
+	if err := feedDocuments(documents, l.documentTransform())(add); err != nil {
+		return err
+	}
+	for _, path := range f.streams {
+		if err := feedNDJSONFile(l.fsys, path, withTransform(add, l.streamTransform())); err != nil {
+			return err
+		}
+	}
+	if l.dedupe {
+		return feedDocuments(provided, nil)(add)
+	}
+
+	addProvided := withTransform(add, l.streamTransform())
+	for i, p := range f.providers {
+		docs, err := p.Documents(l.ctx, indexName)
+		if err != nil {
+			return fmt.Errorf("provider %d: %w", i, err)
+		}
+		for doc, err := range docs {
+			if err != nil {
+				return fmt.Errorf("provider %d: %w", i, err)
+			}
+			if err := addProvided(doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeBulkItem writes the action line of doc and its body, formatted as
+// the bulk indexer of Load formats them.
+func writeBulkItem(w *bufio.Writer, doc Document) {
+	action, body := bulkItem(doc)
+
+	w.WriteString(`{` + strconv.Quote(action) + `:{`)
+	if doc.ID != "" {
+		w.WriteString(`"_id":` + strconv.Quote(doc.ID))
+	}
+	if doc.Routing != "" {
+		if doc.ID != "" {
+			w.WriteByte(',')
+		}
+		w.WriteString(`"routing":` + strconv.Quote(doc.Routing))
+	}
+	w.WriteString("}}\n")
+
+	if body != nil {
+		w.Write(body)
+		w.WriteByte('\n')
+	}
+}
@@ -0,0 +1,78 @@
+package testfixtures
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportBulk(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": `- _id: "1"
+  _routing: tokyo
+  name: Alice
+- _id: "2"
+  _action: create
+  name: Bob
+- _id: "1"
+  _action: update
+  name: Alicia
+- _id: "3"
+  _action: delete
+- name: Carol
+`,
+		"users/extra.ndjson": "{\"index\":{\"_id\":\"4\"}}\n{\"name\":\"Dave\"}\n",
+		"buyers/_config.yml": "alias:\n  indices: [users]\n",
+	})
+
+	var sent bytes.Buffer
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			data, _ := io.ReadAll(req.Body)
+			sent.Write(data)
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	var exported bytes.Buffer
+	if err := loader.ExportBulk(&exported, "users"); err != nil {
+		t.Fatalf("ExportBulk() error: %v", err)
+	}
+	want := `{"index":{"_id":"1","routing":"tokyo"}}
+{"name":"Alice"}
+{"create":{"_id":"2"}}
+{"name":"Bob"}
+{"update":{"_id":"1"}}
+{"doc":{"name":"Alicia"}}
+{"delete":{"_id":"3"}}
+{"index":{}}
+{"name":"Carol"}
+{"index":{"_id":"4"}}
+{"name":"Dave"}
+`
+	if got := exported.String(); got != want {
+		t.Errorf("ExportBulk() wrote:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if sent.String() != exported.String() {
+		t.Errorf("expected the export to match the payload Load sends, got:\n%s", sent.String())
+	}
+
+	for _, name := range []string{"buyers", "missing"} {
+		if err := loader.ExportBulk(io.Discard, name); err == nil || !strings.Contains(err.Error(), "is not a fixture index") {
+			t.Errorf("ExportBulk(%s): expected a not a fixture index error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package testfixtures
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FailureRule selects the requests a FailingTransport fails. Matching
+// requests are counted per rule, from 1, and those from the Nth on are
+// failed Times times in a row.
+type FailureRule struct {
+	Path   string // Suffix of the URL path of matching requests, such as "/_bulk" (empty matches every request)
+	Method string // HTTP method of matching requests (empty matches any)
+	Nth    int    // Position of the first matching request to fail (default 1)
+	Times  int    // Number of consecutive matching requests to fail (default 1; negative fails every one from Nth on)
+	Status int    // Status code of the injected response (default 503)
+	Body   string // Body of the injected response (default: an Elasticsearch error describing the failure)
+}
+
+// FailBulk returns a rule failing the nth bulk request with status, such as
+// http.StatusTooManyRequests or http.StatusServiceUnavailable.
+func FailBulk(nth, status int) FailureRule {
+	return FailureRule{Path: "/_bulk", Nth: nth, Status: status}
+}
+
+// FailingTransport is an http.RoundTripper that answers chosen requests with
+// an error response instead of sending them, so the retry and error handling
+// of the loader, and of the code under test, can be exercised
+// deterministically without a flaky cluster:
+//
+//	ft := testfixtures.NewFailingTransport(nil, testfixtures.FailBulk(2, http.StatusTooManyRequests))
+//	client, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: ft})
+//
+// Requests retried by the client count as new requests, so a 503, which the
+// client retries by default, is seen again by the rule.
+type FailingTransport struct {
+	next  http.RoundTripper
+	rules []FailureRule
+
+	mu       sync.Mutex
+	seen     []int // Matching requests per rule
+	injected int
+}
+
+// NewFailingTransport returns a FailingTransport that sends the requests
+// not failed by rules with next. If next is nil, http.DefaultTransport is
+// used.
+func NewFailingTransport(next http.RoundTripper, rules ...FailureRule) *FailingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &FailingTransport{
+		next:  next,
+		rules: rules,
+		seen:  make([]int, len(rules)),
+	}
+}
+
+// RoundTrip fails the request if a rule selects it, and sends it otherwise.
+func (t *FailingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule, n, ok := t.match(req)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_ = req.Body.Close()
+	}
+
+	status := rule.Status
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	body := rule.Body
+	if body == "" {
+		body = fmt.Sprintf(`{"error":{"type":"injected_failure","reason":"request %d to %s failed by FailingTransport"},"status":%d}`, n, req.URL.Path, status)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}, "X-Elastic-Product": {"Elasticsearch"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Injected returns the number of requests failed so far.
+func (t *FailingTransport) Injected() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.injected
+}
+
+// match counts req against every rule it matches and returns the first rule
+// that fails it, with the position of req among the requests it matches.
+func (t *FailingTransport) match(req *http.Request) (FailureRule, int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var (
+		failed FailureRule
+		pos    int
+		ok     bool
+	)
+	for i, rule := range t.rules {
+		if !strings.HasSuffix(req.URL.Path, rule.Path) || (rule.Method != "" && rule.Method != req.Method) {
+			continue
+		}
+		t.seen[i]++
+		if !ok && rule.selects(t.seen[i]) {
+			failed, pos, ok = rule, t.seen[i], true
+		}
+	}
+	if ok {
+		t.injected++
+	}
+	return failed, pos, ok
+}
+
+// selects reports whether the nth request matching r is failed.
+func (r FailureRule) selects(n int) bool {
+	first, times := max(r.Nth, 1), r.Times
+	if times == 0 {
+		times = 1
+	}
+	return n >= first && (times < 0 || n < first+times)
+}
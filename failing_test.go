@@ -0,0 +1,121 @@
+package testfixtures
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFailingTransport(t *testing.T) {
+	var sent atomic.Int32
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent.Add(1)
+		return jsonResponse(200, `{}`), nil
+	})
+	ft := NewFailingTransport(next,
+		FailureRule{Path: "/_bulk", Nth: 2, Times: 2, Status: http.StatusTooManyRequests},
+		FailureRule{Method: http.MethodDelete, Times: -1, Body: `{"acknowledged":false}`},
+	)
+
+	do := func(method, path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, "http://es.test:9200"+path, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ft.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error: %v", err)
+		}
+		return res
+	}
+
+	var statuses []int
+	for range 4 {
+		statuses = append(statuses, do(http.MethodPost, "/users/_bulk").StatusCode)
+	}
+	if want := []int{200, 429, 429, 200}; !slices.Equal(statuses, want) {
+		t.Errorf("expected bulk statuses %v, got %v", want, statuses)
+	}
+
+	res := do(http.MethodPost, "/_bulk")
+	if res.StatusCode != 200 {
+		t.Errorf("expected the fifth bulk request to pass, got %d", res.StatusCode)
+	}
+
+	for range 2 {
+		res := do(http.MethodDelete, "/users")
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != http.StatusServiceUnavailable || string(body) != `{"acknowledged":false}` {
+			t.Errorf("expected an injected 503 with the rule's body, got %d %s", res.StatusCode, body)
+		}
+	}
+
+	if got := ft.Injected(); got != 4 {
+		t.Errorf("expected 4 injected failures, got %d", got)
+	}
+	if got := sent.Load(); got != 3 {
+		t.Errorf("expected 3 requests to reach the next transport, got %d", got)
+	}
+}
+
+func TestLoad_FailingTransport(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: \"1\"\n  n: 1\n",
+	})
+
+	newLoader := func(rules []FailureRule, opts ...Option) (*FailingTransport, *atomic.Int32, *Loader) {
+		var bulks atomic.Int32
+		ft := NewFailingTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, "/_bulk") {
+				bulks.Add(1)
+				return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+			}
+			return jsonResponse(200, `{}`), nil
+		}), rules...)
+		loader, err := New(newFakeClient(t, ft), append([]Option{Directory(dir)}, opts...)...)
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		return ft, &bulks, loader
+	}
+
+	t.Run("retried by the client", func(t *testing.T) {
+		ft, bulks, loader := newLoader([]FailureRule{FailBulk(1, http.StatusServiceUnavailable)})
+		if err := loader.Load(); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if ft.Injected() != 1 || bulks.Load() != 1 {
+			t.Errorf("expected one injected failure and one bulk request sent, got %d and %d", ft.Injected(), bulks.Load())
+		}
+	})
+
+	t.Run("circuit breaker retried by WithMaxInFlightBytes", func(t *testing.T) {
+		rule := FailBulk(1, http.StatusTooManyRequests)
+		rule.Body = `{"error":{"type":"circuit_breaking_exception","reason":"[parent] Data too large"},"status":429}`
+		ft, bulks, loader := newLoader([]FailureRule{rule}, WithMaxInFlightBytes(1<<20))
+		if err := loader.Load(); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if ft.Injected() != 1 || bulks.Load() != 1 {
+			t.Errorf("expected one injected failure and one bulk request sent, got %d and %d", ft.Injected(), bulks.Load())
+		}
+	})
+
+	t.Run("rejection fails the load", func(t *testing.T) {
+		_, bulks, loader := newLoader([]FailureRule{FailBulk(1, http.StatusTooManyRequests)})
+		var loadErr *LoadError
+		err := loader.Load()
+		if !errors.As(err, &loadErr) || loadErr.Stage != StageIndex || !strings.Contains(err.Error(), "429") {
+			t.Errorf("expected an index LoadError with the 429, got %v", err)
+		}
+		if bulks.Load() != 0 {
+			t.Errorf("expected no bulk request to reach the cluster, got %d", bulks.Load())
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package testfixtures
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// fetchBatchSize is the number of documents FetchAll reads per request.
+const fetchBatchSize = 1000
+
+// fetchKeepAlive is how long the scroll of FetchAll is kept between requests.
+const fetchKeepAlive = time.Minute
+
+// FetchAll returns every document in a fixture index, or an index made from
+// one by ShrinkIndex, SplitIndex, or CloneIndex, as it is stored in the
+// cluster, ordered by _id. Each Document holds the _id, the custom routing
+// if any, and the _source, so the result can be compared with the fixtures
+// to detect drift or written out as new ones.
+func (l *Loader) FetchAll(ctx context.Context, index string) ([]Document, error) {
+	name := l.IndexName(index)
+	if l.fixture(index) == nil && !slices.Contains(l.derived, name) {
+		return nil, fmt.Errorf("testfixtures: %q is not a fixture index", index)
+	}
+
+	docs, err := fetchDocuments(ctx, l.client, name)
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: fetching documents of %q: %w", index, err)
+	}
+	return docs, nil
+}
+
+// fetchDocuments reads every document of the index name with a scroll.
+func fetchDocuments(ctx context.Context, client *elasticsearch.Client, name string) ([]Document, error) {
+	var docs []Document
+	err := scrollDocuments(ctx, client, name, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(docs, func(a, b Document) int { return cmp.Compare(a.ID, b.ID) })
+	return docs, nil
+}
+
+// scrollDocuments calls fn with each document of the index name, in the
+// order of a scroll, holding one page of them in memory at a time.
+func scrollDocuments(ctx context.Context, client *elasticsearch.Client, name string, fn func(Document) error) error {
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(name),
+		client.Search.WithSize(fetchBatchSize),
+		client.Search.WithSort("_doc"),
+		client.Search.WithScroll(fetchKeepAlive),
+	)
+	if err != nil {
+		return err
+	}
+
+	var scrollID string
+	defer func() {
+		if scrollID != "" {
+			clearScroll(client, scrollID)
+		}
+	}()
+	for {
+		page, err := decodeScrollPage(res)
+		if err != nil {
+			return err
+		}
+		if page.ScrollID != "" {
+			scrollID = page.ScrollID
+		}
+		for _, hit := range page.Hits.Hits {
+			if err := fn(Document{ID: hit.ID, Routing: hit.Routing, Source: hit.Source}); err != nil {
+				return err
+			}
+		}
+		if len(page.Hits.Hits) < fetchBatchSize || scrollID == "" {
+			return nil
+		}
+
+		res, err = client.Scroll(
+			client.Scroll.WithContext(ctx),
+			client.Scroll.WithScrollID(scrollID),
+			client.Scroll.WithScroll(fetchKeepAlive),
+		)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scrollPage is a page of search or scroll results.
+type scrollPage struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			ID      string          `json:"_id"`
+			Routing string          `json:"_routing"`
+			Source  json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// decodeScrollPage reads and closes a search or scroll response.
+func decodeScrollPage(res *esapi.Response) (scrollPage, error) {
+	defer func() { _ = res.Body.Close() }()
+
+	var page scrollPage
+	if err := checkResponse(res); err != nil {
+		return page, err
+	}
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		return page, fmt.Errorf("decoding search results: %w", err)
+	}
+	return page, nil
+}
+
+// clearScroll releases a scroll; errors are ignored since the scroll
+// expires on its own.
+func clearScroll(client *elasticsearch.Client, scrollID string) {
+	res, err := client.ClearScroll(client.ClearScroll.WithScrollID(scrollID))
+	if err == nil {
+		_ = res.Body.Close()
+	}
+}
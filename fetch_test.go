@@ -0,0 +1,61 @@
+package testfixtures
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFetchAll(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n",
+	})
+
+	// The first page is full, so FetchAll scrolls for the next one.
+	hits := make([]string, fetchBatchSize)
+	for i := range hits {
+		hits[i] = fmt.Sprintf(`{"_id":"%04d","_source":{"n":%d}}`, fetchBatchSize-i, fetchBatchSize-i)
+	}
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case req.URL.Path == "/ci_users/_search":
+			if q := req.URL.Query(); q.Get("scroll") == "" || q.Get("sort") != "_doc" {
+				t.Errorf("expected a scroll sorted by _doc, got %s", req.URL.RawQuery)
+			}
+			return jsonResponse(200, `{"_scroll_id":"s1","hits":{"hits":[`+strings.Join(hits, ",")+`]}}`), nil
+		case req.URL.Path == "/_search/scroll":
+			return jsonResponse(200, `{"_scroll_id":"s2","hits":{"hits":[{"_id":"0000","_routing":"eu","_source":{"n":0}}]}}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	docs, err := loader.FetchAll(t.Context(), "users")
+	if err != nil {
+		t.Fatalf("FetchAll() error: %v", err)
+	}
+
+	if len(docs) != fetchBatchSize+1 {
+		t.Fatalf("expected %d documents, got %d", fetchBatchSize+1, len(docs))
+	}
+	if first := docs[0]; first.ID != "0000" || first.Routing != "eu" || string(first.Source) != `{"n":0}` {
+		t.Errorf("expected documents ordered by _id with their routing, got %+v first", first)
+	}
+	if last := docs[len(docs)-1]; last.ID != fmt.Sprintf("%04d", fetchBatchSize) {
+		t.Errorf("expected %04d last, got %q", fetchBatchSize, last.ID)
+	}
+	if requests[len(requests)-1] != "DELETE /_search/scroll/s2" {
+		t.Errorf("expected the scroll to be cleared, got %v", requests)
+	}
+
+	if _, err := loader.FetchAll(t.Context(), "orders"); err == nil {
+		t.Error("expected an error for an index that is not a fixture")
+	}
+}
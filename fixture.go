@@ -1,17 +1,83 @@
 package testfixtures
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
 
 // indexFixture represents a single Elasticsearch index and its fixture data.
 type indexFixture struct {
-	name      string            // Directory name = index name
-	mapping   json.RawMessage   // Contents of _mapping.json (may be nil)
-	settings  json.RawMessage   // Contents of _settings.json (may be nil)
-	documents []document        // Parsed documents from YAML files
+	name      string             // Directory name = index name
+	mapping   json.RawMessage    // Contents of _mapping.json, with _runtime_mappings.json merged in (may be nil)
+	settings  json.RawMessage    // Contents of _settings.json (may be nil)
+	config    indexConfig        // Contents of _config.yml (zero value if absent)
+	documents []Document         // Parsed documents from YAML files
+	streams   []string           // Paths of NDJSON files, streamed at load time
+	providers []DocumentProvider // Registered providers, queried at load time
+	aliases   []indexAlias       // Contents of _aliases.json, added when the index is created
+
+	runtimeFields []string           // Names of the fields defined in _runtime_mappings.json
+	expectations  []expectation      // Named queries from _expectations/, checked by Verify
+	counts        *countExpectations // Document counts from _expect.yml, checked by Load (may be nil)
+
+	aliasBody      json.RawMessage // Filter and routing of an alias fixture (may be nil)
+	writeAliasBody json.RawMessage // aliasBody for the write_index of an alias fixture (nil without one)
+}
+
+// Document is a single Elasticsearch document, as parsed from fixture files
+// or supplied by a DocumentProvider. It is the representation shared by
+// providers, hooks, and assertion helpers.
+type Document struct {
+	ID      string          // Document ID (may be empty for auto-generated IDs)
+	Routing string          // Custom routing value (may be empty for the default routing)
+	Source  json.RawMessage // JSON-encoded document body, without metadata fields such as _id
+
+	file string // Fixture file the document came from, relative to the fixtures directory (may be empty)
+	line int    // Line of the document within file (may be zero)
+	pos  int    // Position of the document among those of file, increasing through the file (may be zero)
+
+	action string // Bulk action other than index, from _action or a bulk-format file: create, update, or delete
+	parent string // Parent _id of a join field child, from _parent
 }
 
-// document represents a single Elasticsearch document to be indexed.
-type document struct {
-	ID   string                 // Extracted from _id field (may be empty for auto-generated IDs)
-	Body map[string]interface{} // Document body (without _id)
+// Location describes where the document was defined, as "file:line", for
+// error messages. It is empty for documents not read from fixture files.
+func (d Document) Location() string {
+	if d.file == "" {
+		return ""
+	}
+	if d.line == 0 {
+		return d.file
+	}
+	return fmt.Sprintf("%s:%d", d.file, d.line)
+}
+
+// Decode unmarshals the document source into v.
+func (d Document) Decode(v any) error {
+	return json.Unmarshal(d.Source, v)
+}
+
+// Field returns the value of a field in dot notation, such as "address.city",
+// decoded with numbers as json.Number. Fields inside arrays of objects yield
+// a []any of every value. The second result reports whether the field exists.
+func (d Document) Field(path string) (any, bool) {
+	raw, err := lookupField(d.Source, strings.Split(path, "."))
+	if err != nil || len(raw) == 0 {
+		return nil, false
+	}
+
+	values := make([]any, 0, len(raw))
+	for _, r := range raw {
+		v, err := decodeValue(r)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	if len(values) == 1 {
+		return values[0], true
+	}
+
+	return values, true
 }
@@ -0,0 +1,36 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDocument_Accessors(t *testing.T) {
+	doc := Document{
+		ID:     "1",
+		Source: json.RawMessage(`{"name":"Alice","address":{"city":"Tokyo"},"orders":[{"id":10},{"id":12345678901234567890}]}`),
+		file:   "users/documents.yml",
+		line:   4,
+	}
+
+	if got := doc.Location(); got != "users/documents.yml:4" {
+		t.Errorf("Location() = %q", got)
+	}
+
+	if v, ok := doc.Field("address.city"); !ok || v != "Tokyo" {
+		t.Errorf(`Field("address.city") = %v, %v`, v, ok)
+	}
+	v, ok := doc.Field("orders.id")
+	ids, _ := v.([]any)
+	if !ok || len(ids) != 2 || ids[1] != json.Number("12345678901234567890") {
+		t.Errorf(`Field("orders.id") = %v, %v`, v, ok)
+	}
+	if _, ok := doc.Field("missing"); ok {
+		t.Error(`Field("missing") reported the field as present`)
+	}
+
+	var user struct{ Name string }
+	if err := doc.Decode(&user); err != nil || user.Name != "Alice" {
+		t.Errorf("Decode() = %+v, %v", user, err)
+	}
+}
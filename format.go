@@ -0,0 +1,348 @@
+package testfixtures
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaFiles are the JSON files Format rewrites in each directory.
+var schemaFiles = []string{mappingFile, settingsFile, runtimeMappingsFile, aliasesFile}
+
+// dateTimeValue matches RFC 3339-like timestamps with a time zone, which
+// Format rewrites to RFC 3339 in date fields whose format accepts it. Dates
+// without a time, or times without a zone, are left alone since their
+// meaning depends on the mapping.
+var dateTimeValue = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?([Zz]|[+-]\d{2}:?\d{2})$`)
+
+// Format rewrites the fixture files in dir into canonical form, so that
+// fixture diffs show only meaningful changes:
+//
+//   - document files have _id first and other keys sorted, documents sorted
+//     by _id, block style with two-space indentation, and timestamps with
+//     a time zone in RFC 3339 where the index maps them as date fields in a
+//     format that accepts it, such as the default
+//   - _mapping.json, _settings.json, and _runtime_mappings.json have sorted
+//     keys and two-space indentation
+//
+// Comments are kept and move with the document or key they precede. Document
+// files using YAML anchors are re-indented but not reordered, since moving an
+// alias before its anchor would break them. Format returns the paths of the
+// files it changed.
+func Format(dir string) ([]string, error) {
+	return formatFixtures(dir, true)
+}
+
+// CheckFormat reports the paths of the fixture files in dir that Format
+// would change, without modifying them.
+func CheckFormat(dir string) ([]string, error) {
+	return formatFixtures(dir, false)
+}
+
+func formatFixtures(dir string, write bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: reading fixtures directory %q: %w", dir, err)
+	}
+
+	var changed []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sub := filepath.Join(dir, entry.Name())
+
+		var (
+			paths []string
+			dates map[string]dateFormat
+		)
+		for _, name := range schemaFiles {
+			paths = append(paths, filepath.Join(sub, name))
+		}
+		if !strings.HasPrefix(entry.Name(), "_") {
+			if dates, err = formatDateFields(dir, entry.Name()); err != nil {
+				return nil, fmt.Errorf("testfixtures: reading mapping of %q: %w", sub, err)
+			}
+			files, err := os.ReadDir(sub)
+			if err != nil {
+				return nil, fmt.Errorf("testfixtures: reading directory %q: %w", sub, err)
+			}
+			for _, f := range files {
+				name := f.Name()
+				if !f.IsDir() && !strings.HasPrefix(name, "_") && (strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")) {
+					paths = append(paths, filepath.Join(sub, name))
+				}
+			}
+		}
+
+		for _, path := range paths {
+			ok, err := formatFile(path, dates, write)
+			if err != nil {
+				return nil, fmt.Errorf("testfixtures: formatting %q: %w", path, err)
+			}
+			if ok {
+				changed = append(changed, path)
+			}
+		}
+	}
+
+	return changed, nil
+}
+
+// formatDateFields returns the formats of the date fields of the index
+// fixture name in dir by their dotted path in documents, from its
+// _mapping.json and, with inherit_common, that of _common.
+func formatDateFields(dir, name string) (map[string]dateFormat, error) {
+	fsys := os.DirFS(dir)
+	cfg, err := readIndexConfig(fsys, path.Join(name, configFile))
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := readJSONFile(fsys, path.Join(name, mappingFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if cfg.InheritCommon {
+		common, err := readJSONFile(fsys, path.Join(commonDir, mappingFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if mapping, err = mergeJSONObjects(common, mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	dates := make(map[string]dateFormat)
+	for _, field := range checkedDateFields(mapping) {
+		dates[field.source] = field.format
+	}
+	return dates, nil
+}
+
+// formatFile formats a single fixture file, reporting whether its contents
+// changed. Missing files are skipped. dates holds the formats of the date
+// fields of document files by their dotted path.
+func formatFile(path string, dates map[string]dateFormat, write bool) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var formatted []byte
+	if strings.HasSuffix(path, ".json") {
+		formatted, err = formatJSON(data)
+	} else {
+		formatted, err = formatDocuments(data, dates)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(data, formatted) {
+		return false, nil
+	}
+	if write {
+		if err := os.WriteFile(path, formatted, 0o644); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// formatJSON indents a JSON document with sorted keys, keeping number literals.
+func formatJSON(data []byte) ([]byte, error) {
+	v, err := decodeValue(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatDocuments canonicalizes a YAML document file, whose date fields have
+// the formats of dates.
+func formatDocuments(data []byte, dates map[string]dateFormat) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("unmarshaling YAML: %w", err)
+	}
+	if len(root.Content) == 0 {
+		return data, nil
+	}
+
+	reorder := !hasAliases(&root)
+	canonicalizeNode(root.Content[0], reorder, dates, "")
+	if seq := root.Content[0]; reorder && seq.Kind == yaml.SequenceNode {
+		slices.SortStableFunc(seq.Content, func(a, b *yaml.Node) int {
+			return compareIDs(documentID(a), documentID(b))
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// canonicalizeNode switches node and its children to block style, quotes
+// _id, _routing, and _parent values, normalizes the timestamps of date
+// fields, and, if reorder is set, sorts mapping keys with _id, _action,
+// _routing, _parent, and _traits first. path is the dotted path of node in
+// its document, by which dates holds the formats of date fields.
+func canonicalizeNode(node *yaml.Node, reorder bool, dates map[string]dateFormat, path string) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		node.Style = 0
+		for _, child := range node.Content {
+			canonicalizeNode(child, reorder, dates, path)
+		}
+	case yaml.MappingNode:
+		node.Style = 0
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			canonicalizeNode(k, reorder, nil, "")
+			canonicalizeNode(v, reorder, dates, strings.TrimPrefix(path+"."+k.Value, "."))
+		}
+	case yaml.ScalarNode:
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			node.Style = 0
+		}
+		if format, ok := dates[path]; ok && (node.Tag == "!!str" || node.Tag == "!!timestamp") && dateTimeValue.MatchString(node.Value) {
+			node.Value = normalizeDate(node.Value, format)
+		}
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if (k.Value == "_id" || k.Value == routingKey || k.Value == parentKey) && v.Kind == yaml.ScalarNode {
+			v.Tag, v.Style = "!!str", yaml.DoubleQuotedStyle
+		}
+		pairs = append(pairs, [2]*yaml.Node{k, v})
+	}
+	if reorder {
+		slices.SortStableFunc(pairs, func(a, b [2]*yaml.Node) int {
+			return cmp.Or(cmp.Compare(keyRank(a[0].Value), keyRank(b[0].Value)), strings.Compare(a[0].Value, b[0].Value))
+		})
+	}
+
+	node.Content = node.Content[:0]
+	for _, p := range pairs {
+		node.Content = append(node.Content, p[0], p[1])
+	}
+}
+
+// keyRank orders the keys that Format places before all others.
+func keyRank(key string) int {
+	switch key {
+	case "_id":
+		return 0
+	case actionKey:
+		return 1
+	case routingKey:
+		return 2
+	case parentKey:
+		return 3
+	case traitsKey:
+		return 4
+	}
+	return 5
+}
+
+// normalizeDateTime rewrites a timestamp matched by dateTimeValue in RFC 3339.
+func normalizeDateTime(s string) string {
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05.999999999Z0700", "2006-01-02T15:04Z0700"} {
+		normalized := strings.ToUpper(strings.Replace(s, " ", "T", 1))
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
+
+// normalizeDate returns the timestamp s of a date field in RFC 3339 if its
+// format accepts that, and s unchanged otherwise, since Elasticsearch would
+// then reject the rewritten value.
+func normalizeDate(s string, format dateFormat) string {
+	normalized := normalizeDateTime(s)
+	if alt, ok := format.match(normalized); !ok || alt == nil {
+		return s
+	}
+	return normalized
+}
+
+// hasAliases reports whether node or any of its children is an alias.
+func hasAliases(node *yaml.Node) bool {
+	if node.Kind == yaml.AliasNode {
+		return true
+	}
+	return slices.ContainsFunc(node.Content, hasAliases)
+}
+
+// documentID returns the _id of a document node, or "" if it has none.
+func documentID(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "_id" {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// compareIDs orders document IDs numerically when both are integers and
+// lexically otherwise. Documents without an ID sort last.
+func compareIDs(a, b string) int {
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
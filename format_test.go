@@ -0,0 +1,134 @@
+package testfixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json": `{"properties":{"name":{"type":"text"},"age":{"type":"integer"},"joined":{"type":"date"}}}`,
+		"users/documents.yml": `# Seed users
+
+# Carol has no joined date
+- name: Carol
+  _id: 10
+  tags: [a, b]
+- {_id: "2", name: Bob, joined: "2024-01-02 03:04:05+00:00"}
+- name: anonymous
+`,
+		"_common/_settings.json": `{"number_of_shards": 1}`,
+	})
+
+	changed, err := Format(dir)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	if len(changed) != 3 {
+		t.Errorf("expected 3 changed files, got %v", changed)
+	}
+
+	wantDocs := `# Seed users
+
+- _id: "2"
+  joined: "2024-01-02T03:04:05Z"
+  name: Bob
+# Carol has no joined date
+- _id: "10"
+  name: Carol
+  tags:
+    - a
+    - b
+- name: anonymous
+`
+	wantMapping := `{
+  "properties": {
+    "age": {
+      "type": "integer"
+    },
+    "joined": {
+      "type": "date"
+    },
+    "name": {
+      "type": "text"
+    }
+  }
+}
+`
+	for path, want := range map[string]string{
+		"users/documents.yml":    wantDocs,
+		"users/_mapping.json":    wantMapping,
+		"_common/_settings.json": "{\n  \"number_of_shards\": 1\n}\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s =\n%s\nwant\n%s", path, got, want)
+		}
+	}
+
+	if changed, err := CheckFormat(dir); err != nil || len(changed) != 0 {
+		t.Errorf("expected formatted fixtures to be stable, got %v, %v", changed, err)
+	}
+}
+
+func TestFormat_DateFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"events/_mapping.json": `{"properties":{"at":{"type":"date","format":"yyyy-MM-dd HH:mm:ssZ"},"seen":{"type":"date"},"meta":{"properties":{"ts":{"type":"date_nanos"}}},"label":{"type":"keyword"}}}`,
+		"events/documents.yml": `- _id: "1"
+  at: "2024-01-01 10:00:00+0900"
+  label: "2024-01-01 10:00:00+09:00"
+  meta:
+    ts: "2024-01-01 10:00:00.5+09:00"
+  seen: "2024-01-01 10:00:00+09:00"
+`,
+	})
+
+	if _, err := Format(dir); err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	// Only date fields whose format accepts RFC 3339 are rewritten; the
+	// others stay strings without their quotes
+	want := `- _id: "1"
+  at: 2024-01-01 10:00:00+0900
+  label: 2024-01-01 10:00:00+09:00
+  meta:
+    ts: "2024-01-01T10:00:00.5+09:00"
+  seen: "2024-01-01T10:00:00+09:00"
+`
+	got, err := os.ReadFile(filepath.Join(dir, "events", "documents.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("documents.yml =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := New(newOfflineClient(t), Directory(dir)); err != nil {
+		t.Errorf("expected the formatted fixtures to pass New, got %v", err)
+	}
+}
+
+func TestFormat_KeepsAnchorOrder(t *testing.T) {
+	dir := t.TempDir()
+	docs := `- _id: "2"
+  base: &base
+    role: admin
+- _id: "1"
+  base: *base
+`
+	writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": docs})
+
+	changed, err := CheckFormat(dir)
+	if err != nil {
+		t.Fatalf("CheckFormat() error: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("expected file with anchors to be left in order, got %v", changed)
+	}
+}
@@ -0,0 +1,320 @@
+package testfixtures
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// generateKey is the document file entry that expands into generated
+// documents:
+//
+//	# users/documents.yml
+//	- _generate:
+//	    count: 1000
+//	    template:
+//	      _id: {$fake: seq, format: "user-%d"}
+//	      name: {$fake: name}
+//	      age: {$fake: int, min: 18, max: 90}
+const generateKey = "_generate"
+
+// fakeKey marks a template node that is replaced by a generated value, as
+// {$fake: kind} with the producer's parameters as further keys.
+const fakeKey = "$fake"
+
+// maxGenerated is the most documents a single _generate entry may produce.
+const maxGenerated = 1_000_000
+
+// defaultGenerateSeed seeds generation when a _generate entry sets no seed,
+// so the same fixtures produce the same documents on every run.
+const defaultGenerateSeed = 1
+
+// Default range of the date producer.
+var (
+	defaultDateFrom = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	defaultDateTo   = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Word lists the producers draw from.
+var (
+	fakeFirstNames = []string{
+		"Alice", "Bob", "Carol", "David", "Emma", "Felix", "Grace", "Hiro", "Isla", "Jonas",
+		"Kenji", "Laura", "Mateo", "Nora", "Omar", "Priya", "Quinn", "Rosa", "Sven", "Talia",
+		"Umar", "Vera", "Wei", "Xenia", "Yuki", "Zane",
+	}
+	fakeLastNames = []string{
+		"Anderson", "Brown", "Chen", "Dubois", "Evans", "Fischer", "Garcia", "Hansen", "Ito", "Jensen",
+		"Kowalski", "Lopez", "Martin", "Nakamura", "Okafor", "Patel", "Rossi", "Silva", "Tanaka", "Weber",
+	}
+	fakeWords = []string{
+		"alpha", "amber", "bright", "cedar", "cloud", "coral", "delta", "ember", "field", "forest",
+		"harbor", "island", "lumen", "meadow", "nova", "ocean", "pixel", "quartz", "river", "signal",
+		"stone", "summit", "timber", "urban", "valley", "willow",
+	}
+)
+
+// generateSpec is the contents of a _generate entry.
+type generateSpec struct {
+	Count    int       `yaml:"count"`
+	Seed     *uint64   `yaml:"seed"`
+	Template yaml.Node `yaml:"template"`
+}
+
+// generationEntry returns the _generate spec of a document file entry, or
+// nil if the entry is an ordinary document.
+func generationEntry(node *yaml.Node) (*generateSpec, error) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 || node.Content[0].Value != generateKey {
+		return nil, nil
+	}
+	if len(node.Content) != 2 {
+		return nil, fmt.Errorf("line %d: %s must be the only key of its entry", node.Line, generateKey)
+	}
+
+	var spec generateSpec
+	if err := node.Content[1].Decode(&spec); err != nil {
+		return nil, fmt.Errorf("line %d: %s: %w", node.Line, generateKey, err)
+	}
+	if spec.Count < 1 || spec.Count > maxGenerated {
+		return nil, fmt.Errorf("line %d: %s count must be between 1 and %d", node.Line, generateKey, maxGenerated)
+	}
+	if spec.Template.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: %s requires a template mapping", node.Line, generateKey)
+	}
+	return &spec, nil
+}
+
+// generate returns the documents of the spec, made by replacing each
+// {$fake: kind} node of the template with a generated value.
+func (s *generateSpec) generate() ([]*yaml.Node, error) {
+	seed := uint64(defaultGenerateSeed)
+	if s.Seed != nil {
+		seed = *s.Seed
+	}
+	f := &faker{rand: rand.New(rand.NewPCG(seed, seed))}
+
+	nodes := make([]*yaml.Node, 0, s.Count)
+	for i := range s.Count {
+		f.seq = i + 1
+		node, err := f.fill(&s.Template)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// faker produces the values of one _generate entry.
+type faker struct {
+	rand *rand.Rand
+	seq  int // Position of the document being generated, counting from 1
+}
+
+// fill returns a copy of node with its $fake nodes replaced by values.
+func (f *faker) fill(node *yaml.Node) (*yaml.Node, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return f.fill(node.Alias)
+	case yaml.MappingNode:
+		if len(node.Content) > 0 && node.Content[0].Value == fakeKey {
+			return f.produce(node)
+		}
+	case yaml.SequenceNode:
+	default:
+		return node, nil
+	}
+
+	out := *node
+	out.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		filled, err := f.fill(child)
+		if err != nil {
+			return nil, err
+		}
+		out.Content[i] = filled
+	}
+	return &out, nil
+}
+
+// produce returns the value of the {$fake: kind, param: value, ...} node.
+func (f *faker) produce(node *yaml.Node) (*yaml.Node, error) {
+	kind := node.Content[1].Value
+	params := make(map[string]*yaml.Node)
+	for i := 2; i+1 < len(node.Content); i += 2 {
+		params[node.Content[i].Value] = node.Content[i+1]
+	}
+	fail := func(format string, args ...any) (*yaml.Node, error) {
+		return nil, fmt.Errorf("line %d: %s %s: %s", node.Line, fakeKey, kind, fmt.Sprintf(format, args...))
+	}
+
+	switch kind {
+	case "seq":
+		start, err := intParam(params, "start", 1)
+		if err != nil {
+			return fail("%v", err)
+		}
+		n := start + f.seq - 1
+		if format, ok := params["format"]; ok {
+			return scalar("!!str", fmt.Sprintf(format.Value, n)), nil
+		}
+		return scalar("!!int", strconv.Itoa(n)), nil
+
+	case "int":
+		lo, err := intParam(params, "min", 0)
+		if err != nil {
+			return fail("%v", err)
+		}
+		hi, err := intParam(params, "max", 100)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if hi < lo {
+			return fail("max %d is less than min %d", hi, lo)
+		}
+		return scalar("!!int", strconv.Itoa(lo+f.rand.IntN(hi-lo+1))), nil
+
+	case "float":
+		lo, err := floatParam(params, "min", 0)
+		if err != nil {
+			return fail("%v", err)
+		}
+		hi, err := floatParam(params, "max", 1)
+		if err != nil {
+			return fail("%v", err)
+		}
+		decimals, err := intParam(params, "decimals", 2)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if hi < lo {
+			return fail("max %v is less than min %v", hi, lo)
+		}
+		return scalar("!!float", strconv.FormatFloat(lo+f.rand.Float64()*(hi-lo), 'f', decimals, 64)), nil
+
+	case "bool":
+		return scalar("!!bool", strconv.FormatBool(f.rand.IntN(2) == 1)), nil
+
+	case "first_name":
+		return scalar("!!str", f.pick(fakeFirstNames)), nil
+
+	case "last_name":
+		return scalar("!!str", f.pick(fakeLastNames)), nil
+
+	case "name":
+		return scalar("!!str", f.pick(fakeFirstNames)+" "+f.pick(fakeLastNames)), nil
+
+	case "email":
+		domain := "example.com"
+		if d, ok := params["domain"]; ok {
+			domain = d.Value
+		}
+		local := strings.ToLower(f.pick(fakeFirstNames) + "." + f.pick(fakeLastNames))
+		return scalar("!!str", fmt.Sprintf("%s%d@%s", local, f.seq, domain)), nil
+
+	case "word":
+		return scalar("!!str", f.pick(fakeWords)), nil
+
+	case "sentence":
+		n, err := intParam(params, "words", 6)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if n < 1 {
+			return fail("words must be at least 1")
+		}
+		words := make([]string, n)
+		for i := range words {
+			words[i] = f.pick(fakeWords)
+		}
+		s := strings.Join(words, " ")
+		return scalar("!!str", strings.ToUpper(s[:1])+s[1:]+"."), nil
+
+	case "uuid":
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(f.rand.UintN(256))
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return scalar("!!str", fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])), nil
+
+	case "date":
+		from, err := timeParam(params, "from", defaultDateFrom)
+		if err != nil {
+			return fail("%v", err)
+		}
+		to, err := timeParam(params, "to", defaultDateTo)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if !to.After(from) {
+			return fail("to must be after from")
+		}
+		t := from.Add(time.Duration(f.rand.Int64N(int64(to.Sub(from)))))
+		return scalar("!!str", t.UTC().Truncate(time.Second).Format(time.RFC3339)), nil
+
+	case "choice":
+		values := params["values"]
+		if values == nil || values.Kind != yaml.SequenceNode || len(values.Content) == 0 {
+			return fail("requires a non-empty values list")
+		}
+		return values.Content[f.rand.IntN(len(values.Content))], nil
+	}
+
+	return fail("unknown producer (want seq, int, float, bool, name, first_name, last_name, email, word, sentence, uuid, date, or choice)")
+}
+
+// pick returns a random element of words.
+func (f *faker) pick(words []string) string {
+	return words[f.rand.IntN(len(words))]
+}
+
+// scalar returns a scalar node with the given tag and value.
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// intParam returns the integer parameter name, or def if it is not set.
+func intParam(params map[string]*yaml.Node, name string, def int) (int, error) {
+	node, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(node.Value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", name, node.Value)
+	}
+	return n, nil
+}
+
+// floatParam returns the number parameter name, or def if it is not set.
+func floatParam(params map[string]*yaml.Node, name string, def float64) (float64, error) {
+	node, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseFloat(node.Value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", name, node.Value)
+	}
+	return n, nil
+}
+
+// timeParam returns the date or RFC 3339 timestamp parameter name, or def
+// if it is not set.
+func timeParam(params map[string]*yaml.Node, name string, def time.Time) (time.Time, error) {
+	node, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, node.Value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s must be a date or RFC 3339 timestamp, got %q", name, node.Value)
+}
@@ -0,0 +1,122 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFixtures_Generate(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_traits.yml": "active:\n  status: active\n",
+		"users/documents.yml": `- _id: admin
+  name: Admin
+- _generate:
+    count: 50
+    template:
+      _id: {$fake: seq, format: "user-%03d"}
+      _traits: active
+      name: {$fake: name}
+      email: {$fake: email, domain: example.org}
+      age: {$fake: int, min: 18, max: 20}
+      score: {$fake: float, min: 1, max: 2, decimals: 1}
+      verified: {$fake: bool}
+      plan: {$fake: choice, values: [free, pro]}
+      joined: {$fake: date, from: 2024-01-01, to: 2024-02-01}
+      profile:
+        bio: {$fake: sentence, words: 3}
+        tags: [{$fake: word}, fixed]
+      token: {$fake: uuid}
+      rank: {$fake: seq, start: 100}
+`,
+	})
+
+	parse := func() []Document {
+		t.Helper()
+		fixtures, err := parseFixtures(os.DirFS(dir), ".")
+		if err != nil {
+			t.Fatalf("parseFixtures() error: %v", err)
+		}
+		return fixtures[0].documents
+	}
+	docs := parse()
+	if len(docs) != 51 {
+		t.Fatalf("expected 51 documents, got %d", len(docs))
+	}
+	if docs[1].ID != "user-001" || docs[50].ID != "user-050" {
+		t.Errorf("expected sequential IDs, got %q and %q", docs[1].ID, docs[50].ID)
+	}
+	if docs[50].Location() != "users/documents.yml:3" || docs[50].pos != 51 {
+		t.Errorf("expected generated documents to point at their _generate entry, got %s (position %d)", docs[50].Location(), docs[50].pos)
+	}
+
+	email := regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@example\.org$`)
+	for _, doc := range docs[1:] {
+		var src struct {
+			Status   string    `json:"status"`
+			Name     string    `json:"name"`
+			Email    string    `json:"email"`
+			Age      int       `json:"age"`
+			Score    float64   `json:"score"`
+			Verified *bool     `json:"verified"`
+			Plan     string    `json:"plan"`
+			Joined   time.Time `json:"joined"`
+			Profile  struct {
+				Bio  string   `json:"bio"`
+				Tags []string `json:"tags"`
+			} `json:"profile"`
+			Token string `json:"token"`
+			Rank  int    `json:"rank"`
+		}
+		if err := json.Unmarshal(doc.Source, &src); err != nil {
+			t.Fatalf("decoding %s: %v", doc.Source, err)
+		}
+		switch {
+		case src.Status != "active", !strings.Contains(src.Name, " "), !email.MatchString(src.Email),
+			src.Age < 18 || src.Age > 20, src.Score < 1 || src.Score > 2, src.Verified == nil,
+			src.Plan != "free" && src.Plan != "pro",
+			src.Joined.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !src.Joined.Before(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+			len(strings.Fields(src.Profile.Bio)) != 3, len(src.Profile.Tags) != 2 || src.Profile.Tags[1] != "fixed",
+			len(src.Token) != 36, src.Rank < 100:
+			t.Errorf("unexpected generated document %s", doc.Source)
+		}
+	}
+
+	// The same fixtures generate the same documents.
+	again := parse()
+	for i := range docs {
+		if string(docs[i].Source) != string(again[i].Source) {
+			t.Fatalf("expected generation to be deterministic, got %s then %s", docs[i].Source, again[i].Source)
+		}
+	}
+}
+
+func TestParseFixtures_GenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{name: "no count", doc: "- _generate: {template: {a: 1}}\n", want: "count must be between 1 and"},
+		{name: "no template", doc: "- _generate: {count: 1}\n", want: "requires a template mapping"},
+		{name: "extra key", doc: "- _generate: {count: 1, template: {a: 1}}\n  name: x\n", want: "must be the only key"},
+		{name: "unknown producer", doc: "- _generate: {count: 1, template: {a: {$fake: phone}}}\n", want: "$fake phone: unknown producer"},
+		{name: "bad range", doc: "- _generate: {count: 1, template: {a: {$fake: int, min: 5, max: 1}}}\n", want: "max 1 is less than min 5"},
+		{name: "empty choice", doc: "- _generate: {count: 1, template: {a: {$fake: choice}}}\n", want: "non-empty values list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": tt.doc})
+			_, err := parseFixtures(os.DirFS(dir), ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// geojsonExt is the extension of GeoJSON document files. Each holds a
+// FeatureCollection whose features become documents of the index: the
+// properties of a feature are the fields of the document, its geometry is
+// stored in the geometry field, and its id, if any, is the _id.
+const geojsonExt = ".geojson"
+
+// defaultGeometryField is the field holding feature geometries when
+// _config.yml names none and the mapping has no single geo field.
+const defaultGeometryField = "geometry"
+
+// geojsonConfig is the geojson section of _config.yml.
+type geojsonConfig struct {
+	// GeometryField is the dotted path of the field holding the geometry of
+	// each feature. It defaults to the only geo_shape or geo_point field of
+	// the mapping, or else to "geometry".
+	GeometryField string `yaml:"geometry_field"`
+}
+
+// geometryField returns the field holding feature geometries and its type in
+// the mapping, which is empty if the mapping does not define it.
+func (f *indexFixture) geometryField() (string, string, error) {
+	types := make(map[string]string)
+	var geo []string
+	walkMappedFields(f.mapping, func(path, source string, def json.RawMessage) {
+		var field struct {
+			Type string `json:"type"`
+		}
+		if path != source || json.Unmarshal(def, &field) != nil {
+			return
+		}
+		types[path] = field.Type
+		if field.Type == "geo_shape" || field.Type == "geo_point" {
+			geo = append(geo, path)
+		}
+	})
+
+	name := f.config.GeoJSON.GeometryField
+	switch {
+	case name != "":
+	case len(geo) == 1:
+		name = geo[0]
+	case len(geo) > 1:
+		return "", "", fmt.Errorf("the mapping has several geo fields (%s), so %s must set geojson.geometry_field", quoteAll(geo), configFile)
+	default:
+		name = defaultGeometryField
+	}
+	return name, types[name], nil
+}
+
+// parseGeoJSONFiles finds and parses the GeoJSON document files of the
+// directory, *.geojson files that do not start with "_".
+func parseGeoJSONFiles(fsys fs.FS, dir string, f *indexFixture) ([]Document, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory %q: %w", dir, err)
+	}
+
+	var (
+		docs             []Document
+		field, fieldType string
+		resolved         bool
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, geojsonExt) || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if !resolved {
+			if field, fieldType, err = f.geometryField(); err != nil {
+				return nil, err
+			}
+			resolved = true
+		}
+
+		fileDocs, err := parseGeoJSONFile(fsys, path.Join(dir, name), path.Join(path.Base(dir), name), field, fieldType)
+		if err != nil {
+			return nil, fmt.Errorf("parsing GeoJSON file %q: %w", name, err)
+		}
+		docs = append(docs, fileDocs...)
+	}
+
+	return docs, nil
+}
+
+// parseGeoJSONFile parses the features of a FeatureCollection file into
+// documents recording display as their source file, with each geometry at
+// field, whose mapped type is fieldType.
+func parseGeoJSONFile(fsys fs.FS, name, display, field, fieldType string) ([]Document, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	root, err := jsonToYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling JSON: %w", err)
+	}
+
+	members := geojsonMembers(root)
+	if root.Kind != yaml.MappingNode || members["type"] == nil || members["type"].Value != "FeatureCollection" {
+		return nil, errors.New(`expected a GeoJSON object of type "FeatureCollection"`)
+	}
+	features := members["features"]
+	if features == nil || features.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: features must be an array", root.Line)
+	}
+
+	docs := make([]Document, 0, len(features.Content))
+	for i, node := range features.Content {
+		doc, err := parseGeoJSONFeature(node, field, fieldType)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		doc.file, doc.line, doc.pos = display, node.Line, len(docs)+1
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// parseGeoJSONFeature converts a Feature object into a document.
+func parseGeoJSONFeature(node *yaml.Node, field, fieldType string) (Document, error) {
+	members := geojsonMembers(node)
+	if node.Kind != yaml.MappingNode || members["type"] == nil || members["type"].Value != "Feature" {
+		return Document{}, fmt.Errorf(`line %d: expected a GeoJSON object of type "Feature"`, node.Line)
+	}
+
+	var doc Document
+	if id := members["id"]; id != nil && id.ShortTag() != "!!null" {
+		if id.Kind != yaml.ScalarNode {
+			return Document{}, fmt.Errorf("line %d: id must be a string or number", id.Line)
+		}
+		doc.ID = id.Value
+	}
+
+	source := json.RawMessage(`{}`)
+	if props := members["properties"]; props != nil && props.ShortTag() != "!!null" {
+		if props.Kind != yaml.MappingNode {
+			return Document{}, fmt.Errorf("line %d: properties must be an object or null", props.Line)
+		}
+		encoded, err := yamlToJSON(props)
+		if err != nil {
+			return Document{}, fmt.Errorf("encoding as JSON: %w", err)
+		}
+		source = encoded
+	}
+
+	geometry := members["geometry"]
+	if geometry == nil || geometry.ShortTag() == "!!null" {
+		doc.Source = source
+		return doc, nil
+	}
+	value, err := geometryValue(geometry, fieldType)
+	if err != nil {
+		return Document{}, fmt.Errorf("line %d: %w", geometry.Line, err)
+	}
+
+	set := false
+	source, err = setMissingField(source, strings.Split(field, "."), func() (json.RawMessage, error) {
+		set = true
+		return value, nil
+	})
+	if err != nil {
+		return Document{}, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if !set {
+		return Document{}, fmt.Errorf("line %d: property %q is also the geometry field", node.Line, field)
+	}
+	doc.Source = source
+
+	return doc, nil
+}
+
+// geometryValue returns the JSON value stored for a geometry. A geo_point
+// field takes Point and MultiPoint geometries, stored as [lon, lat] arrays
+// that every version of Elasticsearch accepts; other fields take the
+// geometry as it is, the GeoJSON format geo_shape fields index.
+func geometryValue(node *yaml.Node, fieldType string) (json.RawMessage, error) {
+	members := geojsonMembers(node)
+	if node.Kind != yaml.MappingNode || members["type"] == nil || members["type"].Kind != yaml.ScalarNode {
+		return nil, errors.New("geometry must be a GeoJSON geometry object or null")
+	}
+	if fieldType != "geo_point" {
+		return yamlToJSON(node)
+	}
+
+	kind := members["type"].Value
+	if kind != "Point" && kind != "MultiPoint" {
+		return nil, fmt.Errorf("the geometry field is mapped as geo_point, which holds Point and MultiPoint geometries, not %s", kind)
+	}
+	coordinates := members["coordinates"]
+	if coordinates == nil || coordinates.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s geometry has no coordinates array", kind)
+	}
+	return yamlToJSON(coordinates)
+}
+
+// geojsonMembers returns the members of a JSON object node by name. It is
+// empty for other nodes.
+func geojsonMembers(node *yaml.Node) map[string]*yaml.Node {
+	members := make(map[string]*yaml.Node)
+	if node.Kind != yaml.MappingNode {
+		return members
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		members[node.Content[i].Value] = node.Content[i+1]
+	}
+	return members
+}
@@ -0,0 +1,142 @@
+package testfixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew_GeoJSONDocuments(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"parks/_mapping.json": `{"properties":{"name":{"type":"keyword"},"area":{"type":"geo_shape"}}}`,
+		"parks/parks.geojson": `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "id": "central",
+      "properties": {"name": "Central Park", "acres": 843},
+      "geometry": {"type": "Polygon", "coordinates": [[[-73.98, 40.77], [-73.95, 40.80], [-73.94, 40.79], [-73.98, 40.77]]]}
+    },
+    {"type": "Feature", "id": 7, "properties": null, "geometry": null}
+  ]
+}
+`,
+		"cafes/_mapping.json": `{"properties":{"name":{"type":"keyword"},"location":{"type":"geo_point"}}}`,
+		"cafes/cafes.geojson": `{"type":"FeatureCollection","features":[
+  {"type":"Feature","properties":{"name":"Blue"},"geometry":{"type":"Point","coordinates":[-73.99,40.73]}},
+  {"type":"Feature","properties":{"name":"Chain"},"geometry":{"type":"MultiPoint","coordinates":[[-73.9,40.7],[-74.0,40.8]]}}
+]}
+`,
+		"places/_config.yml": "geojson:\n  geometry_field: geo.shape\n",
+		"places/places.geojson": `{"type":"FeatureCollection","features":[
+  {"type":"Feature","id":"p","properties":{"geo":{"source":"osm"}},"geometry":{"type":"Point","coordinates":[1,2]}}
+]}
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	tests := []struct {
+		index  string
+		want   []Document
+		wantAt string
+	}{
+		{
+			index: "parks",
+			want: []Document{
+				{ID: "central", Source: []byte(`{"name":"Central Park","acres":843,"area":{"type":"Polygon","coordinates":[[[-73.98,40.77],[-73.95,40.80],[-73.94,40.79],[-73.98,40.77]]]}}`)},
+				{ID: "7", Source: []byte(`{}`)},
+			},
+			wantAt: "parks/parks.geojson:4",
+		},
+		{
+			index: "cafes",
+			want: []Document{
+				{Source: []byte(`{"name":"Blue","location":[-73.99,40.73]}`)},
+				{Source: []byte(`{"name":"Chain","location":[[-73.9,40.7],[-74.0,40.8]]}`)},
+			},
+			wantAt: "cafes/cafes.geojson:2",
+		},
+		{
+			index: "places",
+			want: []Document{
+				{ID: "p", Source: []byte(`{"geo":{"source":"osm","shape":{"type":"Point","coordinates":[1,2]}}}`)},
+			},
+			wantAt: "places/places.geojson:2",
+		},
+	}
+	for _, tt := range tests {
+		docs := loader.fixture(tt.index).documents
+		if len(docs) != len(tt.want) {
+			t.Fatalf("index %q: expected %d documents, got %d", tt.index, len(tt.want), len(docs))
+		}
+		for i, want := range tt.want {
+			if docs[i].ID != want.ID || string(docs[i].Source) != string(want.Source) {
+				t.Errorf("index %q: document %d: expected %s %s, got %s %s", tt.index, i, want.ID, want.Source, docs[i].ID, docs[i].Source)
+			}
+		}
+		if got := docs[0].Location(); got != tt.wantAt {
+			t.Errorf("index %q: expected location %q, got %q", tt.index, tt.wantAt, got)
+		}
+	}
+}
+
+func TestNew_GeoJSONErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "not a feature collection",
+			files:   map[string]string{"docs/a.geojson": `{"type":"Feature","geometry":null}`},
+			wantErr: `parsing GeoJSON file "a.geojson": expected a GeoJSON object of type "FeatureCollection"`,
+		},
+		{
+			name:    "not a feature",
+			files:   map[string]string{"docs/a.geojson": `{"type":"FeatureCollection","features":[{"type":"Point","coordinates":[1,2]}]}`},
+			wantErr: `feature 0: line 1: expected a GeoJSON object of type "Feature"`,
+		},
+		{
+			name: "polygon in geo_point",
+			files: map[string]string{
+				"docs/_mapping.json": `{"properties":{"location":{"type":"geo_point"}}}`,
+				"docs/a.geojson":     "{\"type\":\"FeatureCollection\",\"features\":[\n{\"type\":\"Feature\",\"properties\":{},\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[]}}]}",
+			},
+			wantErr: "feature 0: line 2: the geometry field is mapped as geo_point, which holds Point and MultiPoint geometries, not Polygon",
+		},
+		{
+			name: "several geo fields",
+			files: map[string]string{
+				"docs/_mapping.json": `{"properties":{"area":{"type":"geo_shape"},"center":{"type":"geo_point"}}}`,
+				"docs/a.geojson":     `{"type":"FeatureCollection","features":[]}`,
+			},
+			wantErr: `the mapping has several geo fields ("area", "center"), so _config.yml must set geojson.geometry_field`,
+		},
+		{
+			name:    "property collides with geometry",
+			files:   map[string]string{"docs/a.geojson": `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"geometry":"x"},"geometry":{"type":"Point","coordinates":[1,2]}}]}`},
+			wantErr: `feature 0: line 1: property "geometry" is also the geometry field`,
+		},
+		{
+			name:    "bad id",
+			files:   map[string]string{"docs/a.geojson": `{"type":"FeatureCollection","features":[{"type":"Feature","id":[1],"geometry":null}]}`},
+			wantErr: "feature 0: line 1: id must be a string or number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, tt.files)
+
+			_, err := New(newOfflineClient(t), Directory(dir))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
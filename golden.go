@@ -0,0 +1,239 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// UpdateGolden makes AssertGolden and AssertGoldenSearch rewrite golden
+// files from actual results instead of comparing them. The package does not
+// register a command-line flag of its own, which would panic test binaries
+// that define one of the same name; to update with -update-fixtures, bind
+// the flag to UpdateGolden in the test package:
+//
+//	func init() {
+//		flag.BoolVar(&testfixtures.UpdateGolden, "update-fixtures", false, "rewrite golden files")
+//	}
+var UpdateGolden bool
+
+// updateGoldenEnv enables golden file updates without a command-line flag,
+// e.g. UPDATE_FIXTURES=1 go test ./...
+const updateGoldenEnv = "UPDATE_FIXTURES"
+
+// updateGoldenFlag is the flag that also enables golden file updates, when
+// the test binary defines it.
+const updateGoldenFlag = "update-fixtures"
+
+// updatingGolden reports whether golden files should be rewritten instead of compared.
+func updatingGolden() bool {
+	if UpdateGolden {
+		return true
+	}
+	if f := flag.Lookup(updateGoldenFlag); f != nil {
+		if ok, _ := strconv.ParseBool(f.Value.String()); ok {
+			return true
+		}
+	}
+	ok, _ := strconv.ParseBool(os.Getenv(updateGoldenEnv))
+	return ok
+}
+
+// AssertGolden compares actual with the contents of the golden file at path
+// and fails the test if they differ. JSON content is compared semantically
+// and stored indented.
+//
+// When tests run with UPDATE_FIXTURES=1, UpdateGolden set, or an
+// -update-fixtures flag the test binary defines, the golden file is
+// rewritten from actual instead, creating it if needed.
+func AssertGolden(t testing.TB, path string, actual []byte) {
+	t.Helper()
+	assertGolden(t, path, actual, nil)
+}
+
+// assertGolden is AssertGolden with a normalize function, if not nil,
+// applied to the golden file's contents before they are compared.
+func assertGolden(t testing.TB, path string, actual []byte, normalize func(expected []byte) []byte) {
+	t.Helper()
+
+	actual = formatGolden(actual)
+
+	if updatingGolden() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		t.Logf("updated golden file %s", path)
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist; run tests with UPDATE_FIXTURES=1 to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if normalize != nil {
+		expected = normalize(expected)
+	}
+
+	if !goldenEqual(expected, actual) {
+		t.Errorf("result does not match golden file %s (run tests with UPDATE_FIXTURES=1 to accept it)\n--- expected\n%s\n--- actual\n%s", path, expected, actual)
+	}
+}
+
+// AssertGoldenSearch runs query against index and compares the returned hits
+// (index, ID, and source of each, in order) with the golden file at path.
+// Scores, timings, and shard details are left out so the file is stable.
+//
+// Fields of the golden hits that the index mapping leaves out of _source,
+// with _source includes or excludes, are dropped before comparing, so a
+// golden file written before the mapping changed is not reported as
+// differing.
+func AssertGoldenSearch(t testing.TB, client *elasticsearch.Client, index, query, path string) {
+	t.Helper()
+
+	res, err := client.Search(
+		client.Search.WithContext(context.Background()),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(strings.NewReader(query)),
+	)
+	if err != nil {
+		t.Fatalf("searching %q: %v", index, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		t.Fatalf("searching %q: %v", index, err)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Index  string          `json:"_index"`
+				ID     string          `json:"_id"`
+				Source json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatalf("decoding search response: %v", err)
+	}
+
+	type hit struct {
+		Index  string          `json:"_index"`
+		ID     string          `json:"_id"`
+		Source json.RawMessage `json:"_source,omitempty"`
+	}
+	hits := make([]hit, 0, len(result.Hits.Hits))
+	for _, h := range result.Hits.Hits {
+		hits = append(hits, hit(h))
+	}
+
+	actual, err := json.Marshal(hits)
+	if err != nil {
+		t.Fatalf("encoding hits: %v", err)
+	}
+
+	assertGolden(t, path, actual, func(expected []byte) []byte {
+		t.Helper()
+
+		var golden []hit
+		if err := json.Unmarshal(expected, &golden); err != nil {
+			return expected
+		}
+		filters, err := sourceFilters(context.Background(), client, index)
+		if err != nil {
+			t.Fatalf("reading _source filters of %q: %v", index, err)
+		}
+		for i, h := range golden {
+			filter := filters[h.Index]
+			if filter == nil || h.Source == nil {
+				continue
+			}
+			if golden[i].Source, _, err = filter.apply(h.Source); err != nil {
+				t.Fatalf("applying _source filter to golden hit %q: %v", h.ID, err)
+			}
+		}
+		normalized, err := json.Marshal(golden)
+		if err != nil {
+			t.Fatalf("encoding golden hits: %v", err)
+		}
+		return normalized
+	})
+}
+
+// sourceFilters returns the _source filters of the indices matching index,
+// by index name. Indices that keep whole documents are left out.
+func sourceFilters(ctx context.Context, client *elasticsearch.Client, index string) (map[string]*sourceFilter, error) {
+	res, err := client.Indices.GetMapping(
+		client.Indices.GetMapping.WithContext(ctx),
+		client.Indices.GetMapping.WithIndex(index),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	var mappings map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&mappings); err != nil {
+		return nil, fmt.Errorf("decoding mapping response: %w", err)
+	}
+
+	filters := make(map[string]*sourceFilter)
+	for name, m := range mappings {
+		if filter := parseSourceFilter(m.Mappings); filter != nil {
+			filters[name] = filter
+		}
+	}
+	return filters, nil
+}
+
+// formatGolden indents JSON content; other content is returned unchanged.
+func formatGolden(data []byte) []byte {
+	if !json.Valid(data) {
+		return data
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return data
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
+}
+
+// goldenEqual compares JSON documents semantically and anything else byte for byte.
+func goldenEqual(expected, actual []byte) bool {
+	if json.Valid(expected) && json.Valid(actual) {
+		e, err1 := decodeValue(expected)
+		a, err2 := decodeValue(actual)
+		if err1 == nil && err2 == nil {
+			return reflect.DeepEqual(e, a)
+		}
+	}
+
+	return bytes.Equal(expected, actual)
+}
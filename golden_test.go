@@ -0,0 +1,66 @@
+package testfixtures
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssertGolden_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "hits.json")
+
+	t.Setenv(updateGoldenEnv, "1")
+	AssertGolden(t, path, []byte(`{"b":1,"a":[1,2]}`))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	want := "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}\n"
+	if string(data) != want {
+		t.Errorf("unexpected golden file contents:\n%s", data)
+	}
+
+	// Compared semantically once updates are off
+	t.Setenv(updateGoldenEnv, "")
+	AssertGolden(t, path, []byte(`{"a":[1,2],"b":1}`))
+}
+
+func TestAssertGolden_UpdateGoldenAndFlag(t *testing.T) {
+	t.Setenv(updateGoldenEnv, "")
+
+	path := filepath.Join(t.TempDir(), "set.json")
+	UpdateGolden = true
+	AssertGolden(t, path, []byte(`{"a":1}`))
+	UpdateGolden = false
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected UpdateGolden to write the golden file: %v", err)
+	}
+
+	// A flag of the same name defined by the test binary is honored
+	if flag.Lookup(updateGoldenFlag) == nil {
+		flag.Bool(updateGoldenFlag, false, "rewrite golden files")
+	}
+	if err := flag.Set(updateGoldenFlag, "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = flag.Set(updateGoldenFlag, "false") }()
+	path = filepath.Join(t.TempDir(), "flag.json")
+	AssertGolden(t, path, []byte(`{"a":1}`))
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected -%s to write the golden file: %v", updateGoldenFlag, err)
+	}
+}
+
+func TestGoldenEqual(t *testing.T) {
+	if !goldenEqual([]byte(`{"a":1,"b":2}`), []byte(`{"b":2,"a":1}`)) {
+		t.Error("expected JSON with different key order to be equal")
+	}
+	if goldenEqual([]byte(`[1,2]`), []byte(`[2,1]`)) {
+		t.Error("expected arrays in different order to differ")
+	}
+	if goldenEqual([]byte("plain"), []byte("text")) {
+		t.Error("expected different text to differ")
+	}
+}
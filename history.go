@@ -0,0 +1,218 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// HistoryIndex is the index in which RecordHistory records each load.
+const HistoryIndex = ".testfixtures-meta"
+
+// maxHistory is the number of most recent loads returned by History.
+const maxHistory = 100
+
+// historyMapping and historySettings define HistoryIndex. The index is
+// hidden so that it stays out of wildcard searches of the fixture indices.
+var (
+	historyMapping = json.RawMessage(`{"properties":{` +
+		`"user":{"type":"keyword"},"host":{"type":"keyword"},"time":{"type":"date"},` +
+		`"source":{"type":"keyword"},"fixture_hash":{"type":"keyword"},"indices":{"type":"keyword"},` +
+		`"documents":{"type":"long"},"duration_ms":{"type":"long"},"error":{"type":"text"}}}`)
+	historySettings = json.RawMessage(`{"index":{"hidden":true,"number_of_shards":1,"auto_expand_replicas":"0-1"}}`)
+)
+
+// LoadRecord describes a load recorded by RecordHistory.
+type LoadRecord struct {
+	User        string        // Operating system user who ran the load
+	Host        string        // Host the load ran on
+	Time        time.Time     // When the load started
+	Source      string        // Fixtures directory or FS root
+	FixtureHash string        // SHA-256 of the fixture files, to tell whether two loads used the same data
+	Indices     []string      // Indices loaded
+	Documents   int           // Documents in the loaded indices
+	Duration    time.Duration // Time taken by the load
+	Err         string        // Error that stopped the load, if any
+}
+
+// historyEntry is a LoadRecord as stored in HistoryIndex.
+type historyEntry struct {
+	User        string    `json:"user"`
+	Host        string    `json:"host"`
+	Time        time.Time `json:"time"`
+	Source      string    `json:"source"`
+	FixtureHash string    `json:"fixture_hash"`
+	Indices     []string  `json:"indices"`
+	Documents   int       `json:"documents"`
+	DurationMS  int64     `json:"duration_ms"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// History returns the loads recorded in the cluster by RecordHistory, by any
+// Loader, newest first. At most the 100 most recent loads are returned, and
+// none if nothing has been recorded yet.
+func (l *Loader) History(ctx context.Context) ([]LoadRecord, error) {
+	body := fmt.Sprintf(`{"size":%d,"sort":[{"time":"desc"}]}`, maxHistory)
+	res, err := l.client.Search(
+		l.client.Search.WithContext(ctx),
+		l.client.Search.WithIndex(HistoryIndex),
+		l.client.Search.WithBody(strings.NewReader(body)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: reading load history: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, fmt.Errorf("testfixtures: reading load history: %w", err)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source historyEntry `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("testfixtures: decoding load history: %w", err)
+	}
+
+	records := make([]LoadRecord, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		e := hit.Source
+		records = append(records, LoadRecord{
+			User:        e.User,
+			Host:        e.Host,
+			Time:        e.Time,
+			Source:      e.Source,
+			FixtureHash: e.FixtureHash,
+			Indices:     e.Indices,
+			Documents:   e.Documents,
+			Duration:    time.Duration(e.DurationMS) * time.Millisecond,
+			Err:         e.Error,
+		})
+	}
+
+	return records, nil
+}
+
+// recordLoad adds a load that started at start and ended with loadErr to
+// HistoryIndex, creating the index on first use.
+func (l *Loader) recordLoad(ctx context.Context, start time.Time, loadErr error) error {
+	hash, err := hashFixtures(l.fsys, l.dir)
+	if err != nil {
+		return err
+	}
+
+	entry := historyEntry{
+		User:        currentUser(),
+		Time:        start.UTC(),
+		Source:      l.source,
+		FixtureHash: hash,
+		Indices:     []string{},
+		DurationMS:  time.Since(start).Milliseconds(),
+	}
+	entry.Host, _ = os.Hostname()
+	for _, r := range l.results {
+		entry.Indices = append(entry.Indices, r.Index)
+		entry.Documents += r.Documents + r.Skipped
+	}
+	if loadErr != nil {
+		entry.Error = loadErr.Error()
+	}
+
+	if err := ensureHistoryIndex(ctx, l.client); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding load record: %w", err)
+	}
+	res, err := l.client.Index(HistoryIndex, bytes.NewReader(body),
+		l.client.Index.WithRefresh("true"),
+		l.client.Index.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("writing to %s: %w", HistoryIndex, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("writing to %s: %w", HistoryIndex, err)
+	}
+
+	return nil
+}
+
+// ensureHistoryIndex creates HistoryIndex if it does not exist.
+func ensureHistoryIndex(ctx context.Context, client *elasticsearch.Client) error {
+	exists, err := indexOrAliasExists(ctx, client, HistoryIndex)
+	if err != nil || exists {
+		return err
+	}
+
+	err = createIndex(ctx, client, HistoryIndex, historyMapping, historySettings, nil, "")
+	if err != nil && strings.Contains(err.Error(), "resource_already_exists_exception") {
+		// Created by another loader since the check
+		return nil
+	}
+	return err
+}
+
+// currentUser returns the name of the operating system user, or "" if it
+// cannot be determined.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// hashFixtures returns the hex SHA-256 of the names and contents of every
+// file in the fixtures directory dir of fsys, or "" if fsys is nil.
+func hashFixtures(fsys fs.FS, dir string) (string, error) {
+	if fsys == nil {
+		return "", nil
+	}
+
+	h := sha256.New()
+	err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+
+		fmt.Fprintf(h, "%s\x00", strings.TrimPrefix(name, dir+"/"))
+		n, err := io.Copy(h, f)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "\x00%d\x00", n)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("hashing fixtures: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
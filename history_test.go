@@ -0,0 +1,166 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRecordHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n- _id: 2\n  name: Bob\n",
+	})
+
+	var (
+		created  string
+		recorded historyEntry
+		metaSeen bool
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":201}}]}`), nil
+		case req.Method == http.MethodHead && req.URL.Path == "/"+HistoryIndex:
+			if !metaSeen {
+				return jsonResponse(404, ``), nil
+			}
+			return jsonResponse(200, ``), nil
+		case req.Method == http.MethodPut && req.URL.Path == "/"+HistoryIndex:
+			created = string(body)
+			metaSeen = true
+		case req.Method == http.MethodPost && req.URL.Path == "/"+HistoryIndex+"/_doc":
+			if req.URL.Query().Get("refresh") != "true" {
+				t.Errorf("expected the record to be refreshed, got %s", req.URL.RawQuery)
+			}
+			if err := json.Unmarshal(body, &recorded); err != nil {
+				t.Errorf("decoding load record %s: %v", body, err)
+			}
+			return jsonResponse(201, `{"result":"created"}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), RecordHistory())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	start := time.Now()
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if !strings.Contains(created, `"hidden":true`) || !strings.Contains(created, `"fixture_hash":{"type":"keyword"}`) {
+		t.Errorf("unexpected history index body %s", created)
+	}
+	hash, err := hashFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("hashFixtures() error: %v", err)
+	}
+	if recorded.FixtureHash != hash || recorded.Source != dir || recorded.Documents != 2 || recorded.Error != "" {
+		t.Errorf("unexpected load record %+v", recorded)
+	}
+	if len(recorded.Indices) != 1 || recorded.Indices[0] != "users" {
+		t.Errorf("expected the record to list users, got %v", recorded.Indices)
+	}
+	if recorded.Time.Before(start.Add(-time.Second)) || recorded.User == "" {
+		t.Errorf("expected the record to say who loaded when, got %+v", recorded)
+	}
+
+	// The index now exists and is not created again.
+	created = ""
+	if err := loader.LoadIndices("users"); err != nil {
+		t.Fatalf("LoadIndices() error: %v", err)
+	}
+	if created != "" {
+		t.Error("expected the history index to be created once")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n",
+	})
+
+	var query string
+	found := true
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if !found {
+			return jsonResponse(404, `{"error":{"type":"index_not_found_exception"},"status":404}`), nil
+		}
+		body, _ := io.ReadAll(req.Body)
+		query = req.URL.Path + " " + string(body)
+		return jsonResponse(200, `{"hits":{"hits":[
+			{"_source":{"user":"ci","host":"runner-1","time":"2026-10-14T09:00:00Z","source":"testdata/fixtures","fixture_hash":"ab12","indices":["users"],"documents":1,"duration_ms":1500,"error":"boom"}},
+			{"_source":{"user":"alice","host":"laptop","time":"2026-10-13T09:00:00Z","indices":["users"],"documents":1,"duration_ms":20}}
+		]}}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	records, err := loader.History(t.Context())
+	if err != nil {
+		t.Fatalf("History() error: %v", err)
+	}
+	if query != `/`+HistoryIndex+`/_search {"size":100,"sort":[{"time":"desc"}]}` {
+		t.Errorf("unexpected history query %s", query)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	want := LoadRecord{
+		User: "ci", Host: "runner-1", Time: time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+		Source: "testdata/fixtures", FixtureHash: "ab12", Indices: []string{"users"},
+		Documents: 1, Duration: 1500 * time.Millisecond, Err: "boom",
+	}
+	if got := records[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("records[0] = %+v, want %+v", got, want)
+	}
+
+	found = false
+	if records, err := loader.History(t.Context()); err != nil || records != nil {
+		t.Errorf("expected no records without the history index, got %v, %v", records, err)
+	}
+}
+
+func TestHashFixtures(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/users/documents.yml": {Data: []byte("- name: Alice\n")},
+		"fixtures/_traits.yml":         {Data: []byte("a: {b: 1}\n")},
+	}
+	embedded, err := hashFixtures(fsys, "fixtures")
+	if err != nil {
+		t.Fatalf("hashFixtures() error: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- name: Alice\n",
+		"_traits.yml":         "a: {b: 1}\n",
+	})
+	onDisk, err := hashFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("hashFixtures() error: %v", err)
+	}
+	if embedded != onDisk {
+		t.Errorf("expected the same files to hash alike wherever they live, got %s and %s", embedded, onDisk)
+	}
+
+	fsys["fixtures/users/documents.yml"] = &fstest.MapFile{Data: []byte("- name: Bob\n")}
+	if changed, _ := hashFixtures(fsys, "fixtures"); changed == embedded {
+		t.Error("expected the hash to change with a file's contents")
+	}
+}
@@ -0,0 +1,130 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// ilmDir is the top-level directory holding index lifecycle policies, one
+// <name>.json file per policy in the format of the Create Lifecycle Policy
+// API, created by Load before any index.
+const ilmDir = "_ilm"
+
+// lifecyclePolicy is a policy of the _ilm directory.
+type lifecyclePolicy struct {
+	name string
+	body json.RawMessage
+}
+
+// readLifecyclePolicies reads the policies of the _ilm directory, ordered by
+// name. A missing directory means there are none.
+func readLifecyclePolicies(fsys fs.FS, dir string) ([]lifecyclePolicy, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, ilmDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ilmDir, err)
+	}
+
+	var policies []lifecyclePolicy
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		body, err := readJSONFile(fsys, path.Join(dir, ilmDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s/%s: %w", ilmDir, name, err)
+		}
+		var def struct {
+			Policy json.RawMessage `json:"policy"`
+		}
+		if !isJSONObject(body) || json.Unmarshal(body, &def) != nil || !isJSONObject(def.Policy) {
+			return nil, fmt.Errorf(`%s/%s: policy must be an object of the form {"policy": {"phases": ...}}`, ilmDir, name)
+		}
+		policies = append(policies, lifecyclePolicy{name: strings.TrimSuffix(name, ".json"), body: body})
+	}
+
+	return policies, nil
+}
+
+// putLifecyclePolicies creates the policies of _ilm, replacing any of the
+// same name, so that index.lifecycle.name settings refer to existing
+// policies when the indices are created. Nothing is created if _optional.yml
+// lists ilm and the cluster cannot run it.
+func (l *Loader) putLifecyclePolicies(ctx context.Context) error {
+	if skip, err := l.skipsComponent(ctx, "ilm"); err != nil || skip {
+		return err
+	}
+	for _, p := range l.lifecyclePolicies {
+		if err := putLifecyclePolicy(ctx, l.client, p.name, p.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteLifecyclePolicies removes the policies of _ilm and returns the
+// errors of the deletions that failed. Elasticsearch refuses to delete a
+// policy that indices still use, so this runs after the indices are gone.
+func (l *Loader) deleteLifecyclePolicies(ctx context.Context) []error {
+	if skip, err := l.skipsComponent(ctx, "ilm"); err != nil {
+		return []error{err}
+	} else if skip {
+		return nil
+	}
+	var errs []error
+	for _, p := range l.lifecyclePolicies {
+		if err := deleteLifecyclePolicy(ctx, l.client, p.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// putLifecyclePolicy creates or replaces the lifecycle policy name.
+func putLifecyclePolicy(ctx context.Context, client *elasticsearch.Client, name string, body json.RawMessage) error {
+	res, err := client.ILM.PutLifecycle(name,
+		client.ILM.PutLifecycle.WithBody(bytes.NewReader(body)),
+		client.ILM.PutLifecycle.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("creating lifecycle policy %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating lifecycle policy %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// deleteLifecyclePolicy deletes the lifecycle policy name if it exists.
+func deleteLifecyclePolicy(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.ILM.DeleteLifecycle(name, client.ILM.DeleteLifecycle.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting lifecycle policy %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The policy may already be gone, which is fine
+	if res.StatusCode == 404 {
+		return nil
+	}
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting lifecycle policy %q: %w", name, err)
+	}
+
+	return nil
+}
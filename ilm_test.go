@@ -0,0 +1,82 @@
+package testfixtures
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadLifecyclePolicies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_ilm/rollover.json": {Data: []byte(`{"policy": {"phases": {"hot": {"actions": {"rollover": {"max_docs": 10}}}}}}`)},
+		"_ilm/delete.json":   {Data: []byte(`{"policy": {"phases": {"delete": {"min_age": "1d", "actions": {"delete": {}}}}}}`)},
+		"_ilm/README.md":     {Data: []byte("notes")},
+	}
+
+	policies, err := readLifecyclePolicies(fsys, ".")
+	if err != nil {
+		t.Fatalf("readLifecyclePolicies() error: %v", err)
+	}
+	if len(policies) != 2 || policies[0].name != "delete" || policies[1].name != "rollover" {
+		t.Fatalf("unexpected policies: %+v", policies)
+	}
+
+	if policies, err := readLifecyclePolicies(fstest.MapFS{}, "."); policies != nil || err != nil {
+		t.Errorf("expected no policies for a missing directory, got %v, %v", policies, err)
+	}
+}
+
+func TestReadLifecyclePolicies_Errors(t *testing.T) {
+	for _, data := range []string{`[]`, `{"phases": {}}`, `{"policy": "hot"}`} {
+		fsys := fstest.MapFS{"_ilm/hot.json": {Data: []byte(data)}}
+		_, err := readLifecyclePolicies(fsys, ".")
+		if err == nil || !strings.Contains(err.Error(), "_ilm/hot.json: policy must be an object") {
+			t.Errorf("%s: expected a policy error, got %v", data, err)
+		}
+	}
+}
+
+func TestLoad_LifecyclePolicies(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_ilm/hot.json":       `{"policy": {"phases": {"hot": {"actions": {}}}}}`,
+		"logs/_settings.json": `{"index.lifecycle.name": "hot"}`,
+		"logs/documents.yml":  "- _id: \"1\"\n  message: hello\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		if req.Method == http.MethodDelete && req.URL.Path == "/_ilm/policy/hot" {
+			return jsonResponse(404, `{}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	put := slices.Index(requests, "PUT /_ilm/policy/hot")
+	create := slices.Index(requests, "PUT /ci_logs")
+	if put < 0 || create < 0 || put > create {
+		t.Fatalf("expected the policy to be created before the index, got %v", requests)
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if !slices.Contains(requests, "DELETE /_ilm/policy/hot") {
+		t.Errorf("expected Clean to delete the policy, got %v", requests)
+	}
+}
@@ -1,21 +1,30 @@
 package testfixtures
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"io/fs"
+	"net/http"
+	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/elastic/go-elasticsearch/v8/esutil"
 )
 
-// createIndex creates an Elasticsearch index with the given mapping and settings.
-func createIndex(ctx context.Context, client *elasticsearch.Client, name string, mapping, settings json.RawMessage) error {
-	body, err := buildCreateIndexBody(mapping, settings)
+// createIndex creates an Elasticsearch index with the given mapping and
+// settings. A non-empty activeShards sets wait_for_active_shards.
+func createIndex(ctx context.Context, client *elasticsearch.Client, name string, mapping, settings, aliases json.RawMessage, activeShards string) error {
+	body, err := buildCreateIndexBody(mapping, settings, aliases)
 	if err != nil {
 		return fmt.Errorf("building request body: %w", err)
 	}
@@ -24,6 +33,9 @@ func createIndex(ctx context.Context, client *elasticsearch.Client, name string,
 	if body != nil {
 		opts = append(opts, client.Indices.Create.WithBody(bytes.NewReader(body)))
 	}
+	if activeShards != "" {
+		opts = append(opts, client.Indices.Create.WithWaitForActiveShards(activeShards))
+	}
 	opts = append(opts, client.Indices.Create.WithContext(ctx))
 
 	res, err := client.Indices.Create(name, opts...)
@@ -40,8 +52,8 @@ func createIndex(ctx context.Context, client *elasticsearch.Client, name string,
 }
 
 // buildCreateIndexBody constructs the JSON body for the Create Index API.
-func buildCreateIndexBody(mapping, settings json.RawMessage) ([]byte, error) {
-	if mapping == nil && settings == nil {
+func buildCreateIndexBody(mapping, settings, aliases json.RawMessage) ([]byte, error) {
+	if mapping == nil && settings == nil && aliases == nil {
 		return nil, nil
 	}
 
@@ -52,6 +64,9 @@ func buildCreateIndexBody(mapping, settings json.RawMessage) ([]byte, error) {
 	if settings != nil {
 		body["settings"] = settings
 	}
+	if aliases != nil {
+		body["aliases"] = aliases
+	}
 
 	return json.Marshal(body)
 }
@@ -82,63 +97,352 @@ func deleteIndex(ctx context.Context, client *elasticsearch.Client, name string)
 }
 
 // bulkInsertDocuments inserts documents into an Elasticsearch index using BulkIndexer.
-func bulkInsertDocuments(ctx context.Context, client *elasticsearch.Client, indexName string, docs []document) error {
+// When concurrency is greater than one, documents are hash-partitioned by ID
+// across that many indexers, each with a single worker, so writes to the same
+// ID keep their fixture order. If transform is non-nil, it is applied to each
+// document before indexing.
+func bulkInsertDocuments(ctx context.Context, client *elasticsearch.Client, indexName string, cfg bulkConfig, docs []Document, concurrency int, transform func(Document) (Document, error)) error {
 	if len(docs) == 0 {
 		return nil
 	}
 
-	indexer, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-		Client: client,
-		Index:  indexName,
+	if concurrency <= 1 {
+		return bulkInsertPartition(ctx, client, indexName, 0, cfg, feedDocuments(docs, transform))
+	}
+
+	partitions := partitionDocuments(docs, concurrency)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, part := range partitions {
+		if len(part) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(part []Document) {
+			defer wg.Done()
+			if err := bulkInsertPartition(ctx, client, indexName, 1, cfg, feedDocuments(part, transform)); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(part)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// partitionDocuments splits docs into n partitions. Documents with an ID are
+// assigned by hash so that every write to a given ID lands in the same
+// partition; documents without an ID are distributed round-robin.
+func partitionDocuments(docs []Document, n int) [][]Document {
+	partitions := make([][]Document, n)
+	next := 0
+	for _, doc := range docs {
+		var i int
+		if doc.ID != "" {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(doc.ID))
+			i = int(h.Sum32() % uint32(n))
+		} else {
+			i = next
+			next = (next + 1) % n
+		}
+		partitions[i] = append(partitions[i], doc)
+	}
+
+	return partitions
+}
+
+// streamDocuments inserts every line of the given NDJSON files as a document.
+// Lines are handed to the bulk indexer as read, so memory use does not grow
+// with the size of the files. If transform is non-nil, it is applied to each
+// line before indexing; lines for which cfg.skip reports true are left out.
+func streamDocuments(ctx context.Context, client *elasticsearch.Client, indexName string, cfg bulkConfig, fsys fs.FS, paths []string, transform func(Document) (Document, error)) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return bulkInsertPartition(ctx, client, indexName, 0, cfg, func(add func(Document) error) error {
+		add = withTransform(add, transform)
+		queue := func(doc Document) error {
+			if cfg.skip != nil && cfg.skip(doc) {
+				return nil
+			}
+			if cfg.onQueue != nil {
+				cfg.onQueue(doc)
+			}
+			return add(doc)
+		}
+		for _, path := range paths {
+			if err := feedNDJSONFile(fsys, path, queue); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+}
+
+// withTransform wraps add so that transform is applied to each document first.
+func withTransform(add func(Document) error, transform func(Document) (Document, error)) func(Document) error {
+	if transform == nil {
+		return add
+	}
+	return func(doc Document) error {
+		transformed, err := transform(doc)
+		if err != nil {
+			return err
+		}
+		return add(transformed)
+	}
+}
+
+// feedNDJSONFile passes each document of an NDJSON file to add. A file
+// whose first line is a bulk action, such as {"index":{"_id":"1"}}, is read
+// in the bulk format: each document line follows an action line giving its
+// _id, routing, and whether it is created or indexed. Any other file holds
+// one document per non-blank line.
+func feedNDJSONFile(fsys fs.FS, name string, add func(Document) error) error {
+	file, err := fsys.Open(name)
 	if err != nil {
-		return fmt.Errorf("creating bulk indexer for %q: %w", indexName, err)
+		return fmt.Errorf("opening %q: %w", name, err)
 	}
+	defer func() { _ = file.Close() }()
 
-	var bulkErrors []string
-	for _, doc := range docs {
-		body, err := json.Marshal(doc.Body)
+	display := path.Join(path.Base(path.Dir(name)), path.Base(name))
+	r := bufio.NewReader(file)
+
+	var (
+		started, bulk bool
+		action        *bulkAction // Action line waiting for its document
+		actionLine    int
+	)
+	feed := func(line []byte, lineNo int) error {
+		if !started {
+			started, bulk = true, isBulkAction(line)
+		}
+		if !bulk {
+			return add(Document{Source: line, file: display, line: lineNo, pos: lineNo})
+		}
+		if action == nil {
+			a, err := parseBulkAction(line)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", display, lineNo, err)
+			}
+			action, actionLine = &a, lineNo
+			return nil
+		}
+		doc := Document{ID: action.id, Routing: action.routing, Source: line, file: display, line: actionLine, pos: actionLine, action: action.action}
+		action = nil
+		return add(doc)
+	}
+
+	for lineNo := 1; ; lineNo++ {
+		// ReadBytes returns a fresh slice, which the indexer may hold until flush.
+		line, err := r.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if feedErr := feed(trimmed, lineNo); feedErr != nil {
+				return feedErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			if action != nil {
+				return fmt.Errorf("%s:%d: bulk action has no document line", display, actionLine)
+			}
+			return nil
+		}
 		if err != nil {
-			return fmt.Errorf("marshaling document: %w", err)
+			return fmt.Errorf("reading %q: %w", name, err)
 		}
+	}
+}
+
+// docFeed supplies documents to a bulk indexer by calling add for each one.
+type docFeed func(add func(Document) error) error
+
+// feedDocuments returns a docFeed over parsed documents.
+func feedDocuments(docs []Document, transform func(Document) (Document, error)) docFeed {
+	return func(add func(Document) error) error {
+		add = withTransform(add, transform)
+		for _, doc := range docs {
+			if err := add(doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// bulkInsertPartition inserts the documents supplied by feed with a single
+// BulkIndexer. A numWorkers of zero uses the BulkIndexer default. Documents
+// too large to share a request are sent one per request by a second indexer,
+// and documents larger than the request limit are rejected. If cfg.onFlush is
+// set, it receives the outcome of each bulk request.
+func bulkInsertPartition(ctx context.Context, client *elasticsearch.Client, indexName string, numWorkers int, cfg bulkConfig, feed docFeed) error {
+	newIndexer := func(numWorkers, flushBytes int) (esutil.BulkIndexer, error) {
+		bic := esutil.BulkIndexerConfig{
+			Client:     client,
+			Index:      indexName,
+			NumWorkers: numWorkers,
+			FlushBytes: flushBytes,
+		}
+		if cfg.onFlush != nil {
+			bic.OnFlushStart = func(ctx context.Context) context.Context {
+				return context.WithValue(ctx, flushCountsKey{}, new(flushCounts))
+			}
+			bic.OnFlushEnd = func(ctx context.Context) {
+				if c, ok := ctx.Value(flushCountsKey{}).(*flushCounts); ok {
+					cfg.onFlush(int(c.succeeded.Load()), int(c.failed.Load()))
+				}
+			}
+		}
+		indexer, err := esutil.NewBulkIndexer(bic)
+		if err != nil {
+			return nil, fmt.Errorf("creating bulk indexer for %q: %w", indexName, err)
+		}
+		return indexer, nil
+	}
+
+	indexer, err := newIndexer(numWorkers, cfg.flushBytes)
+	if err != nil {
+		return err
+	}
+	// oversize indexes documents at or above the flush threshold. With a
+	// single worker and a one-byte threshold, each is flushed on its own.
+	var oversize esutil.BulkIndexer
 
+	var (
+		mu             sync.Mutex
+		bulkErrors     []string
+		missingDeletes atomic.Uint64 // Deletes of absent documents, counted as failed by the indexer
+	)
+	feedErr := feed(func(doc Document) error {
+		size := bulkPayloadSize(doc)
+		if cfg.maxRequestBytes > 0 && size > cfg.maxRequestBytes {
+			return documentTooLarge(doc, size, cfg.maxRequestBytes)
+		}
+
+		target := indexer
+		if cfg.flushBytes > 0 && size >= cfg.flushBytes {
+			if oversize == nil {
+				var err error
+				if oversize, err = newIndexer(1, 1); err != nil {
+					return err
+				}
+			}
+			target = oversize
+		}
+
+		onSuccess := func(ctx context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem) {
+			if c, ok := ctx.Value(flushCountsKey{}).(*flushCounts); ok {
+				c.succeeded.Add(1)
+			}
+			if cfg.onIndexed != nil {
+				cfg.onIndexed(doc)
+			}
+		}
+		action, body := bulkItem(doc)
 		item := esutil.BulkIndexerItem{
-			Action: "index",
-			Body:   bytes.NewReader(body),
-			OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+			Action:    action,
+			OnSuccess: onSuccess,
+			OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+				if err == nil && doc.action == actionDelete && res.Status == http.StatusNotFound {
+					// Deleting a missing document still leaves a tombstone.
+					missingDeletes.Add(1)
+					onSuccess(ctx, item, res)
+					return
+				}
+				if c, ok := ctx.Value(flushCountsKey{}).(*flushCounts); ok {
+					c.failed.Add(1)
+				}
+				mu.Lock()
+				defer mu.Unlock()
 				if err != nil {
-					bulkErrors = append(bulkErrors, err.Error())
+					bulkErrors = append(bulkErrors, describeFlushError(err))
 				} else {
-					bulkErrors = append(bulkErrors, fmt.Sprintf("[%d] %s: %s", res.Status, res.Error.Type, res.Error.Reason))
+					bulkErrors = append(bulkErrors, describeBulkFailure(doc, res))
 				}
 			},
 		}
 
+		if body != nil {
+			item.Body = bytes.NewReader(body)
+		}
 		if doc.ID != "" {
 			item.DocumentID = doc.ID
 		}
+		item.Routing = doc.Routing
 
-		if err := indexer.Add(ctx, item); err != nil {
+		if err := target.Add(ctx, item); err != nil {
 			return fmt.Errorf("adding document to bulk indexer: %w", err)
 		}
+		return nil
+	})
+
+	indexers := []esutil.BulkIndexer{indexer}
+	if oversize != nil {
+		indexers = append(indexers, oversize)
 	}
 
-	if err := indexer.Close(ctx); err != nil {
-		return fmt.Errorf("closing bulk indexer for %q: %w", indexName, err)
+	if feedErr != nil {
+		for _, bi := range indexers {
+			_ = bi.Close(ctx)
+		}
+		return feedErr
 	}
 
+	var numFailed uint64
+	for _, bi := range indexers {
+		if err := bi.Close(ctx); err != nil {
+			return fmt.Errorf("closing bulk indexer for %q: %w", indexName, err)
+		}
+		numFailed += bi.Stats().NumFailed
+	}
+	numFailed -= missingDeletes.Load()
+
 	if len(bulkErrors) > 0 {
 		return fmt.Errorf("bulk insert errors for %q: %s", indexName, strings.Join(bulkErrors, "; "))
 	}
 
-	stats := indexer.Stats()
-	if stats.NumFailed > 0 {
-		return fmt.Errorf("bulk insert for %q: %d documents failed", indexName, stats.NumFailed)
+	if numFailed > 0 {
+		return fmt.Errorf("bulk insert for %q: %d documents failed", indexName, numFailed)
 	}
 
 	return nil
 }
 
+// flushCountsKey is the context key under which a bulk indexer flush carries
+// its flushCounts, from OnFlushStart to the item callbacks and OnFlushEnd.
+type flushCountsKey struct{}
+
+// flushCounts tallies the item outcomes of a single bulk request.
+type flushCounts struct {
+	succeeded atomic.Int64
+	failed    atomic.Int64
+}
+
+// indexOrAliasExists reports whether an index, or an alias, named name exists.
+func indexOrAliasExists(ctx context.Context, client *elasticsearch.Client, name string) (bool, error) {
+	res, err := client.Indices.Exists([]string{name}, client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("checking for index %q: %w", name, err)
+	}
+	_ = res.Body.Close()
+
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	}
+	return false, fmt.Errorf("checking for index %q: elasticsearch error [%s]", name, res.Status())
+}
+
 // refreshIndex forces a refresh on the index so documents are immediately searchable.
 func refreshIndex(ctx context.Context, client *elasticsearch.Client, name string) error {
 	res, err := client.Indices.Refresh(
@@ -157,12 +461,13 @@ func refreshIndex(ctx context.Context, client *elasticsearch.Client, name string
 	return nil
 }
 
-// checkResponse checks an Elasticsearch API response for errors.
+// checkResponse checks an Elasticsearch API response for errors, which are
+// rendered with formatErrorBody.
 func checkResponse(res *esapi.Response) error {
 	if !res.IsError() {
 		return nil
 	}
 
 	body, _ := io.ReadAll(res.Body)
-	return fmt.Errorf("elasticsearch error [%s]: %s", res.Status(), string(body))
+	return fmt.Errorf("elasticsearch error [%s]: %s", res.Status(), formatErrorBody(body))
 }
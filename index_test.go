@@ -0,0 +1,383 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// newFakeClient returns a client whose requests are answered by rt.
+func newFakeClient(t *testing.T, rt http.RoundTripper) *elasticsearch.Client {
+	t.Helper()
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{"http://es.test:9200"},
+		Transport: rt,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	return client
+}
+
+func TestPartitionDocuments(t *testing.T) {
+	docs := []Document{
+		{ID: "a", Source: json.RawMessage(`{"v":1}`)},
+		{ID: "b", Source: json.RawMessage(`{"v":1}`)},
+		{ID: "a", Source: json.RawMessage(`{"v":2}`)},
+		{Source: json.RawMessage(`{"v":1}`)},
+		{Source: json.RawMessage(`{"v":2}`)},
+		{ID: "a", Source: json.RawMessage(`{"v":3}`)},
+	}
+
+	partitions := partitionDocuments(docs, 3)
+	if len(partitions) != 3 {
+		t.Fatalf("expected 3 partitions, got %d", len(partitions))
+	}
+
+	total := 0
+	var withA []string
+	for _, part := range partitions {
+		total += len(part)
+		for _, doc := range part {
+			if doc.ID == "a" {
+				withA = append(withA, string(doc.Source))
+			}
+		}
+	}
+
+	if total != len(docs) {
+		t.Errorf("expected %d documents across partitions, got %d", len(docs), total)
+	}
+
+	// All writes to "a" must land in one partition, in fixture order
+	if len(withA) != 3 || withA[0] != `{"v":1}` || withA[1] != `{"v":2}` || withA[2] != `{"v":3}` {
+		t.Errorf("expected writes to ID 'a' in fixture order, got %v", withA)
+	}
+	for _, part := range partitions {
+		n := 0
+		for _, doc := range part {
+			if doc.ID == "a" {
+				n++
+			}
+		}
+		if n != 0 && n != 3 {
+			t.Errorf("writes to ID 'a' split across partitions")
+		}
+	}
+}
+
+func TestLoad_Results(t *testing.T) {
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		case req.Method == http.MethodPut && req.URL.Path == "/users":
+			return jsonResponse(400, `{"error":{"type":"mapper_parsing_exception","reason":"bad mapping"},"status":400}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err == nil {
+		t.Fatal("expected Load to fail creating users")
+	}
+
+	results := loader.Results()
+	if len(results) != 2 {
+		t.Fatalf("expected results for products and users, got %+v", results)
+	}
+	if results[0].Index != "products" || results[0].Documents != 3 || results[0].Err != nil {
+		t.Errorf("unexpected products result: %+v", results[0])
+	}
+	if results[1].Index != "users" || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "bad mapping") {
+		t.Errorf("unexpected users result: %+v", results[1])
+	}
+}
+
+func TestLoad_Events(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n- _id: 2\n  name: Bob\n",
+	})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":true,"items":[
+				{"index":{"_id":"1","status":201}},
+				{"index":{"_id":"2","status":400,"error":{"type":"document_parsing_exception","reason":"bad"}}}
+			]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	var events []Event
+	loader, err := New(client, Directory(dir), WithEventHandler(func(e Event) {
+		events = append(events, e)
+	}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	loadErr := loader.Load()
+	if loadErr == nil {
+		t.Fatal("expected Load to report the rejected document")
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %#v", events)
+	}
+	if e, ok := events[0].(IndexDeleted); !ok || e.Index != "users" {
+		t.Errorf("expected IndexDeleted for users first, got %#v", events[0])
+	}
+	if e, ok := events[1].(IndexCreated); !ok || e.Index != "users" {
+		t.Errorf("expected IndexCreated for users second, got %#v", events[1])
+	}
+	if e, ok := events[2].(BulkFlushed); !ok || e != (BulkFlushed{Index: "users", Succeeded: 1, Failed: 1}) {
+		t.Errorf("expected BulkFlushed with one success and one failure, got %#v", events[2])
+	}
+	e, ok := events[3].(LoadFinished)
+	if !ok || e.Err != loadErr || len(e.Results) != 1 || e.Results[0].Index != "users" {
+		t.Errorf("expected LoadFinished carrying the Load error and results, got %#v", events[3])
+	}
+
+	events = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if len(events) != 1 || events[0] != (IndexDeleted{Index: "users"}) {
+		t.Errorf("expected Clean to emit IndexDeleted for users, got %#v", events)
+	}
+}
+
+func TestWithEventHandler_Nil(t *testing.T) {
+	if _, err := New(newOfflineClient(t), Directory("testdata/fixtures"), WithEventHandler(nil)); err == nil {
+		t.Fatal("expected an error for a nil event handler")
+	}
+}
+
+func TestLoadContext_UsesCallContext(t *testing.T) {
+	type key struct{}
+	var seen []any
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// The bulk indexer flushes on its own goroutines with a context of
+		// its own; cancellation reaches it through Add.
+		if !strings.HasSuffix(req.URL.Path, "/_bulk") {
+			seen = append(seen, req.Context().Value(key{}))
+		}
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+	}))
+
+	loader, err := New(client, Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "call"))
+	if err := loader.LoadContext(ctx); err != nil {
+		t.Fatalf("LoadContext() error: %v", err)
+	}
+	for _, v := range seen {
+		if v != "call" {
+			t.Fatalf("expected every request to carry the call context, got %v", seen)
+		}
+	}
+
+	cancel()
+	if err := loader.LoadContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected LoadContext to fail with the cancelled context, got %v", err)
+	}
+	if err := loader.CleanContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected CleanContext to fail with the cancelled context, got %v", err)
+	}
+
+	// The no-argument methods keep using the default context
+	if err := loader.Clean(); err != nil {
+		t.Errorf("Clean() error: %v", err)
+	}
+}
+
+func TestLoadIndices(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml":    "- _id: 1\n  name: Alice\n",
+		"products/documents.yml": "- _id: 1\n  name: Book\n",
+		"orders/documents.yml":   "- _id: 1\n  total: 10\n",
+		"buyers/_config.yml":     "alias:\n  indices: [users]\n",
+	})
+
+	var touched []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		if req.Method == http.MethodPut {
+			touched = append(touched, req.URL.Path)
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.LoadIndices("users", "orders"); err != nil {
+		t.Fatalf("LoadIndices() error: %v", err)
+	}
+
+	want := []string{"/orders", "/users", "/users/_aliases/buyers"}
+	if !slices.Equal(touched, want) {
+		t.Errorf("expected LoadIndices to create %v, got %v", want, touched)
+	}
+	var loaded []string
+	for _, r := range loader.Results() {
+		loaded = append(loaded, r.Index)
+	}
+	if !slices.Equal(loaded, []string{"orders", "users", "buyers"}) {
+		t.Errorf("unexpected results for %v", loaded)
+	}
+
+	err = loader.LoadIndices("users", "customers")
+	if err == nil || !strings.Contains(err.Error(), `no fixture index "customers" (available: buyers, orders, products, users)`) {
+		t.Errorf("expected an error listing the available fixtures, got %v", err)
+	}
+	if err := loader.LoadIndices(); err == nil {
+		t.Error("expected an error without index names")
+	}
+}
+
+func TestCleanIndices(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml":    "- _id: 1\n  name: Alice\n",
+		"products/documents.yml": "- _id: 1\n  name: Book\n",
+		"buyers/_config.yml":     "alias:\n  indices: [users]\n",
+	})
+
+	var deleted []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodDelete {
+			deleted = append(deleted, req.URL.Path)
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.CleanIndices("users", "buyers"); err != nil {
+		t.Fatalf("CleanIndices() error: %v", err)
+	}
+	if !slices.Equal(deleted, []string{"/users"}) {
+		t.Errorf("expected CleanIndices to delete only users, got %v", deleted)
+	}
+
+	err = loader.CleanIndices("orders")
+	if err == nil || !strings.Contains(err.Error(), `no fixture index "orders" (available: buyers, products, users)`) {
+		t.Errorf("expected an error listing the available fixtures, got %v", err)
+	}
+}
+
+func TestWithIndexPrefix(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n",
+		"buyers/_config.yml":  "alias:\n  indices: [users]\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("job42_"), WithIndexSuffix("_v1"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if got := loader.IndexName("users"); got != "job42_users_v1" {
+		t.Errorf("IndexName(users) = %q, want job42_users_v1", got)
+	}
+	if got := loader.Indices(); !slices.Equal(got, []string{"users"}) {
+		t.Errorf("Indices() = %q, want only users", got)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	for _, want := range []string{"DELETE /job42_users_v1", "PUT /job42_users_v1", "PUT /job42_users_v1/_aliases/job42_buyers_v1"} {
+		if !slices.Contains(requests, want) {
+			t.Errorf("expected request %q, got %v", want, requests)
+		}
+	}
+	if r := loader.Results(); r[0].Index != "job42_users_v1" {
+		t.Errorf("expected results to carry the index name, got %q", r[0].Index)
+	}
+
+	requests = nil
+	if err := loader.CleanIndices("users"); err != nil {
+		t.Fatalf("CleanIndices() error: %v", err)
+	}
+	if !slices.Equal(requests, []string{"DELETE /job42_users_v1"}) {
+		t.Errorf("expected Clean to delete only the prefixed index, got %v", requests)
+	}
+}
+
+func TestWithIndexPrefix_Invalid(t *testing.T) {
+	for _, opt := range []Option{WithIndexPrefix("Job_"), WithIndexPrefix("_job"), WithIndexPrefix("job/"), WithIndexSuffix("-v 1")} {
+		if _, err := New(newOfflineClient(t), opt); err == nil {
+			t.Error("expected an invalid index prefix or suffix to be rejected")
+		}
+	}
+}
+
+func TestCreateIndexBody(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json":  `{"properties":{"name":{"type":"keyword"}}}`,
+		"users/_settings.json": `{"number_of_shards":1}`,
+		"users/_aliases.json":  `{"people":{}}`,
+		"users/documents.yml":  "- _id: 1\n  name: Alice\n",
+		"plain/documents.yml":  "- _id: 1\n  name: Bob\n",
+		"buyers/_config.yml":   "alias:\n  indices: [users]\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	body, err := loader.CreateIndexBody("users")
+	if err != nil {
+		t.Fatalf("CreateIndexBody() error: %v", err)
+	}
+	want := `{"aliases":{"ci_people":{}},"mappings":{"properties":{"name":{"type":"keyword"}}},"settings":{"number_of_shards":1}}`
+	if string(body) != want {
+		t.Errorf("CreateIndexBody(users) = %s, want %s", body, want)
+	}
+
+	if body, err := loader.CreateIndexBody("plain"); body != nil || err != nil {
+		t.Errorf("expected no body for an index without mappings or settings, got %s, %v", body, err)
+	}
+	for _, name := range []string{"buyers", "missing"} {
+		if _, err := loader.CreateIndexBody(name); err == nil || !strings.Contains(err.Error(), "is not a fixture index") {
+			t.Errorf("CreateIndexBody(%s): expected a not a fixture index error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// indexAlias is a single alias of an index's _aliases.json, in the format of
+// the aliases section of the Create Index API.
+type indexAlias struct {
+	name     string
+	body     json.RawMessage // Filter, routing, is_write_index, and is_hidden
+	write    bool            // is_write_index is true
+	declared bool            // is_write_index is set, to true or false
+}
+
+// readIndexAliases reads an index's _aliases.json, mapping alias names to
+// their definitions:
+//
+//	{"users_read": {}, "users_write": {"is_write_index": true}}
+//
+// A missing file means the index has no aliases of its own.
+func readIndexAliases(fsys fs.FS, dir string) ([]indexAlias, error) {
+	data, err := readJSONFile(fsys, path.Join(dir, aliasesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", aliasesFile, err)
+	}
+
+	fields, err := decodeObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s must map alias names to alias definitions: %w", aliasesFile, err)
+	}
+	aliases := make([]indexAlias, 0, len(fields))
+	for _, field := range fields {
+		if field.key == "" {
+			return nil, fmt.Errorf("%s: alias name must not be empty", aliasesFile)
+		}
+		var def struct {
+			IsWriteIndex *bool `json:"is_write_index"`
+		}
+		if !isJSONObject(field.value) || json.Unmarshal(field.value, &def) != nil {
+			return nil, fmt.Errorf("%s: alias %q must be an object such as {} or {\"is_write_index\": true}", aliasesFile, field.key)
+		}
+		aliases = append(aliases, indexAlias{
+			name:     field.key,
+			body:     field.value,
+			write:    def.IsWriteIndex != nil && *def.IsWriteIndex,
+			declared: def.IsWriteIndex != nil,
+		})
+	}
+
+	return aliases, nil
+}
+
+// checkIndexAliases checks the aliases of every fixture against the others:
+// an alias cannot have the name of a fixture, and only one index can be the
+// write index of an alias. An alias shared by several indices that declares
+// is_write_index on any of them must have exactly one write index, since
+// writes through it fail otherwise; without the declaration it is read-only.
+func (l *Loader) checkIndexAliases() error {
+	var (
+		errs     []error
+		writer   = make(map[string]string)   // Write index of each alias
+		indices  = make(map[string][]string) // Indices of each alias
+		declared = make(map[string]bool)     // Aliases declaring is_write_index
+		names    []string                    // Aliases in order of appearance
+	)
+	for _, f := range l.fixtures {
+		for _, a := range f.aliases {
+			if l.fixture(a.name) != nil {
+				errs = append(errs, fmt.Errorf("index %q: %s: alias %q has the name of a fixture", f.name, aliasesFile, a.name))
+				continue
+			}
+			if indices[a.name] == nil {
+				names = append(names, a.name)
+			}
+			indices[a.name] = append(indices[a.name], f.name)
+			declared[a.name] = declared[a.name] || a.declared
+			if !a.write {
+				continue
+			}
+			if other, ok := writer[a.name]; ok {
+				errs = append(errs, fmt.Errorf("index %q: %s: alias %q already has %q as its write index", f.name, aliasesFile, a.name, other))
+				continue
+			}
+			writer[a.name] = f.name
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := writer[name]; !ok && declared[name] && len(indices[name]) > 1 {
+			errs = append(errs, fmt.Errorf("%s: alias %q of %s declares is_write_index but none of them is its write index", aliasesFile, name, quoteAll(indices[name])))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// indexAliasesBody returns the aliases section of the Create Index request
+// for f, with the prefix and suffix of WithIndexPrefix and WithIndexSuffix
+// applied to the alias names, or nil if f has no aliases.
+func (l *Loader) indexAliasesBody(f *indexFixture) json.RawMessage {
+	if len(f.aliases) == 0 {
+		return nil
+	}
+	fields := make([]jsonField, len(f.aliases))
+	for i, a := range f.aliases {
+		fields[i] = jsonField{key: l.aliasName(a.name), value: a.body}
+	}
+	return encodeObject(fields)
+}
@@ -0,0 +1,144 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadIndexAliases(t *testing.T) {
+	fsys := fstest.MapFS{"users/_aliases.json": {Data: []byte(`{
+		"users_write": {"is_write_index": true},
+		"active_users": {"filter": {"term": {"active": true}}, "routing": "1"},
+		"users_read": {}
+	}`)}}
+
+	aliases, err := readIndexAliases(fsys, "users")
+	if err != nil {
+		t.Fatalf("readIndexAliases() error: %v", err)
+	}
+	want := []struct {
+		name  string
+		write bool
+	}{{"users_write", true}, {"active_users", false}, {"users_read", false}}
+	if len(aliases) != len(want) {
+		t.Fatalf("expected %d aliases, got %d", len(want), len(aliases))
+	}
+	for i, w := range want {
+		if aliases[i].name != w.name || aliases[i].write != w.write {
+			t.Errorf("alias %d: expected %+v, got %+v", i, w, aliases[i])
+		}
+	}
+
+	if aliases, err := readIndexAliases(fsys, "orders"); aliases != nil || err != nil {
+		t.Errorf("expected no aliases for a missing file, got %v, %v", aliases, err)
+	}
+}
+
+func TestReadIndexAliases_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "array", data: `["users_read"]`, want: "_aliases.json must map alias names to alias definitions"},
+		{name: "not an object", data: `{"users_read": true}`, want: `_aliases.json: alias "users_read" must be an object`},
+		{name: "bad write flag", data: `{"users_read": {"is_write_index": "yes"}}`, want: `_aliases.json: alias "users_read" must be an object`},
+		{name: "empty name", data: `{"": {}}`, want: "_aliases.json: alias name must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"users/_aliases.json": {Data: []byte(tt.data)}}
+			_, err := readIndexAliases(fsys, "users")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNew_IndexAliasConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "fixture name",
+			files: map[string]string{
+				"users/_aliases.json":  `{"orders": {}}`,
+				"orders/documents.yml": "- _id: \"1\"\n",
+			},
+			want: `index "users": _aliases.json: alias "orders" has the name of a fixture`,
+		},
+		{
+			name: "two write indices",
+			files: map[string]string{
+				"users_v1/_aliases.json": `{"users": {"is_write_index": true}}`,
+				"users_v2/_aliases.json": `{"users": {"is_write_index": true}}`,
+			},
+			want: `index "users_v2": _aliases.json: alias "users" already has "users_v1" as its write index`,
+		},
+		{
+			name: "no write index",
+			files: map[string]string{
+				"users_v1/_aliases.json": `{"users": {"is_write_index": false}}`,
+				"users_v2/_aliases.json": `{"users": {}}`,
+			},
+			want: `_aliases.json: alias "users" of "users_v1", "users_v2" declares is_write_index but none of them is its write index`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, tt.files)
+			_, err := New(newOfflineClient(t), Directory(dir))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_IndexAliases(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users_v1/_aliases.json": `{"users_read": {}, "users": {"is_write_index": false}}`,
+		"users_v2/_aliases.json": `{"users_read": {}, "users": {"is_write_index": true, "filter": {"term": {"active": true}}}}`,
+		"users_v2/documents.yml": "- _id: \"1\"\n  active: true\n",
+	})
+
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	want := map[string]string{
+		"/ci_users_v1": `{"aliases":{"ci_users_read":{},"ci_users":{"is_write_index":false}}}`,
+		"/ci_users_v2": `{"aliases":{"ci_users_read":{},"ci_users":{"is_write_index":true,"filter":{"term":{"active":true}}}}}`,
+	}
+	for path, body := range want {
+		if bodies[path] != body {
+			t.Errorf("%s: expected create body %s, got %s", path, body, bodies[path])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// indexSortFields returns the fields listed in index.sort.field, which may be
+// a single name or an array of names.
+func indexSortFields(settings json.RawMessage) ([]string, error) {
+	v, ok, err := indexSetting(settings, "index.sort.field")
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var fields []string
+	if isJSONArray(v) {
+		err = json.Unmarshal(v, &fields)
+	} else {
+		var field string
+		err = json.Unmarshal(v, &field)
+		fields = []string{field}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("index.sort.field must be a field name or an array of names, got %s", v)
+	}
+
+	return fields, nil
+}
+
+// mappedField returns the mapping type of a dotted field path, following
+// object properties and multi-fields. source is the path holding the field's
+// value in documents, which for a multi-field is that of its parent. The
+// last result is false if the field is not mapped.
+func mappedField(mapping json.RawMessage, field string) (typ, source string, ok bool) {
+	if mapping == nil {
+		return "", "", false
+	}
+
+	var (
+		node = mapping
+		path []string
+	)
+	for i, name := range strings.Split(field, ".") {
+		next, inProperties := childMapping(node, "properties", name)
+		if !inProperties {
+			if i == 0 {
+				return "", "", false
+			}
+			if next, ok = childMapping(node, "fields", name); !ok {
+				return "", "", false
+			}
+		} else {
+			path = append(path, name)
+		}
+		node = next
+	}
+
+	var def struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(node, &def); err != nil {
+		return "", "", false
+	}
+	if def.Type == "" {
+		def.Type = "object"
+	}
+
+	return def.Type, strings.Join(path, "."), true
+}
+
+// childMapping returns the definition of name under the given section
+// ("properties" or "fields") of a mapping node.
+func childMapping(node json.RawMessage, section, name string) (json.RawMessage, bool) {
+	var obj, defs map[string]json.RawMessage
+	if err := json.Unmarshal(node, &obj); err != nil {
+		return nil, false
+	}
+	if err := json.Unmarshal(obj[section], &defs); err != nil {
+		return nil, false
+	}
+	def, ok := defs[name]
+	return def, ok
+}
+
+// walkMappedFields calls fn for every field of a mapping, in path order,
+// descending into object and nested properties and multi-fields. path is
+// the dotted path of the field and source the path holding its value in
+// documents, which for a multi-field is that of its parent.
+func walkMappedFields(mapping json.RawMessage, fn func(path, source string, def json.RawMessage)) {
+	var walk func(node json.RawMessage, prefix string)
+	walk = func(node json.RawMessage, prefix string) {
+		var m struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+		if json.Unmarshal(node, &m) != nil {
+			return
+		}
+		for _, name := range slices.Sorted(maps.Keys(m.Properties)) {
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			def := m.Properties[name]
+			fn(path, path, def)
+
+			var sub struct {
+				Fields map[string]json.RawMessage `json:"fields"`
+			}
+			if json.Unmarshal(def, &sub) == nil {
+				for _, field := range slices.Sorted(maps.Keys(sub.Fields)) {
+					fn(path+"."+field, path, sub.Fields[field])
+				}
+			}
+			walk(def, path)
+		}
+	}
+	walk(mapping, "")
+}
+
+// sortValueCompatible reports whether a document value can be indexed into a
+// sort field of the given mapping type. Unknown types accept any scalar.
+func sortValueCompatible(typ string, value json.RawMessage) bool {
+	v, err := decodeValue(value)
+	if err != nil {
+		return false
+	}
+
+	switch typ {
+	case "long", "integer", "short", "byte", "unsigned_long", "double", "float", "half_float", "scaled_float":
+		switch v := v.(type) {
+		case json.Number:
+			return true
+		case string:
+			_, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return err == nil
+		}
+		return false
+	case "boolean":
+		switch v := v.(type) {
+		case bool:
+			return true
+		case string:
+			return v == "true" || v == "false" || v == ""
+		}
+		return false
+	case "date", "date_nanos":
+		switch v.(type) {
+		case string, json.Number:
+			return true
+		}
+		return false
+	}
+
+	switch v.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return true
+}
+
+// checkIndexSort verifies that the values parsed documents of f give each
+// index.sort.field are compatible with the field's mapping, since
+// Elasticsearch only reports such problems as failures of the bulk request.
+// Sort fields that are not mapped are reported as well, as index creation
+// would fail on them. Documents may omit a sort field, which Elasticsearch
+// sorts as index.sort.missing says.
+func checkIndexSort(f *indexFixture) error {
+	fields, err := indexSortFields(f.settings)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	var errs []error
+	for _, field := range fields {
+		typ, source, ok := mappedField(f.mapping, field)
+		if !ok {
+			errs = append(errs, fmt.Errorf("index sort field %q is not defined in %s", field, mappingFile))
+			continue
+		}
+
+		for _, doc := range f.documents {
+			values, err := lookupField(doc.Source, strings.Split(source, "."))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: reading index sort field %q: %w", doc.Location(), field, err))
+				continue
+			}
+
+			for _, v := range values {
+				if string(v) != "null" && !sortValueCompatible(typ, v) {
+					errs = append(errs, fmt.Errorf("%s: index sort field %q holds %s, which is not a valid %s value", doc.Location(), field, v, typ))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkIndexSorts runs checkIndexSort on every fixture.
+func (l *Loader) checkIndexSorts() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		if err := checkIndexSort(f); err != nil {
+			errs = append(errs, fmt.Errorf("index %q: %w", f.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package testfixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew_IndexSortFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"events/_settings.json": `{"index":{"sort.field":["timestamp","name.raw","priority"],"sort.order":["desc","asc","asc"]}}`,
+		"events/_mapping.json": `{"properties":{
+			"timestamp":{"type":"date"},
+			"name":{"type":"text","fields":{"raw":{"type":"keyword"}}},
+			"priority":{"type":"integer"}
+		}}`,
+		"events/documents.yml": `- _id: 1
+  timestamp: 2024-01-01T00:00:00Z
+  name: deploy
+  priority: 1
+- _id: 2
+  timestamp: 2024-01-02T00:00:00Z
+  priority: high
+- _id: 3
+  name: rollback
+  priority: [1, 2]
+`,
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil {
+		t.Fatal("expected New to report index sort problems")
+	}
+	if want := `events/documents.yml:5: index sort field "priority" holds "high", which is not a valid integer value`; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got:\n%v", want, err)
+	}
+	// Elasticsearch sorts documents without a value as index.sort.missing says
+	if strings.Contains(err.Error(), "no value") {
+		t.Errorf("expected documents leaving out sort fields to pass, got:\n%v", err)
+	}
+	if strings.Contains(err.Error(), "documents.yml:1:") {
+		t.Errorf("expected the complete document to pass, got:\n%v", err)
+	}
+}
+
+func TestNew_IndexSortFieldNotMapped(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"events/_settings.json": `{"index.sort.field":"created"}`,
+		"events/_mapping.json":  `{"properties":{"timestamp":{"type":"date"}}}`,
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil || !strings.Contains(err.Error(), `index sort field "created" is not defined in _mapping.json`) {
+		t.Fatalf("expected an unmapped sort field error, got %v", err)
+	}
+}
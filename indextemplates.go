@@ -0,0 +1,175 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// indexTemplatesDir is the top-level directory holding index templates,
+// one <name>.json file per template in the format of the Put Index
+// Template API, installed by Load before it creates any index.
+const indexTemplatesDir = "_templates"
+
+// indexTemplate is a template of the _templates directory.
+type indexTemplate struct {
+	name     string
+	body     json.RawMessage
+	patterns []string // index_patterns of the body
+}
+
+// readIndexTemplates reads the templates of the _templates directory,
+// ordered by name. A missing directory means there are none.
+func readIndexTemplates(fsys fs.FS, dir string) ([]indexTemplate, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, indexTemplatesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", indexTemplatesDir, err)
+	}
+
+	var templates []indexTemplate
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		file := indexTemplatesDir + "/" + name
+		body, err := readJSONFile(fsys, path.Join(dir, indexTemplatesDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		patterns, err := indexPatterns(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		templates = append(templates, indexTemplate{name: strings.TrimSuffix(name, ".json"), body: body, patterns: patterns})
+	}
+
+	return templates, nil
+}
+
+// indexPatterns returns the index_patterns of a template, which may be a
+// single pattern or a list of them.
+func indexPatterns(body json.RawMessage) ([]string, error) {
+	var def struct {
+		IndexPatterns json.RawMessage `json:"index_patterns"`
+	}
+	if !isJSONObject(body) || json.Unmarshal(body, &def) != nil {
+		return nil, errors.New("template must be an object in the format of the Put Index Template API")
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(def.IndexPatterns, &patterns); err != nil {
+		var pattern string
+		if json.Unmarshal(def.IndexPatterns, &pattern) != nil {
+			return nil, errors.New("index_patterns must be an index pattern or a list of them")
+		}
+		patterns = []string{pattern}
+	}
+	if len(patterns) == 0 {
+		return nil, errors.New("index_patterns must not be empty")
+	}
+
+	return patterns, nil
+}
+
+// indexTemplateBody returns the body of t with the prefix and suffix of
+// WithIndexPrefix and WithIndexSuffix applied to its index patterns, so the
+// template applies to the fixture indices of this Loader only.
+func (l *Loader) indexTemplateBody(t indexTemplate) (json.RawMessage, error) {
+	if l.indexPrefix == "" && l.indexSuffix == "" {
+		return t.body, nil
+	}
+
+	patterns := make([]string, len(t.patterns))
+	for i, p := range t.patterns {
+		patterns[i] = l.aliasName(p)
+	}
+	value, err := json.Marshal(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := decodeObject(t.body)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range fields {
+		if f.key == "index_patterns" {
+			fields[i].value = value
+		}
+	}
+	return encodeObject(fields), nil
+}
+
+// putIndexTemplates installs the templates of _templates, named with the
+// prefix and suffix of WithIndexPrefix and WithIndexSuffix.
+func (l *Loader) putIndexTemplates(ctx context.Context) error {
+	for _, t := range l.indexTemplates {
+		body, err := l.indexTemplateBody(t)
+		if err != nil {
+			return fmt.Errorf("index template %q: %w", t.name, err)
+		}
+		if err := putIndexTemplate(ctx, l.client, l.aliasName(t.name), body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteIndexTemplates removes the templates installed by putIndexTemplates
+// and returns the errors of the deletions that failed.
+func (l *Loader) deleteIndexTemplates(ctx context.Context) []error {
+	var errs []error
+	for _, t := range l.indexTemplates {
+		if err := deleteIndexTemplate(ctx, l.client, l.aliasName(t.name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// putIndexTemplate creates or replaces the index template name.
+func putIndexTemplate(ctx context.Context, client *elasticsearch.Client, name string, body json.RawMessage) error {
+	res, err := client.Indices.PutIndexTemplate(name, bytes.NewReader(body), client.Indices.PutIndexTemplate.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating index template %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating index template %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// deleteIndexTemplate deletes the index template name if it exists.
+func deleteIndexTemplate(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.Indices.DeleteIndexTemplate(name, client.Indices.DeleteIndexTemplate.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting index template %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The template may already be gone, which is fine
+	if res.StatusCode == 404 {
+		return nil
+	}
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting index template %q: %w", name, err)
+	}
+
+	return nil
+}
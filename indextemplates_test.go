@@ -0,0 +1,106 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadIndexTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_templates/metrics.json": {Data: []byte(`{"index_patterns": "metrics-*", "priority": 200}`)},
+		"_templates/logs.json":    {Data: []byte(`{"index_patterns": ["logs-*", "audit-*"], "template": {"settings": {"number_of_shards": 1}}}`)},
+		"_templates/README.md":    {Data: []byte("notes")},
+	}
+
+	templates, err := readIndexTemplates(fsys, ".")
+	if err != nil {
+		t.Fatalf("readIndexTemplates() error: %v", err)
+	}
+	if len(templates) != 2 {
+		t.Fatalf("expected 2 templates, got %d", len(templates))
+	}
+	if templates[0].name != "logs" || !slices.Equal(templates[0].patterns, []string{"logs-*", "audit-*"}) {
+		t.Errorf("unexpected first template: %+v", templates[0])
+	}
+	if templates[1].name != "metrics" || !slices.Equal(templates[1].patterns, []string{"metrics-*"}) {
+		t.Errorf("unexpected second template: %+v", templates[1])
+	}
+
+	if templates, err := readIndexTemplates(fstest.MapFS{}, "."); templates != nil || err != nil {
+		t.Errorf("expected no templates for a missing directory, got %v, %v", templates, err)
+	}
+}
+
+func TestReadIndexTemplates_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "array", data: `[{"index_patterns": ["logs-*"]}]`, want: "_templates/logs.json: template must be an object"},
+		{name: "no patterns", data: `{"template": {}}`, want: "_templates/logs.json: index_patterns must be an index pattern or a list of them"},
+		{name: "empty patterns", data: `{"index_patterns": []}`, want: "_templates/logs.json: index_patterns must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"_templates/logs.json": {Data: []byte(tt.data)}}
+			_, err := readIndexTemplates(fsys, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_IndexTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_templates/logs.json":   `{"index_patterns": ["logs-*"], "template": {"settings":{"number_of_shards":1}}}`,
+		"logs-app/documents.yml": "- _id: \"1\"\n  message: hello\n",
+	})
+
+	var requests []string
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	put := slices.Index(requests, "PUT /_index_template/ci_logs")
+	create := slices.Index(requests, "PUT /ci_logs-app")
+	if put < 0 || create < 0 || put > create {
+		t.Fatalf("expected the template to be installed before the index is created, got %v", requests)
+	}
+	want := `{"index_patterns":["ci_logs-*"],"template":{"settings":{"number_of_shards":1}}}`
+	if got := bodies["/_index_template/ci_logs"]; got != want {
+		t.Errorf("expected template body %s, got %s", want, got)
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if !slices.Contains(requests, "DELETE /_index_template/ci_logs") {
+		t.Errorf("expected Clean to delete the template, got %v", requests)
+	}
+}
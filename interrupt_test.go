@@ -0,0 +1,160 @@
+package testfixtures
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// interruptingTransport answers every request and, on the refresh of the
+// index interrupt once its documents are sent, sends SIGINT to the test
+// process and holds the request until the Loader cancels it, then fails
+// it as a cancelled request would. DELETE requests that
+// follow the signal are recorded, and fail with status deleteStatus if
+// it is set.
+type interruptingTransport struct {
+	t            *testing.T
+	interrupt    string
+	deleteStatus int
+
+	mu          sync.Mutex
+	interrupted bool
+	deleted     []string
+}
+
+func (rt *interruptingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	interrupted := rt.interrupted
+	if interrupted && req.Method == http.MethodDelete {
+		rt.deleted = append(rt.deleted, req.URL.Path)
+	}
+	rt.mu.Unlock()
+
+	switch {
+	case interrupted && req.Method == http.MethodDelete && rt.deleteStatus != 0:
+		return jsonResponse(rt.deleteStatus, `{"error":{"type":"internal"}}`), nil
+	case !interrupted && req.URL.Path == "/"+rt.interrupt+"/_refresh":
+		rt.mu.Lock()
+		rt.interrupted = true
+		rt.mu.Unlock()
+
+		p, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			rt.t.Fatal(err)
+		}
+		if err := p.Signal(os.Interrupt); err != nil {
+			rt.t.Fatal(err)
+		}
+		select {
+		case <-req.Context().Done():
+			return jsonResponse(500, `{"error":{"type":"task_cancelled_exception"}}`), nil
+		case <-time.After(5 * time.Second):
+			rt.t.Error("expected the signal to cancel the refresh")
+			return nil, errors.New("not cancelled")
+		}
+	case strings.HasSuffix(req.URL.Path, "/_bulk"):
+		return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+	}
+	return jsonResponse(200, `{}`), nil
+}
+
+func newInterruptFixtures(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("sending SIGINT to the test process needs a Unix-like system")
+	}
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"a_users/documents.yml":  "- _id: \"1\"\n",
+		"b_orders/documents.yml": "- _id: \"1\"\n",
+	})
+	return dir
+}
+
+func TestLoad_InterruptRollsBack(t *testing.T) {
+	dir := newInterruptFixtures(t)
+	rt := &interruptingTransport{t: t, interrupt: "b_orders"}
+
+	loader, err := New(newFakeClient(t, rt), Directory(dir), HandleSignals())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	err = loader.Load()
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+	if want := []string{"/a_users", "/b_orders"}; !slices.Equal(rt.deleted, want) {
+		t.Errorf("expected the created indices %v to be deleted, got %v", want, rt.deleted)
+	}
+}
+
+func TestLoad_InterruptKeepsCheckpointedIndices(t *testing.T) {
+	dir := newInterruptFixtures(t)
+	rt := &interruptingTransport{t: t, interrupt: "b_orders"}
+
+	path := filepath.Join(t.TempDir(), "load.checkpoint")
+	loader, err := New(newFakeClient(t, rt), Directory(dir), HandleSignals(), WithCheckpoint(path))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	err = loader.Load()
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+	if len(rt.deleted) != 0 {
+		t.Errorf("expected the indices to be kept for Resume, got deletes %v", rt.deleted)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the checkpoint to be kept: %v", err)
+	}
+}
+
+func TestLoad_InterruptRollbackError(t *testing.T) {
+	dir := newInterruptFixtures(t)
+	rt := &interruptingTransport{t: t, interrupt: "b_orders", deleteStatus: 500}
+
+	loader, err := New(newFakeClient(t, rt), Directory(dir), HandleSignals())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	err = loader.Load()
+	if !errors.Is(err, ErrInterrupted) || !strings.Contains(err.Error(), "rolling back") {
+		t.Fatalf("expected ErrInterrupted with the rollback failure, got %v", err)
+	}
+	if len(rt.deleted) != 2 {
+		t.Errorf("expected every created index to be deleted despite failures, got %v", rt.deleted)
+	}
+}
+
+func TestLoad_CancelledParentIsNotInterrupted(t *testing.T) {
+	dir := newInterruptFixtures(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/b_orders/_refresh" {
+			cancel()
+			<-req.Context().Done()
+			return jsonResponse(500, `{"error":{"type":"task_cancelled_exception"}}`), nil
+		}
+		return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), HandleSignals(), WithContext(ctx))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	err = loader.Load()
+	if err == nil || errors.Is(err, ErrInterrupted) {
+		t.Errorf("expected the load error of a cancelled context, not ErrInterrupted, got %v", err)
+	}
+}
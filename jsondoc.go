@@ -0,0 +1,333 @@
+package testfixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// jsonField is a single member of a JSON object.
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+// decodeObject splits a JSON object into its members, keeping their order.
+func decodeObject(data json.RawMessage) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("expected a JSON object")
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: key, value: value})
+	}
+
+	return fields, nil
+}
+
+// encodeObject joins members back into a JSON object.
+func encodeObject(fields []jsonField) json.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
+
+// decodeValue unmarshals a JSON value, keeping numbers as json.Number.
+func decodeValue(data json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// isJSONArray reports whether data holds a JSON array.
+func isJSONArray(data json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// isJSONObject reports whether data holds a JSON object.
+func isJSONObject(data json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// mapField replaces the value at a dotted field path with the result of fn.
+// Arrays of objects along the path are traversed element by element, the
+// way Elasticsearch flattens them. Bodies without the field are returned
+// unchanged.
+func mapField(body json.RawMessage, path []string, fn func(json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	if isJSONArray(body) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			mapped, err := mapField(item, path, fn)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = mapped
+		}
+		return json.Marshal(items)
+	}
+
+	fields, err := decodeObject(body)
+	if err != nil {
+		// Scalars have no sub-fields to map
+		return body, nil
+	}
+
+	for i, f := range fields {
+		if f.key != path[0] {
+			continue
+		}
+
+		var mapped json.RawMessage
+		if len(path) == 1 {
+			mapped, err = fn(f.value)
+		} else {
+			mapped, err = mapField(f.value, path[1:], fn)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.key, err)
+		}
+		fields[i].value = mapped
+	}
+
+	return encodeObject(fields), nil
+}
+
+// setMissingField adds the value returned by fn at a dotted field path if the
+// body does not already contain it, creating intermediate objects as needed.
+// fn is only called when the field is missing.
+func setMissingField(body json.RawMessage, path []string, fn func() (json.RawMessage, error)) (json.RawMessage, error) {
+	fields, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, f := range fields {
+		if f.key != path[0] {
+			continue
+		}
+		if len(path) == 1 || isJSONArray(f.value) {
+			return body, nil
+		}
+		if _, err := decodeObject(f.value); err != nil {
+			// A scalar already occupies the parent field
+			return body, nil
+		}
+
+		nested, err := setMissingField(f.value, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		fields[i].value = nested
+		return encodeObject(fields), nil
+	}
+
+	value, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		value = encodeObject([]jsonField{{key: path[i], value: value}})
+	}
+
+	return encodeObject(append(fields, jsonField{key: path[0], value: value})), nil
+}
+
+// lookupField returns the values at a dotted field path. Arrays along the
+// path and at the leaf are flattened, so a field inside an array of objects
+// or holding an array yields one value per element.
+func lookupField(body json.RawMessage, path []string) ([]json.RawMessage, error) {
+	if isJSONArray(body) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+
+		var values []json.RawMessage
+		for _, item := range items {
+			v, err := lookupField(item, path)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v...)
+		}
+		return values, nil
+	}
+
+	if len(path) == 0 {
+		return []json.RawMessage{body}, nil
+	}
+
+	fields, err := decodeObject(body)
+	if err != nil {
+		return nil, nil
+	}
+	for _, f := range fields {
+		if f.key == path[0] {
+			return lookupField(f.value, path[1:])
+		}
+	}
+
+	return nil, nil
+}
+
+// mergeJSONObjects deep-merges override into base. Nested objects are merged
+// recursively; any other value in override replaces the one in base. Keys
+// keep their order from base, with new keys from override appended. Either
+// argument may be nil.
+func mergeJSONObjects(base, override json.RawMessage) (json.RawMessage, error) {
+	if base == nil {
+		return override, nil
+	}
+	if override == nil {
+		return base, nil
+	}
+
+	baseFields, err := decodeObject(base)
+	if err != nil {
+		return nil, err
+	}
+	overrideFields, err := decodeObject(override)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := make(map[string]int, len(baseFields))
+	for i, f := range baseFields {
+		pos[f.key] = i
+	}
+
+	for _, o := range overrideFields {
+		i, ok := pos[o.key]
+		if !ok {
+			pos[o.key] = len(baseFields)
+			baseFields = append(baseFields, o)
+			continue
+		}
+
+		merged, err := mergeJSONObjects(baseFields[i].value, o.value)
+		if err != nil {
+			// Not both objects: the override wins
+			merged = o.value
+		}
+		baseFields[i].value = merged
+	}
+
+	return encodeObject(baseFields), nil
+}
+
+// encodeInOrder encodes v, a value decoded from original and then changed,
+// keeping the key order of original: keys of objects stay where they were,
+// deleted ones are dropped, and new ones follow in sorted order. Arrays
+// keep the order of the objects they hold as long as their length is
+// unchanged.
+func encodeInOrder(original json.RawMessage, v any) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if !isJSONObject(original) {
+			break
+		}
+		fields, err := decodeObject(original)
+		if err != nil {
+			return nil, err
+		}
+		encoded := make([]jsonField, 0, len(v))
+		seen := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			value, ok := v[f.key]
+			if !ok || seen[f.key] {
+				continue
+			}
+			seen[f.key] = true
+			body, err := encodeInOrder(f.value, value)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, jsonField{key: f.key, value: body})
+		}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if seen[key] {
+				continue
+			}
+			body, err := marshalUnescaped(v[key])
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, jsonField{key: key, value: body})
+		}
+		return encodeObject(encoded), nil
+	case []any:
+		var items []json.RawMessage
+		if !isJSONArray(original) || json.Unmarshal(original, &items) != nil || len(items) != len(v) {
+			break
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			body, err := encodeInOrder(items[i], item)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(body)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	}
+	return marshalUnescaped(v)
+}
+
+// marshalUnescaped is json.Marshal without escaping <, >, and &, which
+// often appear in script sources and synonym rules and read better as they
+// are.
+func marshalUnescaped(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,127 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// keywordField is a keyword field of a mapping without a normalizer.
+type keywordField struct {
+	path   string // Dotted path of the field, including a multi-field's name
+	source string // Dotted path holding its value in documents
+}
+
+// exactKeywordFields returns the keyword fields and keyword multi-fields of
+// a mapping that have no normalizer, so their values are indexed exactly as
+// written, ordered by path.
+func exactKeywordFields(mapping json.RawMessage) []keywordField {
+	var fields []keywordField
+	walkMappedFields(mapping, func(path, source string, def json.RawMessage) {
+		var field struct {
+			Type       string `json:"type"`
+			Normalizer string `json:"normalizer"`
+		}
+		if json.Unmarshal(def, &field) == nil && field.Type == "keyword" && field.Normalizer == "" {
+			fields = append(fields, keywordField{path: path, source: source})
+		}
+	})
+
+	return fields
+}
+
+// checkKeywordCase records a warning for each keyword field without a
+// normalizer whose fixture values include strings that differ only by case
+// or accents, such as "Tokyo" and "tokyo". A term query for one does not
+// match the other, which tests tend to discover as a missing hit rather
+// than as a fixture problem.
+func (l *Loader) checkKeywordCase() {
+	for _, f := range l.fixtures {
+		for _, field := range exactKeywordFields(f.mapping) {
+			var (
+				seen   = make(map[string]bool)     // Distinct values
+				groups = make(map[string][]string) // Distinct values by folded form
+				order  []string                    // Folded forms in order of appearance
+			)
+			for _, doc := range f.documents {
+				values, err := lookupField(doc.Source, strings.Split(field.source, "."))
+				if err != nil {
+					continue
+				}
+				for _, raw := range values {
+					var s string
+					if json.Unmarshal(raw, &s) != nil || seen[s] {
+						continue
+					}
+					seen[s] = true
+					key := foldKeyword(s)
+					if groups[key] == nil {
+						order = append(order, key)
+					}
+					groups[key] = append(groups[key], s)
+				}
+			}
+
+			for _, key := range order {
+				if values := groups[key]; len(values) > 1 {
+					l.warnings = append(l.warnings, fmt.Sprintf("index %q: keyword field %q has no normalizer, but its fixture values %s differ only by case or accents, so a term query for one does not match the others; add a normalizer with the lowercase and asciifolding filters or make the values consistent", f.name, field.path, quoteAll(values)))
+				}
+			}
+		}
+	}
+}
+
+// quoteAll joins values as a comma-separated list of quoted strings.
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// accentFolds maps accented Latin letters to their unaccented lowercase
+// forms, covering Latin-1 and Latin Extended-A as the asciifolding token
+// filter does for the common cases.
+var accentFolds = func() map[rune]rune {
+	table := map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏő",
+		'r': "ŕŗř",
+		's': "śŝşš",
+		't': "ţťŧ",
+		'u': "ùúûüũūŭůűų",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	}
+	folds := make(map[rune]rune)
+	for base, accented := range table {
+		for _, r := range accented {
+			folds[r] = base
+		}
+	}
+	return folds
+}()
+
+// foldKeyword returns s in lowercase with the accents of Latin letters
+// removed, the form under which values that a lowercase and asciifolding
+// normalizer would index identically compare equal.
+func foldKeyword(s string) string {
+	return strings.Map(func(r rune) rune {
+		if base, ok := accentFolds[r]; ok {
+			return base
+		}
+		return r
+	}, strings.ToLower(s))
+}
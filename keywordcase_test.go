@@ -0,0 +1,68 @@
+package testfixtures
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExactKeywordFields(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"city": {"type": "keyword"},
+		"code": {"type": "keyword", "normalizer": "lowercase"},
+		"name": {"type": "text", "fields": {"raw": {"type": "keyword"}, "norm": {"type": "keyword", "normalizer": "folded"}}},
+		"address": {"properties": {"country": {"type": "keyword"}}},
+		"count": {"type": "integer"}
+	}}`)
+
+	got := exactKeywordFields(mapping)
+	want := []keywordField{
+		{path: "address.country", source: "address.country"},
+		{path: "city", source: "city"},
+		{path: "name.raw", source: "name"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestFoldKeyword(t *testing.T) {
+	for in, want := range map[string]string{
+		"Tokyo":   "tokyo",
+		"Café":    "cafe",
+		"ÉCOLE":   "ecole",
+		"Zürich":  "zurich",
+		"東京":      "東京",
+		"a-b_123": "a-b_123",
+	} {
+		if got := foldKeyword(in); got != want {
+			t.Errorf("foldKeyword(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNew_KeywordCaseWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"shops/_mapping.json": `{"properties": {
+			"city": {"type": "keyword"},
+			"tags": {"type": "keyword", "normalizer": "lowercase"},
+			"name": {"type": "text", "fields": {"raw": {"type": "keyword"}}}
+		}}`,
+		"shops/documents.yml": "- _id: \"1\"\n  city: Tokyo\n  tags: [Sale]\n  name: Café Mori\n" +
+			"- _id: \"2\"\n  city: tokyo\n  tags: [sale]\n  name: Cafe Mori\n" +
+			"- _id: \"3\"\n  city: [Osaka, TOKYO]\n  name: Bistro\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	want := []string{
+		`index "shops": keyword field "city" has no normalizer, but its fixture values "Tokyo", "tokyo", "TOKYO" differ only by case or accents, so a term query for one does not match the others; add a normalizer with the lowercase and asciifolding filters or make the values consistent`,
+		`index "shops": keyword field "name.raw" has no normalizer, but its fixture values "Café Mori", "Cafe Mori" differ only by case or accents, so a term query for one does not match the others; add a normalizer with the lowercase and asciifolding filters or make the values consistent`,
+	}
+	if got := loader.Warnings(); !slices.Equal(got, want) {
+		t.Errorf("expected warnings\n%q\ngot\n%q", want, got)
+	}
+}
@@ -0,0 +1,143 @@
+package testfixtures
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Defaults for LatencyBudget fields left at zero; a negative Warmup skips
+// the warmup runs.
+const (
+	defaultLatencyRuns   = 20
+	defaultLatencyWarmup = 3
+)
+
+// LatencyBudget configures AssertQueryLatency.
+type LatencyBudget struct {
+	P95    time.Duration // Maximum allowed 95th percentile latency (required)
+	Runs   int           // Number of measured runs (default 20)
+	Warmup int           // Number of unmeasured runs before measuring (default 3, negative for none)
+}
+
+// LatencyStats summarizes the measured latencies of a query.
+type LatencyStats struct {
+	Runs int
+	Min  time.Duration
+	Max  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P95  time.Duration
+}
+
+// MeasureQueryLatency runs query against index warmup times without
+// measuring, then runs times while timing each round trip. The request
+// cache is bypassed so repeated runs measure actual search work.
+func MeasureQueryLatency(ctx context.Context, client *elasticsearch.Client, index, query string, runs, warmup int) (LatencyStats, error) {
+	if runs < 1 {
+		return LatencyStats{}, fmt.Errorf("testfixtures: runs must be at least 1, got %d", runs)
+	}
+
+	search := func() (time.Duration, error) {
+		start := time.Now()
+		res, err := client.Search(
+			client.Search.WithContext(ctx),
+			client.Search.WithIndex(index),
+			client.Search.WithBody(strings.NewReader(query)),
+			client.Search.WithRequestCache(false),
+		)
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = res.Body.Close() }()
+
+		if err := checkResponse(res); err != nil {
+			return 0, err
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+
+		return time.Since(start), nil
+	}
+
+	for i := 0; i < warmup; i++ {
+		if _, err := search(); err != nil {
+			return LatencyStats{}, fmt.Errorf("testfixtures: warming up query on %q: %w", index, err)
+		}
+	}
+
+	samples := make([]time.Duration, 0, runs)
+	for i := 0; i < runs; i++ {
+		d, err := search()
+		if err != nil {
+			return LatencyStats{}, fmt.Errorf("testfixtures: measuring query on %q: %w", index, err)
+		}
+		samples = append(samples, d)
+	}
+
+	return summarizeLatencies(samples), nil
+}
+
+// AssertQueryLatency fails the test if the 95th percentile latency of query
+// against index exceeds budget.P95. It is meant for lightweight performance
+// regression tests on realistic fixture datasets; keep budgets generous
+// enough to absorb noise on shared CI machines.
+func AssertQueryLatency(t testing.TB, client *elasticsearch.Client, index, query string, budget LatencyBudget) {
+	t.Helper()
+
+	if budget.P95 <= 0 {
+		t.Fatal("testfixtures: LatencyBudget.P95 must be positive")
+	}
+	runs := budget.Runs
+	if runs == 0 {
+		runs = defaultLatencyRuns
+	}
+	warmup := budget.Warmup
+	switch {
+	case warmup == 0:
+		warmup = defaultLatencyWarmup
+	case warmup < 0:
+		warmup = 0
+	}
+
+	stats, err := MeasureQueryLatency(context.Background(), client, index, query, runs, warmup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.P95 > budget.P95 {
+		t.Errorf("query on %q exceeded latency budget: p95 %v > %v (p50 %v, max %v over %d runs)",
+			index, stats.P95, budget.P95, stats.P50, stats.Max, stats.Runs)
+	}
+}
+
+// summarizeLatencies computes LatencyStats using nearest-rank percentiles.
+func summarizeLatencies(samples []time.Duration) LatencyStats {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return sorted[max(rank, 1)-1]
+	}
+
+	return LatencyStats{
+		Runs: len(sorted),
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(50),
+		P95:  percentile(95),
+	}
+}
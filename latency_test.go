@@ -0,0 +1,59 @@
+package testfixtures
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSummarizeLatencies(t *testing.T) {
+	var samples []time.Duration
+	for i := 20; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+
+	stats := summarizeLatencies(samples)
+
+	if stats.Runs != 20 {
+		t.Errorf("expected 20 runs, got %d", stats.Runs)
+	}
+	if stats.Min != time.Millisecond || stats.Max != 20*time.Millisecond {
+		t.Errorf("unexpected min/max: %v/%v", stats.Min, stats.Max)
+	}
+	if stats.P50 != 10*time.Millisecond {
+		t.Errorf("expected p50 of 10ms, got %v", stats.P50)
+	}
+	if stats.P95 != 19*time.Millisecond {
+		t.Errorf("expected p95 of 19ms, got %v", stats.P95)
+	}
+	if stats.Mean != 10500*time.Microsecond {
+		t.Errorf("expected mean of 10.5ms, got %v", stats.Mean)
+	}
+}
+
+func TestAssertQueryLatency_Warmup(t *testing.T) {
+	tests := []struct {
+		name   string
+		warmup int
+		want   int
+	}{
+		{name: "default", warmup: 0, want: 2 + defaultLatencyWarmup},
+		{name: "set", warmup: 1, want: 2 + 1},
+		{name: "none", warmup: -1, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searches := 0
+			client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				searches++
+				return jsonResponse(200, `{"hits":{"hits":[]}}`), nil
+			}))
+
+			AssertQueryLatency(t, client, "products", `{"query":{"match_all":{}}}`, LatencyBudget{P95: time.Minute, Runs: 2, Warmup: tt.warmup})
+			if searches != tt.want {
+				t.Errorf("expected %d searches, got %d", tt.want, searches)
+			}
+		})
+	}
+}
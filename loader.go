@@ -2,24 +2,88 @@ package testfixtures
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 )
 
+// ErrInterrupted is returned by Load when HandleSignals is enabled and
+// the load was stopped by SIGINT or SIGTERM.
+var ErrInterrupted = errors.New("testfixtures: load interrupted by signal")
+
 // Loader manages Elasticsearch test fixtures.
 // It creates indices with mappings/settings and inserts test documents
 // from fixture files organized in a directory structure.
 type Loader struct {
 	client   *elasticsearch.Client
-	dir      string
+	fsys     fs.FS  // File system holding the fixtures (nil if documents come only from providers)
+	dir      string // Fixtures directory within fsys
+	source   string // Directory path or FS root, for error messages
 	ctx      context.Context
 	fixtures []*indexFixture
+
+	indexPrefix  string            // Added before each fixture name to form its index name
+	indexSuffix  string            // Added after each fixture name to form its index name
+	uniqueSuffix string            // Random suffix of index names under WithUniqueIndices
+	strategy     Strategy          // Default Strategy of the fixture indices (empty for recreate)
+	checkpoint   *checkpointWriter // Records Load progress for Resume (nil unless WithCheckpoint)
+	tenant       *tenantConfig
+	templates    template.FuncMap // Functions for document templates beyond the built-in ones (nil unless WithTemplates)
+	expiryField  string           // Field read by ApplyExpiry (empty if WithExpiryField is not set)
+	clock        func() time.Time // Source of the current time (nil for time.Now)
+	expandEnv    bool             // Whether ${VAR} references in fixture files are expanded
+
+	createPipelines   bool                       // Whether Load creates the pipelines index settings name
+	pipelines         map[string]json.RawMessage // Contents of _pipelines/, by pipeline name
+	indexTemplates    []indexTemplate            // Contents of _templates/, installed by Load
+	lifecyclePolicies []lifecyclePolicy          // Contents of _ilm/, created by Load
+	storedScripts     []storedScript             // Contents of _scripts/, installed by Load
+	synonymSets       []synonymSet               // Contents of _synonyms/, created by Load
+	enrichPolicies    []enrichPolicy             // Contents of _enrich/, executed by Load
+	transforms        []esTransform              // Contents of _transforms/, created by Load
+	clusterSettings   map[string]json.RawMessage // Contents of _cluster_settings.json, by dotted name
+	clusterRestore    map[string]json.RawMessage // Transient values of clusterSettings before the first Load (nil until then)
+
+	handleSignals  bool
+	recordHistory  bool
+	docConcurrency int
+	normalizers    []fieldNormalizer
+	generators     []fieldGenerator
+	providers      []indexProvider
+
+	checkRuntimeFields bool
+	warmup             bool
+	checkReplicas      bool
+	dataNodes          int // Data nodes in the cluster, read by checkReplicaCounts (zero if unknown)
+	stripAllocation    bool
+	dedupe             bool
+	maxRequestBytes    int // Request size limit; read from the cluster on first Load if zero
+
+	results  []IndexResult // Per-index outcome of the most recent Load
+	derived  []string      // Indices created from fixtures by ShrinkIndex, SplitIndex, or CloneIndex
+	events   eventBus      // Handlers registered with WithEventHandler
+	warnings []string      // Problems found by New that do not prevent loading, and components Load skips
+
+	optional map[string]bool  // Components of _optional.yml
+	features *clusterFeatures // License and features of the cluster, once read for optional components
 }
 
 // New creates a new Loader with the given Elasticsearch client and options.
-// The Directory option is required.
+// The Directory or FS option is required unless documents come only from
+// providers registered with WithProvider.
 //
 // Fixture files are parsed during construction, so any file format errors
 // are reported immediately.
@@ -39,39 +103,366 @@ func New(client *elasticsearch.Client, opts ...Option) (*Loader, error) {
 		}
 	}
 
-	if l.dir == "" {
-		return nil, errors.New("testfixtures: Directory option is required")
+	if l.fsys == nil && len(l.providers) == 0 {
+		return nil, errors.New("testfixtures: Directory or FS option is required")
+	}
+	if l.tenant != nil && l.tenant.field == "" {
+		return nil, errors.New("testfixtures: WithTenantAliases requires WithTenantField")
 	}
 
-	fixtures, err := parseFixtures(l.dir)
-	if err != nil {
+	if l.fsys != nil {
+		if err := l.verifyPacks(); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+
+		fsys := l.fsys
+		if l.templates != nil {
+			funcs := templateFuncs(l.now())
+			maps.Copy(funcs, l.templates)
+			fsys = templateFS{FS: fsys, root: l.dir, funcs: funcs}
+		}
+		if l.expandEnv {
+			fsys = envFS{FS: fsys, root: l.dir}
+		}
+		fixtures, err := parseFixtures(fsys, l.dir)
+		if err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		l.fixtures = fixtures
+		if u, ok := l.fsys.(unionFS); ok {
+			l.orderByLayer(u)
+		}
+
+		// Without CreatePipelines the definitions only feed warnings, so
+		// a broken file is reported as one.
+		if l.pipelines, err = readPipelines(fsys, l.dir); err != nil {
+			if l.createPipelines {
+				return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+			}
+			l.warnings = append(l.warnings, fmt.Sprintf("%v; pipelines are not checked, since CreatePipelines is not set, and the definitions would fail New with it", err))
+		}
+		if l.indexTemplates, err = readIndexTemplates(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.lifecyclePolicies, err = readLifecyclePolicies(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.storedScripts, err = readStoredScripts(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.synonymSets, err = readSynonymSets(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.enrichPolicies, err = readEnrichPolicies(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.transforms, err = readTransforms(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.clusterSettings, err = readClusterSettings(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+		if l.optional, err = readOptionalComponents(fsys, l.dir); err != nil {
+			return nil, fmt.Errorf("testfixtures: fixtures %q: %w", l.source, err)
+		}
+	}
+	l.attachProviders()
+	if err := l.resolveAliases(); err != nil {
 		return nil, fmt.Errorf("testfixtures: %w", err)
 	}
-	l.fixtures = fixtures
+	if err := l.checkEnrichPolicies(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking enrich policies: %w", err)
+	}
+	l.orderEnrichSources()
+	if err := l.checkTransforms(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking transforms: %w", err)
+	}
+
+	if l.stripAllocation {
+		if err := l.stripAllocationSettings(); err != nil {
+			return nil, fmt.Errorf("testfixtures: stripping allocation settings: %w", err)
+		}
+	}
+
+	if err := l.expandVectors(); err != nil {
+		return nil, fmt.Errorf("testfixtures: %w", err)
+	}
+
+	if err := l.transformFixtures(); err != nil {
+		return nil, fmt.Errorf("testfixtures: %w", err)
+	}
+
+	if err := l.addTenantMappings(); err != nil {
+		return nil, fmt.Errorf("testfixtures: %w", err)
+	}
+
+	if err := l.checkDocumentActions(); err != nil {
+		return nil, fmt.Errorf("testfixtures: %w", err)
+	}
+
+	if err := l.checkRouting(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking routing: %w", err)
+	}
+
+	if err := l.checkIndexAliases(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking aliases: %w", err)
+	}
+
+	if err := l.checkExpiryField(); err != nil {
+		return nil, fmt.Errorf("testfixtures: %w", err)
+	}
+
+	if err := l.checkIndexSorts(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking index sort fields: %w", err)
+	}
+
+	if err := l.checkDateFormats(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking date formats: %w", err)
+	}
+
+	if err := l.checkCompletionFields(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking completion fields: %w", err)
+	}
+
+	if err := l.checkObjectFields(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking object fields: %w", err)
+	}
+
+	if err := l.checkSourceFilters(); err != nil {
+		return nil, fmt.Errorf("testfixtures: checking _source filters: %w", err)
+	}
+
+	l.checkKeywordCase()
+
+	if l.createPipelines {
+		if err := l.checkPipelines(); err != nil {
+			return nil, fmt.Errorf("testfixtures: checking pipelines: %w", err)
+		}
+	} else {
+		l.checkUncreatedPipelines()
+	}
 
 	return l, nil
 }
 
+// now returns the current time, from the clock of WithClock if one is set.
+func (l *Loader) now() time.Time {
+	if l.clock != nil {
+		return l.clock()
+	}
+	return time.Now()
+}
+
 // Load deletes existing managed indices, recreates them with their
 // schema definitions, inserts fixture documents, and refreshes the indices
-// so that documents are immediately searchable.
-func (l *Loader) Load() error {
+// so that documents are immediately searchable. It uses the context set by
+// WithContext; see LoadContext.
+func (l *Loader) Load(opts ...LoadOption) error {
+	return l.LoadContext(l.ctx, opts...)
+}
+
+// LoadContext is like Load but uses ctx for this call, so a single test can
+// set a deadline or cancel a long bulk load.
+func (l *Loader) LoadContext(ctx context.Context, opts ...LoadOption) error {
+	var lc loadConfig
+	for _, opt := range opts {
+		opt(&lc)
+	}
+	if lc.resume && l.checkpoint == nil {
+		return errors.New("testfixtures: Resume requires WithCheckpoint")
+	}
+
+	start := time.Now()
+	err := l.load(ctx, l.fixtures, true, lc)
+	return l.loadFinished(ctx, start, err)
+}
+
+// LoadIndices is like Load but recreates only the named fixture indices,
+// leaving the others as they are, for tests that touch a few indices of a
+// larger fixture set. Alias fixtures pointing to a named index are recreated
+// with it, since deleting an index removes its aliases, and so are the
+// indices whose analyzers use a synonym set of _synonyms that a named index
+// uses, since the set is created again. Indices made by ShrinkIndex,
+// SplitIndex, or CloneIndex are kept.
+func (l *Loader) LoadIndices(names ...string) error {
+	fixtures, err := l.selectFixtures("LoadIndices", l.withSynonymUsers(names))
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = l.load(l.ctx, fixtures, false, loadConfig{})
+	return l.loadFinished(l.ctx, start, err)
+}
+
+// loadFinished reports a load that started at start and returned err to the
+// event handlers and, under RecordHistory, to HistoryIndex. It returns err,
+// joined with any error recording the load.
+func (l *Loader) loadFinished(ctx context.Context, start time.Time, err error) error {
+	l.events.emit(LoadFinished{Results: l.Results(), Duration: time.Since(start), Err: err})
+	if l.recordHistory {
+		if recErr := l.recordLoad(ctx, start, err); recErr != nil {
+			err = errors.Join(err, fmt.Errorf("testfixtures: recording load history: %w", recErr))
+		}
+	}
+	return err
+}
+
+// selectFixtures returns the fixtures named in names, together with the
+// alias fixtures pointing to them, in load order. method names the caller
+// in errors.
+func (l *Loader) selectFixtures(method string, names []string) ([]*indexFixture, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("testfixtures: %s requires at least one index name", method)
+	}
+
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		if l.fixture(name) == nil {
+			available := make([]string, len(l.fixtures))
+			for i, f := range l.fixtures {
+				available[i] = f.name
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("testfixtures: no fixture index %q (available: %s)", name, strings.Join(available, ", "))
+		}
+		selected[name] = true
+	}
+
+	var fixtures []*indexFixture
 	for _, f := range l.fixtures {
-		indexName := f.name
+		switch {
+		case selected[f.name]:
+		case f.isAlias() && slices.ContainsFunc(f.config.Alias.Indices, func(index string) bool { return selected[index] }):
+		default:
+			continue
+		}
+		fixtures = append(fixtures, f)
+	}
+
+	return fixtures, nil
+}
+
+// load performs LoadContext for the given fixtures. A full load also deletes
+// the indices made by the resize helpers and records its progress in the
+// checkpoint file of WithCheckpoint.
+func (l *Loader) load(parent context.Context, fixtures []*indexFixture, full bool, lc loadConfig) error {
+	ctx := parent
+	if l.handleSignals {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
 
-		if err := deleteIndex(l.ctx, l.client, indexName); err != nil {
+	if l.checkReplicas {
+		if err := l.checkReplicaCounts(ctx); err != nil {
 			return fmt.Errorf("testfixtures: %w", err)
 		}
+	}
 
-		if err := createIndex(l.ctx, l.client, indexName, f.mapping, f.settings); err != nil {
+	var (
+		cp     *checkpointWriter
+		resume checkpoint
+	)
+	if full {
+		if err := l.deleteDerived(ctx); err != nil {
 			return fmt.Errorf("testfixtures: %w", err)
 		}
 
-		if err := bulkInsertDocuments(l.ctx, l.client, indexName, f.documents); err != nil {
-			return fmt.Errorf("testfixtures: %w", err)
+		cp = l.checkpoint
+		if lc.resume {
+			var err error
+			if resume, err = readCheckpoint(cp.path); err != nil {
+				return fmt.Errorf("testfixtures: %w", err)
+			}
+		}
+		if cp != nil {
+			cp.reset(resume)
 		}
+	}
+
+	if err := l.applyClusterSettings(ctx); err != nil {
+		return fmt.Errorf("testfixtures: %w", err)
+	}
+	if err := l.putLifecyclePolicies(ctx); err != nil {
+		return fmt.Errorf("testfixtures: %w", err)
+	}
+	if err := l.putStoredScripts(ctx); err != nil {
+		return fmt.Errorf("testfixtures: %w", err)
+	}
+	if err := l.putSynonymSets(ctx); err != nil {
+		return fmt.Errorf("testfixtures: %w", err)
+	}
+	if err := l.putIndexTemplates(ctx); err != nil {
+		return fmt.Errorf("testfixtures: %w", err)
+	}
+
+	cfg := l.bulkLimits(ctx)
 
-		if err := refreshIndex(l.ctx, l.client, indexName); err != nil {
+	l.results = l.results[:0]
+	var created []string
+	pipelines := make(map[string]bool) // Pipelines created by this Load
+	dropped := make(map[string]bool)   // Pipelines deleted to replace enrich policies
+	for _, f := range fixtures {
+		start := time.Now()
+		run := &indexLoad{checkpoint: cp, stage: StageCheckpoint}
+		err := l.resumeFrom(ctx, f, resume.Indices[f.name], run)
+		if err == nil && run.resume != nil && run.resume.Complete {
+			l.results = append(l.results, IndexResult{
+				Index:    l.IndexName(f.name),
+				Skipped:  run.resume.Documents,
+				Duration: time.Since(start),
+			})
+			continue
+		}
+		if err == nil {
+			run.stage = StagePipeline
+			err = l.putIndexPipelines(ctx, f, pipelines)
+		}
+		if err == nil {
+			err = l.loadIndex(ctx, f, cfg, &created, run)
+		}
+		if err == nil {
+			run.stage = StageEnrich
+			err = l.executeEnrichPolicies(ctx, f, fixtures, pipelines, dropped)
+		}
+		if err == nil {
+			run.stage = StageTransform
+			err = l.runTransforms(ctx, f, fixtures)
+		}
+		if err == nil && cp != nil {
+			run.stage = StageCheckpoint
+			err = cp.complete(int(run.docs.Load() + run.skipped.Load()))
+		}
+		l.results = append(l.results, IndexResult{
+			Index:      l.IndexName(f.name),
+			Documents:  int(run.docs.Load()),
+			Skipped:    int(run.skipped.Load()),
+			Duplicates: run.duplicates,
+			Duration:   time.Since(start),
+			Err:        err,
+		})
+		if err != nil {
+			if cp != nil {
+				if cpErr := cp.failed(); cpErr != nil {
+					err = errors.Join(err, cpErr)
+				}
+			}
+			return l.loadFailed(parent, ctx, created, &LoadError{
+				Index:  l.IndexName(f.name),
+				Stage:  run.stage,
+				Cause:  err,
+				Loaded: l.loadedIndices(),
+			})
+		}
+	}
+
+	if err := l.restoreEnrichPipelines(ctx, pipelines, dropped); err != nil {
+		return fmt.Errorf("testfixtures: %w", err)
+	}
+
+	if cp != nil {
+		if err := cp.remove(); err != nil {
 			return fmt.Errorf("testfixtures: %w", err)
 		}
 	}
@@ -79,13 +470,348 @@ func (l *Loader) Load() error {
 	return nil
 }
 
-// Clean deletes all indices managed by this Loader.
-func (l *Loader) Clean() error {
-	var errs []error
+// indexLoad is the state of loading a single fixture index.
+type indexLoad struct {
+	checkpoint *checkpointWriter // Records progress (may be nil)
+	resume     *indexCheckpoint  // Progress of an interrupted Load to continue from (may be nil)
+
+	docs       atomic.Int64        // Documents sent to Elasticsearch
+	skipped    atomic.Int64        // Documents left out because the interrupted Load had indexed them
+	duplicates []DuplicateDocument // IDs removed by DedupeByID
+	stage      LoadStage           // Step in progress, reported by LoadError
+}
+
+// loadedIndices returns the indices of the current Load that loaded
+// without error.
+func (l *Loader) loadedIndices() []string {
+	var loaded []string
+	for _, r := range l.results {
+		if r.Err == nil {
+			loaded = append(loaded, r.Index)
+		}
+	}
+	return loaded
+}
+
+// resumeFrom sets run to continue from prev, the checkpointed progress of f,
+// if prev is not nil and the index it describes still exists.
+func (l *Loader) resumeFrom(ctx context.Context, f *indexFixture, prev *indexCheckpoint, run *indexLoad) error {
+	if prev == nil {
+		return nil
+	}
+
+	index := l.IndexName(f.name)
+	if prev.Index != index {
+		return fmt.Errorf("checkpoint was written for index %q, not %q; delete %s to start over", prev.Index, index, l.checkpoint.path)
+	}
+	exists, err := indexOrAliasExists(ctx, l.client, index)
+	if err != nil || !exists {
+		return err
+	}
+	run.resume = prev
+
+	return nil
+}
+
+// bulkLimits returns the bulk request limits for the cluster. The cluster's
+// http.max_content_length is read once, unless WithMaxRequestBytes set a
+// limit; if it cannot be read, the Elasticsearch default is assumed.
+func (l *Loader) bulkLimits(ctx context.Context) bulkConfig {
+	if l.maxRequestBytes == 0 {
+		n, err := maxContentLength(ctx, l.client)
+		if err != nil {
+			n = defaultMaxContentLength
+		}
+		l.maxRequestBytes = n
+	}
+	return newBulkConfig(l.maxRequestBytes)
+}
+
+// loadIndex recreates, truncates, or syncs a single fixture index, as its
+// Strategy says, and inserts its documents, appending the index to created
+// once this Load has created it and recording its progress in run. An index
+// resumed from a checkpoint is kept, and only the documents the checkpoint
+// does not cover are sent.
+func (l *Loader) loadIndex(ctx context.Context, f *indexFixture, cfg bulkConfig, created *[]string, run *indexLoad) error {
+	indexName := l.IndexName(f.name)
+	if f.isAlias() {
+		run.stage = StageAlias
+		// Recreating the target indices removed any previous alias.
+		return l.putFixtureAlias(ctx, f)
+	}
+
+	strategy := l.indexStrategy(f)
+	truncated, synced := false, false
+	if run.resume == nil && strategy == StrategyTruncate {
+		run.stage = StageDelete
+		deleted, exists, err := truncateIndex(ctx, l.client, indexName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			truncated = true
+			l.events.emit(IndexTruncated{Index: indexName, Deleted: deleted})
+		}
+	}
+	if run.resume == nil && strategy == StrategySync {
+		run.stage = StageDelete
+		exists, err := indexOrAliasExists(ctx, l.client, indexName)
+		if err != nil {
+			return err
+		}
+		synced = exists
+	}
+	if run.resume == nil && !truncated && !synced {
+		run.stage = StageDelete
+		if err := deleteIndex(ctx, l.client, indexName); err != nil {
+			return err
+		}
+		l.events.emit(IndexDeleted{Index: indexName})
+
+		run.stage = StageCreate
+		if err := createIndex(ctx, l.client, indexName, f.mapping, f.settings, l.indexAliasesBody(f), l.activeShards(f)); err != nil {
+			return err
+		}
+		*created = append(*created, indexName)
+		l.events.emit(IndexCreated{Index: indexName})
+	}
+
+	if len(l.events.handlers) > 0 {
+		cfg.onFlush = func(succeeded, failed int) {
+			l.events.emit(BulkFlushed{Index: indexName, Succeeded: succeeded, Failed: failed})
+		}
+	}
+	if cp := run.checkpoint; cp != nil {
+		cp.begin(f.name, indexName, run.resume)
+		onFlush := cfg.onFlush
+		cfg.onFlush = func(succeeded, failed int) {
+			if onFlush != nil {
+				onFlush(succeeded, failed)
+			}
+			cp.flushed()
+		}
+		cfg.onIndexed = cp.indexed
+		cfg.onQueue = func(doc Document) { cp.expect(doc) }
+	}
+	if run.resume != nil {
+		cfg.skip = func(doc Document) bool {
+			if !run.resume.indexed(doc) {
+				return false
+			}
+			run.skipped.Add(1)
+			return true
+		}
+	}
+
+	run.stage = StageIndex
+	if synced {
+		if err := l.syncIndex(ctx, f, indexName, cfg, run); err != nil {
+			return err
+		}
+	} else if err := l.insertDocuments(ctx, f, indexName, cfg, run); err != nil {
+		return err
+	}
+
+	run.stage = StageRefresh
+	if err := refreshIndex(ctx, l.client, indexName); err != nil {
+		return err
+	}
+
+	run.stage = StageValidate
+	if l.checkRuntimeFields {
+		if err := checkRuntimeFields(ctx, l.client, indexName, f.runtimeFields); err != nil {
+			return err
+		}
+	}
+
+	run.stage = StageExpect
+	if err := checkCounts(ctx, l.client, indexName, f.counts); err != nil {
+		return err
+	}
+
+	run.stage = StageWarmup
+	if l.warmup {
+		if err := l.warmUp(ctx, f); err != nil {
+			return err
+		}
+	}
+
+	run.stage = StageAlias
+	if l.tenant != nil && l.tenant.aliases {
+		if err := l.createTenantAliases(ctx, f); err != nil {
+			return err
+		}
+	}
+
+	if l.uniqueSuffix != "" {
+		if err := claimAlias(ctx, l.client, []string{indexName}, l.aliasName(f.name), nil); err != nil {
+			return err
+		}
+	}
+
+	run.stage = StageState
+	if err := applyIndexState(ctx, l.client, indexName, f.config.State); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// insertDocuments sends the documents of f to the index indexName: those of
+// its document files, its NDJSON files, and its providers, in that order.
+func (l *Loader) insertDocuments(ctx context.Context, f *indexFixture, indexName string, cfg bulkConfig, run *indexLoad) error {
+	documents := f.documents
+	var provided []Document
+	if l.dedupe {
+		// Provider documents are read up front so that an ID they share with
+		// a fixture file is only written once.
+		groups, err := collectProviderDocuments(ctx, indexName, f.providers, l.streamTransform())
+		if err != nil {
+			return err
+		}
+		groups, run.duplicates = dedupeByID(append([][]Document{f.documents}, groups...))
+		documents, provided = groups[0], slices.Concat(groups[1:]...)
+	}
+	if cfg.skip != nil {
+		documents = slices.DeleteFunc(slices.Clone(documents), cfg.skip)
+	}
+	if run.checkpoint != nil {
+		run.checkpoint.expect(documents...)
+	}
+
+	if err := bulkInsertDocuments(ctx, l.client, indexName, cfg, documents, l.docConcurrency, countDocuments(l.documentTransform(), &run.docs)); err != nil {
+		return err
+	}
+
+	if err := streamDocuments(ctx, l.client, indexName, cfg, l.fsys, f.streams, countDocuments(l.streamTransform(), &run.docs)); err != nil {
+		return err
+	}
+
+	if l.dedupe {
+		if err := bulkInsertDocuments(ctx, l.client, indexName, cfg, provided, l.docConcurrency, countDocuments(nil, &run.docs)); err != nil {
+			return err
+		}
+	} else if err := provideDocuments(ctx, l.client, indexName, cfg, f.providers, countDocuments(l.streamTransform(), &run.docs)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IndexResult describes how loading a single fixture index went.
+type IndexResult struct {
+	Index      string              // Index name
+	Documents  int                 // Documents sent to the index
+	Skipped    int                 // Documents not sent again because the load resumed from a checkpoint
+	Duplicates []DuplicateDocument // IDs deduplicated by DedupeByID, in order of first appearance
+	Duration   time.Duration       // Time taken to recreate and fill the index
+	Err        error               // Error that stopped the load, if any
+}
+
+// IndexName returns the name in the cluster of the index or alias loaded
+// from the named fixture, with the prefix and suffix set by WithIndexPrefix
+// and WithIndexSuffix, and the random suffix of WithUniqueIndices. Without
+// them, it is the fixture name itself.
+func (l *Loader) IndexName(fixture string) string {
+	return l.aliasName(fixture) + l.uniqueSuffix
+}
+
+// Indices returns the names of the fixture indices, leaving out alias
+// fixtures, in the order Load creates them.
+func (l *Loader) Indices() []string {
+	var names []string
 	for _, f := range l.fixtures {
-		if err := deleteIndex(l.ctx, l.client, f.name); err != nil {
+		if !f.isAlias() {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// CreateIndexBody returns the body Load sends to the Create Index API for
+// the named fixture index: its mappings, its settings, and the aliases of
+// its _aliases.json, after every adjustment New makes to them. It is nil
+// for an index created without a body. Alias fixtures have no such body and
+// are an error.
+func (l *Loader) CreateIndexBody(index string) (json.RawMessage, error) {
+	f := l.fixture(index)
+	if f == nil || f.isAlias() {
+		return nil, fmt.Errorf("testfixtures: %q is not a fixture index", index)
+	}
+
+	body, err := buildCreateIndexBody(f.mapping, f.settings, l.indexAliasesBody(f))
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: building create index body of %q: %w", index, err)
+	}
+	return body, nil
+}
+
+// Results returns a result for each index processed by the most recent Load,
+// in load order. If Load failed, the last result holds the error and later
+// indices are absent.
+func (l *Loader) Results() []IndexResult {
+	return slices.Clone(l.results)
+}
+
+// loadFailed returns the error of a failed Load. If the load was
+// interrupted by a signal, that is, ctx was cancelled but not its parent,
+// ErrInterrupted is returned instead, once the indices created so far are
+// rolled back, unless WithCheckpoint keeps them to be resumed.
+func (l *Loader) loadFailed(parent, ctx context.Context, created []string, err *LoadError) error {
+	if !l.handleSignals || ctx.Err() == nil || parent.Err() != nil {
+		return err
+	}
+	if l.checkpoint != nil {
+		// The indices are kept for Load(Resume()).
+		return ErrInterrupted
+	}
+
+	// The signal context is already cancelled; roll back with a context
+	// that keeps the caller's values but not its cancellation.
+	rollbackCtx := context.WithoutCancel(parent)
+	var errs []error
+	for _, name := range created {
+		if err := deleteIndex(rollbackCtx, l.client, name); err != nil {
 			errs = append(errs, err)
+			continue
 		}
+		l.events.emit(IndexDeleted{Index: name})
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: rolling back: %w", ErrInterrupted, errors.Join(errs...))
+	}
+
+	return ErrInterrupted
+}
+
+// Clean deletes all indices managed by this Loader, the index templates of
+// _templates, the lifecycle policies of _ilm, the stored scripts of _scripts,
+// the synonym sets of _synonyms, the enrich policies of _enrich with their
+// enrich indices, and the transforms of _transforms with their destination
+// indices, and restores the cluster settings of _cluster_settings.json to
+// their values before the first Load. Alias fixtures are removed along with
+// the indices they point to. It uses the context set by WithContext; see
+// CleanContext.
+func (l *Loader) Clean() error {
+	return l.CleanContext(l.ctx)
+}
+
+// CleanContext is like Clean but uses ctx for this call.
+func (l *Loader) CleanContext(ctx context.Context) error {
+	var errs []error
+	if err := l.deleteDerived(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, l.deleteTransforms(ctx)...)
+	errs = append(errs, l.deleteFixtureIndices(ctx, l.fixtures)...)
+	errs = append(errs, l.deleteEnrichPolicies(ctx)...)
+	errs = append(errs, l.deleteIndexTemplates(ctx)...)
+	errs = append(errs, l.deleteLifecyclePolicies(ctx)...)
+	errs = append(errs, l.deleteStoredScripts(ctx)...)
+	errs = append(errs, l.deleteSynonymSets(ctx)...)
+	if err := l.restoreClusterSettings(ctx); err != nil {
+		errs = append(errs, err)
 	}
 
 	if len(errs) > 0 {
@@ -94,3 +820,54 @@ func (l *Loader) Clean() error {
 
 	return nil
 }
+
+// CleanIndices is like Clean but deletes only the named fixture indices, so
+// a test can tear down the indices it used while other tests keep theirs.
+// Naming an alias fixture is allowed but has no effect, since aliases are
+// removed with their target indices. Indices made by ShrinkIndex,
+// SplitIndex, or CloneIndex are kept.
+func (l *Loader) CleanIndices(names ...string) error {
+	fixtures, err := l.selectFixtures("CleanIndices", names)
+	if err != nil {
+		return err
+	}
+	if errs := l.deleteFixtureIndices(l.ctx, fixtures); len(errs) > 0 {
+		return fmt.Errorf("testfixtures: cleaning up: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// deleteFixtureIndices deletes the indices of the given fixtures, skipping
+// alias fixtures, and returns the errors of the deletions that failed.
+func (l *Loader) deleteFixtureIndices(ctx context.Context, fixtures []*indexFixture) []error {
+	var errs []error
+	for _, f := range fixtures {
+		if f.isAlias() {
+			continue
+		}
+		name := l.IndexName(f.name)
+		if err := deleteIndex(ctx, l.client, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		l.events.emit(IndexDeleted{Index: name})
+	}
+	return errs
+}
+
+// deleteDerived deletes the indices created by ShrinkIndex, SplitIndex, and
+// CloneIndex, which would otherwise outlive the fixtures they were made from.
+func (l *Loader) deleteDerived(ctx context.Context) error {
+	var errs []error
+	for _, name := range l.derived {
+		if err := deleteIndex(ctx, l.client, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		l.events.emit(IndexDeleted{Index: name})
+	}
+	l.derived = nil
+
+	return errors.Join(errs...)
+}
@@ -5,9 +5,14 @@ package testfixtures
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 )
@@ -336,3 +341,1601 @@ func TestLoad_NoMappingOrSettings(t *testing.T) {
 		t.Errorf("expected 1 document, got %d", count)
 	}
 }
+
+func TestLoad_DocConcurrency(t *testing.T) {
+	client := setupTestClient(t)
+
+	loader, err := New(client, Directory("testdata/fixtures"), WithDocConcurrency(4))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if count := getDocCount(t, client, "products"); count != 3 {
+		t.Errorf("expected 3 products documents, got %d", count)
+	}
+}
+
+func TestLoad_DocumentProvider(t *testing.T) {
+	client := setupTestClient(t)
+
+	extra := DocumentProviderFunc(func(_ context.Context, index string) (iter.Seq2[Document, error], error) {
+		return func(yield func(Document, error) bool) {
+			yield(Document{ID: "3", Source: json.RawMessage(`{"name":"Carol","email":"carol@example.com","age":41}`)}, nil)
+		}, nil
+	})
+
+	loader, err := New(client, Directory("testdata/fixtures"), WithProvider("users", extra))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if count := getDocCount(t, client, "users"); count != 3 {
+		t.Errorf("expected 3 users documents, got %d", count)
+	}
+
+	doc := getDocument(t, client, "users", "3")
+	if name, ok := doc["name"].(string); !ok || name != "Carol" {
+		t.Errorf("expected name 'Carol', got %v", doc["name"])
+	}
+}
+
+func TestVerify_Expectations(t *testing.T) {
+	client := setupTestClient(t)
+
+	loader, err := New(client, Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if err := loader.Verify(); err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+
+	// A document that changes a query's result set is reported
+	res, err := client.Index("users", strings.NewReader(`{"name":"Dave","email":"dave@example.com","age":50}`),
+		client.Index.WithDocumentID("4"),
+		client.Index.WithRefresh("true"),
+	)
+	if err != nil {
+		t.Fatalf("indexing extra document: %v", err)
+	}
+	res.Body.Close()
+
+	if err := loader.Verify(); err == nil {
+		t.Fatal("expected Verify() to report the unexpected document")
+	}
+}
+
+func TestAssertQueryLatency(t *testing.T) {
+	client := setupTestClient(t)
+
+	loader, err := New(client, Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	AssertQueryLatency(t, client, "products", `{"query":{"term":{"category":"books"}}}`, LatencyBudget{
+		P95:  5 * time.Second,
+		Runs: 5,
+	})
+}
+
+func TestIndexStats(t *testing.T) {
+	client := setupTestClient(t)
+
+	loader, err := New(client, Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	stats, err := loader.IndexStats("products")
+	if err != nil {
+		t.Fatalf("IndexStats() error: %v", err)
+	}
+	if stats.DocCount != 3 {
+		t.Errorf("expected 3 documents, got %d", stats.DocCount)
+	}
+	if stats.StoreBytes <= 0 {
+		t.Errorf("expected a positive store size, got %d", stats.StoreBytes)
+	}
+
+	loader.AssertDocCount(t, "users", 2)
+	loader.AssertMaxSegments(t, "users", 10)
+
+	if _, err := loader.IndexStats("unmanaged"); err == nil {
+		t.Error("expected error for an index not managed by the loader")
+	}
+}
+
+func TestLoad_RuntimeMappings(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"runtime_orders/_mapping.json":          `{"properties":{"price":{"type":"double"}}}`,
+		"runtime_orders/_runtime_mappings.json": `{"price_with_tax":{"type":"double","script":{"source":"emit(doc['price'].value * 1.1)"}}}`,
+		"runtime_orders/documents.yml":          "- _id: \"1\"\n  price: 10\n",
+	})
+
+	loader, err := New(client, Directory(dir), ValidateRuntimeFields())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	mapping := getIndexMapping(t, client, "runtime_orders")
+	mappings := mapping["runtime_orders"].(map[string]interface{})["mappings"].(map[string]interface{})
+	if _, ok := mappings["runtime"].(map[string]interface{})["price_with_tax"]; !ok {
+		t.Errorf("expected price_with_tax runtime field in mapping, got %v", mappings)
+	}
+}
+
+func TestLoad_FilteredAlias(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"tenant_orders/_mapping.json": `{"properties":{"tenant_id":{"type":"keyword"}}}`,
+		"tenant_orders/documents.yml": "- _id: \"1\"\n  tenant_id: acme\n- _id: \"2\"\n  tenant_id: globex\n- _id: \"3\"\n  tenant_id: acme\n",
+		"acme_orders/_config.yml":     "alias:\n  indices: [tenant_orders]\n  filter:\n    term: {tenant_id: acme}\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if count := getDocCount(t, client, "acme_orders"); count != 2 {
+		t.Errorf("expected 2 documents through the acme_orders alias, got %d", count)
+	}
+
+	// Loading again recreates the alias over the recreated index
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+	if count := getDocCount(t, client, "acme_orders"); count != 2 {
+		t.Errorf("expected 2 documents through the acme_orders alias after reloading, got %d", count)
+	}
+}
+
+func TestLoad_ClosedIndexState(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"closed_orders/_config.yml":   "state: closed\n",
+		"closed_orders/documents.yml": "- _id: \"1\"\n  total: 10\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	res, err := client.Search(client.Search.WithIndex("closed_orders"))
+	if err != nil {
+		t.Fatalf("searching closed index: %v", err)
+	}
+	defer res.Body.Close()
+	if !res.IsError() || !strings.Contains(res.String(), "index_closed_exception") {
+		t.Errorf("expected index_closed_exception searching a closed index, got %s", res.String())
+	}
+
+	// A second Load replaces the closed index
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+}
+
+func TestResizeIndex(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"resize_orders/_settings.json": `{"number_of_shards":2,"number_of_replicas":0}`,
+		"resize_orders/documents.yml":  "- _id: \"1\"\n  total: 10\n- _id: \"2\"\n  total: 20\n- _id: \"3\"\n  total: 30\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if err := loader.ShrinkIndex("resize_orders", "resize_orders_shrunk", 1); err != nil {
+		t.Fatalf("ShrinkIndex() error: %v", err)
+	}
+	if err := loader.SplitIndex("resize_orders", "resize_orders_split", 4); err != nil {
+		t.Fatalf("SplitIndex() error: %v", err)
+	}
+	if err := loader.CloneIndex("resize_orders", "resize_orders_clone"); err != nil {
+		t.Fatalf("CloneIndex() error: %v", err)
+	}
+
+	for _, name := range []string{"resize_orders_shrunk", "resize_orders_split", "resize_orders_clone"} {
+		if count := getDocCount(t, client, name); count != 3 {
+			t.Errorf("expected 3 documents in %s, got %d", name, count)
+		}
+	}
+
+	// The source accepts writes again
+	res, err := client.Index("resize_orders", strings.NewReader(`{"total":40}`), client.Index.WithDocumentID("4"))
+	if err != nil {
+		t.Fatalf("indexing into source: %v", err)
+	}
+	res.Body.Close()
+	if res.IsError() {
+		t.Errorf("expected the source to accept writes after resizing, got %s", res.Status())
+	}
+
+	// Reloading removes the derived indices
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+	res, err = client.Indices.Exists([]string{"resize_orders_shrunk"})
+	if err != nil {
+		t.Fatalf("checking shrunk index: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("expected resize_orders_shrunk to be deleted by Load, got %s", res.Status())
+	}
+}
+
+func TestAssertSuggestions(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"suggest_songs/_mapping.json": `{"properties":{"suggest":{"type":"completion","contexts":[{"name":"genre","type":"category"}]}}}`,
+		"suggest_songs/documents.yml": `- _id: "1"
+  suggest:
+    input: [Beat It, Thriller]
+    weight: 10
+    contexts: {genre: pop}
+- _id: "2"
+  suggest:
+    input: Black Dog
+    weight: 5
+    contexts: {genre: rock}
+`,
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	AssertSuggestions(t, client, "suggest_songs", SuggestRequest{
+		Field:    "suggest",
+		Prefix:   "b",
+		Contexts: map[string][]string{"genre": {"pop", "rock"}},
+		Size:     2,
+	}, "Beat It", "Black Dog")
+	AssertSuggestions(t, client, "suggest_songs", SuggestRequest{
+		Field:    "suggest",
+		Prefix:   "b",
+		Contexts: map[string][]string{"genre": {"rock"}},
+	}, "Black Dog")
+}
+
+func TestLoad_TenantAliases(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"tenant_users/documents.yml": "- _id: \"1\"\n  name: Alice\n- _id: \"2\"\n  name: Bob\n- _id: \"3\"\n  name: Carol\n  tenant_id: globex\n",
+	})
+
+	loader, err := New(client, Directory(dir), WithTenantField("tenant_id", "acme"), WithTenantAliases())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if count := getDocCount(t, client, loader.TenantAlias("tenant_users", "acme")); count != 2 {
+		t.Errorf("expected 2 documents for tenant acme, got %d", count)
+	}
+	if count := getDocCount(t, client, loader.TenantAlias("tenant_users", "globex")); count != 1 {
+		t.Errorf("expected 1 document for tenant globex, got %d", count)
+	}
+}
+
+func TestLoad_UniqueIndices(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"unique_users/documents.yml": "- _id: \"1\"\n  name: Alice\n- _id: \"2\"\n  name: Bob\n",
+	})
+
+	first, err := New(client, Directory(dir), WithUniqueIndices())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	second, err := New(client, Directory(dir), WithUniqueIndices())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := first.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { first.Clean() })
+
+	if count := getDocCount(t, client, "unique_users"); count != 2 {
+		t.Errorf("expected 2 documents through the unique_users alias, got %d", count)
+	}
+
+	if err := second.Load(); !errors.Is(err, ErrAliasInUse) {
+		t.Fatalf("expected the second Load to fail with ErrAliasInUse, got %v", err)
+	}
+	t.Cleanup(func() { second.Clean() })
+
+	// Once the first loader is done, the alias is free again
+	if err := first.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if err := second.Load(); err != nil {
+		t.Fatalf("second Load() after Clean error: %v", err)
+	}
+	if count := getDocCount(t, client, "unique_users"); count != 2 {
+		t.Errorf("expected 2 documents through the alias of the second loader, got %d", count)
+	}
+}
+
+func TestLoad_ResumeFromCheckpoint(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	docs := "- _id: \"1\"\n  age: 1\n- _id: \"2\"\n  age: 2\n- _id: \"3\"\n  age: %s\n"
+	writeFixtureFiles(t, dir, map[string]string{
+		"resume_users/_mapping.json": `{"properties":{"age":{"type":"integer"}}}`,
+		"resume_users/documents.yml": fmt.Sprintf(docs, "unknown"),
+	})
+	path := filepath.Join(t.TempDir(), "load.checkpoint")
+
+	loader, err := New(client, Directory(dir), WithCheckpoint(path))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if err := loader.Load(); err == nil {
+		t.Fatal("expected Load to fail on the unparsable age")
+	}
+
+	// Fix the document and pick up where the load stopped
+	writeFixtureFiles(t, dir, map[string]string{
+		"resume_users/documents.yml": fmt.Sprintf(docs, "3"),
+	})
+	loader, err = New(client, Directory(dir), WithCheckpoint(path))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(Resume()); err != nil {
+		t.Fatalf("Load(Resume()) error: %v", err)
+	}
+
+	if r := loader.Results()[0]; r.Documents != 1 || r.Skipped != 2 {
+		t.Errorf("expected 1 document sent and 2 skipped, got %+v", r)
+	}
+	if count := getDocCount(t, client, "resume_users"); count != 3 {
+		t.Errorf("expected 3 documents in resume_users, got %d", count)
+	}
+}
+
+func TestLoad_RecordHistory(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"history_users/documents.yml": "- _id: \"1\"\n  name: Alice\n",
+	})
+
+	loader, err := New(client, Directory(dir), RecordHistory())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	start := time.Now().Add(-time.Second)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	records, err := loader.History(context.Background())
+	if err != nil {
+		t.Fatalf("History() error: %v", err)
+	}
+	if len(records) == 0 {
+		t.Fatal("expected the load to be recorded")
+	}
+	if r := records[0]; r.Source != dir || r.Documents != 1 || r.Time.Before(start) || r.FixtureHash == "" {
+		t.Errorf("unexpected latest record %+v", r)
+	}
+}
+
+func TestLoad_FetchAll(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"fetch_users/documents.yml": "- _id: \"2\"\n  name: Bob\n- _id: \"1\"\n  _routing: eu\n  name: Alice\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	docs, err := loader.FetchAll(context.Background(), "fetch_users")
+	if err != nil {
+		t.Fatalf("FetchAll() error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+	if docs[0].ID != "1" || docs[0].Routing != "eu" || string(docs[0].Source) != `{"name":"Alice"}` {
+		t.Errorf("unexpected first document %+v", docs[0])
+	}
+	if docs[1].ID != "2" || string(docs[1].Source) != `{"name":"Bob"}` {
+		t.Errorf("unexpected second document %+v", docs[1])
+	}
+}
+
+func TestLoad_DefaultPipeline(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_pipelines/testfixtures-lowercase.json": `{"processors":[{"lowercase":{"field":"email"}}]}`,
+		"_pipelines/testfixtures-users.json":     `{"processors":[{"pipeline":{"name":"testfixtures-lowercase"}}]}`,
+		"pipeline_users/_settings.json":          `{"index":{"default_pipeline":"testfixtures-users"}}`,
+		"pipeline_users/documents.yml":           "- _id: \"1\"\n  email: Alice@Example.com\n",
+	})
+
+	loader, err := New(client, Directory(dir), CreatePipelines())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	doc := getDocument(t, client, "pipeline_users", "1")
+	if doc["email"] != "alice@example.com" {
+		t.Errorf("expected the default pipeline to lowercase email, got %v", doc["email"])
+	}
+}
+
+func TestLoad_ExpectedCounts(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"expect_users/documents.yml": "- _id: \"1\"\n  age: 25\n- _id: \"2\"\n  age: 35\n",
+		"expect_users/_expect.yml":   "count: 2\nqueries:\n  adults:\n    query: {range: {age: {gte: 30}}}\n    count: 2\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	var loadErr *LoadError
+	err = loader.Load()
+	if !errors.As(err, &loadErr) || loadErr.Stage != StageExpect || !strings.Contains(err.Error(), `query "adults": expected 2 documents, found 1`) {
+		t.Errorf("expected a count mismatch LoadError, got %v", err)
+	}
+}
+
+func TestLoad_JoinFieldRouting(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"join_qa/_mapping.json": `{"properties":{"relation":{"type":"join","relations":{"question":"answer"}}}}`,
+		"join_qa/documents.yml": "- _id: q1\n  relation: question\n- _id: a1\n  relation: {name: answer, parent: q1}\n",
+		"join_qa/_expect.yml":   "count: 2\nqueries:\n  answers:\n    query: {has_parent: {parent_type: question, query: {match_all: {}}}}\n    count: 1\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+}
+
+func TestLoad_DocumentActionsInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"action_users/documents.yml": `- _id: "1"
+  _action: create
+  name: Alice
+- _id: "2"
+  name: Bob
+- _id: "1"
+  _action: update
+  age: 30
+- _id: "2"
+  _action: delete
+- _id: "3"
+  _action: delete
+`,
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if n := getDocCount(t, client, "action_users"); n != 1 {
+		t.Errorf("expected 1 document, got %d", n)
+	}
+	doc := getDocument(t, client, "action_users", "1")
+	if doc["name"] != "Alice" || doc["age"] != float64(30) {
+		t.Errorf("expected the updated document, got %v", doc)
+	}
+}
+
+func TestLoad_IndexAliasesFile(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"alias_users/_aliases.json": `{"alias_users_active": {"filter": {"term": {"active": true}}}, "alias_users_write": {"is_write_index": true}}`,
+		"alias_users/documents.yml": "- _id: \"1\"\n  active: true\n- _id: \"2\"\n  active: false\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if n := getDocCount(t, client, "alias_users_active"); n != 1 {
+		t.Errorf("expected 1 document through the filtered alias, got %d", n)
+	}
+	if n := getDocCount(t, client, "alias_users_write"); n != 2 {
+		t.Errorf("expected 2 documents through the write alias, got %d", n)
+	}
+}
+
+func TestLoad_ApplyExpiryInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"expiry_sessions/_mapping.json": `{"properties": {"expires_at": {"type": "date"}}}`,
+		"expiry_sessions/documents.yml": "- _id: \"1\"\n  expires_at: \"2024-01-01T00:00:00Z\"\n" +
+			"- _id: \"2\"\n  expires_at: \"2024-02-01T00:00:00Z\"\n" +
+			"- _id: \"3\"\n",
+	})
+
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	loader, err := New(client, Directory(dir), WithExpiryField("expires_at"), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	for _, step := range []struct {
+		now     time.Time
+		deleted int
+		left    int
+	}{
+		{now, 1, 2},
+		{now.AddDate(0, 1, 0), 1, 1},
+	} {
+		now = step.now
+		deleted, err := loader.ApplyExpiry(context.Background())
+		if err != nil {
+			t.Fatalf("ApplyExpiry() error: %v", err)
+		}
+		if deleted != step.deleted {
+			t.Errorf("at %s: expected %d documents deleted, got %d", now, step.deleted, deleted)
+		}
+		if n := getDocCount(t, client, "expiry_sessions"); n != step.left {
+			t.Errorf("at %s: expected %d documents left, got %d", now, step.left, n)
+		}
+	}
+}
+
+func TestLoad_IndexTemplatesDir(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_templates/tmpl_logs.json":   `{"index_patterns": ["tmpl_logs-*"], "template": {"mappings": {"properties": {"level": {"type": "keyword"}}}}}`,
+		"tmpl_logs-app/documents.yml": "- _id: \"1\"\n  level: info\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	mapping, _ := json.Marshal(getIndexMapping(t, client, "tmpl_logs-app"))
+	if !strings.Contains(string(mapping), `"level":{"type":"keyword"}`) {
+		t.Errorf("expected the template's mapping to be applied, got %s", mapping)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	res, err := client.Indices.ExistsIndexTemplate("tmpl_logs")
+	if err != nil {
+		t.Fatalf("checking template: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("expected Clean to delete the template, got status %d", res.StatusCode)
+	}
+}
+
+func TestLoad_LifecyclePolicyDir(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_ilm/ilm_hot.json":       `{"policy": {"phases": {"hot": {"actions": {}}}}}`,
+		"ilm_logs/_settings.json": `{"index.lifecycle.name": "ilm_hot"}`,
+		"ilm_logs/documents.yml":  "- _id: \"1\"\n  message: hello\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if count := getDocCount(t, client, "ilm_logs"); count != 1 {
+		t.Errorf("expected 1 document, got %d", count)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	res, err := client.ILM.GetLifecycle(client.ILM.GetLifecycle.WithPolicy("ilm_hot"))
+	if err != nil {
+		t.Fatalf("checking policy: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("expected Clean to delete the policy, got status %d", res.StatusCode)
+	}
+}
+
+func TestLoad_StoredScriptsDir(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_scripts/scr_boost.painless": "doc['rank'].value * params.factor",
+		"scr_posts/documents.yml":     "- _id: \"1\"\n  rank: 3\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	query := `{"query": {"script_score": {"query": {"match_all": {}}, "script": {"id": "scr_boost", "params": {"factor": 2}}}}}`
+	res, err := client.Search(client.Search.WithIndex("scr_posts"), client.Search.WithBody(strings.NewReader(query)))
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	var result struct {
+		Hits struct {
+			MaxScore float64 `json:"max_score"`
+		} `json:"hits"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil || res.IsError() {
+		t.Fatalf("search failed: %s, %v", res.Status(), err)
+	}
+	if result.Hits.MaxScore != 6 {
+		t.Errorf("expected the stored script to score 6, got %v", result.Hits.MaxScore)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	res, err = client.GetScript("scr_boost")
+	if err != nil {
+		t.Fatalf("checking script: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("expected Clean to delete the script, got status %d", res.StatusCode)
+	}
+}
+
+func TestLoad_SearchTemplateScript(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_scripts/tpl_by_tag.mustache": `{"query": {"terms": {"tag": {{#toJson}}tags{{/toJson}}}}}`,
+		"tpl_posts/_mapping.json":      `{"properties": {"tag": {"type": "keyword"}}}`,
+		"tpl_posts/documents.yml":      "- _id: \"1\"\n  tag: go\n- _id: \"2\"\n  tag: rust\n- _id: \"3\"\n  tag: zig\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	req := `{"id": "tpl_by_tag", "params": {"tags": ["go", "zig"]}}`
+	res, err := client.SearchTemplate(strings.NewReader(req), client.SearchTemplate.WithIndex("tpl_posts"))
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil || res.IsError() {
+		t.Fatalf("search template failed: %s, %v", res.Status(), err)
+	}
+	if result.Hits.Total.Value != 2 {
+		t.Errorf("expected the search template to match 2 documents, got %d", result.Hits.Total.Value)
+	}
+}
+
+func TestLoad_SynonymSetsDir(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_synonyms/syn_products.txt": "tv, television\n",
+		"syn_items/_settings.json":   `{"analysis": {"filter": {"syn": {"type": "synonym_graph", "synonyms_set": "syn_products", "updateable": true}}, "analyzer": {"syn_search": {"tokenizer": "standard", "filter": ["lowercase", "syn"]}}}}`,
+		"syn_items/_mapping.json":    `{"properties": {"name": {"type": "text", "search_analyzer": "syn_search"}}}`,
+		"syn_items/documents.yml":    "- _id: \"1\"\n  name: television\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	query := `{"query": {"match": {"name": "tv"}}}`
+	res, err := client.Search(client.Search.WithIndex("syn_items"), client.Search.WithBody(strings.NewReader(query)))
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil || res.IsError() {
+		t.Fatalf("search failed: %s, %v", res.Status(), err)
+	}
+	if result.Hits.Total.Value != 1 {
+		t.Errorf("expected the synonym to match 1 document, got %d", result.Hits.Total.Value)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	res, err = client.SynonymsGetSynonym("syn_products")
+	if err != nil {
+		t.Fatalf("checking synonym set: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("expected Clean to delete the synonym set, got status %d", res.StatusCode)
+	}
+}
+
+func TestLoad_EnrichPolicyInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_enrich/enr_user_lookup.json":      `{"match": {"indices": "enr_users", "match_field": "email", "enrich_fields": ["name"]}}`,
+		"_pipelines/enr_orders_ingest.json": `{"processors": [{"enrich": {"policy_name": "enr_user_lookup", "field": "email", "target_field": "user"}}]}`,
+		"enr_orders/_settings.json":         `{"index": {"default_pipeline": "enr_orders_ingest"}}`,
+		"enr_orders/documents.yml":          "- _id: \"1\"\n  email: alice@example.com\n",
+		"enr_users/_mapping.json":           `{"properties": {"email": {"type": "keyword"}}}`,
+		"enr_users/documents.yml":           "- _id: \"1\"\n  email: alice@example.com\n  name: Alice\n",
+	})
+
+	loader, err := New(client, Directory(dir), CreatePipelines())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	// A second Load replaces the policy although a pipeline uses it.
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+
+	docs, err := loader.FetchAll(context.Background(), "enr_orders")
+	if err != nil {
+		t.Fatalf("FetchAll() error: %v", err)
+	}
+	if len(docs) != 1 || !strings.Contains(string(docs[0].Source), `"name":"Alice"`) {
+		t.Errorf("expected the order to be enriched with the user's name, got %v", docs)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	res, err := client.Indices.Get([]string{".enrich-enr_user_lookup*"}, client.Indices.Get.WithExpandWildcards("all"))
+	if err != nil {
+		t.Fatalf("listing enrich indices: %v", err)
+	}
+	var enrichIndices map[string]json.RawMessage
+	err = json.NewDecoder(res.Body).Decode(&enrichIndices)
+	_ = res.Body.Close()
+	if err != nil || len(enrichIndices) > 0 {
+		t.Errorf("expected Clean to delete the enrich indices, got %v, %v", enrichIndices, err)
+	}
+}
+
+func TestLoad_TransformInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_transforms/tr_user_totals.json": `{"source": {"index": "tr_orders"}, "dest": {"index": "tr_user_totals"}, "pivot": {"group_by": {"user": {"terms": {"field": "user"}}}, "aggregations": {"orders": {"value_count": {"field": "user"}}}}, "start": true}`,
+		"tr_orders/_mapping.json":         `{"properties": {"user": {"type": "keyword"}}}`,
+		"tr_orders/documents.yml":         "- _id: \"1\"\n  user: alice\n- _id: \"2\"\n  user: alice\n- _id: \"3\"\n  user: bob\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	// A second Load replaces the transform and its destination index.
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+	if got := getDocCount(t, client, "tr_user_totals"); got != 2 {
+		t.Errorf("expected a total for each of the 2 users, got %d", got)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if indexExists(t, client, "tr_user_totals") {
+		t.Error("expected Clean to delete the destination index")
+	}
+}
+
+func TestLoad_ClusterSettingsRestored(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_cluster_settings.json": `{"search": {"max_buckets": 12345}}`,
+		"cs_users/documents.yml": "- _id: \"1\"\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	settings, err := transientClusterSettings(context.Background(), client)
+	if err != nil {
+		t.Fatalf("reading cluster settings: %v", err)
+	}
+	if got := string(settings["search.max_buckets"]); got != `"12345"` {
+		t.Errorf("expected search.max_buckets to be 12345 after Load, got %s", got)
+	}
+
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if settings, err = transientClusterSettings(context.Background(), client); err != nil {
+		t.Fatalf("reading cluster settings: %v", err)
+	}
+	if v, ok := settings["search.max_buckets"]; ok {
+		t.Errorf("expected Clean to reset search.max_buckets, got %s", v)
+	}
+}
+
+func TestLoad_JoinParentInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"parent_qa/_mapping.json": `{"properties":{"relation":{"type":"join","relations":{"question":"answer","answer":"comment"}}}}`,
+		"parent_qa/documents.yml": "- _id: q1\n  relation: question\n- _id: a1\n  _parent: q1\n- _id: c1\n  _parent: a1\n",
+		"parent_qa/_expect.yml":   "count: 3\nqueries:\n  comments:\n    query: {has_parent: {parent_type: answer, query: {has_parent: {parent_type: question, query: {ids: {values: [q1]}}}}}}\n    count: 1\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+}
+
+func TestLoad_VectorDirectiveKNN(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"vec_docs/_mapping.json": `{"properties":{"embedding":{"type":"dense_vector","dims":16,"index":true,"similarity":"dot_product"}}}`,
+		"vec_docs/documents.yml": "- _generate:\n    count: 50\n    template:\n      _id: {$fake: seq, format: \"doc-%d\"}\n      embedding: {$vector: {$fake: seq, format: \"doc-%d\"}}\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	vector, err := json.Marshal(Vector("doc-17", 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := fmt.Sprintf(`{"knn":{"field":"embedding","query_vector":%s,"k":1,"num_candidates":50}}`, vector)
+	res, err := client.Search(client.Search.WithIndex("vec_docs"), client.Search.WithBody(strings.NewReader(query)))
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil || res.IsError() {
+		t.Fatalf("search failed: %s, %v", res.Status(), err)
+	}
+	if len(result.Hits.Hits) != 1 || result.Hits.Hits[0].ID != "doc-17" {
+		t.Errorf("expected doc-17 as the nearest neighbor, got %+v", result.Hits.Hits)
+	}
+}
+
+func TestLoad_DiffInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"diff_users/documents.yml": "- _id: \"1\"\n  name: Alice\n- _id: \"2\"\n  name: Bob\n- name: Anonymous\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	diff, err := loader.Diff(t.Context(), "diff_users")
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected no differences after Load, got %+v", diff)
+	}
+
+	res, err := client.Index("diff_users", strings.NewReader(`{"name":"Bobby"}`), client.Index.WithDocumentID("2"), client.Index.WithRefresh("true"))
+	if err != nil {
+		t.Fatalf("updating document: %v", err)
+	}
+	_ = res.Body.Close()
+
+	diff, err = loader.Diff(t.Context(), "diff_users")
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != "2" || len(diff.Missing)+len(diff.Extra)+diff.MissingUnkeyed != 0 {
+		t.Errorf("expected only document 2 changed, got %+v", diff)
+	}
+}
+
+func TestLoad_ExpectRoutingInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"routed_orders/documents.yml": "- _id: \"1\"\n  _routing: tenant-a\n- _id: \"2\"\n  _routing: tenant-a\n- _id: \"3\"\n  _routing: tenant-b\n- _id: \"4\"\n",
+		"routed_orders/_expect.yml":   "count: 4\nrouting:\n  tenant-a: 2\n  tenant-b: 1\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	// The same _id under another routing value makes a second document
+	res, err := client.Index("routed_orders", strings.NewReader(`{}`), client.Index.WithDocumentID("1"), client.Index.WithRouting("tenant-b"), client.Index.WithRefresh("true"))
+	if err != nil {
+		t.Fatalf("indexing document: %v", err)
+	}
+	_ = res.Body.Close()
+
+	err = checkCounts(t.Context(), client, "routed_orders", loader.fixture("routed_orders").counts)
+	if err == nil || !strings.Contains(err.Error(), `routing "tenant-b": expected 1 documents, found 2`) {
+		t.Errorf("expected a routing count mismatch, got %v", err)
+	}
+}
+
+func TestLoad_GeoJSONInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"geo_parks/_mapping.json": `{"properties":{"name":{"type":"keyword"},"area":{"type":"geo_shape"}}}`,
+		"geo_parks/parks.geojson": `{"type":"FeatureCollection","features":[
+  {"type":"Feature","id":"square","properties":{"name":"Square"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+  {"type":"Feature","id":"far","properties":{"name":"Far"},"geometry":{"type":"Polygon","coordinates":[[[10,10],[11,10],[11,11],[10,11],[10,10]]]}}
+]}`,
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	query := `{"query":{"geo_shape":{"area":{"shape":{"type":"point","coordinates":[0.5,0.5]},"relation":"intersects"}}}}`
+	res, err := client.Search(client.Search.WithIndex("geo_parks"), client.Search.WithBody(strings.NewReader(query)))
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil || res.IsError() {
+		t.Fatalf("search failed: %s, %v", res.Status(), err)
+	}
+	if len(result.Hits.Hits) != 1 || result.Hits.Hits[0].ID != "square" {
+		t.Errorf("expected only the square to contain the point, got %+v", result.Hits.Hits)
+	}
+}
+
+func TestLoad_DumpRoundTrip(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"dump_src/_mapping.json":  `{"properties":{"name":{"type":"keyword"},"price":{"type":"double"}}}`,
+		"dump_src/_settings.json": `{"number_of_shards":2,"number_of_replicas":0}`,
+		"dump_src/documents.yml":  "- _id: \"1\"\n  name: Pen\n  price: 1.5\n- _id: \"2\"\n  _routing: eu\n  name: Book\n  price: 12\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	dumped := t.TempDir()
+	if err := NewDumper(client).Dump(t.Context(), "dump_src", dumped); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	reloaded, err := New(client, Directory(dumped), WithIndexPrefix("again_"))
+	if err != nil {
+		t.Fatalf("New() on the dump error: %v", err)
+	}
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load() of the dump error: %v", err)
+	}
+	t.Cleanup(func() { reloaded.Clean() })
+
+	diff, err := reloaded.Diff(t.Context(), "dump_src")
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected the reloaded dump to hold the dumped documents, got %+v", diff)
+	}
+	if n := getDocCount(t, client, "again_dump_src"); n != 2 {
+		t.Errorf("expected 2 documents in the reloaded index, got %d", n)
+	}
+}
+
+func TestLoad_TruncateStrategyKeepsIndex(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"truncated_catalog/_config.yml":   "strategy: truncate\n",
+		"truncated_catalog/documents.yml": "- _id: \"1\"\n  name: Pen\n- _id: \"2\"\n  name: Book\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("first Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	uuid := func() string {
+		t.Helper()
+		res, err := client.Indices.GetSettings(client.Indices.GetSettings.WithIndex("truncated_catalog"))
+		if err != nil {
+			t.Fatalf("getting settings: %v", err)
+		}
+		defer res.Body.Close()
+		var settings map[string]struct {
+			Settings struct {
+				Index struct {
+					UUID string `json:"uuid"`
+				} `json:"index"`
+			} `json:"settings"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&settings); err != nil {
+			t.Fatalf("decoding settings: %v", err)
+		}
+		return settings["truncated_catalog"].Settings.Index.UUID
+	}
+	before := uuid()
+
+	res, err := client.Index("truncated_catalog", strings.NewReader(`{"name":"Stray"}`), client.Index.WithRefresh("true"))
+	if err != nil {
+		t.Fatalf("indexing: %v", err)
+	}
+	res.Body.Close()
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+	if after := uuid(); after != before {
+		t.Errorf("expected the index to be kept, but its uuid changed from %s to %s", before, after)
+	}
+	if n := getDocCount(t, client, "truncated_catalog"); n != 2 {
+		t.Errorf("expected the 2 fixture documents only, got %d", n)
+	}
+}
+
+func TestLoad_DumpFiltered(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"dump_orders/_mapping.json": `{"properties":{"status":{"type":"keyword"}}}`,
+		"dump_orders/documents.yml": "- _id: \"1\"\n  status: active\n  customer: {name: Ann, email: ann@example.com}\n- _id: \"2\"\n  status: closed\n  customer: {name: Bob, email: bob@example.com}\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	dumped := t.TempDir()
+	d := NewDumper(client, DumpQuery(`{"term":{"status":"active"}}`), DumpSourceExcludes("customer.email"))
+	if err := d.Dump(t.Context(), "dump_orders", dumped); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dumped, "dump_orders", "documents.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "- _id: \"1\"\n  status: active\n  customer:\n    name: Ann\n"
+	if string(got) != want {
+		t.Errorf("expected documents\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestLoad_OptionalComponentsIncludedByLicense(t *testing.T) {
+	client := setupTestClient(t)
+
+	// ILM is part of the basic license, so the policy is created on every
+	// cluster the integration tests run against.
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_optional.yml":                 "- ilm\n",
+		"_ilm/optional_policy.json":     `{"policy":{"phases":{"hot":{"actions":{}}}}}`,
+		"optional_events/documents.yml": "- _id: \"1\"\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	if w := loader.Warnings(); len(w) != 0 {
+		t.Errorf("expected no skipped components, got %q", w)
+	}
+	res, err := client.ILM.GetLifecycle(client.ILM.GetLifecycle.WithPolicy("optional_policy"))
+	if err != nil {
+		t.Fatalf("getting policy: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Errorf("expected the optional policy to be created, got %s", res.Status())
+	}
+}
+
+func TestLoad_DumpPagesWithMaxDocs(t *testing.T) {
+	client := setupTestClient(t)
+
+	var docs strings.Builder
+	for i := range 2500 {
+		fmt.Fprintf(&docs, "- {_id: \"%04d\", n: %d}\n", i, i)
+	}
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"dump_pages/documents.yml": docs.String(),
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	var last [2]int
+	d := NewDumper(client,
+		DumpMaxDocs(2200),
+		DumpProgress(func(dumped, total int) { last = [2]int{dumped, total} }),
+	)
+	dumped := t.TempDir()
+	if err := d.Dump(t.Context(), "dump_pages", dumped); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+	if last != [2]int{2200, 2200} {
+		t.Errorf("expected the last progress to be 2200 of 2200, got %v", last)
+	}
+
+	reloaded, err := New(client, Directory(dumped))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if n := len(reloaded.fixture("dump_pages").documents); n != 2200 {
+		t.Errorf("expected 2200 dumped documents, got %d", n)
+	}
+}
+
+func TestLoad_DumpTransformMasksFields(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"dump_people/documents.yml": "- {_id: \"1\", name: Ann, email: ann@example.org}\n- {_id: \"2\", name: Bob, email: bob@example.org}\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	d := NewDumper(client, DumpTransform(func(doc map[string]any) map[string]any {
+		doc["email"] = "masked@example.com"
+		return doc
+	}))
+	dumped := t.TempDir()
+	if err := d.Dump(t.Context(), "dump_people", dumped); err != nil {
+		t.Fatalf("Dump() error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dumped, "dump_people", "documents.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "example.org") || strings.Count(string(got), "masked@example.com") != 2 {
+		t.Errorf("expected every email to be masked, got\n%s", got)
+	}
+}
+
+func TestLoad_SyncStrategySendsOnlyChanges(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"synced_catalog/_config.yml":   "strategy: sync\n",
+		"synced_catalog/documents.yml": "- {_id: \"1\", name: Pen}\n- {_id: \"2\", name: Book}\n",
+	})
+
+	var synced []IndexSynced
+	loader, err := New(client, Directory(dir), WithEventHandler(func(e Event) {
+		if e, ok := e.(IndexSynced); ok {
+			synced = append(synced, e)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("first Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	// A test changes one document and adds another
+	res, err := client.Index("synced_catalog", strings.NewReader(`{"name":"Ink"}`), client.Index.WithDocumentID("1"), client.Index.WithRefresh("true"))
+	if err != nil {
+		t.Fatalf("indexing: %v", err)
+	}
+	res.Body.Close()
+	res, err = client.Index("synced_catalog", strings.NewReader(`{"name":"Stray"}`), client.Index.WithRefresh("true"))
+	if err != nil {
+		t.Fatalf("indexing: %v", err)
+	}
+	res.Body.Close()
+
+	if err := loader.Load(); err != nil {
+		t.Fatalf("second Load() error: %v", err)
+	}
+	if len(synced) != 1 || synced[0] != (IndexSynced{Index: "synced_catalog", Indexed: 1, Deleted: 1, Unchanged: 1}) {
+		t.Errorf("expected one IndexSynced event with 1 indexed, 1 deleted, and 1 unchanged, got %+v", synced)
+	}
+	diff, err := loader.Diff(t.Context(), "synced_catalog")
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected the index to hold the fixtures after syncing, got %+v", diff)
+	}
+}
+
+func TestLoad_AwaitSearchableAlias(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"await_orders/_config.yml":         "alias:\n  indices: [await_orders_2023, await_orders_2024]\n",
+		"await_orders_2023/documents.yml":  "- _id: \"1\"\n",
+		"await_orders_2024/_settings.json": `{"index":{"number_of_replicas":0}}`,
+		"await_orders_2024/documents.yml":  "- _id: \"2\"\n- _id: \"3\"\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := loader.AwaitSearchable(ctx, "await_orders", 3); err != nil {
+		t.Fatalf("AwaitSearchable() error: %v", err)
+	}
+	if err := loader.AwaitSearchable(ctx, "await_orders_2024", 2); err != nil {
+		t.Fatalf("AwaitSearchable() error: %v", err)
+	}
+}
+
+func TestLoad_AliasFixtureWriteIndexInCluster(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"wi_orders/_config.yml":        "alias:\n  indices: [wi_orders_2023, wi_orders_2024]\n  write_index: wi_orders_2024\n",
+		"wi_orders_2023/documents.yml": "- _id: \"1\"\n  n: 1\n",
+		"wi_orders_2024/documents.yml": "- _id: \"2\"\n  n: 2\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+
+	res, err := client.Index("wi_orders", strings.NewReader(`{"n": 3}`), client.Index.WithDocumentID("3"), client.Index.WithRefresh("true"))
+	if err != nil {
+		t.Fatalf("indexing through the alias: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.IsError() {
+		t.Fatalf("indexing through the alias: %s", res.Status())
+	}
+
+	if n := getDocCount(t, client, "wi_orders_2024"); n != 2 {
+		t.Errorf("expected the write through the alias to reach wi_orders_2024, which has %d documents", n)
+	}
+	if n := getDocCount(t, client, "wi_orders"); n != 3 {
+		t.Errorf("expected 3 documents through the alias, got %d", n)
+	}
+}
+
+func TestLoad_CatAPIs(t *testing.T) {
+	client := setupTestClient(t)
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"cat_users/_settings.json": `{"number_of_shards": 2, "number_of_replicas": 0}`,
+		"cat_users/_aliases.json":  `{"cat_users_write": {"is_write_index": true}}`,
+		"cat_users/documents.yml":  "- _id: \"1\"\n- _id: \"2\"\n",
+	})
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	t.Cleanup(func() { loader.Clean() })
+	ctx := context.Background()
+
+	indices, err := loader.CatIndices(ctx)
+	if err != nil {
+		t.Fatalf("CatIndices() error: %v", err)
+	}
+	if len(indices) != 1 || indices[0].Index != "cat_users" || indices[0].Primaries != 2 || indices[0].DocsCount != 2 {
+		t.Errorf("unexpected indices: %+v", indices)
+	}
+
+	aliases, err := loader.CatAliases(ctx)
+	if err != nil {
+		t.Fatalf("CatAliases() error: %v", err)
+	}
+	if len(aliases) != 1 || aliases[0].Alias != "cat_users_write" || !aliases[0].IsWriteIndex {
+		t.Errorf("unexpected aliases: %+v", aliases)
+	}
+
+	shards, err := loader.CatShards(ctx)
+	if err != nil {
+		t.Fatalf("CatShards() error: %v", err)
+	}
+	if len(shards) != 2 || !shards[0].Primary || shards[0].State != "STARTED" || shards[1].Shard != 1 {
+		t.Errorf("unexpected shards: %+v", shards)
+	}
+}
@@ -0,0 +1,51 @@
+package testfixtures
+
+import "fmt"
+
+// LoadStage names the step of loading an index at which a Load failed.
+type LoadStage string
+
+// Stages of loading an index, in the order they run.
+const (
+	StageCheckpoint LoadStage = "checkpoint" // Reading or writing the progress of WithCheckpoint
+	StagePipeline   LoadStage = "pipeline"   // Creating the ingest pipelines of CreatePipelines
+	StageDelete     LoadStage = "delete"     // Deleting the previous index
+	StageCreate     LoadStage = "create"     // Creating the index with its mapping and settings
+	StageIndex      LoadStage = "index"      // Sending documents from files and providers
+	StageRefresh    LoadStage = "refresh"    // Refreshing the index
+	StageValidate   LoadStage = "validate"   // Checking runtime fields under ValidateRuntimeFields
+	StageExpect     LoadStage = "expect"     // Checking the document counts of _expect.yml
+	StageWarmup     LoadStage = "warmup"     // Running the declared queries under WarmupQueries
+	StageAlias      LoadStage = "alias"      // Adding alias fixtures, tenant aliases, or unique-index aliases
+	StageState      LoadStage = "state"      // Applying the state set in _config.yml
+	StageEnrich     LoadStage = "enrich"     // Executing the enrich policies of _enrich sourced from the index
+	StageTransform  LoadStage = "transform"  // Creating and starting the transforms of _transforms sourced from the index
+)
+
+// LoadError is returned by Load and LoadIndices when an index fails to load.
+// Loaded lists the indices that were loaded before it, so a caller can tell
+// what partial state the cluster is in:
+//
+//	var loadErr *testfixtures.LoadError
+//	if errors.As(err, &loadErr) && loadErr.Stage == testfixtures.StageIndex {
+//		// The index exists but holds only some of its documents
+//	}
+//
+// Loads stopped by a signal under HandleSignals return ErrInterrupted
+// instead.
+type LoadError struct {
+	Index  string    // Name of the index that failed
+	Stage  LoadStage // Step that failed
+	Cause  error     // Error from that step
+	Loaded []string  // Indices loaded before the failure, in load order
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("testfixtures: loading %q (%s): %v", e.Index, e.Stage, e.Cause)
+}
+
+// Unwrap returns the cause, so errors.Is and errors.As see through a
+// LoadError.
+func (e *LoadError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,63 @@
+package testfixtures
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad_LoadError(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml":   "- _id: 1\n  n: 1\n",
+		"products/documents.yml": "- _id: 1\n  n: 1\n",
+		"users/documents.yml":    "- _id: 1\n  n: 1\n",
+	})
+
+	// fail is the request that fails, as "METHOD /path".
+	var fail string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method+" "+req.URL.Path == fail {
+			return jsonResponse(400, `{"error":{"type":"illegal_argument_exception","reason":"broken"},"status":400}`), nil
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	tests := []struct {
+		fail   string
+		index  string
+		stage  LoadStage
+		loaded []string
+	}{
+		{fail: "PUT /products", index: "products", stage: StageCreate, loaded: []string{"orders"}},
+		{fail: "POST /users/_refresh", index: "users", stage: StageRefresh, loaded: []string{"orders", "products"}},
+		{fail: "DELETE /orders", index: "orders", stage: StageDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fail, func(t *testing.T) {
+			fail = tt.fail
+			err := loader.Load()
+
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %T: %v", err, err)
+			}
+			if loadErr.Index != tt.index || loadErr.Stage != tt.stage || !slices.Equal(loadErr.Loaded, tt.loaded) {
+				t.Errorf("got %+v, want index %s, stage %s, loaded %v", loadErr, tt.index, tt.stage, tt.loaded)
+			}
+			if !strings.Contains(err.Error(), `loading "`+tt.index+`" (`+string(tt.stage)+`)`) || !strings.Contains(err.Error(), "broken") {
+				t.Errorf("expected the index, stage, and cause in %q", err)
+			}
+		})
+	}
+}
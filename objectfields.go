@@ -0,0 +1,187 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// objectMapping is the part of a field mapping that decides how document
+// objects under the field are indexed.
+type objectMapping struct {
+	Type       string                     `json:"type"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Dynamic    any                        `json:"dynamic"`
+}
+
+// fieldMapping returns the mapping of the property at a dotted key under
+// node, following object properties. The result is nil if the key is not
+// mapped.
+func fieldMapping(node *objectMapping, key string) *objectMapping {
+	for _, name := range strings.Split(key, ".") {
+		if node == nil || node.Properties[name] == nil {
+			return nil
+		}
+		var def objectMapping
+		if err := json.Unmarshal(node.Properties[name], &def); err != nil {
+			return nil
+		}
+		node = &def
+	}
+	return node
+}
+
+// objectFieldChecker walks the documents of a fixture alongside its mapping.
+type objectFieldChecker struct {
+	doc      Document
+	warnings []string
+	errs     []error
+}
+
+// check walks an object value whose fields are mapped by node. dynamic is
+// false once an enclosing mapping disables dynamic fields, since unmapped
+// fields are then not indexed at all.
+func (c *objectFieldChecker) check(node *objectMapping, prefix string, value json.RawMessage, dynamic bool) {
+	fields, err := decodeObject(value)
+	if err != nil {
+		return
+	}
+	if node != nil && node.Dynamic != nil {
+		dynamic = fmt.Sprint(node.Dynamic) == "true" || fmt.Sprint(node.Dynamic) == "runtime"
+	}
+
+	for _, f := range fields {
+		path := f.key
+		if prefix != "" {
+			path = prefix + "." + f.key
+		}
+
+		def := fieldMapping(node, f.key)
+		if def == nil && !dynamic {
+			continue
+		}
+
+		typ := "object"
+		switch {
+		case def == nil:
+			typ = ""
+		case def.Type != "":
+			typ = def.Type
+		}
+		switch typ {
+		case "", "object", "nested", "flattened":
+		default:
+			continue
+		}
+
+		objects, scalar := objectsIn(f.value)
+		if scalar != nil && typ != "" && typ != "flattened" {
+			c.errs = append(c.errs, fmt.Errorf("%s: field %q is mapped as %s but holds %s, which Elasticsearch rejects", c.doc.Location(), path, typ, scalar))
+			continue
+		}
+
+		if len(objects) > 1 {
+			switch typ {
+			case "":
+				c.warnings = append(c.warnings, fmt.Sprintf("%s: field %q holds an array of %d objects but is not mapped, so dynamic mapping makes it an object and their fields are flattened together; map it as nested to query each object on its own", c.doc.Location(), path, len(objects)))
+			case "object":
+				c.warnings = append(c.warnings, fmt.Sprintf("%s: field %q holds an array of %d objects but is mapped as object, so their fields are flattened together; map it as nested to query each object on its own", c.doc.Location(), path, len(objects)))
+			case "flattened":
+				c.warnings = append(c.warnings, fmt.Sprintf("%s: field %q holds an array of %d objects but is mapped as flattened, so their values are merged into one set of keys", c.doc.Location(), path, len(objects)))
+			}
+		}
+
+		if typ == "flattened" {
+			continue
+		}
+		for _, obj := range objects {
+			c.check(def, path, obj, dynamic)
+		}
+	}
+}
+
+// objectsIn returns the objects in a field value, which may be a single
+// object or an array of them, or the first non-null scalar it holds.
+func objectsIn(value json.RawMessage) (objects []json.RawMessage, scalar json.RawMessage) {
+	items := []json.RawMessage{value}
+	if isJSONArray(value) {
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, nil
+		}
+	}
+
+	for _, item := range items {
+		switch {
+		case string(item) == "null":
+		case isJSONObject(item):
+			objects = append(objects, item)
+		case isJSONArray(item):
+			nested, s := objectsIn(item)
+			objects = append(objects, nested...)
+			if scalar == nil {
+				scalar = s
+			}
+		case scalar == nil:
+			scalar = item
+		}
+	}
+
+	return objects, scalar
+}
+
+// checkObjectValues compares the documents of f against the object, nested,
+// and flattened fields of its mapping. A concrete value in an object or
+// nested field is an error, since indexing would fail on it. An array of
+// objects under a field that is not nested is only a warning: Elasticsearch
+// accepts it, but flattens the objects together, so a query can match
+// fields of different objects as if they belonged to one.
+func checkObjectValues(f *indexFixture) ([]string, error) {
+	var root objectMapping
+	if f.mapping != nil {
+		if err := json.Unmarshal(f.mapping, &root); err != nil {
+			return nil, nil
+		}
+	}
+
+	var (
+		warnings []string
+		errs     []error
+	)
+	for _, doc := range f.documents {
+		c := &objectFieldChecker{doc: doc}
+		c.check(&root, "", doc.Source, true)
+		warnings = append(warnings, c.warnings...)
+		errs = append(errs, c.errs...)
+	}
+
+	return warnings, errors.Join(errs...)
+}
+
+// checkObjectFields runs checkObjectValues on every fixture, recording the
+// warnings for Warnings.
+func (l *Loader) checkObjectFields() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		warnings, err := checkObjectValues(f)
+		for _, w := range warnings {
+			l.warnings = append(l.warnings, fmt.Sprintf("index %q: %s", f.name, w))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("index %q: %w", f.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings returns problems found in the fixtures by New that do not stop
+// them from loading but likely make them behave differently than intended,
+// such as arrays of objects that Elasticsearch flattens because the field is
+// not mapped as nested. Components of _optional.yml that Load or Clean
+// leave out, as the cluster's license does not include them, are added
+// once the cluster has been asked.
+func (l *Loader) Warnings() []string {
+	return slices.Clone(l.warnings)
+}
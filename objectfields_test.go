@@ -0,0 +1,88 @@
+package testfixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew_ObjectFieldWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_mapping.json": `{"properties":{
+			"lines":{"type":"nested","properties":{"sku":{"type":"keyword"}}},
+			"customer":{"properties":{"addresses":{"properties":{"city":{"type":"keyword"}}}}},
+			"labels":{"type":"flattened"},
+			"meta":{"type":"object","dynamic":false}
+		}}`,
+		"orders/documents.yml": `- _id: 1
+  lines: [{sku: a}, {sku: b}]
+  customer:
+    addresses: [{city: Tokyo}, {city: Osaka}]
+  labels: [{env: prod}, {team: search}]
+  notes: [{by: alice}, {by: bob}]
+  meta:
+    history: [{at: 1}, {at: 2}]
+- _id: 2
+  lines: {sku: c}
+  customer:
+    addresses: {city: Kyoto}
+  notes: [{by: carol}]
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	warnings := loader.Warnings()
+	for _, want := range []string{
+		`orders/documents.yml:1: field "customer.addresses" holds an array of 2 objects but is mapped as object`,
+		`orders/documents.yml:1: field "labels" holds an array of 2 objects but is mapped as flattened`,
+		`orders/documents.yml:1: field "notes" holds an array of 2 objects but is not mapped`,
+	} {
+		if !containsWarning(warnings, want) {
+			t.Errorf("expected a warning containing %q, got %q", want, warnings)
+		}
+	}
+	if len(warnings) != 3 {
+		t.Errorf("expected 3 warnings, got %q", warnings)
+	}
+}
+
+func TestNew_ObjectFieldScalar(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_mapping.json": `{"properties":{
+			"lines":{"type":"nested","properties":{"sku":{"type":"keyword"}}},
+			"customer":{"properties":{"name":{"type":"keyword"}}}
+		}}`,
+		"orders/documents.yml": `- _id: 1
+  lines: [{sku: a}, b]
+- _id: 2
+  customer: Alice
+`,
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil {
+		t.Fatal("expected New to reject concrete values in object fields")
+	}
+	for _, want := range []string{
+		`orders/documents.yml:1: field "lines" is mapped as nested but holds "b"`,
+		`orders/documents.yml:3: field "customer" is mapped as object but holds "Alice"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func containsWarning(warnings []string, want string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"gopkg.in/yaml.v3"
+)
+
+// optionalFile is the optional top-level file listing the components that
+// Load skips, with a warning, when the cluster's license does not include
+// them, so the same fixtures load into basic-license and unlicensed clusters:
+//
+//	# fixtures/_optional.yml
+//	- ilm
+//	- transforms
+const optionalFile = "_optional.yml"
+
+// optionalComponent is a component of the fixtures that _optional.yml can
+// mark as optional.
+type optionalComponent struct {
+	name    string // Name in _optional.yml
+	dir     string // Directory holding its definitions
+	feature string // Name of its feature in the X-Pack info API
+}
+
+// optionalComponents are the components _optional.yml can name. Enrich
+// policies and synonym sets are not among them, as pipelines and analyzers
+// that use them cannot be created without them.
+var optionalComponents = []optionalComponent{
+	{name: "ilm", dir: ilmDir, feature: "ilm"},
+	{name: "transforms", dir: transformsDir, feature: "transform"},
+}
+
+// readOptionalComponents reads _optional.yml, a YAML list of component
+// names, returning the named components. A missing file names none.
+func readOptionalComponents(fsys fs.FS, dir string) (map[string]bool, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, optionalFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", optionalFile, err)
+	}
+
+	var names []string
+	if err := yaml.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("parsing %s: must be a list of component names: %w", optionalFile, err)
+	}
+	optional := make(map[string]bool, len(names))
+	for _, name := range names {
+		if findOptionalComponent(name) == nil {
+			known := make([]string, len(optionalComponents))
+			for i, c := range optionalComponents {
+				known[i] = c.name
+			}
+			return nil, fmt.Errorf("%s: unknown component %q (want %s)", optionalFile, name, strings.Join(known, " or "))
+		}
+		optional[name] = true
+	}
+	return optional, nil
+}
+
+// findOptionalComponent returns the optional component called name, or nil.
+func findOptionalComponent(name string) *optionalComponent {
+	for i := range optionalComponents {
+		if optionalComponents[i].name == name {
+			return &optionalComponents[i]
+		}
+	}
+	return nil
+}
+
+// definitions returns the number of definitions the fixtures hold for c.
+func (l *Loader) definitions(c optionalComponent) int {
+	switch c.name {
+	case "ilm":
+		return len(l.lifecyclePolicies)
+	case "transforms":
+		return len(l.transforms)
+	}
+	return 0
+}
+
+// skipsComponent reports whether Load and Clean leave out the component
+// called name: it is optional, has definitions, and the cluster cannot run
+// it. The cluster's features are read once, on the first call for such a
+// component, and each component skipped is recorded in Warnings.
+func (l *Loader) skipsComponent(ctx context.Context, name string) (bool, error) {
+	c := findOptionalComponent(name)
+	if !l.optional[name] || l.definitions(*c) == 0 {
+		return false, nil
+	}
+	if l.features == nil {
+		features, err := readClusterFeatures(ctx, l.client)
+		if err != nil {
+			return false, fmt.Errorf("checking the cluster's license for %s: %w", optionalFile, err)
+		}
+		l.features = &features
+		for _, c := range optionalComponents {
+			if !l.optional[c.name] || l.definitions(c) == 0 {
+				continue
+			}
+			if reason := features.unavailable(c.feature); reason != "" {
+				l.warnings = append(l.warnings, fmt.Sprintf("%s is not loaded, as %s marks it optional and %s", c.dir, optionalFile, reason))
+			}
+		}
+	}
+	return l.features.unavailable(c.feature) != "", nil
+}
+
+// clusterFeatures is what the X-Pack info API reports of a cluster.
+type clusterFeatures struct {
+	license  string // License type, such as basic or platinum; empty without X-Pack
+	status   string // License status, such as active or expired
+	features map[string]featureInfo
+}
+
+// featureInfo is the state of one X-Pack feature.
+type featureInfo struct {
+	Available bool `json:"available"` // Whether the license includes it
+	Enabled   bool `json:"enabled"`   // Whether the cluster runs it
+}
+
+// unavailable returns why the cluster cannot run feature, or "" if it can.
+func (c clusterFeatures) unavailable(feature string) string {
+	f, ok := c.features[feature]
+	switch {
+	case c.license == "":
+		return "the cluster has no license information, so it has no X-Pack features"
+	case c.status != "active":
+		return fmt.Sprintf("the cluster's %s license is %s", c.license, c.status)
+	case !ok || !f.Available:
+		return fmt.Sprintf("the cluster's %s license does not include %s", c.license, feature)
+	case !f.Enabled:
+		return fmt.Sprintf("%s is disabled in the cluster", feature)
+	}
+	return ""
+}
+
+// readClusterFeatures reads the license and features of the cluster from
+// the X-Pack info API. A cluster without the API, such as one built without
+// X-Pack, has no license and no features.
+func readClusterFeatures(ctx context.Context, client *elasticsearch.Client) (clusterFeatures, error) {
+	res, err := client.XPack.Info(client.XPack.Info.WithContext(ctx))
+	if err != nil {
+		return clusterFeatures{}, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case 400, 404, 410:
+		return clusterFeatures{}, nil
+	}
+	if err := checkResponse(res); err != nil {
+		return clusterFeatures{}, err
+	}
+
+	var info struct {
+		License *struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"license"`
+		Features map[string]featureInfo `json:"features"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return clusterFeatures{}, fmt.Errorf("decoding X-Pack info: %w", err)
+	}
+	if info.License == nil {
+		return clusterFeatures{features: info.Features}, nil
+	}
+	return clusterFeatures{license: info.License.Type, status: info.License.Status, features: info.Features}, nil
+}
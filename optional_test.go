@@ -0,0 +1,146 @@
+package testfixtures
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoad_OptionalComponents(t *testing.T) {
+	tests := []struct {
+		name        string
+		xpack       string
+		status      int
+		wantSkipped bool
+		wantWarning string
+	}{
+		{
+			name:   "licensed",
+			xpack:  `{"license":{"type":"trial","status":"active"},"features":{"ilm":{"available":true,"enabled":true},"transform":{"available":true,"enabled":true}}}`,
+			status: 200,
+		},
+		{
+			name:        "not in license",
+			xpack:       `{"license":{"type":"basic","status":"active"},"features":{"ilm":{"available":false,"enabled":true},"transform":{"available":false,"enabled":true}}}`,
+			status:      200,
+			wantSkipped: true,
+			wantWarning: "_ilm is not loaded, as _optional.yml marks it optional and the cluster's basic license does not include ilm",
+		},
+		{
+			name:        "expired",
+			xpack:       `{"license":{"type":"platinum","status":"expired"},"features":{}}`,
+			status:      200,
+			wantSkipped: true,
+			wantWarning: "_transforms is not loaded, as _optional.yml marks it optional and the cluster's platinum license is expired",
+		},
+		{
+			name:        "no X-Pack",
+			xpack:       `{"error":"no handler found for uri [/_xpack]"}`,
+			status:      400,
+			wantSkipped: true,
+			wantWarning: "the cluster has no license information, so it has no X-Pack features",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{
+				"_optional.yml":            "- ilm\n- transforms\n",
+				"_ilm/logs_policy.json":    `{"policy":{"phases":{"hot":{"actions":{}}}}}`,
+				"_transforms/by_user.json": `{"source":{"index":"events"},"dest":{"index":"events_by_user"},"pivot":{"group_by":{"user":{"terms":{"field":"user"}}},"aggregations":{"n":{"value_count":{"field":"user"}}}}}`,
+				"events/documents.yml":     "- _id: 1\n  user: ann\n",
+			})
+
+			var requests []string
+			client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				requests = append(requests, req.Method+" "+req.URL.Path)
+				switch {
+				case req.URL.Path == "/_xpack":
+					return jsonResponse(tt.status, tt.xpack), nil
+				case strings.HasSuffix(req.URL.Path, "/_bulk"):
+					return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+				}
+				return jsonResponse(200, `{}`), nil
+			}))
+
+			loader, err := New(client, Directory(dir))
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			if err := loader.Load(); err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if err := loader.Clean(); err != nil {
+				t.Fatalf("Clean() error: %v", err)
+			}
+
+			got := strings.Join(requests, "\n")
+			for _, call := range []string{"PUT /_ilm/policy/logs_policy", "PUT /_transform/by_user", "DELETE /_ilm/policy/logs_policy", "DELETE /_transform/by_user"} {
+				if strings.Contains(got, call) == tt.wantSkipped {
+					t.Errorf("expected %q to be sent: %v, got requests:\n%s", call, !tt.wantSkipped, got)
+				}
+			}
+			if n := strings.Count(got, "GET /_xpack"); n != 1 {
+				t.Errorf("expected the cluster's features to be read once, got %d reads", n)
+			}
+
+			warnings := strings.Join(loader.Warnings(), "\n")
+			if tt.wantWarning == "" && warnings != "" {
+				t.Errorf("expected no warnings, got %s", warnings)
+			}
+			if tt.wantWarning != "" && !strings.Contains(warnings, tt.wantWarning) {
+				t.Errorf("expected a warning containing %q, got %q", tt.wantWarning, warnings)
+			}
+			if tt.wantSkipped && len(loader.Warnings()) != 2 {
+				t.Errorf("expected a warning for each of the 2 skipped components, got %q", loader.Warnings())
+			}
+		})
+	}
+}
+
+func TestLoad_RequiredComponentsAreNotChecked(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_optional.yml":         "- transforms\n",
+		"_ilm/logs_policy.json": `{"policy":{"phases":{"hot":{"actions":{}}}}}`,
+		"events/documents.yml":  "- _id: 1\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	got := strings.Join(requests, "\n")
+	if strings.Contains(got, "/_xpack") || !strings.Contains(got, "PUT /_ilm/policy/logs_policy") {
+		t.Errorf("expected ilm to be created without reading the license, got requests:\n%s", got)
+	}
+}
+
+func TestReadOptionalComponents(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"_optional.yml": "- ilm\n- security\n"})
+
+	_, err := readOptionalComponents(os.DirFS(dir), ".")
+	if err == nil || !strings.Contains(err.Error(), `_optional.yml: unknown component "security" (want ilm or transforms)`) {
+		t.Errorf("expected an error for an unknown component, got %v", err)
+	}
+
+	writeFixtureFiles(t, dir, map[string]string{"_optional.yml": "ilm: true\n"})
+	if _, err := readOptionalComponents(os.DirFS(dir), "."); err == nil || !strings.Contains(err.Error(), "must be a list of component names") {
+		t.Errorf("expected an error for a mapping, got %v", err)
+	}
+}
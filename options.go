@@ -1,20 +1,124 @@
 package testfixtures
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
 
 // Option configures the Loader.
 type Option func(*Loader) error
 
 // Directory sets the path to the fixtures directory.
-// This option is required.
+// Either this option or FS is required, unless documents come only from
+// providers registered with WithProvider.
 func Directory(dir string) Option {
 	return func(l *Loader) error {
-		l.dir = dir
+		l.fsys, l.dir, l.source = os.DirFS(dir), ".", dir
+		return nil
+	}
+}
+
+// FS reads the fixtures from the directory root of fsys instead of the
+// operating system's file system, so fixtures embedded with go:embed travel
+// with the test binary:
+//
+//	//go:embed testdata/fixtures
+//	var fixtures embed.FS
+//
+//	loader, err := testfixtures.New(client, testfixtures.FS(fixtures, "testdata/fixtures"))
+func FS(fsys fs.FS, root string) Option {
+	return func(l *Loader) error {
+		if fsys == nil {
+			return errors.New("fixtures file system must not be nil")
+		}
+		if !fs.ValidPath(root) {
+			return fmt.Errorf("invalid fixtures root %q: must be an unrooted, slash-separated path", root)
+		}
+		l.fsys, l.dir, l.source = fsys, root, root
 		return nil
 	}
 }
 
-// WithContext sets the default context for Elasticsearch operations.
+// Compose builds the fixtures from several sources: Directory, FS, and
+// Builder options supply fixture files, and WithProvider options add
+// providers. Later sources take precedence: a file in a later source replaces
+// the file at the same path in earlier ones, the others are merged per index,
+// and documents of later sources are written after, and so replace, those of
+// earlier ones with the same _id. Provider documents are written after all
+// files, in registration order.
+//
+//	loader, err := testfixtures.New(client, testfixtures.Compose(
+//		testfixtures.Directory("testdata/base"),
+//		testfixtures.Builder(extra),
+//		testfixtures.WithProvider("orders", provider),
+//	))
+//
+// Compose replaces any fixtures set by an earlier Directory or FS option.
+func Compose(sources ...Option) Option {
+	return func(l *Loader) error {
+		var (
+			layers unionFS
+			names  []string
+		)
+		for i, source := range sources {
+			if source == nil {
+				return fmt.Errorf("source %d must not be nil", i)
+			}
+			scratch := &Loader{}
+			if err := source(scratch); err != nil {
+				return fmt.Errorf("source %d: %w", i, err)
+			}
+			if scratch.fsys != nil {
+				sub, err := fs.Sub(scratch.fsys, scratch.dir)
+				if err != nil {
+					return fmt.Errorf("source %d: %w", i, err)
+				}
+				layers = append(layers, sub)
+				names = append(names, scratch.source)
+			}
+			l.providers = append(l.providers, scratch.providers...)
+
+			scratch.fsys, scratch.dir, scratch.source, scratch.providers = nil, "", "", nil
+			if !reflect.DeepEqual(scratch, &Loader{}) {
+				return fmt.Errorf("source %d: Compose accepts only Directory, FS, Builder, and WithProvider", i)
+			}
+		}
+		if len(layers) > 0 {
+			l.fsys, l.dir, l.source = layers, ".", strings.Join(names, "+")
+		}
+		return nil
+	}
+}
+
+// Builder loads the fixtures built in code by d, on its own or as a source of
+// Compose. Changes made to d afterwards are not seen.
+func Builder(d *Dataset) Option {
+	return func(l *Loader) error {
+		if d == nil {
+			return errors.New("dataset must not be nil")
+		}
+		fsys, err := d.mapFS()
+		if err != nil {
+			return fmt.Errorf("dataset: %w", err)
+		}
+		l.fsys, l.dir, l.source = fsys, ".", "dataset"
+		return nil
+	}
+}
+
+// WithContext sets the default context for Elasticsearch operations,
+// used by Load, Clean, and the other methods without a context parameter.
 // If not set, context.Background() is used.
 func WithContext(ctx context.Context) Option {
 	return func(l *Loader) error {
@@ -22,3 +126,473 @@ func WithContext(ctx context.Context) Option {
 		return nil
 	}
 }
+
+// HandleSignals makes Load trap SIGINT and SIGTERM while it runs.
+// When a signal arrives, in-flight requests are cancelled, every index
+// created by the interrupted Load is deleted, and Load returns ErrInterrupted.
+func HandleSignals() Option {
+	return func(l *Loader) error {
+		l.handleSignals = true
+		return nil
+	}
+}
+
+// WithDocConcurrency splits the documents of each index across n bulk
+// indexers running in parallel. Documents are partitioned by _id, so
+// repeated writes to the same ID are still applied in fixture order.
+// If not set, each index is loaded by a single bulk indexer.
+func WithDocConcurrency(n int) Option {
+	return func(l *Loader) error {
+		if n < 1 {
+			return fmt.Errorf("document concurrency must be at least 1, got %d", n)
+		}
+		l.docConcurrency = n
+		return nil
+	}
+}
+
+// WithFieldNormalizer registers fn to rewrite the value of field in every
+// fixture document before it is indexed, e.g. to lowercase keywords or trim
+// whitespace. Nested fields use dot notation ("address.city"). Array values
+// are passed to fn one element at a time, and numbers arrive as json.Number.
+// Normalizers for the same field run in the order they are registered.
+func WithFieldNormalizer(field string, fn func(any) any) Option {
+	return func(l *Loader) error {
+		if field == "" {
+			return errors.New("field normalizer requires a field name")
+		}
+		if fn == nil {
+			return fmt.Errorf("field normalizer for %q must not be nil", field)
+		}
+		l.normalizers = append(l.normalizers, fieldNormalizer{field: field, fn: fn})
+		return nil
+	}
+}
+
+// WithFieldGenerator registers fn to supply the value of field for every
+// fixture document that does not define it. Generators run on each Load, so
+// volatile values such as timestamps or signatures are produced fresh while
+// fixture files keep only stable data. Nested fields use dot notation.
+func WithFieldGenerator(field string, fn func() any) Option {
+	return func(l *Loader) error {
+		if field == "" {
+			return errors.New("field generator requires a field name")
+		}
+		if fn == nil {
+			return fmt.Errorf("field generator for %q must not be nil", field)
+		}
+		l.generators = append(l.generators, fieldGenerator{field: field, fn: fn})
+		return nil
+	}
+}
+
+// WithProvider registers p as a source of documents for index, in addition
+// to any fixture files for that index. If there is no fixture directory for
+// index, it is created without a mapping or settings. Multiple providers may
+// be registered for the same index; they are queried in registration order.
+func WithProvider(index string, p DocumentProvider) Option {
+	return func(l *Loader) error {
+		if index == "" {
+			return errors.New("provider requires an index name")
+		}
+		if p == nil {
+			return fmt.Errorf("provider for %q must not be nil", index)
+		}
+		l.providers = append(l.providers, indexProvider{index: index, provider: p})
+		return nil
+	}
+}
+
+// DedupeByID makes Load index each document ID of an index only once. When
+// an _id appears more than once across the index's fixture files and
+// providers, the occurrence that would have been written last wins: later
+// documents in a file over earlier ones, later files over earlier ones, and
+// providers, in registration order, over fixture files. Provider documents
+// are read into memory before indexing. What was dropped is reported in the
+// Duplicates field of Results.
+func DedupeByID() Option {
+	return func(l *Loader) error {
+		l.dedupe = true
+		return nil
+	}
+}
+
+// ValidateRuntimeFields makes Load run a search computing the fields defined
+// in each index's _runtime_mappings.json once its documents are loaded, so
+// script errors fail the load instead of the first test that uses them.
+func ValidateRuntimeFields() Option {
+	return func(l *Loader) error {
+		l.checkRuntimeFields = true
+		return nil
+	}
+}
+
+// WarmupQueries makes Load run each query declared in an index's
+// _expectations directory and _expect.yml once its documents are loaded,
+// filling caches and building lazily loaded structures so the first test to
+// search the index is not slower than the rest. The queries run before the
+// state of _config.yml is applied; a failing query fails the load.
+func WarmupQueries() Option {
+	return func(l *Loader) error {
+		l.warmup = true
+		return nil
+	}
+}
+
+// ValidateReplicaCounts makes Load compare each index's number_of_replicas
+// with the number of data nodes in the cluster before touching any index,
+// failing with a clear error for fixtures whose replicas could never be
+// assigned, such as three replicas on a single-node cluster. Indices are then
+// created with wait_for_active_shards covering every copy the cluster can
+// hold, so documents are only loaded once the index is fully allocated.
+func ValidateReplicaCounts() Option {
+	return func(l *Loader) error {
+		l.checkReplicas = true
+		return nil
+	}
+}
+
+// WithDebugRequests writes every request the Loader sends to Elasticsearch
+// to w as an equivalent curl command (method, URL, and request body, truncated
+// for large bulk requests), so a failing load can be replayed by hand.
+// Credentials are not included.
+func WithDebugRequests(w io.Writer) Option {
+	return func(l *Loader) error {
+		if w == nil {
+			return errors.New("debug request writer must not be nil")
+		}
+		client := l.client
+		l.client = wrapClient(client, func(next esapi.Transport) esapi.Transport {
+			return newDebugTransport(client, next, w)
+		})
+		return nil
+	}
+}
+
+// WithCredentials authenticates every request of the Loader with
+// credentials from provider, in place of those of the client, for loads
+// that outlast short-lived API keys or tokens. The provider is called before
+// the first request, again shortly before the Expires time of the last
+// credentials it returned, and whenever Elasticsearch rejects them with 401
+// Unauthorized, in which case the request is sent once more with the new
+// ones.
+func WithCredentials(provider CredentialProvider) Option {
+	return func(l *Loader) error {
+		if provider == nil {
+			return errors.New("credential provider must not be nil")
+		}
+		l.client = wrapClient(l.client, func(next esapi.Transport) esapi.Transport {
+			return newCredentialsTransport(next, provider)
+		})
+		return nil
+	}
+}
+
+// StripAllocationSettings removes index.routing.allocation.* settings, such
+// as _tier_preference and box_type filters, from every fixture before the
+// indices are created. Settings copied from a hot/warm production cluster
+// otherwise create indices whose shards cannot be assigned on a single-tier
+// test cluster.
+func StripAllocationSettings() Option {
+	return func(l *Loader) error {
+		l.stripAllocation = true
+		return nil
+	}
+}
+
+// WithMaxInFlightBytes limits the total size of request bodies the Loader
+// has in flight at once to n bytes, so loading large fixtures into a small
+// cluster does not trip its circuit breakers. Requests rejected by a circuit
+// breaker anyway, and the items of bulk requests rejected with
+// es_rejected_execution_exception or circuit_breaking_exception, are retried
+// with backoff, and the limit is halved for the rest of the load. A single
+// request larger than n is sent on its own.
+func WithMaxInFlightBytes(n int64) Option {
+	return func(l *Loader) error {
+		if n < 1 {
+			return fmt.Errorf("max in-flight bytes must be at least 1, got %d", n)
+		}
+		l.client = wrapClient(l.client, func(next esapi.Transport) esapi.Transport {
+			return newThrottleTransport(next, n)
+		})
+		return nil
+	}
+}
+
+// WithMaxRequestBytes sets the largest bulk request the Loader sends, instead
+// of reading http.max_content_length from the cluster. Bulk requests are
+// flushed well below this size, documents too large to share a request are
+// sent on their own, and a document larger than n fails the load with its
+// fixture file and ID.
+func WithMaxRequestBytes(n int) Option {
+	return func(l *Loader) error {
+		if n < 1 {
+			return fmt.Errorf("max request bytes must be at least 1, got %d", n)
+		}
+		l.maxRequestBytes = n
+		return nil
+	}
+}
+
+// WithEventHandler registers fn to receive the Loader's events, such as
+// IndexCreated and BulkFlushed, for progress reporting, metrics, or audit
+// logs. Handlers are called synchronously and one at a time, so they should
+// return quickly. The option may be given more than once.
+func WithEventHandler(fn func(Event)) Option {
+	return func(l *Loader) error {
+		if fn == nil {
+			return errors.New("event handler must not be nil")
+		}
+		l.events.handlers = append(l.events.handlers, fn)
+		return nil
+	}
+}
+
+// WithStrategy sets how Load prepares every fixture index before inserting
+// its documents: StrategyRecreate, the default, StrategyTruncate, or
+// StrategySync. The strategy key of an index's _config.yml overrides it for
+// that index, so a large index can be truncated while small ones are
+// recreated.
+func WithStrategy(s Strategy) Option {
+	return func(l *Loader) error {
+		if err := checkStrategy(s); err != nil {
+			return err
+		}
+		l.strategy = s
+		return nil
+	}
+}
+
+// WithIndexPrefix prepends prefix to the name of every index and alias
+// created from the fixtures, so that several test runs, such as parallel CI
+// jobs, can share one cluster: with prefix "job42_", the users fixture is
+// loaded into job42_users. Clean deletes only the prefixed names. Methods
+// taking an index name, such as LoadIndices and IndexStats, still take the
+// fixture's directory name; IndexName returns the name in the cluster.
+func WithIndexPrefix(prefix string) Option {
+	return func(l *Loader) error {
+		if err := checkIndexAffix(prefix); err != nil {
+			return fmt.Errorf("invalid index prefix %q: %w", prefix, err)
+		}
+		if prefix != "" && strings.ContainsAny(prefix[:1], "-_+") {
+			return fmt.Errorf("invalid index prefix %q: index names must not start with '-', '_', or '+'", prefix)
+		}
+		l.indexPrefix = prefix
+		return nil
+	}
+}
+
+// WithIndexSuffix appends suffix to the name of every index and alias
+// created from the fixtures, like WithIndexPrefix.
+func WithIndexSuffix(suffix string) Option {
+	return func(l *Loader) error {
+		if err := checkIndexAffix(suffix); err != nil {
+			return fmt.Errorf("invalid index suffix %q: %w", suffix, err)
+		}
+		l.indexSuffix = suffix
+		return nil
+	}
+}
+
+// checkIndexAffix rejects characters that Elasticsearch does not allow in
+// index names.
+func checkIndexAffix(s string) error {
+	if s != strings.ToLower(s) {
+		return errors.New("index names must be lowercase")
+	}
+	if i := strings.IndexAny(s, `\/*?"<>| ,#:`); i >= 0 {
+		return fmt.Errorf("index names must not contain %q", s[i])
+	}
+	return nil
+}
+
+// WithTenantField sets field (dot notation for nested fields) to value in
+// every document that does not define it, so one fixture set can stand in
+// for a tenant of a multi-tenant index; documents that set the field keep
+// their own tenant. Fixture indices whose mapping lacks the field get it as
+// a keyword. The value is applied on each Load, like WithFieldGenerator.
+func WithTenantField(field string, value any) Option {
+	return func(l *Loader) error {
+		if field == "" {
+			return errors.New("tenant field requires a field name")
+		}
+		if l.tenant != nil && l.tenant.field != "" {
+			return fmt.Errorf("tenant field is already set to %q", l.tenant.field)
+		}
+		if l.tenant == nil {
+			l.tenant = &tenantConfig{}
+		}
+		l.tenant.field = field
+		l.generators = append(l.generators, fieldGenerator{field: field, fn: func() any { return value }})
+		return nil
+	}
+}
+
+// WithTenantAliases makes Load create, for every fixture index, a filtered
+// alias per tenant found in the field set by WithTenantField, so
+// tenant-scoped search code can be tested against one physical index. The
+// aliases are named by TenantAlias, e.g. users_acme, and are removed with
+// their index.
+func WithTenantAliases() Option {
+	return func(l *Loader) error {
+		if l.tenant == nil {
+			l.tenant = &tenantConfig{}
+		}
+		l.tenant.aliases = true
+		return nil
+	}
+}
+
+// WithUniqueIndices makes Load create each fixture index under a name with a
+// random suffix unique to this Loader, such as users_3f9a1c0e, and point an
+// alias with the plain name at it, so application code keeps querying users
+// while each test's data lives in its own index. Clean deletes only this
+// Loader's indices, together with their aliases.
+//
+// An alias points to one loader's index at a time: if it already points to
+// another index, such as one loaded by a concurrent test, Load fails with
+// ErrAliasInUse instead of mixing their data. Tests sharing a fixture name
+// must therefore not run at the same time; tests using different fixtures,
+// or whose code takes the name from IndexName, can run in parallel.
+func WithUniqueIndices() Option {
+	return func(l *Loader) error {
+		suffix, err := newUniqueSuffix()
+		if err != nil {
+			return err
+		}
+		l.uniqueSuffix = suffix
+		return nil
+	}
+}
+
+// WithCheckpoint makes Load record its progress in the file at path: the
+// indices it has completed and, for the index it is loading, how far into
+// each fixture file every document has been indexed. After an interrupted or
+// failed Load, Load(Resume()) continues from there instead of repeating a
+// long ingest. The file is removed when a Load succeeds. Under
+// HandleSignals, an interrupted Load keeps its indices rather than rolling
+// them back.
+func WithCheckpoint(path string) Option {
+	return func(l *Loader) error {
+		if path == "" {
+			return errors.New("checkpoint requires a file path")
+		}
+		l.checkpoint = &checkpointWriter{path: path}
+		return nil
+	}
+}
+
+// WithTemplates renders each YAML document file through text/template
+// before it is parsed, so fixtures can hold values computed when New runs
+// instead of hard-coded dates and IDs:
+//
+//	# users/documents.yml
+//	- _id: "{{ uuid }}"
+//	  created_at: "{{ now "-72h" }}"
+//	  score: {{ randInt 1 100 }}
+//	  region: {{ env "AWS_REGION" }}
+//
+// The built-in functions are now (the time New was called, or the time of
+// WithClock, in RFC 3339, with an optional duration offset), uuid, randInt
+// (min inclusive, max exclusive), and env. funcs adds functions or replaces
+// built-in ones and may be nil. Line numbers in error messages refer to the
+// rendered file.
+func WithTemplates(funcs template.FuncMap) Option {
+	return func(l *Loader) error {
+		l.templates = make(template.FuncMap, len(funcs))
+		maps.Copy(l.templates, funcs)
+		return nil
+	}
+}
+
+// WithClock makes clock the source of the current time for the now function
+// of WithTemplates and the fields of WithTimestampField, in place of
+// time.Now, so tests can freeze time and assert exact values:
+//
+//	frozen := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+//	loader, err := testfixtures.New(client,
+//		testfixtures.Directory("testdata/fixtures"),
+//		testfixtures.WithClock(func() time.Time { return frozen }),
+//		testfixtures.WithTimestampField("created_at"),
+//	)
+func WithClock(clock func() time.Time) Option {
+	return func(l *Loader) error {
+		if clock == nil {
+			return errors.New("clock must not be nil")
+		}
+		l.clock = clock
+		return nil
+	}
+}
+
+// WithTimestampField sets field, for every fixture document that does not
+// define it, to the current time in RFC 3339 format with nanoseconds, in
+// UTC. Like WithFieldGenerator, the time is read on each Load; WithClock
+// decides where it comes from. Nested fields use dot notation.
+func WithTimestampField(field string) Option {
+	return func(l *Loader) error {
+		if field == "" {
+			return errors.New("timestamp field requires a field name")
+		}
+		l.generators = append(l.generators, fieldGenerator{field: field, fn: func() any {
+			return l.now().UTC().Format(time.RFC3339Nano)
+		}})
+		return nil
+	}
+}
+
+// WithExpiryField names the field holding the time each document expires
+// at, in dot notation, for ApplyExpiry. Where a fixture maps the field, it
+// must be mapped as date or date_nanos.
+func WithExpiryField(field string) Option {
+	return func(l *Loader) error {
+		if field == "" {
+			return errors.New("expiry field name must not be empty")
+		}
+		l.expiryField = field
+		return nil
+	}
+}
+
+// RecordHistory makes Load and LoadIndices add a LoadRecord to the hidden
+// HistoryIndex of the cluster after each load, successful or not, noting
+// who ran it, when, how long it took, and a hash of the fixture files.
+// History lists the records, so users of a shared environment can tell
+// when and by whom its data was last refreshed. Clean leaves the index in
+// place.
+func RecordHistory() Option {
+	return func(l *Loader) error {
+		l.recordHistory = true
+		return nil
+	}
+}
+
+// ExpandEnv expands environment variable references in _mapping.json,
+// _settings.json, _settings.yml, and YAML document files before they are
+// parsed, so one fixture set can vary per environment:
+//
+//	{"number_of_replicas": ${ES_REPLICAS:-0}, "analysis": {"analyzer": {"default": {"type": "${ES_ANALYZER}"}}}}
+//
+// ${VAR} is replaced by the value of VAR as written, and ${VAR:-default} by
+// default when VAR is unset or empty. New fails if a variable without a
+// default is not set. $VAR without braces is left alone.
+func ExpandEnv() Option {
+	return func(l *Loader) error {
+		l.expandEnv = true
+		return nil
+	}
+}
+
+// CreatePipelines makes Load create the ingest pipelines that index settings
+// name in index.default_pipeline or index.final_pipeline, from the
+// definitions in the top-level _pipelines directory, before the indices
+// that use them. Pipelines those call with pipeline processors are created
+// first when they are defined there too. New fails if a pipeline named by
+// index settings has no definition. Existing pipelines of the same name are
+// replaced, and Clean leaves pipelines in place.
+func CreatePipelines() Option {
+	return func(l *Loader) error {
+		l.createPipelines = true
+		return nil
+	}
+}
@@ -0,0 +1,426 @@
+package testfixtures
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// packManifestFile is the manifest of a fixture pack: the first entry of the
+// archive, and a file of the directory Unpack writes. Pack leaves it out of
+// the files it bundles, so a directory made by Unpack can be packed again.
+const packManifestFile = "_pack.json"
+
+// PackManifest describes a fixture pack, a gzipped tar archive of a fixtures
+// directory made by Pack for sharing a dataset across repositories.
+type PackManifest struct {
+	Name          string            `json:"name"`                    // Name of the dataset
+	Version       string            `json:"version"`                 // Version of the dataset, such as "1.4.0"
+	Elasticsearch string            `json:"elasticsearch,omitempty"` // Compatible Elasticsearch versions, such as ">=8.12 <9"
+	Files         map[string]string `json:"files"`                   // Hex SHA-256 of each file, by slash-separated path
+}
+
+// Supports reports whether the Elasticsearch version, such as "8.15.2", is
+// within the range of m. A range is a space-separated list of comparisons
+// (=, >, >=, <, or <= and a version with one to three parts, all of which
+// must hold); an empty range supports every version.
+func (m PackManifest) Supports(version string) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("testfixtures: %w", err)
+	}
+	constraints, err := parseVersionRange(m.Elasticsearch)
+	if err != nil {
+		return false, fmt.Errorf("testfixtures: pack %q: %w", m.Name, err)
+	}
+	for _, c := range constraints {
+		if !c.matches(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Pack writes the fixtures directory dir to w as a fixture pack described by
+// manifest, which must have a name and a version. Files are bundled in path
+// order with fixed timestamps, so packing the same fixtures twice gives the
+// same archive. It returns the manifest with the checksum of every file.
+func Pack(w io.Writer, dir string, manifest PackManifest) (PackManifest, error) {
+	if manifest.Name == "" || manifest.Version == "" {
+		return PackManifest{}, errors.New("testfixtures: pack manifest needs a name and a version")
+	}
+	if _, err := parseVersionRange(manifest.Elasticsearch); err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: pack manifest: %w", err)
+	}
+
+	fsys := os.DirFS(dir)
+	manifest.Files = make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || name == packManifestFile {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%s: not a regular file", name)
+		}
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		manifest.Files[name] = sum
+		return nil
+	})
+	if err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: packing %q: %w", dir, err)
+	}
+	if len(manifest.Files) == 0 {
+		return PackManifest{}, fmt.Errorf("testfixtures: packing %q: no fixture files found", dir)
+	}
+
+	if err := writePack(w, fsys, manifest); err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: packing %q: %w", dir, err)
+	}
+	return manifest, nil
+}
+
+// writePack writes the archive of Pack: the manifest, then the files it
+// lists.
+func writePack(w io.Writer, fsys fs.FS, manifest PackManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := tw.WriteHeader(&tar.Header{Name: packManifestFile, Mode: 0o644, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(manifest.Files)) {
+		if err := writePackFile(tw, fsys, name); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writePackFile adds the file name of fsys to tw.
+func writePackFile(tw *tar.Writer, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: info.Size()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Unpack extracts the fixture pack read from r into dir, which must not
+// exist or be empty, and returns its manifest, which is also written to
+// _pack.json in dir. Every file is checked against the checksum of the
+// manifest, and the pack is rejected if any is missing, altered, or not
+// listed; dir is then left as it was.
+func Unpack(r io.Reader, dir string) (PackManifest, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return PackManifest{}, fmt.Errorf("testfixtures: unpacking into %q: directory is not empty", dir)
+	}
+
+	// Extract next to dir, and move the result into place once verified.
+	parent := filepath.Dir(filepath.Clean(dir))
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: unpacking into %q: %w", dir, err)
+	}
+	tmp, err := os.MkdirTemp(parent, ".unpack-*")
+	if err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: unpacking into %q: %w", dir, err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	manifest, err := extractPack(r, tmp)
+	if err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: unpacking into %q: %w", dir, err)
+	}
+	if err := os.Remove(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return PackManifest{}, fmt.Errorf("testfixtures: unpacking into %q: %w", dir, err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return PackManifest{}, fmt.Errorf("testfixtures: unpacking into %q: %w", dir, err)
+	}
+
+	return manifest, nil
+}
+
+// extractPack writes the manifest and the files of the pack read from r to
+// dir, verifying each file against the manifest.
+func extractPack(r io.Reader, dir string) (PackManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return PackManifest{}, fmt.Errorf("reading pack: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != packManifestFile {
+		return PackManifest{}, fmt.Errorf("reading pack: %s must be its first entry", packManifestFile)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return PackManifest{}, fmt.Errorf("reading %s: %w", packManifestFile, err)
+	}
+	var manifest PackManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return PackManifest{}, fmt.Errorf("reading %s: %w", packManifestFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, packManifestFile), data, 0o644); err != nil {
+		return PackManifest{}, err
+	}
+
+	seen := make(map[string]bool, len(manifest.Files))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return PackManifest{}, fmt.Errorf("reading pack: %w", err)
+		}
+		name := hdr.Name
+		want, ok := manifest.Files[name]
+		switch {
+		case hdr.Typeflag != tar.TypeReg:
+			return PackManifest{}, fmt.Errorf("%s: not a regular file", name)
+		case !fs.ValidPath(name) || name == packManifestFile:
+			return PackManifest{}, fmt.Errorf("%s: invalid file name", name)
+		case !ok:
+			return PackManifest{}, fmt.Errorf("%s: not listed in %s", name, packManifestFile)
+		case seen[name]:
+			return PackManifest{}, fmt.Errorf("%s: packed twice", name)
+		}
+		seen[name] = true
+
+		sum, err := writeUnpackedFile(filepath.Join(dir, filepath.FromSlash(name)), tr)
+		if err != nil {
+			return PackManifest{}, fmt.Errorf("%s: %w", name, err)
+		}
+		if sum != want {
+			return PackManifest{}, fmt.Errorf("%s: checksum %s does not match %s in %s", name, sum, want, packManifestFile)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(manifest.Files)) {
+		if !seen[name] {
+			return PackManifest{}, fmt.Errorf("%s: listed in %s but missing from the pack", name, packManifestFile)
+		}
+	}
+
+	return manifest, nil
+}
+
+// verifyPacks runs verifyPack on the fixtures, or on each source of Compose,
+// before templates or environment variables change their contents.
+func (l *Loader) verifyPacks() error {
+	layers, ok := l.fsys.(unionFS)
+	if !ok {
+		layers = unionFS{l.fsys}
+	}
+	for _, layer := range layers {
+		dir := "."
+		if !ok {
+			dir = l.dir
+		}
+		warnings, err := verifyPack(layer, dir)
+		if err != nil {
+			return err
+		}
+		l.warnings = append(l.warnings, warnings...)
+	}
+	return nil
+}
+
+// verifyPack checks the fixtures directory dir of fsys against its
+// _pack.json, if it has one, so a pack that was altered or only partly
+// copied fails before anything is loaded rather than loading with documents
+// missing. Files the manifest does not list are returned as warnings, since
+// they may be deliberate additions.
+func verifyPack(fsys fs.FS, dir string) ([]string, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, packManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", packManifestFile, err)
+	}
+	var manifest PackManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", packManifestFile, err)
+	}
+
+	var (
+		errs     []error
+		warnings []string
+		seen     = make(map[string]bool, len(manifest.Files))
+	)
+	err = fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimPrefix(name, dir+"/")
+		if dir == "." {
+			rel = name
+		}
+		if rel == packManifestFile {
+			return nil
+		}
+		want, ok := manifest.Files[rel]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: not in %s of pack %s %s, so it is loaded unverified", rel, packManifestFile, manifest.Name, manifest.Version))
+			return nil
+		}
+		seen[rel] = true
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if sum != want {
+			errs = append(errs, fmt.Errorf("%s: checksum %s does not match %s in %s", rel, sum, want, packManifestFile))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verifying pack: %w", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(manifest.Files)) {
+		if !seen[name] {
+			errs = append(errs, fmt.Errorf("%s: listed in %s but missing", name, packManifestFile))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("pack %s %s is corrupt or incomplete: %w", manifest.Name, manifest.Version, errors.Join(errs...))
+	}
+
+	return warnings, nil
+}
+
+// writeUnpackedFile writes the contents of r to the file name, creating its
+// directory, and returns their hex SHA-256.
+func writeUnpackedFile(name string, r io.Reader) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the hex SHA-256 of the file name of fsys.
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// versionConstraint is a comparison of a PackManifest range.
+type versionConstraint struct {
+	op      string
+	version [3]int
+}
+
+// matches reports whether v satisfies c.
+func (c versionConstraint) matches(v [3]int) bool {
+	cmp := slices.Compare(v[:], c.version[:])
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// parseVersionRange parses the Elasticsearch range of a PackManifest.
+func parseVersionRange(s string) ([]versionConstraint, error) {
+	var constraints []versionConstraint
+	for _, field := range strings.Fields(s) {
+		version := strings.TrimLeft(field, "<>=")
+		op := strings.TrimSuffix(field, version)
+		if !slices.Contains([]string{"", "=", ">", ">=", "<", "<="}, op) {
+			return nil, fmt.Errorf("invalid elasticsearch range %q: unknown comparison %q", s, op)
+		}
+		v, err := parseVersion(version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid elasticsearch range %q: %w", s, err)
+		}
+		constraints = append(constraints, versionConstraint{op: op, version: v})
+	}
+	return constraints, nil
+}
+
+// parseVersion parses a version with one to three numeric parts, such as
+// "8", "8.12", or "8.12.1"; missing parts are zero. A pre-release suffix
+// such as "-SNAPSHOT" is ignored.
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+	core, _, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
@@ -0,0 +1,240 @@
+package testfixtures
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPackUnpack(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json":    `{"properties": {"name": {"type": "keyword"}}}`,
+		"users/documents.yml":    "- _id: \"1\"\n  name: Alice\n",
+		"_pipelines/users.json":  `{"processors": []}`,
+		"_cluster_settings.json": `{"search.max_buckets": 20000}`,
+	})
+
+	var first, second bytes.Buffer
+	manifest, err := Pack(&first, dir, PackManifest{Name: "users", Version: "1.2.0", Elasticsearch: ">=8.12 <9"})
+	if err != nil {
+		t.Fatalf("Pack() error: %v", err)
+	}
+	if len(manifest.Files) != 4 || len(manifest.Files["users/documents.yml"]) != 64 {
+		t.Errorf("expected a checksum for each of the 4 files, got %v", manifest.Files)
+	}
+	if _, err := Pack(&second, dir, PackManifest{Name: "users", Version: "1.2.0", Elasticsearch: ">=8.12 <9"}); err != nil {
+		t.Fatalf("Pack() error: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("expected packing the same fixtures twice to give the same archive")
+	}
+
+	dest := filepath.Join(t.TempDir(), "fixtures")
+	got, err := Unpack(bytes.NewReader(first.Bytes()), dest)
+	if err != nil {
+		t.Fatalf("Unpack() error: %v", err)
+	}
+	if got.Name != "users" || got.Version != "1.2.0" || got.Elasticsearch != ">=8.12 <9" || len(got.Files) != 4 {
+		t.Errorf("unexpected manifest %+v", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "users", "documents.yml"))
+	if err != nil || string(data) != "- _id: \"1\"\n  name: Alice\n" {
+		t.Errorf("expected the documents to be unpacked, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, packManifestFile)); err != nil {
+		t.Errorf("expected the manifest to be written: %v", err)
+	}
+
+	// The unpacked directory packs to the same archive, and loads.
+	var again bytes.Buffer
+	if _, err := Pack(&again, dest, PackManifest{Name: "users", Version: "1.2.0", Elasticsearch: ">=8.12 <9"}); err != nil {
+		t.Fatalf("Pack() of the unpacked fixtures error: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), again.Bytes()) {
+		t.Error("expected the unpacked fixtures to pack to the same archive")
+	}
+	if _, err := New(newOfflineClient(t), Directory(dest)); err != nil {
+		t.Errorf("New() on the unpacked fixtures error: %v", err)
+	}
+
+	if _, err := Unpack(bytes.NewReader(first.Bytes()), dest); err == nil || !strings.Contains(err.Error(), "directory is not empty") {
+		t.Errorf("expected unpacking into a non-empty directory to fail, got %v", err)
+	}
+}
+
+func TestPack_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": "- _id: \"1\"\n"})
+
+	tests := []struct {
+		manifest PackManifest
+		want     string
+	}{
+		{PackManifest{Version: "1.0.0"}, "needs a name and a version"},
+		{PackManifest{Name: "users"}, "needs a name and a version"},
+		{PackManifest{Name: "users", Version: "1.0.0", Elasticsearch: "~8.12"}, `invalid elasticsearch range "~8.12"`},
+	}
+	for _, tt := range tests {
+		if _, err := Pack(&bytes.Buffer{}, dir, tt.manifest); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Pack(%+v) error = %v, want it to contain %q", tt.manifest, err, tt.want)
+		}
+	}
+	if _, err := Pack(&bytes.Buffer{}, t.TempDir(), PackManifest{Name: "users", Version: "1.0.0"}); err == nil {
+		t.Error("expected an error for a directory without fixture files")
+	}
+}
+
+// writeTestPack writes a pack with the manifest data and the given files.
+func writeTestPack(t *testing.T, manifest string, files ...[2]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range append([][2]string{{packManifestFile, manifest}}, files...) {
+		if err := tw.WriteHeader(&tar.Header{Name: f[0], Mode: 0o644, Size: int64(len(f[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnpack_Rejected(t *testing.T) {
+	// SHA-256 of "- _id: 1\n"
+	const sum = "9218c871e134ad38ded6fdb9baed8d333d24f8557939af8a30c7a3d60d21766a"
+	docs := `{"name":"users","version":"1","files":{"users/documents.yml":"` + sum + `"}}`
+
+	tests := []struct {
+		name string
+		pack []byte
+		want string
+	}{
+		{name: "altered", pack: writeTestPack(t, docs, [2]string{"users/documents.yml", "- _id: 2\n"}), want: "users/documents.yml: checksum"},
+		{name: "missing", pack: writeTestPack(t, docs), want: "users/documents.yml: listed in _pack.json but missing from the pack"},
+		{name: "unlisted", pack: writeTestPack(t, `{"name":"users","version":"1","files":{}}`, [2]string{"users/documents.yml", "- _id: 1\n"}), want: "users/documents.yml: not listed in _pack.json"},
+		{name: "outside", pack: writeTestPack(t, `{"name":"users","version":"1","files":{"../evil.yml":"x"}}`, [2]string{"../evil.yml", "x"}), want: "../evil.yml: invalid file name"},
+		{name: "no manifest", pack: writeTestPack(t, "")[:0], want: "reading pack"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "fixtures")
+			_, err := Unpack(bytes.NewReader(tt.pack), dest)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+			if entries, _ := os.ReadDir(parent); len(entries) != 0 {
+				t.Errorf("expected nothing to be left behind, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestPackManifest_Supports(t *testing.T) {
+	tests := []struct {
+		versions string
+		version  string
+		want     bool
+	}{
+		{"", "7.17.0", true},
+		{">=8.12 <9", "8.12.0", true},
+		{">=8.12 <9", "8.19.2", true},
+		{">=8.12 <9", "8.11.4", false},
+		{">=8.12 <9", "9.0.0", false},
+		{">8.15.1", "8.15.1", false},
+		{"<=8.15", "8.15.0-SNAPSHOT", true},
+		{"8.15.1", "8.15.1", true},
+	}
+	for _, tt := range tests {
+		got, err := PackManifest{Elasticsearch: tt.versions}.Supports(tt.version)
+		if err != nil {
+			t.Fatalf("Supports(%q) with %q error: %v", tt.version, tt.versions, err)
+		}
+		if got != tt.want {
+			t.Errorf("Supports(%q) with %q = %v, want %v", tt.version, tt.versions, got, tt.want)
+		}
+	}
+
+	if _, err := (PackManifest{}).Supports("eight"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
+
+func TestNew_VerifiesPack(t *testing.T) {
+	src := t.TempDir()
+	writeFixtureFiles(t, src, map[string]string{
+		"users/documents.yml":  "- _id: \"1\"\n",
+		"orders/documents.yml": "- _id: \"1\"\n",
+	})
+	var pack bytes.Buffer
+	if _, err := Pack(&pack, src, PackManifest{Name: "shop", Version: "2.0.0"}); err != nil {
+		t.Fatalf("Pack() error: %v", err)
+	}
+	unpacked := func(t *testing.T) string {
+		dir := filepath.Join(t.TempDir(), "fixtures")
+		if _, err := Unpack(bytes.NewReader(pack.Bytes()), dir); err != nil {
+			t.Fatalf("Unpack() error: %v", err)
+		}
+		return dir
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		dir := unpacked(t)
+		writeFixtureFiles(t, dir, map[string]string{"users/extra.yml": "- _id: \"2\"\n"})
+
+		l, err := New(newOfflineClient(t), Directory(dir))
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		want := "users/extra.yml: not in _pack.json of pack shop 2.0.0, so it is loaded unverified"
+		if !slices.Contains(l.Warnings(), want) {
+			t.Errorf("expected warning %q, got %v", want, l.Warnings())
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		dir := unpacked(t)
+		writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": "- _id: \"1\n"})
+		if err := os.Remove(filepath.Join(dir, "orders", "documents.yml")); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := New(newOfflineClient(t), Directory(dir))
+		for _, want := range []string{
+			"pack shop 2.0.0 is corrupt or incomplete",
+			"users/documents.yml: checksum",
+			"orders/documents.yml: listed in _pack.json but missing",
+		} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected an error containing %q, got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("composed", func(t *testing.T) {
+		dir := unpacked(t)
+		local := t.TempDir()
+		writeFixtureFiles(t, local, map[string]string{"users/documents.yml": "- _id: \"3\"\n"})
+
+		// A later source replacing a packed file does not alter the pack.
+		if _, err := New(newOfflineClient(t), Compose(Directory(dir), Directory(local))); err != nil {
+			t.Errorf("New() error: %v", err)
+		}
+	})
+}
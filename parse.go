@@ -0,0 +1,87 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// IndexFixture is an index directory of a fixtures directory, as Parse reads
+// it for tools that work with fixtures without loading them.
+type IndexFixture struct {
+	Name       string            // Directory name, which is the index name
+	Mapping    json.RawMessage   // _mapping.json, with _runtime_mappings.json merged in and _common inherited (may be nil)
+	Settings   json.RawMessage   // _settings.json or _settings.yml, with _common inherited (may be nil)
+	Aliases    []IndexAlias      // Aliases of _aliases.json, in file order
+	Documents  []Document        // Documents of the document files, in load order
+	Streams    []string          // NDJSON files, relative to the fixtures directory, which Load streams unparsed
+	References map[string]string // Document fields mapped to the index whose _id values they refer to, from _config.yml
+	State      string            // State of _config.yml the index is left in: open, closed, or read_only (empty for open)
+	Strategy   Strategy          // Strategy of _config.yml (empty for that of WithStrategy)
+
+	// Alias is set for an alias fixture, a directory that is created as an
+	// alias over other fixture indices and has no mapping, settings, or
+	// documents of its own.
+	Alias *AliasFixture
+}
+
+// IndexAlias is an alias of an index fixture, declared in its _aliases.json.
+type IndexAlias struct {
+	Name string          // Alias name
+	Body json.RawMessage // Filter, routing, is_write_index, and is_hidden (may be nil)
+}
+
+// AliasFixture is the alias section of an alias fixture's _config.yml.
+type AliasFixture struct {
+	Indices    []string        // Fixture indices the alias points to
+	WriteIndex string          // Index of Indices that receives writes through the alias (may be empty)
+	Body       json.RawMessage // Filter and routing of the alias (may be nil)
+}
+
+// Parse reads the fixtures directory dir the way New does, returning its
+// index and alias fixtures in directory order, so that linters, code
+// generators, and custom loaders can use the fixture definitions without
+// reimplementing the directory conventions. Variables in settings, traits,
+// _generate, and _repeat are expanded; what depends on options of New is
+// not, so templates and environment variables are left as written, as are
+// {$vector: seed} values. Parse does not check the fixtures against each
+// other the way New does, such as that alias fixtures point to index
+// fixtures.
+func Parse(dir string) ([]IndexFixture, error) {
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: fixtures %q: %w", dir, err)
+	}
+
+	parsed := make([]IndexFixture, len(fixtures))
+	for i, f := range fixtures {
+		parsed[i] = f.export()
+	}
+	return parsed, nil
+}
+
+// export returns f as an IndexFixture.
+func (f *indexFixture) export() IndexFixture {
+	p := IndexFixture{
+		Name:       f.name,
+		Mapping:    f.mapping,
+		Settings:   f.settings,
+		Documents:  slices.Clone(f.documents),
+		Streams:    slices.Clone(f.streams),
+		References: f.config.References,
+		State:      f.config.State,
+		Strategy:   Strategy(f.config.Strategy),
+	}
+	for _, a := range f.aliases {
+		p.Aliases = append(p.Aliases, IndexAlias{Name: a.name, Body: a.body})
+	}
+	if alias := f.config.Alias; alias != nil {
+		p.Alias = &AliasFixture{
+			Indices:    slices.Clone(alias.Indices),
+			WriteIndex: alias.WriteIndex,
+			Body:       f.aliasBody,
+		}
+	}
+	return p
+}
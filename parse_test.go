@@ -0,0 +1,79 @@
+package testfixtures
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_common/_settings.json": `{"number_of_shards": 1}`,
+		"orders/_config.yml":     "inherit_common: true\nreferences: {user_id: users}\nstrategy: truncate\n",
+		"orders/_mapping.json":   `{"properties":{"user_id":{"type":"keyword"}}}`,
+		"orders/_aliases.json":   `{"orders-read":{},"orders-write":{"is_write_index":true}}`,
+		"orders/documents.yml":   "- {_id: o1, user_id: u1}\n- {_id: o0, _action: delete}\n",
+		"orders/more.ndjson":     `{"user_id":"u2"}` + "\n",
+		"users/documents.yml":    "- {_id: u1, name: Alice}\n",
+		"acme/_config.yml":       "alias:\n  indices: [orders]\n  filter: {term: {user_id: u1}}\n",
+	})
+
+	fixtures, err := Parse(dir)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	var names []string
+	for _, f := range fixtures {
+		names = append(names, f.Name)
+	}
+	if want := []string{"acme", "orders", "users"}; !slices.Equal(names, want) {
+		t.Fatalf("fixtures = %v, want %v", names, want)
+	}
+
+	acme := fixtures[0]
+	if acme.Alias == nil || !slices.Equal(acme.Alias.Indices, []string{"orders"}) {
+		t.Fatalf("acme.Alias = %+v, want an alias over orders", acme.Alias)
+	}
+	if got := string(acme.Alias.Body); got != `{"filter":{"term":{"user_id":"u1"}}}` {
+		t.Errorf("acme.Alias.Body = %s", got)
+	}
+
+	orders := fixtures[1]
+	if orders.Alias != nil {
+		t.Errorf("orders.Alias = %+v, want nil", orders.Alias)
+	}
+	if !strings.Contains(string(orders.Settings), `"number_of_shards"`) {
+		t.Errorf("orders.Settings = %s, want the _common settings", orders.Settings)
+	}
+	if orders.References["user_id"] != "users" || orders.Strategy != StrategyTruncate {
+		t.Errorf("orders config = %v, %q", orders.References, orders.Strategy)
+	}
+	if len(orders.Aliases) != 2 || orders.Aliases[0].Name != "orders-read" || orders.Aliases[1].Name != "orders-write" {
+		t.Errorf("orders.Aliases = %+v", orders.Aliases)
+	}
+	if len(orders.Documents) != 2 {
+		t.Fatalf("orders.Documents = %+v, want 2 documents", orders.Documents)
+	}
+	if d := orders.Documents[0]; d.ID != "o1" || d.Action() != "index" || d.Location() != "orders/documents.yml:1" {
+		t.Errorf("first document = %s %s at %s", d.ID, d.Action(), d.Location())
+	}
+	if d := orders.Documents[1]; d.ID != "o0" || d.Action() != "delete" {
+		t.Errorf("second document = %s %s", d.ID, d.Action())
+	}
+	if want := []string{"orders/more.ndjson"}; !slices.Equal(orders.Streams, want) {
+		t.Errorf("orders.Streams = %v, want %v", orders.Streams, want)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json": `{"properties":`,
+	})
+
+	_, err := Parse(dir)
+	if err == nil || !strings.Contains(err.Error(), `parsing index "users"`) {
+		t.Fatalf("Parse() error = %v, want one naming the index", err)
+	}
+}
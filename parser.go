@@ -1,34 +1,61 @@
 package testfixtures
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	mappingFile  = "_mapping.json"
-	settingsFile = "_settings.json"
+	mappingFile         = "_mapping.json"
+	settingsFile        = "_settings.json"
+	settingsYAMLFile    = "_settings.yml"
+	runtimeMappingsFile = "_runtime_mappings.json"
+	aliasesFile         = "_aliases.json"
 )
 
-// parseFixtures scans the fixtures directory and parses all index subdirectories.
-func parseFixtures(dir string) ([]*indexFixture, error) {
-	entries, err := os.ReadDir(dir)
+// commonDir is the top-level directory holding the mapping and settings that
+// index fixtures can inherit with inherit_common in _config.yml.
+const commonDir = "_common"
+
+// fixtureRoot holds definitions shared by all index fixtures, read from the
+// top level of the fixtures directory.
+type fixtureRoot struct {
+	commonMapping  json.RawMessage // Contents of _common/_mapping.json (may be nil)
+	commonSettings json.RawMessage // Contents of _common/_settings.json (may be nil)
+	hasCommon      bool            // Whether the _common directory exists
+
+	variables map[string]json.RawMessage // Contents of _variables.yml, referenced from settings
+	traits    map[string]json.RawMessage // Contents of _traits.yml, mixed into documents
+}
+
+// parseFixtures scans the fixtures directory dir of fsys and parses all index
+// subdirectories. Directories starting with "_" hold shared definitions
+// rather than indices.
+func parseFixtures(fsys fs.FS, dir string) ([]*indexFixture, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
-		return nil, fmt.Errorf("reading fixtures directory %q: %w", dir, err)
+		return nil, fmt.Errorf("reading fixtures directory: %w", err)
+	}
+
+	root, err := parseFixtureRoot(fsys, dir)
+	if err != nil {
+		return nil, err
 	}
 
 	var fixtures []*indexFixture
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), "_") {
 			continue
 		}
 
-		f, err := parseIndexDir(filepath.Join(dir, entry.Name()), entry.Name())
+		f, err := parseIndexDir(fsys, path.Join(dir, entry.Name()), entry.Name(), root)
 		if err != nil {
 			return nil, fmt.Errorf("parsing index %q: %w", entry.Name(), err)
 		}
@@ -36,74 +63,204 @@ func parseFixtures(dir string) ([]*indexFixture, error) {
 	}
 
 	if len(fixtures) == 0 {
-		return nil, fmt.Errorf("no index directories found in %q", dir)
+		return nil, errors.New("no index directories found")
 	}
 
 	return fixtures, nil
 }
 
+// parseFixtureRoot reads the shared definitions at the top of the fixtures directory.
+func parseFixtureRoot(fsys fs.FS, dir string) (*fixtureRoot, error) {
+	vars, err := readVariables(fsys, path.Join(dir, variablesFile))
+	if err != nil {
+		return nil, err
+	}
+	traits, err := readTraits(fsys, path.Join(dir, traitsFile))
+	if err != nil {
+		return nil, err
+	}
+	root := &fixtureRoot{variables: vars, traits: traits}
+
+	common := path.Join(dir, commonDir)
+	if info, err := fs.Stat(fsys, common); err == nil && info.IsDir() {
+		root.hasCommon = true
+
+		mapping, err := readJSONFile(fsys, path.Join(common, mappingFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("reading %s/%s: %w", commonDir, mappingFile, err)
+		}
+		root.commonMapping = mapping
+
+		settings, err := readSettingsFile(fsys, common)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", commonDir, err)
+		}
+		root.commonSettings = settings
+	}
+
+	return root, nil
+}
+
 // parseIndexDir parses a single index directory containing schema and document files.
-func parseIndexDir(dir string, name string) (*indexFixture, error) {
+func parseIndexDir(fsys fs.FS, dir string, name string, root *fixtureRoot) (*indexFixture, error) {
 	f := &indexFixture{name: name}
 
-	mapping, err := readJSONFile(filepath.Join(dir, mappingFile))
-	if err != nil && !os.IsNotExist(err) {
+	cfg, err := readIndexConfig(fsys, path.Join(dir, configFile))
+	if err != nil {
+		return nil, err
+	}
+	f.config = cfg
+	if f.isAlias() {
+		return parseAliasDir(fsys, dir, f)
+	}
+
+	mapping, err := readJSONFile(fsys, path.Join(dir, mappingFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("reading %s: %w", mappingFile, err)
 	}
 	f.mapping = mapping
 
-	settings, err := readJSONFile(filepath.Join(dir, settingsFile))
-	if err != nil && !os.IsNotExist(err) {
-		return nil, fmt.Errorf("reading %s: %w", settingsFile, err)
+	settings, err := readSettingsFile(fsys, dir)
+	if err != nil {
+		return nil, err
 	}
 	f.settings = settings
 
-	docs, err := parseDocumentFiles(dir)
+	aliases, err := readIndexAliases(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	f.aliases = aliases
+
+	if cfg.InheritCommon {
+		if !root.hasCommon {
+			return nil, fmt.Errorf("%s sets inherit_common but there is no %s directory", configFile, commonDir)
+		}
+		if f.mapping, err = mergeJSONObjects(root.commonMapping, f.mapping); err != nil {
+			return nil, fmt.Errorf("merging %s with %s/%s: %w", mappingFile, commonDir, mappingFile, err)
+		}
+		if f.settings, err = mergeJSONObjects(root.commonSettings, f.settings); err != nil {
+			return nil, fmt.Errorf("merging settings with %s settings: %w", commonDir, err)
+		}
+	}
+
+	if f.settings != nil {
+		if f.settings, err = expandVariables(f.settings, root.variables); err != nil {
+			return nil, fmt.Errorf("expanding settings: %w", err)
+		}
+	}
+
+	runtime, err := readJSONFile(fsys, path.Join(dir, runtimeMappingsFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading %s: %w", runtimeMappingsFile, err)
+	}
+	if runtime != nil {
+		merged, names, err := mergeRuntimeMappings(f.mapping, runtime)
+		if err != nil {
+			return nil, err
+		}
+		f.mapping = merged
+		f.runtimeFields = names
+	}
+
+	docs, err := parseDocumentFiles(fsys, dir, root.traits)
 	if err != nil {
 		return nil, err
 	}
 	f.documents = docs
 
+	features, err := parseGeoJSONFiles(fsys, dir, f)
+	if err != nil {
+		return nil, err
+	}
+	f.documents = append(f.documents, features...)
+
+	streams, err := findNDJSONFiles(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	f.streams = streams
+
+	expectations, err := parseExpectations(fsys, path.Join(dir, expectationsDir))
+	if err != nil {
+		return nil, err
+	}
+	f.expectations = expectations
+
+	counts, err := readCountExpectations(fsys, path.Join(dir, expectFile))
+	if err != nil {
+		return nil, err
+	}
+	f.counts = counts
+
 	return f, nil
 }
 
 // readJSONFile reads a JSON file and returns its content as json.RawMessage.
-// Returns nil, nil if the file does not exist (os.IsNotExist).
-func readJSONFile(path string) (json.RawMessage, error) {
-	data, err := os.ReadFile(path)
+// Errors from reading the file are returned unwrapped so callers can check
+// for fs.ErrNotExist.
+func readJSONFile(fsys fs.FS, name string) (json.RawMessage, error) {
+	data, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, err
 	}
 
 	if !json.Valid(data) {
-		return nil, fmt.Errorf("invalid JSON in %q", path)
+		return nil, fmt.Errorf("invalid JSON in %q", name)
 	}
 
 	return json.RawMessage(data), nil
 }
 
+// readSettingsFile reads an index's settings from _settings.json or
+// _settings.yml, which may not both exist. Returns nil, nil if neither does.
+func readSettingsFile(fsys fs.FS, dir string) (json.RawMessage, error) {
+	settings, err := readJSONFile(fsys, path.Join(dir, settingsFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading %s: %w", settingsFile, err)
+	}
+
+	data, err := fs.ReadFile(fsys, path.Join(dir, settingsYAMLFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", settingsYAMLFile, err)
+	}
+	if settings != nil {
+		return nil, fmt.Errorf("both %s and %s exist; keep only one", settingsFile, settingsYAMLFile)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", settingsYAMLFile, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	return yamlToJSON(&root)
+}
+
 // parseDocumentFiles finds and parses all YAML document files in the directory.
 // Document files are *.yml files that do not start with "_".
-func parseDocumentFiles(dir string) ([]document, error) {
-	entries, err := os.ReadDir(dir)
+func parseDocumentFiles(fsys fs.FS, dir string, traits map[string]json.RawMessage) ([]Document, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, fmt.Errorf("reading directory %q: %w", dir, err)
 	}
 
-	var docs []document
+	var docs []Document
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml") {
-			continue
-		}
-		if strings.HasPrefix(name, "_") {
+		if !isDocumentFileName(name) || strings.HasPrefix(name, "_") {
 			continue
 		}
 
-		fileDocs, err := parseYAMLDocuments(filepath.Join(dir, name))
+		fileDocs, err := parseYAMLDocuments(fsys, path.Join(dir, name), path.Join(path.Base(dir), name), traits)
 		if err != nil {
 			return nil, fmt.Errorf("parsing document file %q: %w", name, err)
 		}
@@ -113,31 +270,196 @@ func parseDocumentFiles(dir string) ([]document, error) {
 	return docs, nil
 }
 
-// parseYAMLDocuments parses a YAML file containing an array of documents.
-func parseYAMLDocuments(path string) ([]document, error) {
-	data, err := os.ReadFile(path)
+// findNDJSONFiles returns the paths of all NDJSON document files in the directory.
+// NDJSON files are *.ndjson files that do not start with "_". They are not read
+// here; their lines are streamed into the index at load time.
+func findNDJSONFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory %q: %w", dir, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".ndjson") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		paths = append(paths, path.Join(dir, name))
+	}
+
+	return paths, nil
+}
+
+// isDocumentFileName reports whether name has the extension of a document
+// file parsed by parseYAMLDocuments: .yml, .yaml, or .json.
+func isDocumentFileName(name string) bool {
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".json")
+}
+
+// parseYAMLDocuments parses a YAML file containing an array of documents,
+// expanding _generate and _repeat entries into the documents they describe.
+// A .json file holds a JSON array of documents instead, read into the same
+// nodes so its documents keep their field order and numbers the same way.
+// The documents record display as their source file for error messages.
+func parseYAMLDocuments(fsys fs.FS, name, display string, traits map[string]json.RawMessage) ([]Document, error) {
+	data, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, fmt.Errorf("reading file: %w", err)
 	}
 
-	var rawDocs []map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawDocs); err != nil {
+	var root yaml.Node
+	format := "YAML"
+	if strings.HasSuffix(name, ".json") {
+		format = "JSON"
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, nil
+		}
+		node, err := jsonToYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshaling JSON: %w", err)
+		}
+		root = *node
+	} else if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("unmarshaling YAML: %w", err)
 	}
 
-	docs := make([]document, 0, len(rawDocs))
-	for _, raw := range rawDocs {
-		doc := document{
-			Body: raw,
+	seq := &root
+	if seq.Kind == yaml.DocumentNode && len(seq.Content) > 0 {
+		seq = seq.Content[0]
+	}
+	switch {
+	case seq.Kind == 0 || seq.Kind == yaml.DocumentNode || seq.ShortTag() == "!!null":
+		return nil, nil
+	case seq.Kind != yaml.SequenceNode:
+		return nil, fmt.Errorf("unmarshaling %s: line %d: expected a sequence of documents", format, seq.Line)
+	}
+
+	docs := make([]Document, 0, len(seq.Content))
+	for i, item := range seq.Content {
+		nodes, err := expandEntry(item)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		for j, node := range nodes {
+			doc, err := parseYAMLDocument(node, traits)
+			if err != nil {
+				if len(nodes) > 1 {
+					return nil, fmt.Errorf("document %d: copy %d: %w", i, j+1, err)
+				}
+				return nil, fmt.Errorf("document %d: %w", i, err)
+			}
+			doc.file, doc.line, doc.pos = display, item.Line, len(docs)+1
+			docs = append(docs, doc)
 		}
+	}
 
-		if id, ok := raw["_id"]; ok {
-			doc.ID = fmt.Sprintf("%v", id)
-			delete(doc.Body, "_id")
+	return docs, nil
+}
+
+// expandEntry returns the documents a document file entry stands for: the
+// generated documents of a _generate entry, the copies of a _repeat entry,
+// or the entry itself.
+func expandEntry(item *yaml.Node) ([]*yaml.Node, error) {
+	spec, err := generationEntry(item)
+	if err != nil {
+		return nil, err
+	}
+	if spec != nil {
+		return spec.generate()
+	}
+
+	count, err := repeatCount(item)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return repeat(item, count), nil
+	}
+	return []*yaml.Node{item}, nil
+}
+
+// routingKey is the document key holding a custom routing value.
+const routingKey = "_routing"
+
+// parentKey is the document key naming the parent of a join field child.
+const parentKey = "_parent"
+
+// parseYAMLDocument converts a single YAML mapping into a document,
+// extracting the _id, _routing, _parent, and _action fields and mixing in
+// any _traits it lists.
+func parseYAMLDocument(node *yaml.Node, traits map[string]json.RawMessage) (Document, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return Document{}, fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+
+	var (
+		doc       Document
+		docTraits []string
+	)
+	body := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if k.Value == "_id" && !isMergeKey(k) {
+			if v.Kind != yaml.ScalarNode {
+				return Document{}, fmt.Errorf("line %d: _id must be a scalar", v.Line)
+			}
+			doc.ID = v.Value
+			continue
 		}
+		if k.Value == routingKey && !isMergeKey(k) {
+			if v.Kind != yaml.ScalarNode {
+				return Document{}, fmt.Errorf("line %d: %s must be a scalar", v.Line, routingKey)
+			}
+			doc.Routing = v.Value
+			continue
+		}
+		if k.Value == parentKey && !isMergeKey(k) {
+			if v.Kind != yaml.ScalarNode || v.Value == "" {
+				return Document{}, fmt.Errorf("line %d: %s must be the _id of the parent document", v.Line, parentKey)
+			}
+			doc.parent = v.Value
+			continue
+		}
+		if k.Value == actionKey && !isMergeKey(k) {
+			if v.Kind != yaml.ScalarNode {
+				return Document{}, fmt.Errorf("line %d: %s must be a scalar", v.Line, actionKey)
+			}
+			action, err := parseDocumentAction(v.Value)
+			if err != nil {
+				return Document{}, fmt.Errorf("line %d: %w", v.Line, err)
+			}
+			doc.action = action
+			continue
+		}
+		if k.Value == traitsKey && !isMergeKey(k) {
+			names, err := traitNames(v)
+			if err != nil {
+				return Document{}, err
+			}
+			docTraits = names
+			continue
+		}
+		body.Content = append(body.Content, k, v)
+	}
 
-		docs = append(docs, doc)
+	encoded, err := yamlToJSON(body)
+	if err != nil {
+		return Document{}, fmt.Errorf("encoding as JSON: %w", err)
 	}
+	doc.Source = encoded
 
-	return docs, nil
+	if len(docTraits) > 0 {
+		if doc.Source, err = applyTraits(doc.Source, docTraits, traits); err != nil {
+			return Document{}, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+	}
+	if err := checkDocumentAction(doc); err != nil {
+		return Document{}, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+
+	return doc, nil
 }
@@ -1,13 +1,28 @@
 package testfixtures
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
+// decodeBody unmarshals a parsed document body for assertions.
+func decodeBody(t *testing.T, doc Document) map[string]interface{} {
+	t.Helper()
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(doc.Source, &body); err != nil {
+		t.Fatalf("decoding document body: %v", err)
+	}
+
+	return body
+}
+
 func TestParseFixtures(t *testing.T) {
-	fixtures, err := parseFixtures("testdata/fixtures")
+	fixtures, err := parseFixtures(os.DirFS("testdata/fixtures"), ".")
 	if err != nil {
 		t.Fatalf("parseFixtures() error: %v", err)
 	}
@@ -38,6 +53,11 @@ func TestParseFixtures(t *testing.T) {
 			t.Errorf("expected 2 documents, got %d", len(users.documents))
 		}
 
+		// Verify source locations are recorded for error messages
+		if loc := users.documents[1].Location(); loc != "users/documents.yml:6" {
+			t.Errorf("expected second document location 'users/documents.yml:6', got %q", loc)
+		}
+
 		// Verify document IDs
 		if users.documents[0].ID != "1" {
 			t.Errorf("expected first document ID to be '1', got %q", users.documents[0].ID)
@@ -47,13 +67,14 @@ func TestParseFixtures(t *testing.T) {
 		}
 
 		// Verify _id is removed from body
-		if _, ok := users.documents[0].Body["_id"]; ok {
+		body := decodeBody(t, users.documents[0])
+		if _, ok := body["_id"]; ok {
 			t.Error("_id should be removed from document body")
 		}
 
 		// Verify document body fields
-		if name, ok := users.documents[0].Body["name"].(string); !ok || name != "Alice" {
-			t.Errorf("expected name 'Alice', got %v", users.documents[0].Body["name"])
+		if name, ok := body["name"].(string); !ok || name != "Alice" {
+			t.Errorf("expected name 'Alice', got %v", body["name"])
 		}
 	})
 
@@ -80,14 +101,14 @@ func TestParseFixtures(t *testing.T) {
 
 func TestParseFixtures_EmptyDirectory(t *testing.T) {
 	dir := t.TempDir()
-	_, err := parseFixtures(dir)
+	_, err := parseFixtures(os.DirFS(dir), ".")
 	if err == nil {
 		t.Fatal("expected error for empty directory")
 	}
 }
 
 func TestParseFixtures_NonExistentDirectory(t *testing.T) {
-	_, err := parseFixtures("/nonexistent/path")
+	_, err := parseFixtures(os.DirFS("/nonexistent/path"), ".")
 	if err == nil {
 		t.Fatal("expected error for non-existent directory")
 	}
@@ -103,7 +124,7 @@ func TestParseFixtures_InvalidJSON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err := parseFixtures(dir)
+	_, err := parseFixtures(os.DirFS(dir), ".")
 	if err == nil {
 		t.Fatal("expected error for invalid JSON")
 	}
@@ -119,7 +140,7 @@ func TestParseFixtures_InvalidYAML(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err := parseFixtures(dir)
+	_, err := parseFixtures(os.DirFS(dir), ".")
 	if err == nil {
 		t.Fatal("expected error for invalid YAML")
 	}
@@ -135,7 +156,7 @@ func TestParseFixtures_NoMappingOrSettings(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	fixtures, err := parseFixtures(dir)
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
 	if err != nil {
 		t.Fatalf("parseFixtures() error: %v", err)
 	}
@@ -165,7 +186,7 @@ func TestParseFixtures_DocumentWithoutID(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	fixtures, err := parseFixtures(dir)
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
 	if err != nil {
 		t.Fatalf("parseFixtures() error: %v", err)
 	}
@@ -174,7 +195,472 @@ func TestParseFixtures_DocumentWithoutID(t *testing.T) {
 	if doc.ID != "" {
 		t.Errorf("expected empty ID, got %q", doc.ID)
 	}
-	if doc.Body["name"] != "test" {
-		t.Errorf("expected name 'test', got %v", doc.Body["name"])
+	if body := decodeBody(t, doc); body["name"] != "test" {
+		t.Errorf("expected name 'test', got %v", body["name"])
+	}
+}
+
+func TestParseFixtures_NDJSONFiles(t *testing.T) {
+	dir := t.TempDir()
+	indexDir := filepath.Join(dir, "events")
+	if err := os.Mkdir(indexDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(indexDir, "events.ndjson"), []byte("{\"n\":1}\n{\"n\":2}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(indexDir, "_ignored.ndjson"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := os.DirFS(dir)
+	fixtures, err := parseFixtures(fsys, ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	streams := fixtures[0].streams
+	if len(streams) != 1 || filepath.Base(streams[0]) != "events.ndjson" {
+		t.Fatalf("expected only events.ndjson to be streamed, got %v", streams)
+	}
+	if len(fixtures[0].documents) != 0 {
+		t.Errorf("expected NDJSON lines not to be parsed eagerly, got %d documents", len(fixtures[0].documents))
+	}
+
+	var lines []string
+	err = feedNDJSONFile(fsys, streams[0], func(doc Document) error {
+		lines = append(lines, string(doc.Source))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("feedNDJSONFile() error: %v", err)
+	}
+	if len(lines) != 2 || lines[0] != `{"n":1}` || lines[1] != `{"n":2}` {
+		t.Errorf("unexpected streamed lines: %v", lines)
+	}
+}
+
+func TestParseFixtures_JSONDocumentFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json": `{"properties":{"name":{"type":"keyword"}}}`,
+		"users/documents.json": `[
+  {"_id": "1", "name": "Alice", "id": 12345678901234567890, "tags": ["a\/b"]},
+  {"name": "Bob", "_routing": "eu", "score": 1.50}
+]`,
+		"users/more.yml": "- _id: \"3\"\n  name: Carol\n",
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+	docs := fixtures[0].documents
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents, got %d", len(docs))
+	}
+	if docs[0].ID != "1" || string(docs[0].Source) != `{"name":"Alice","id":12345678901234567890,"tags":["a/b"]}` {
+		t.Errorf("unexpected first document %+v", docs[0])
+	}
+	if docs[1].ID != "" || docs[1].Routing != "eu" || string(docs[1].Source) != `{"name":"Bob","score":1.50}` {
+		t.Errorf("unexpected second document %+v", docs[1])
+	}
+	if docs[1].Location() != "users/documents.json:3" {
+		t.Errorf("expected the second document at users/documents.json:3, got %s", docs[1].Location())
+	}
+}
+
+func TestParseFixtures_JSONDocumentFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"[\n  {\"name\": \"Alice\"},\n  {\"name\": Bob}\n]": "unmarshaling JSON: line 3: invalid character",
+		`{"name": "Alice"}`:            "unmarshaling JSON: line 1: expected a sequence of documents",
+		"[\n  {\"name\": \"a\"}, 1\n]": "document 1: line 2: expected a mapping",
+	}
+	for data, want := range tests {
+		dir := t.TempDir()
+		writeFixtureFiles(t, dir, map[string]string{"users/documents.json": data})
+		_, err := parseFixtures(os.DirFS(dir), ".")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", data, want, err)
+		}
+	}
+}
+
+func TestParseFixtures_NumericAndKeyOrderFidelity(t *testing.T) {
+	dir := t.TempDir()
+	indexDir := filepath.Join(dir, "events")
+	if err := os.Mkdir(indexDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	yamlContent := `- _id: "e1"
+  zeta: 1
+  alpha: 9007199254740993
+  epoch_millis: 1700000000123
+  price: 1.50
+  huge: 123456789012345678901234567890
+  nested:
+    b: true
+    a: null
+`
+	if err := os.WriteFile(filepath.Join(indexDir, "documents.yml"), []byte(yamlContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	want := `{"zeta":1,"alpha":9007199254740993,"epoch_millis":1700000000123,"price":1.50,"huge":123456789012345678901234567890,"nested":{"b":true,"a":null}}`
+	if got := string(fixtures[0].documents[0].Source); got != want {
+		t.Errorf("unexpected document body:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestParseFixtures_YAMLMergeKeys(t *testing.T) {
+	dir := t.TempDir()
+	indexDir := filepath.Join(dir, "users")
+	if err := os.Mkdir(indexDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	yamlContent := `- _id: "1"
+  name: Alice
+  address: &addr
+    city: Tokyo
+    country: JP
+- _id: "2"
+  name: Bob
+  address:
+    <<: *addr
+    city: Osaka
+`
+	if err := os.WriteFile(filepath.Join(indexDir, "documents.yml"), []byte(yamlContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	want := `{"name":"Bob","address":{"country":"JP","city":"Osaka"}}`
+	if got := string(fixtures[0].documents[1].Source); got != want {
+		t.Errorf("unexpected document body:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestParseFixtures_Expectations(t *testing.T) {
+	fixtures, err := parseFixtures(os.DirFS("testdata/fixtures"), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	for _, f := range fixtures {
+		if f.name != "users" {
+			if len(f.expectations) != 0 {
+				t.Errorf("expected no expectations for %q", f.name)
+			}
+			continue
+		}
+
+		if len(f.expectations) != 2 {
+			t.Fatalf("expected 2 expectations, got %d", len(f.expectations))
+		}
+		e := f.expectations[0]
+		if e.name != "thirty_and_over" || e.file != "queries.yml" {
+			t.Errorf("unexpected expectation %q from %q", e.name, e.file)
+		}
+		if string(e.query) != `{"range":{"age":{"gte":30}}}` {
+			t.Errorf("unexpected query: %s", e.query)
+		}
+		if len(e.ids) != 1 || e.ids[0] != "1" {
+			t.Errorf("unexpected ids: %v", e.ids)
+		}
+	}
+}
+
+func TestParseFixtures_ExpectationWithoutQuery(t *testing.T) {
+	dir := t.TempDir()
+	expDir := filepath.Join(dir, "users", expectationsDir)
+	if err := os.MkdirAll(expDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(expDir, "bad.yml"), []byte("broken:\n  ids: [\"1\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseFixtures(os.DirFS(dir), "."); err == nil {
+		t.Fatal("expected error for expectation without query")
+	}
+}
+
+func TestParseFixtures_RuntimeMappings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_mapping.json":          `{"properties":{"placed_at":{"type":"date"}}}`,
+		"orders/_runtime_mappings.json": `{"day_of_week":{"type":"keyword","script":{"source":"emit(doc['placed_at'].value.dayOfWeekEnum.toString())"}}}`,
+		"events/_runtime_mappings.json": `{"n2":{"type":"long"}}`,
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	for _, f := range fixtures {
+		var mapping map[string]map[string]interface{}
+		if err := json.Unmarshal(f.mapping, &mapping); err != nil {
+			t.Fatalf("decoding merged mapping for %q: %v", f.name, err)
+		}
+
+		switch f.name {
+		case "orders":
+			if _, ok := mapping["runtime"]["day_of_week"]; !ok {
+				t.Errorf("expected day_of_week runtime field, got %s", f.mapping)
+			}
+			if _, ok := mapping["properties"]["placed_at"]; !ok {
+				t.Errorf("expected properties to be kept, got %s", f.mapping)
+			}
+			if len(f.runtimeFields) != 1 || f.runtimeFields[0] != "day_of_week" {
+				t.Errorf("unexpected runtime fields: %v", f.runtimeFields)
+			}
+		case "events":
+			if _, ok := mapping["runtime"]["n2"]; !ok {
+				t.Errorf("expected runtime section without _mapping.json, got %s", f.mapping)
+			}
+		}
+	}
+}
+
+func TestParseFixtures_RuntimeMappingConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_mapping.json":          `{"runtime":{"day_of_week":{"type":"keyword"}}}`,
+		"orders/_runtime_mappings.json": `{"day_of_week":{"type":"keyword"}}`,
+	})
+
+	if _, err := parseFixtures(os.DirFS(dir), "."); err == nil {
+		t.Fatal("expected error for runtime field defined twice")
+	}
+}
+
+func TestParseFixtures_InheritCommon(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_common/_mapping.json":  `{"properties":{"tenant_id":{"type":"keyword"},"created_at":{"type":"date"}}}`,
+		"_common/_settings.json": `{"number_of_shards":1,"analysis":{"analyzer":{"folded":{"type":"custom","tokenizer":"standard"}}}}`,
+		"orders/_config.yml":     "inherit_common: true\n",
+		"orders/_mapping.json":   `{"properties":{"created_at":{"type":"date","format":"epoch_millis"},"total":{"type":"long"}}}`,
+		"orders/_settings.json":  `{"number_of_shards":2}`,
+		"audit/_mapping.json":    `{"properties":{"action":{"type":"keyword"}}}`,
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+	if len(fixtures) != 2 {
+		t.Fatalf("expected _common to be skipped as an index, got %d fixtures", len(fixtures))
+	}
+
+	for _, f := range fixtures {
+		switch f.name {
+		case "orders":
+			wantMapping := `{"properties":{"tenant_id":{"type":"keyword"},"created_at":{"type":"date","format":"epoch_millis"},"total":{"type":"long"}}}`
+			if string(f.mapping) != wantMapping {
+				t.Errorf("mapping = %s, want %s", f.mapping, wantMapping)
+			}
+			wantSettings := `{"number_of_shards":2,"analysis":{"analyzer":{"folded":{"type":"custom","tokenizer":"standard"}}}}`
+			if string(f.settings) != wantSettings {
+				t.Errorf("settings = %s, want %s", f.settings, wantSettings)
+			}
+		case "audit":
+			if string(f.mapping) != `{"properties":{"action":{"type":"keyword"}}}` {
+				t.Errorf("expected audit not to inherit, got %s", f.mapping)
+			}
+			if f.settings != nil {
+				t.Errorf("expected no settings for audit, got %s", f.settings)
+			}
+		}
+	}
+}
+
+func TestParseFixtures_InheritCommonWithoutCommonDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_config.yml": "inherit_common: true\n",
+	})
+
+	if _, err := parseFixtures(os.DirFS(dir), "."); err == nil {
+		t.Fatal("expected error for inherit_common without _common directory")
+	}
+}
+
+func TestParseFixtures_SettingsVariables(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_variables.yml": "folding_filters: [lowercase, asciifolding]\nstopwords: [the, a]\n",
+		"products/_settings.yml": `analysis:
+  filter:
+    custom_stop: {type: stop, stopwords: {$var: stopwords}}
+  analyzer:
+    folded: {type: custom, tokenizer: standard, filter: {$var: folding_filters}}
+`,
+		"articles/_settings.json": `{"analysis":{"analyzer":{"folded":{"filter":{"$var":"folding_filters"}}}}}`,
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	want := map[string]string{
+		"products": `{"analysis":{"filter":{"custom_stop":{"type":"stop","stopwords":["the","a"]}},"analyzer":{"folded":{"type":"custom","tokenizer":"standard","filter":["lowercase","asciifolding"]}}}}`,
+		"articles": `{"analysis":{"analyzer":{"folded":{"filter":["lowercase","asciifolding"]}}}}`,
+	}
+	for _, f := range fixtures {
+		if string(f.settings) != want[f.name] {
+			t.Errorf("%s settings = %s, want %s", f.name, f.settings, want[f.name])
+		}
+	}
+}
+
+func TestParseFixtures_UndefinedSettingsVariable(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"products/_settings.json": `{"analysis":{"analyzer":{"folded":{"filter":{"$var":"missing"}}}}}`,
+	})
+
+	_, err := parseFixtures(os.DirFS(dir), ".")
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Fatalf("expected undefined variable error, got %v", err)
+	}
+}
+
+func TestParseFixtures_SettingsJSONAndYAML(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"products/_settings.json": `{}`,
+		"products/_settings.yml":  "number_of_shards: 1\n",
+	})
+
+	if _, err := parseFixtures(os.DirFS(dir), "."); err == nil {
+		t.Fatal("expected error when both settings files exist")
+	}
+}
+
+func TestParseFixtures_Traits(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_traits.yml": `premium_user:
+  plan: premium
+  limits: {seats: 50, storage_gb: 100}
+eu_region:
+  region: eu-west-1
+  limits: {storage_gb: 20}
+`,
+		"users/users.yml": `- _id: "1"
+  _traits: [premium_user, eu_region]
+  name: Alice
+  plan: enterprise
+- _id: "2"
+  _traits: eu_region
+  name: Bob
+`,
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	docs := fixtures[0].documents
+	want := []string{
+		`{"plan":"enterprise","limits":{"seats":50,"storage_gb":20},"region":"eu-west-1","name":"Alice"}`,
+		`{"region":"eu-west-1","limits":{"storage_gb":20},"name":"Bob"}`,
+	}
+	for i, doc := range docs {
+		if string(doc.Source) != want[i] {
+			t.Errorf("document %d body = %s, want %s", i, doc.Source, want[i])
+		}
+	}
+}
+
+func TestParseFixtures_UndefinedTrait(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/users.yml": "- _id: \"1\"\n  _traits: [missing]\n",
+	})
+
+	_, err := parseFixtures(os.DirFS(dir), ".")
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Fatalf("expected undefined trait error, got %v", err)
+	}
+}
+
+func TestParseFixtures_Routing(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/orders.yml": "- _id: \"1\"\n  _routing: tenant-a\n  total: 10\n",
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	doc := fixtures[0].documents[0]
+	if doc.Routing != "tenant-a" {
+		t.Errorf("Routing = %q, want tenant-a", doc.Routing)
+	}
+	if string(doc.Source) != `{"total":10}` {
+		t.Errorf("expected _routing to be removed from the source, got %s", doc.Source)
+	}
+}
+
+func TestParseFixtures_FS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/_traits.yml":                 {Data: []byte("admin:\n  role: admin\n")},
+		"fixtures/users/_mapping.json":         {Data: []byte(`{"properties":{"name":{"type":"keyword"}}}`)},
+		"fixtures/users/documents.yml":         {Data: []byte("- _id: 1\n  name: Alice\n  _traits: admin\n")},
+		"fixtures/users/events.ndjson":         {Data: []byte("{\"n\":1}\n")},
+		"fixtures/users/_expectations/all.yml": {Data: []byte("all:\n  query: {match_all: {}}\n  ids: [\"1\"]\n")},
+	}
+
+	fixtures, err := parseFixtures(fsys, "fixtures")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	users := fixtures[0]
+	if users.name != "users" || users.mapping == nil || len(users.expectations) != 1 {
+		t.Fatalf("unexpected users fixture: %+v", users)
+	}
+	if len(users.documents) != 1 || string(users.documents[0].Source) != `{"role":"admin","name":"Alice"}` {
+		t.Errorf("unexpected documents: %+v", users.documents)
+	}
+	if loc := users.documents[0].Location(); loc != "users/documents.yml:1" {
+		t.Errorf("Location() = %q, want users/documents.yml:1", loc)
+	}
+
+	var lines []string
+	err = feedNDJSONFile(fsys, users.streams[0], func(doc Document) error {
+		lines = append(lines, doc.Location())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("feedNDJSONFile() error: %v", err)
+	}
+	if len(lines) != 1 || lines[0] != "users/events.ndjson:1" {
+		t.Errorf("unexpected streamed lines: %v", lines)
+	}
+}
+
+func TestNew_FSInvalidRoot(t *testing.T) {
+	if _, err := New(newOfflineClient(t), FS(fstest.MapFS{}, "/abs/path")); err == nil {
+		t.Error("expected an error for a rooted FS path")
+	}
+	if _, err := New(newOfflineClient(t), FS(nil, ".")); err == nil {
+		t.Error("expected an error for a nil FS")
 	}
 }
@@ -0,0 +1,202 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// pipelinesDir is the top-level directory holding ingest pipeline
+// definitions, one <name>.json file per pipeline in the format of the Put
+// Pipeline API, created by CreatePipelines.
+const pipelinesDir = "_pipelines"
+
+// pipelineSettings are the index settings naming the ingest pipelines
+// documents pass through.
+var pipelineSettings = []string{"index.default_pipeline", "index.final_pipeline"}
+
+// noPipeline is the pipeline setting value that turns a pipeline off.
+const noPipeline = "_none"
+
+// readPipelines reads the pipeline definitions of the _pipelines directory,
+// by pipeline name. A missing directory means there are none.
+func readPipelines(fsys fs.FS, dir string) (map[string]json.RawMessage, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, pipelinesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", pipelinesDir, err)
+	}
+
+	pipelines := make(map[string]json.RawMessage)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		body, err := readJSONFile(fsys, path.Join(dir, pipelinesDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s/%s: %w", pipelinesDir, name, err)
+		}
+		pipelines[strings.TrimSuffix(name, ".json")] = body
+	}
+
+	return pipelines, nil
+}
+
+// indexPipelines returns the pipelines named by the default_pipeline and
+// final_pipeline settings of an index.
+func indexPipelines(settings json.RawMessage) ([]string, error) {
+	var names []string
+	for _, setting := range pipelineSettings {
+		v, ok, err := indexSetting(settings, setting)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		var name string
+		if err := json.Unmarshal(v, &name); err != nil {
+			return nil, fmt.Errorf("%s must be a pipeline name, got %s", setting, v)
+		}
+		if name != noPipeline && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// pipelineReferences returns the pipelines a pipeline definition calls with
+// pipeline processors, wherever they appear in it (including on_failure
+// handlers and foreach processors).
+func pipelineReferences(body json.RawMessage) []string {
+	v, err := decodeValue(body)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		names []string
+		walk  func(any)
+	)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if p, ok := v["pipeline"].(map[string]any); ok {
+				if name, ok := p["name"].(string); ok && !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(v)
+
+	slices.Sort(names)
+	return names
+}
+
+// checkPipelines checks that every pipeline named by index settings is
+// defined in _pipelines, so CreatePipelines can create it before the index.
+func (l *Loader) checkPipelines() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		names, err := indexPipelines(f.settings)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("index %q: %w", f.name, err))
+			continue
+		}
+		for _, name := range names {
+			if _, ok := l.pipelines[name]; !ok {
+				errs = append(errs, fmt.Errorf("index %q: pipeline %q is not defined in %s/%s.json", f.name, name, pipelinesDir, name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkUncreatedPipelines records a warning for each pipeline that index
+// settings name and _pipelines defines while CreatePipelines is not set,
+// since Load then leaves it to the cluster and indexing fails unless it
+// already exists there.
+func (l *Loader) checkUncreatedPipelines() {
+	for _, f := range l.fixtures {
+		names, err := indexPipelines(f.settings)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if _, ok := l.pipelines[name]; ok {
+				l.warnings = append(l.warnings, fmt.Sprintf("index %q: pipeline %q is defined in %s/%s.json but is not created, since CreatePipelines is not set; documents fail to index unless the cluster has it", f.name, name, pipelinesDir, name))
+			}
+		}
+	}
+}
+
+// putIndexPipelines creates the pipelines named by the settings of f, after
+// the pipelines they call. put holds the pipelines already created by the
+// current Load, which are not sent again.
+func (l *Loader) putIndexPipelines(ctx context.Context, f *indexFixture, put map[string]bool) error {
+	if !l.createPipelines {
+		return nil
+	}
+	names, err := indexPipelines(f.settings)
+	if err != nil {
+		return err
+	}
+
+	var create func(name string) error
+	create = func(name string) error {
+		body, ok := l.pipelines[name]
+		if put[name] || !ok {
+			// Pipelines not in _pipelines are expected to exist in the cluster.
+			return nil
+		}
+		put[name] = true
+		for _, ref := range pipelineReferences(body) {
+			if err := create(ref); err != nil {
+				return err
+			}
+		}
+		return putPipeline(ctx, l.client, name, body)
+	}
+	for _, name := range names {
+		if err := create(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putPipeline creates or replaces the ingest pipeline name.
+func putPipeline(ctx context.Context, client *elasticsearch.Client, name string, body json.RawMessage) error {
+	res, err := client.Ingest.PutPipeline(name, bytes.NewReader(body), client.Ingest.PutPipeline.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating pipeline %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating pipeline %q: %w", name, err)
+	}
+
+	return nil
+}
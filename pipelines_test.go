@@ -0,0 +1,141 @@
+package testfixtures
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad_CreatePipelines(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_pipelines/lowercase.json": `{"processors":[{"lowercase":{"field":"email"}}]}`,
+		"_pipelines/users.json":     `{"processors":[{"pipeline":{"name":"lowercase"}},{"pipeline":{"name":"managed-elsewhere"}}]}`,
+		"_pipelines/unused.json":    `{"processors":[]}`,
+		"_pipelines/audit.json":     `{"processors":[{"set":{"field":"audited","value":true}}]}`,
+		"users/_settings.json":      `{"index":{"default_pipeline":"users","final_pipeline":"audit"}}`,
+		"users/documents.yml":       "- _id: 1\n  email: A@B.C\n",
+		"admins/_settings.json":     `{"index.default_pipeline":"users","index.final_pipeline":"_none"}`,
+		"admins/documents.yml":      "- _id: 1\n  email: A@B.C\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut || req.Method == http.MethodDelete {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), CreatePipelines())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	want := []string{
+		"PUT /_ingest/pipeline/lowercase",
+		"PUT /_ingest/pipeline/users",
+		"DELETE /admins",
+		"PUT /admins",
+		"PUT /_ingest/pipeline/audit",
+		"DELETE /users",
+		"PUT /users",
+	}
+	if !slices.Equal(requests, want) {
+		t.Errorf("expected requests\n%v\ngot\n%v", want, requests)
+	}
+}
+
+func TestLoad_CreatePipelinesFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_pipelines/users.json": `{"processors":[{"script":{"source":"bad("}}]}`,
+		"users/_settings.json":  `{"index":{"default_pipeline":"users"}}`,
+		"users/documents.yml":   "- _id: 1\n  n: 1\n",
+	})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasPrefix(req.URL.Path, "/_ingest/pipeline/") {
+			return jsonResponse(400, `{"error":{"type":"script_exception","reason":"compile error"},"status":400}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), CreatePipelines())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	var loadErr *LoadError
+	err = loader.Load()
+	if !errors.As(err, &loadErr) || loadErr.Stage != StagePipeline || !strings.Contains(err.Error(), `creating pipeline "users"`) {
+		t.Errorf("expected a pipeline LoadError, got %v", err)
+	}
+}
+
+func TestNew_CreatePipelinesUndefined(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_settings.json": `{"index":{"final_pipeline":"audit"}}`,
+		"users/documents.yml":  "- _id: 1\n  n: 1\n",
+	})
+
+	// Without the option the settings are sent as written.
+	if _, err := New(newOfflineClient(t), Directory(dir)); err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	_, err := New(newOfflineClient(t), Directory(dir), CreatePipelines())
+	if err == nil || !strings.Contains(err.Error(), `index "users": pipeline "audit" is not defined in _pipelines/audit.json`) {
+		t.Errorf("expected an undefined pipeline error, got %v", err)
+	}
+}
+
+func TestNew_PipelinesWithoutCreatePipelines(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_pipelines/users-ingest.json": `{"processors": [{"lowercase": {"field": "email"}}]}`,
+		"users/_settings.json":         `{"index":{"default_pipeline":"users-ingest","final_pipeline":"audit"}}`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	want := []string{`index "users": pipeline "users-ingest" is defined in _pipelines/users-ingest.json but is not created, since CreatePipelines is not set; documents fail to index unless the cluster has it`}
+	if got := loader.Warnings(); !slices.Equal(got, want) {
+		t.Errorf("expected warnings %q, got %q", want, got)
+	}
+
+	if _, err := New(newOfflineClient(t), Directory(dir), CreatePipelines()); err == nil {
+		t.Fatal("expected the undefined audit pipeline to fail New with CreatePipelines")
+	}
+}
+
+func TestNew_BrokenPipelinesWithoutCreatePipelines(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_pipelines/users-ingest.json": `{"processors": [`,
+		"users/documents.yml":          "- _id: 1\n  n: 1\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if w := loader.Warnings(); len(w) != 1 || !strings.HasPrefix(w[0], "reading _pipelines/users-ingest.json: ") {
+		t.Errorf("expected a warning for the broken pipeline file, got %q", w)
+	}
+
+	if _, err := New(newOfflineClient(t), Directory(dir), CreatePipelines()); err == nil || !strings.Contains(err.Error(), "_pipelines/users-ingest.json") {
+		t.Errorf("expected the broken pipeline file to fail New with CreatePipelines, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package testfixtures
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// DocumentProvider supplies fixture documents for an index from a source
+// other than fixture files, such as a database, a service, or a generator.
+//
+// Documents is called on every Load. Errors yielded by the sequence abort
+// the load of that index.
+type DocumentProvider interface {
+	Documents(ctx context.Context, index string) (iter.Seq2[Document, error], error)
+}
+
+// DocumentProviderFunc adapts an ordinary function to a DocumentProvider.
+type DocumentProviderFunc func(ctx context.Context, index string) (iter.Seq2[Document, error], error)
+
+// Documents calls f(ctx, index).
+func (f DocumentProviderFunc) Documents(ctx context.Context, index string) (iter.Seq2[Document, error], error) {
+	return f(ctx, index)
+}
+
+// indexProvider is a DocumentProvider registered for a single index.
+type indexProvider struct {
+	index    string
+	provider DocumentProvider
+}
+
+// attachProviders adds registered providers to their fixtures. Indices that
+// have no fixture directory get an empty fixture so they are still created
+// and cleaned like any other managed index.
+func (l *Loader) attachProviders() {
+	for _, p := range l.providers {
+		f := l.fixture(p.index)
+		if f == nil {
+			f = &indexFixture{name: p.index}
+			l.fixtures = append(l.fixtures, f)
+		}
+		f.providers = append(f.providers, p.provider)
+	}
+}
+
+// fixture returns the fixture for the named index, or nil.
+func (l *Loader) fixture(name string) *indexFixture {
+	for _, f := range l.fixtures {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// provideDocuments inserts the documents of each provider into the index.
+func provideDocuments(ctx context.Context, client *elasticsearch.Client, indexName string, cfg bulkConfig, providers []DocumentProvider, transform func(Document) (Document, error)) error {
+	for i, p := range providers {
+		docs, err := p.Documents(ctx, indexName)
+		if err != nil {
+			return fmt.Errorf("provider %d for %q: %w", i, indexName, err)
+		}
+
+		err = bulkInsertPartition(ctx, client, indexName, 0, cfg, func(add func(Document) error) error {
+			add = withTransform(add, transform)
+			for doc, err := range docs {
+				if err != nil {
+					return fmt.Errorf("provider %d for %q: %w", i, indexName, err)
+				}
+				if err := add(doc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
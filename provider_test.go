@@ -0,0 +1,48 @@
+package testfixtures
+
+import (
+	"context"
+	"iter"
+	"testing"
+)
+
+func TestWithProvider_AttachesToFixtures(t *testing.T) {
+	p := DocumentProviderFunc(func(context.Context, string) (iter.Seq2[Document, error], error) {
+		return func(func(Document, error) bool) {}, nil
+	})
+
+	loader, err := New(newOfflineClient(t),
+		Directory("testdata/fixtures"),
+		WithProvider("users", p),
+		WithProvider("audit_log", p),
+	)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if users := loader.fixture("users"); users == nil || len(users.providers) != 1 || len(users.documents) != 2 {
+		t.Errorf("expected users fixture to keep its documents and gain one provider")
+	}
+
+	audit := loader.fixture("audit_log")
+	if audit == nil {
+		t.Fatal("expected provider-only index to be managed")
+	}
+	if audit.mapping != nil || audit.settings != nil || len(audit.providers) != 1 {
+		t.Errorf("expected provider-only fixture without schema, got %+v", audit)
+	}
+}
+
+func TestWithProvider_WithoutDirectory(t *testing.T) {
+	p := DocumentProviderFunc(func(context.Context, string) (iter.Seq2[Document, error], error) {
+		return func(func(Document, error) bool) {}, nil
+	})
+
+	loader, err := New(newOfflineClient(t), WithProvider("generated", p))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if len(loader.fixtures) != 1 || loader.fixtures[0].name != "generated" {
+		t.Errorf("expected a single generated fixture, got %d", len(loader.fixtures))
+	}
+}
@@ -0,0 +1,341 @@
+package testfixtures
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Recorder is an http.RoundTripper that records the documents an application
+// indexes through it, so fixtures can be authored by exercising the real
+// code path once and writing out what was sent.
+//
+// Install it as the transport of the application's Elasticsearch client:
+//
+//	rec := testfixtures.NewRecorder(nil)
+//	client, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: rec})
+//	// ... run the code under test ...
+//	err := rec.WriteFixtures("testdata/fixtures")
+//
+// Single-document index/create requests and bulk requests are recorded.
+// Documents are stored with the IDs Elasticsearch reports, including
+// generated ones; deletes remove previously recorded documents.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	indices map[string]*recordedIndex
+	order   []string
+}
+
+// recordedIndex holds the documents recorded for one index in first-write order.
+type recordedIndex struct {
+	docs []Document
+	pos  map[string]int
+}
+
+// NewRecorder returns a Recorder that sends requests with next.
+// If next is nil, http.DefaultTransport is used.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Recorder{
+		next:    next,
+		indices: make(map[string]*recordedIndex),
+	}
+}
+
+// RoundTrip sends the request and records any documents it successfully indexed.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	op, ok := recordedOperation(req)
+	if !ok {
+		return r.next.RoundTrip(req)
+	}
+
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil || res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		// The response was cut short, which the application must see
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	// Recording is best effort; never fail the application's request
+	_ = r.record(op, reqBody, resBody)
+
+	return res, nil
+}
+
+// recordOp describes a document-writing request.
+type recordOp struct {
+	bulk   bool
+	action string // "index" for single-document requests
+	index  string // Target index from the URL path (may be empty for bulk)
+	id     string // Document ID from the URL path (may be empty)
+}
+
+// recordedOperation classifies a request by method and path.
+func recordedOperation(req *http.Request) (recordOp, bool) {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	switch {
+	case req.Method != http.MethodPost && req.Method != http.MethodPut && req.Method != http.MethodDelete:
+		return recordOp{}, false
+	case len(segments) >= 1 && segments[len(segments)-1] == "_bulk":
+		op := recordOp{bulk: true}
+		if len(segments) == 2 {
+			op.index = segments[0]
+		}
+		return op, req.Method != http.MethodDelete
+	case len(segments) >= 2 && (segments[1] == "_doc" || segments[1] == "_create"):
+		op := recordOp{action: "index", index: segments[0]}
+		if len(segments) == 3 {
+			op.id = segments[2]
+		}
+		if req.Method == http.MethodDelete {
+			op.action = "delete"
+		}
+		return op, !strings.HasPrefix(op.index, ".")
+	}
+
+	return recordOp{}, false
+}
+
+// readRequestBody reads the request body and replaces it so it can be sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: recorder: reading request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("testfixtures: recorder: decompressing request body: %w", err)
+		}
+		return io.ReadAll(zr)
+	}
+
+	return data, nil
+}
+
+// writeResult is the subset of an index/delete response used for recording.
+type writeResult struct {
+	Index  string `json:"_index"`
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+}
+
+func (r *Recorder) record(op recordOp, reqBody, resBody []byte) error {
+	if !op.bulk {
+		var res writeResult
+		if err := json.Unmarshal(resBody, &res); err != nil {
+			return err
+		}
+		if res.Index == "" {
+			res.Index = op.index
+		}
+		if res.ID == "" {
+			res.ID = op.id
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if op.action == "delete" {
+			r.remove(res.Index, res.ID)
+		} else {
+			r.put(res.Index, Document{ID: res.ID, Source: reqBody})
+		}
+		return nil
+	}
+
+	var res struct {
+		Items []map[string]writeResult `json:"items"`
+	}
+	if err := json.Unmarshal(resBody, &res); err != nil {
+		return err
+	}
+
+	items, err := parseBulkRequest(reqBody, op.index)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, item := range items {
+		if i >= len(res.Items) {
+			break
+		}
+		result := res.Items[i][item.action]
+		if result.Status < 200 || result.Status >= 300 {
+			continue
+		}
+		if result.Index == "" {
+			result.Index = item.index
+		}
+		if result.ID == "" {
+			result.ID = item.id
+		}
+
+		switch item.action {
+		case "index", "create":
+			r.put(result.Index, Document{ID: result.ID, Routing: item.routing, Source: item.source})
+		case "delete":
+			r.remove(result.Index, result.ID)
+		}
+	}
+
+	return nil
+}
+
+// bulkRequestItem is one action of a bulk request body.
+type bulkRequestItem struct {
+	action  string
+	index   string
+	id      string
+	routing string
+	source  json.RawMessage
+}
+
+// parseBulkRequest splits an NDJSON bulk body into its actions.
+func parseBulkRequest(body []byte, defaultIndex string) ([]bulkRequestItem, error) {
+	var items []bulkRequestItem
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var meta map[string]struct {
+			Index   string `json:"_index"`
+			ID      string `json:"_id"`
+			Routing string `json:"routing"`
+		}
+		if err := json.Unmarshal(line, &meta); err != nil {
+			return nil, fmt.Errorf("parsing bulk action: %w", err)
+		}
+
+		for action, m := range meta {
+			item := bulkRequestItem{action: action, index: m.Index, id: m.ID, routing: m.Routing}
+			if item.index == "" {
+				item.index = defaultIndex
+			}
+			if action != "delete" {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("bulk action %q has no source line", action)
+				}
+				item.source = append(json.RawMessage(nil), bytes.TrimSpace(scanner.Bytes())...)
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items, scanner.Err()
+}
+
+// put records doc for index, replacing an earlier write to the same ID.
+// The caller must hold r.mu.
+func (r *Recorder) put(index string, doc Document) {
+	if index == "" || strings.HasPrefix(index, ".") {
+		return
+	}
+
+	ri, ok := r.indices[index]
+	if !ok {
+		ri = &recordedIndex{pos: make(map[string]int)}
+		r.indices[index] = ri
+		r.order = append(r.order, index)
+	}
+
+	if doc.ID != "" {
+		if i, ok := ri.pos[doc.ID]; ok {
+			ri.docs[i] = doc
+			return
+		}
+		ri.pos[doc.ID] = len(ri.docs)
+	}
+	ri.docs = append(ri.docs, doc)
+}
+
+// remove drops a recorded document. The caller must hold r.mu.
+func (r *Recorder) remove(index, id string) {
+	ri, ok := r.indices[index]
+	if !ok {
+		return
+	}
+	i, ok := ri.pos[id]
+	if !ok {
+		return
+	}
+
+	ri.docs = append(ri.docs[:i], ri.docs[i+1:]...)
+	delete(ri.pos, id)
+	for id, p := range ri.pos {
+		if p > i {
+			ri.pos[id] = p - 1
+		}
+	}
+}
+
+// Indices returns the names of the indices with recorded documents,
+// in the order they were first written to.
+func (r *Recorder) Indices() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.order...)
+}
+
+// WriteFixtures writes the recorded documents into dir, one subdirectory per
+// index containing a documents.yml file, in the layout read by Directory.
+// Mapping and settings files are not written. With DocsPerFile, large
+// indices are split across numbered document files.
+func (r *Recorder) WriteFixtures(dir string, opts ...WriteOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var cfg writeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	for _, index := range r.order {
+		if err := writeDocumentFiles(filepath.Join(dir, index), r.indices[index].docs, cfg); err != nil {
+			return fmt.Errorf("testfixtures: writing fixtures for %q: %w", index, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package testfixtures
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// roundTripFunc adapts a function to an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// jsonResponse returns a canned Elasticsearch response.
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}, "X-Elastic-Product": {"Elasticsearch"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestRecorder_WriteFixtures(t *testing.T) {
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"items":[
+				{"index":{"_index":"users","_id":"1","status":201}},
+				{"create":{"_index":"users","_id":"generated","status":201}},
+				{"index":{"_index":"users","_id":"bad","status":400}},
+				{"delete":{"_index":"users","_id":"2","status":200}}
+			]}`), nil
+		case strings.Contains(req.URL.Path, "/_doc"):
+			return jsonResponse(201, `{"_index":"users","_id":"2"}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	})
+	rec := NewRecorder(fake)
+
+	send := func(method, path, body string) {
+		t.Helper()
+		req, err := http.NewRequest(method, "http://localhost:9200"+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := rec.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	send(http.MethodPut, "/users/_doc/2", `{"name":"Bob"}`)
+	send(http.MethodPost, "/users/_bulk", strings.Join([]string{
+		`{"index":{"_id":"1"}}`,
+		`{"name":"Alice","joined":1700000000123}`,
+		`{"create":{}}`,
+		`{"name":"Carol"}`,
+		`{"index":{"_id":"bad"}}`,
+		`{"name":1}`,
+		`{"delete":{"_id":"2"}}`,
+	}, "\n")+"\n")
+	send(http.MethodGet, "/users/_search", `{}`)
+
+	if got := rec.Indices(); len(got) != 1 || got[0] != "users" {
+		t.Fatalf("expected only users to be recorded, got %v", got)
+	}
+
+	dir := t.TempDir()
+	if err := rec.WriteFixtures(dir); err != nil {
+		t.Fatalf("WriteFixtures() error: %v", err)
+	}
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+
+	docs := fixtures[0].documents
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 recorded documents, got %d", len(docs))
+	}
+	if docs[0].ID != "1" || string(docs[0].Source) != `{"name":"Alice","joined":1700000000123}` {
+		t.Errorf("unexpected first document: %s %s", docs[0].ID, docs[0].Source)
+	}
+	if docs[1].ID != "generated" {
+		t.Errorf("expected generated ID to be recorded, got %q", docs[1].ID)
+	}
+}
+
+func TestRecorder_ResponseReadError(t *testing.T) {
+	rec := NewRecorder(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := jsonResponse(200, "")
+		res.Body = io.NopCloser(io.MultiReader(strings.NewReader(`{"_index":"us`), iotest.ErrReader(io.ErrUnexpectedEOF)))
+		return res, nil
+	}))
+
+	req, err := http.NewRequest(http.MethodPut, "http://localhost:9200/users/_doc/1", strings.NewReader(`{"name":"Alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := rec.RoundTrip(req)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || res != nil {
+		t.Errorf("expected the read error without a response, got %v, %v", res, err)
+	}
+	if indices := rec.Indices(); len(indices) != 0 {
+		t.Errorf("expected nothing to be recorded, got %v", indices)
+	}
+}
+
+func TestWriteDocumentFiles_DocsPerFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: \"old\"\n  name: Stale\n",
+		"users/extra.yml":     "- _id: \"x\"\n  name: Kept\n",
+	})
+
+	var docs []Document
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave", "Erin"} {
+		docs = append(docs, Document{ID: strings.ToLower(name), Source: []byte(`{"name":"` + name + `"}`)})
+	}
+	var cfg writeConfig
+	DocsPerFile(2)(&cfg)
+	if err := writeDocumentFiles(filepath.Join(dir, "users"), docs, cfg); err != nil {
+		t.Fatalf("writeDocumentFiles() error: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"documents-01.yml", "documents-02.yml", "documents-03.yml", "extra.yml"}; !slices.Equal(names, want) {
+		t.Fatalf("expected files %v, got %v", want, names)
+	}
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+	var ids []string
+	for _, doc := range fixtures[0].documents {
+		ids = append(ids, doc.ID)
+	}
+	if want := []string{"alice", "bob", "carol", "dave", "erin", "x"}; !slices.Equal(ids, want) {
+		t.Errorf("expected documents %v in order, got %v", want, ids)
+	}
+
+	// Rewriting without a limit replaces the numbered files.
+	if err := writeDocumentFiles(filepath.Join(dir, "users"), docs[:1], writeConfig{}); err != nil {
+		t.Fatalf("writeDocumentFiles() error: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "users", "documents*.yml")); len(matches) != 1 || filepath.Base(matches[0]) != documentsFile {
+		t.Errorf("expected only %s to remain, got %v", documentsFile, matches)
+	}
+}
@@ -0,0 +1,91 @@
+package testfixtures
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repeatKey is the document key that makes an entry stand for several
+// documents. Each copy replaces seqPlaceholder with its number, counting
+// from 1:
+//
+//	# orders/documents.yml
+//	- _id: "order-{{seq}}"
+//	  _repeat: 200
+//	  position: "{{seq}}"
+//	  status: open
+const repeatKey = "_repeat"
+
+// seqPlaceholder is replaced by the copy's number in the values and keys of
+// a repeated entry. A value that is exactly the placeholder becomes an
+// integer.
+const seqPlaceholder = "{{seq}}"
+
+// repeatCount returns the _repeat count of a document file entry, or zero if
+// the entry is not repeated.
+func repeatCount(node *yaml.Node) (int, error) {
+	if node.Kind != yaml.MappingNode {
+		return 0, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if k.Value != repeatKey || isMergeKey(k) {
+			continue
+		}
+		n, err := strconv.Atoi(v.Value)
+		if v.Kind != yaml.ScalarNode || err != nil || n < 1 || n > maxGenerated {
+			return 0, fmt.Errorf("line %d: %s must be an integer between 1 and %d", v.Line, repeatKey, maxGenerated)
+		}
+		return n, nil
+	}
+	return 0, nil
+}
+
+// repeat returns count copies of the entry node without its _repeat key,
+// with seqPlaceholder replaced by each copy's number.
+func repeat(node *yaml.Node, count int) []*yaml.Node {
+	entry := *node
+	entry.Content = make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if k := node.Content[i]; k.Value == repeatKey && !isMergeKey(k) {
+			continue
+		}
+		entry.Content = append(entry.Content, node.Content[i], node.Content[i+1])
+	}
+
+	nodes := make([]*yaml.Node, count)
+	for i := range nodes {
+		nodes[i] = withSeq(&entry, strconv.Itoa(i+1), false)
+	}
+	return nodes
+}
+
+// withSeq returns a copy of node with seqPlaceholder replaced by seq. Nodes
+// without the placeholder are shared rather than copied.
+func withSeq(node *yaml.Node, seq string, key bool) *yaml.Node {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, seqPlaceholder) {
+			return node
+		}
+		out := *node
+		if node.Value == seqPlaceholder && !key {
+			out.Tag, out.Style = "!!int", 0
+		}
+		out.Value = strings.ReplaceAll(node.Value, seqPlaceholder, seq)
+		return &out
+	case yaml.AliasNode:
+		return withSeq(node.Alias, seq, key)
+	case yaml.MappingNode, yaml.SequenceNode:
+		out := *node
+		out.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			out.Content[i] = withSeq(child, seq, node.Kind == yaml.MappingNode && i%2 == 0)
+		}
+		return &out
+	}
+	return node
+}
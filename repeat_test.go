@@ -0,0 +1,80 @@
+package testfixtures
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseFixtures_Repeat(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml": `- _id: first
+  position: 0
+- _id: "order-{{seq}}"
+  _repeat: 3
+  position: "{{seq}}"
+  label: "Order {{seq}} of 3"
+  tags: ["batch-{{seq}}", "{{seq}}"]
+  status: open
+`,
+	})
+
+	fixtures, err := parseFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("parseFixtures() error: %v", err)
+	}
+	docs := fixtures[0].documents
+	if len(docs) != 4 {
+		t.Fatalf("expected 4 documents, got %d", len(docs))
+	}
+
+	want := []string{
+		`{"position":1,"label":"Order 1 of 3","tags":["batch-1",1],"status":"open"}`,
+		`{"position":2,"label":"Order 2 of 3","tags":["batch-2",2],"status":"open"}`,
+		`{"position":3,"label":"Order 3 of 3","tags":["batch-3",3],"status":"open"}`,
+	}
+	for i, doc := range docs[1:] {
+		if wantID := fmt.Sprintf("order-%d", i+1); doc.ID != wantID {
+			t.Errorf("copy %d: expected _id %q, got %q", i+1, wantID, doc.ID)
+		}
+		if string(doc.Source) != want[i] {
+			t.Errorf("copy %d: expected %s, got %s", i+1, want[i], doc.Source)
+		}
+		if doc.Location() != "orders/documents.yml:3" || doc.pos != i+2 {
+			t.Errorf("copy %d: expected position %d at the entry's line, got %d at %s", i+1, i+2, doc.pos, doc.Location())
+		}
+	}
+}
+
+func TestParseFixtures_RepeatWithTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/documents.yml": "- _id: \"{{ \"order\" }}-{{seq}}\"\n  _repeat: 2\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir), WithTemplates(nil))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	docs := loader.fixtures[0].documents
+	if len(docs) != 2 || docs[0].ID != "order-1" || docs[1].ID != "order-2" {
+		t.Errorf("expected order-1 and order-2, got %+v", docs)
+	}
+}
+
+func TestParseFixtures_RepeatErrors(t *testing.T) {
+	for _, count := range []string{"0", "many", "[1]"} {
+		t.Run(count, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{
+				"orders/documents.yml": "- _id: a\n  _repeat: " + count + "\n",
+			})
+			_, err := parseFixtures(os.DirFS(dir), ".")
+			if err == nil || !strings.Contains(err.Error(), "_repeat must be an integer between 1 and") {
+				t.Errorf("expected a _repeat error, got %v", err)
+			}
+		})
+	}
+}
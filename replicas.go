@@ -0,0 +1,155 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// defaultReplicas is the number_of_replicas Elasticsearch gives an index
+// whose settings do not set it.
+const defaultReplicas = 1
+
+// indexSetting returns the value of an index setting, such as
+// "index.number_of_replicas", whether written nested, dotted, or without the
+// "index." prefix. The second result reports whether the setting is present.
+func indexSetting(settings json.RawMessage, name string) (json.RawMessage, bool, error) {
+	if settings == nil {
+		return nil, false, nil
+	}
+	return findSetting(settings, "", strings.TrimPrefix(name, "index."))
+}
+
+func findSetting(obj json.RawMessage, prefix, name string) (json.RawMessage, bool, error) {
+	fields, err := decodeObject(obj)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, f := range fields {
+		path := f.key
+		if prefix != "" {
+			path = prefix + "." + f.key
+		}
+		path = strings.TrimPrefix(path, "index.")
+		if path == "index" {
+			path = ""
+		}
+
+		if path == name {
+			return f.value, true, nil
+		}
+		if path == "" || strings.HasPrefix(name, path+".") {
+			if nested, err := decodeObject(f.value); err == nil && len(nested) > 0 {
+				value, ok, err := findSetting(f.value, path, name)
+				if err != nil || ok {
+					return value, ok, err
+				}
+			}
+		}
+	}
+
+	return nil, false, nil
+}
+
+// replicaCount returns the number_of_replicas in settings, or the default
+// if it is not set; explicit reports whether it is. auto reports an
+// auto_expand_replicas setting, under which the count always fits the cluster.
+func replicaCount(settings json.RawMessage) (n int, explicit, auto bool, err error) {
+	v, ok, err := indexSetting(settings, "index.auto_expand_replicas")
+	if err != nil {
+		return 0, false, false, err
+	}
+	if ok && string(v) != "false" && string(v) != `"false"` && string(v) != "null" {
+		return 0, false, true, nil
+	}
+
+	v, ok, err = indexSetting(settings, "index.number_of_replicas")
+	if err != nil || !ok {
+		return defaultReplicas, false, false, err
+	}
+
+	var s string
+	if err := json.Unmarshal(v, &s); err != nil {
+		s = string(v)
+	}
+	if n, err = strconv.Atoi(s); err != nil {
+		return 0, false, false, fmt.Errorf("number_of_replicas must be an integer, got %s", v)
+	}
+
+	return n, true, false, nil
+}
+
+// dataNodeCount returns the number of data nodes in the cluster.
+func dataNodeCount(ctx context.Context, client *elasticsearch.Client) (int, error) {
+	res, err := client.Cluster.Health(client.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("getting cluster health: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return 0, fmt.Errorf("getting cluster health: %w", err)
+	}
+
+	var health struct {
+		DataNodes int `json:"number_of_data_nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return 0, fmt.Errorf("decoding cluster health: %w", err)
+	}
+
+	return health.DataNodes, nil
+}
+
+// checkReplicaCounts reads the cluster's data node count and reports every
+// fixture index asking for more replicas than the cluster can assign, since
+// a copy of a shard is never allocated to the node holding another copy.
+func (l *Loader) checkReplicaCounts(ctx context.Context) error {
+	nodes, err := dataNodeCount(ctx, l.client)
+	if err != nil {
+		return err
+	}
+	l.dataNodes = nodes
+
+	var errs []error
+	for _, f := range l.fixtures {
+		if f.isAlias() {
+			continue
+		}
+		replicas, explicit, _, err := replicaCount(f.settings)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("index %q: %w", f.name, err))
+			continue
+		}
+		// Only replicas set in the fixture are errors; the default of one
+		// replica on a single-node cluster is waited for as far as it can be.
+		if explicit && replicas > nodes-1 {
+			errs = append(errs, fmt.Errorf("index %q has number_of_replicas %d, but the cluster has %d data node(s), so at most %d can be assigned", f.name, replicas, nodes, max(nodes-1, 0)))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("checking replica counts: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// activeShards returns the wait_for_active_shards value with which to create
+// f: every copy that the cluster can assign, once checkReplicaCounts has read
+// the node count, and the Elasticsearch default otherwise.
+func (l *Loader) activeShards(f *indexFixture) string {
+	if l.dataNodes == 0 {
+		return ""
+	}
+	replicas, _, auto, err := replicaCount(f.settings)
+	if err != nil || auto {
+		return ""
+	}
+	return strconv.Itoa(min(replicas, l.dataNodes-1) + 1)
+}
@@ -0,0 +1,83 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReplicaCount(t *testing.T) {
+	tests := []struct {
+		settings string
+		want     int
+		explicit bool
+		auto     bool
+	}{
+		{settings: `{"number_of_shards":1}`, want: 1},
+		{settings: `{"number_of_replicas":3}`, want: 3, explicit: true},
+		{settings: `{"index.number_of_replicas":"2"}`, want: 2, explicit: true},
+		{settings: `{"index":{"number_of_replicas":0}}`, want: 0, explicit: true},
+		{settings: `{"index":{"auto_expand_replicas":"0-all","number_of_replicas":5}}`, auto: true},
+		{settings: `{"auto_expand_replicas":false,"number_of_replicas":2}`, want: 2, explicit: true},
+	}
+	for _, tt := range tests {
+		n, explicit, auto, err := replicaCount(json.RawMessage(tt.settings))
+		if err != nil {
+			t.Errorf("replicaCount(%s) error: %v", tt.settings, err)
+			continue
+		}
+		if n != tt.want || explicit != tt.explicit || auto != tt.auto {
+			t.Errorf("replicaCount(%s) = %d, %v, %v; want %d, %v, %v", tt.settings, n, explicit, auto, tt.want, tt.explicit, tt.auto)
+		}
+	}
+}
+
+func TestLoad_ValidateReplicaCounts(t *testing.T) {
+	newDir := func(settings string) string {
+		dir := t.TempDir()
+		writeFixtureFiles(t, dir, map[string]string{
+			"orders/_settings.json": settings,
+			"orders/documents.yml":  "- _id: 1\n",
+		})
+		return dir
+	}
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/_cluster/health"):
+			return jsonResponse(200, `{"number_of_data_nodes":2}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(newDir(`{"number_of_replicas":3}`)), ValidateReplicaCounts())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	err = loader.Load()
+	if err == nil || !strings.Contains(err.Error(), `index "orders" has number_of_replicas 3, but the cluster has 2 data node(s)`) {
+		t.Fatalf("expected a replica count error, got %v", err)
+	}
+	for _, r := range requests {
+		if strings.HasPrefix(r, "DELETE ") || strings.HasPrefix(r, "PUT ") {
+			t.Errorf("expected no index to be touched before the check, got %q", r)
+		}
+	}
+
+	requests = nil
+	loader, err = New(client, Directory(newDir(`{"number_of_replicas":1}`)), ValidateReplicaCounts())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !strings.Contains(strings.Join(requests, "\n"), "PUT /orders?wait_for_active_shards=2") {
+		t.Errorf("expected the index to be created waiting for both copies, got:\n%s", strings.Join(requests, "\n"))
+	}
+}
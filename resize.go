@@ -0,0 +1,214 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ShrinkIndex shrinks a loaded fixture index into a new index target with
+// the given number of primary shards, which must divide the source's. The
+// preconditions of the Shrink API are handled: the source is write-blocked
+// and a copy of every shard is moved to a single node first, and both are
+// undone afterwards on the source and cleared on the target.
+//
+// The target is managed like a fixture index: it is named with the prefix
+// and suffix of WithIndexPrefix and WithIndexSuffix, and deleted by Clean
+// and by the next Load.
+func (l *Loader) ShrinkIndex(source, target string, shards int) error {
+	if shards < 1 {
+		return fmt.Errorf("testfixtures: shrinking %q: shards must be at least 1, got %d", source, shards)
+	}
+	return l.resize("shrinking", source, target, shards, func(ctx context.Context, source, target string, body io.Reader) (*esapi.Response, error) {
+		return l.client.Indices.Shrink(source, target, l.client.Indices.Shrink.WithBody(body), l.client.Indices.Shrink.WithContext(ctx))
+	})
+}
+
+// SplitIndex splits a loaded fixture index into a new index target with the
+// given number of primary shards, which must be a multiple of the source's.
+// The source is write-blocked for the duration of the split. Like
+// ShrinkIndex, the target is deleted by Clean and by the next Load.
+func (l *Loader) SplitIndex(source, target string, shards int) error {
+	if shards < 1 {
+		return fmt.Errorf("testfixtures: splitting %q: shards must be at least 1, got %d", source, shards)
+	}
+	return l.resize("splitting", source, target, shards, func(ctx context.Context, source, target string, body io.Reader) (*esapi.Response, error) {
+		return l.client.Indices.Split(source, target, l.client.Indices.Split.WithBody(body), l.client.Indices.Split.WithContext(ctx))
+	})
+}
+
+// CloneIndex copies a loaded fixture index, with its mapping, settings, and
+// documents, into a new index target. The source is write-blocked for the
+// duration of the clone. Like ShrinkIndex, the target is deleted by Clean and
+// by the next Load.
+func (l *Loader) CloneIndex(source, target string) error {
+	return l.resize("cloning", source, target, 0, func(ctx context.Context, source, target string, body io.Reader) (*esapi.Response, error) {
+		return l.client.Indices.Clone(source, target, l.client.Indices.Clone.WithBody(body), l.client.Indices.Clone.WithContext(ctx))
+	})
+}
+
+// resize runs a Shrink, Split, or Clone request from source to target,
+// preparing the source beforehand and restoring it afterwards. A shards of
+// zero keeps the source's shard count; shrinking also colocates the shards.
+// Both names are given as fixture names and passed to do as index names.
+func (l *Loader) resize(verb, source, target string, shards int, do func(ctx context.Context, source, target string, body io.Reader) (*esapi.Response, error)) error {
+	f := l.fixture(source)
+	if f == nil || f.isAlias() {
+		return fmt.Errorf("testfixtures: %s %q: not a fixture index", verb, source)
+	}
+	if l.fixture(target) != nil {
+		return fmt.Errorf("testfixtures: %s %q: target %q is a fixture index", verb, source, target)
+	}
+	source, target = l.IndexName(source), l.IndexName(target)
+
+	ctx := l.ctx
+	prepare := map[string]any{"index.blocks.write": true}
+	if verb == "shrinking" {
+		node, err := primaryNode(ctx, l.client, source)
+		if err != nil {
+			return fmt.Errorf("testfixtures: %s %q: %w", verb, source, err)
+		}
+		prepare["index.routing.allocation.require._name"] = node
+	}
+	if err := updateIndexSettings(ctx, l.client, source, prepare); err != nil {
+		return fmt.Errorf("testfixtures: %s %q: %w", verb, source, err)
+	}
+	if err := waitForRelocation(ctx, l.client, source); err != nil {
+		return fmt.Errorf("testfixtures: %s %q: %w", verb, source, err)
+	}
+
+	// The target inherits the source's settings, including the ones set
+	// above; they are cleared in the request.
+	settings := map[string]any{
+		"index.blocks.write":                     nil,
+		"index.routing.allocation.require._name": nil,
+	}
+	if shards > 0 {
+		settings["index.number_of_shards"] = shards
+	}
+	body, err := json.Marshal(map[string]any{"settings": settings})
+	if err != nil {
+		return fmt.Errorf("testfixtures: %s %q: %w", verb, source, err)
+	}
+
+	resizeErr := func() error {
+		res, err := do(ctx, source, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = res.Body.Close() }()
+		return checkResponse(res)
+	}()
+	if resizeErr == nil {
+		l.derived = append(l.derived, target)
+	}
+
+	// A source declared read_only in _config.yml keeps its write block.
+	restore := map[string]any{"index.routing.allocation.require._name": nil}
+	if f.config.State != stateReadOnly {
+		restore["index.blocks.write"] = nil
+	}
+	restoreErr := updateIndexSettings(ctx, l.client, source, restore)
+
+	if resizeErr != nil {
+		return fmt.Errorf("testfixtures: %s %q into %q: %w", verb, source, target, resizeErr)
+	}
+	if restoreErr != nil {
+		return fmt.Errorf("testfixtures: %s %q: restoring settings: %w", verb, source, restoreErr)
+	}
+
+	return nil
+}
+
+// updateIndexSettings applies settings to an index; nil values reset a
+// setting to its default.
+func updateIndexSettings(ctx context.Context, client *elasticsearch.Client, name string, settings map[string]any) error {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("encoding settings: %w", err)
+	}
+
+	res, err := client.Indices.PutSettings(bytes.NewReader(body),
+		client.Indices.PutSettings.WithIndex(name),
+		client.Indices.PutSettings.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("updating settings of %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("updating settings of %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// primaryNode returns the name of a node holding a started primary shard of
+// the index, to which its other shards can be moved for a shrink.
+func primaryNode(ctx context.Context, client *elasticsearch.Client, name string) (string, error) {
+	res, err := client.Cat.Shards(
+		client.Cat.Shards.WithIndex(name),
+		client.Cat.Shards.WithFormat("json"),
+		client.Cat.Shards.WithH("prirep", "state", "node"),
+		client.Cat.Shards.WithContext(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("listing shards of %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return "", fmt.Errorf("listing shards of %q: %w", name, err)
+	}
+
+	var shards []struct {
+		PriRep string `json:"prirep"`
+		State  string `json:"state"`
+		Node   string `json:"node"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&shards); err != nil {
+		return "", fmt.Errorf("decoding shards of %q: %w", name, err)
+	}
+	for _, s := range shards {
+		if s.PriRep == "p" && s.State == "STARTED" && s.Node != "" {
+			return s.Node, nil
+		}
+	}
+
+	return "", fmt.Errorf("index %q has no started primary shard", name)
+}
+
+// waitForRelocation waits until no shard of the index is relocating.
+func waitForRelocation(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.Cluster.Health(
+		client.Cluster.Health.WithIndex(name),
+		client.Cluster.Health.WithWaitForNoRelocatingShards(true),
+		client.Cluster.Health.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("waiting for shards of %q to relocate: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("waiting for shards of %q to relocate: %w", name, err)
+	}
+
+	var health struct {
+		TimedOut bool `json:"timed_out"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return fmt.Errorf("decoding health of %q: %w", name, err)
+	}
+	if health.TimedOut {
+		return fmt.Errorf("timed out waiting for shards of %q to relocate", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestShrinkIndex_Requests(t *testing.T) {
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		line := req.Method + " " + req.URL.Path
+		if req.Body != nil && !strings.HasSuffix(req.URL.Path, "/_bulk") {
+			body, _ := io.ReadAll(req.Body)
+			line += " " + string(body)
+		}
+		requests = append(requests, line)
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/_cat/shards"):
+			return jsonResponse(200, `[{"prirep":"r","state":"UNASSIGNED","node":null},{"prirep":"p","state":"STARTED","node":"es01"}]`), nil
+		case strings.HasPrefix(req.URL.Path, "/_cluster/health"):
+			return jsonResponse(200, `{"timed_out":false}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.ShrinkIndex("products", "products_small", 1); err != nil {
+		t.Fatalf("ShrinkIndex() error: %v", err)
+	}
+
+	want := []string{
+		"GET /_cat/shards/products",
+		`PUT /products/_settings {"index.blocks.write":true,"index.routing.allocation.require._name":"es01"}`,
+		"GET /_cluster/health/products",
+		`PUT /products/_shrink/products_small {"settings":{"index.blocks.write":null,"index.number_of_shards":1,"index.routing.allocation.require._name":null}}`,
+		`PUT /products/_settings {"index.blocks.write":null,"index.routing.allocation.require._name":null}`,
+	}
+	if got := strings.Join(requests, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// The target is deleted with the fixtures
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if len(requests) == 0 || requests[0] != "DELETE /products_small" {
+		t.Errorf("expected Clean to delete products_small first, got %v", requests)
+	}
+}
+
+func TestCloneIndex_NotAFixture(t *testing.T) {
+	loader, err := New(newOfflineClient(t), Directory("testdata/fixtures"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.CloneIndex("unmanaged", "copy"); err == nil {
+		t.Error("expected an error cloning an index not managed by the loader")
+	}
+	if err := loader.CloneIndex("products", "users"); err == nil {
+		t.Error("expected an error cloning over a fixture index")
+	}
+}
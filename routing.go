@@ -0,0 +1,274 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// mappingRouting is the part of a mapping that decides how documents must be
+// routed: the _routing meta field and the properties, for a join field.
+type mappingRouting struct {
+	Routing struct {
+		Required bool `json:"required"`
+	} `json:"_routing"`
+	Properties map[string]struct {
+		Type      string                     `json:"type"`
+		Relations map[string]json.RawMessage `json:"relations"`
+	} `json:"properties"`
+}
+
+// parseMappingRouting returns whether the mapping requires every document to
+// have a custom routing value, and the name of its join field, if any. Join
+// fields can only be mapped at the top level.
+func parseMappingRouting(mapping json.RawMessage) (required bool, join string) {
+	if mapping == nil {
+		return false, ""
+	}
+	var m mappingRouting
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return false, ""
+	}
+	for name, p := range m.Properties {
+		if p.Type == "join" {
+			join = name
+		}
+	}
+	return m.Routing.Required, join
+}
+
+// joinParent returns the parent _id of a child document of a join field,
+// given as {"name": "answer", "parent": "1"}.
+func joinParent(source json.RawMessage, join string) (string, bool) {
+	values, err := lookupField(source, []string{join})
+	if err != nil || len(values) != 1 {
+		return "", false
+	}
+	var relation struct {
+		Parent json.RawMessage `json:"parent"`
+	}
+	if err := json.Unmarshal(values[0], &relation); err != nil || relation.Parent == nil {
+		return "", false
+	}
+	var parent any
+	if err := json.Unmarshal(relation.Parent, &parent); err != nil {
+		return "", false
+	}
+	switch parent := parent.(type) {
+	case string:
+		return parent, true
+	case float64:
+		return string(relation.Parent), true
+	}
+	return "", false
+}
+
+// joinRelations returns the relations of the join field join of a mapping,
+// as the child relations of each parent relation.
+func joinRelations(mapping json.RawMessage, join string) map[string][]string {
+	var m mappingRouting
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil
+	}
+	relations := make(map[string][]string)
+	for parent, raw := range m.Properties[join].Relations {
+		var children []string
+		if json.Unmarshal(raw, &children) != nil {
+			var child string
+			if json.Unmarshal(raw, &child) != nil {
+				continue
+			}
+			children = []string{child}
+		}
+		relations[parent] = children
+	}
+	return relations
+}
+
+// joinRelation returns the relation name of a document of the join field
+// join, given as "question" or {"name": "answer", ...}, or "" if it has none.
+func joinRelation(source json.RawMessage, join string) string {
+	values, err := lookupField(source, []string{join})
+	if err != nil || len(values) != 1 {
+		return ""
+	}
+	var name string
+	if json.Unmarshal(values[0], &name) == nil {
+		return name
+	}
+	var relation struct {
+		Name string `json:"name"`
+	}
+	_ = json.Unmarshal(values[0], &relation)
+	return relation.Name
+}
+
+// expandParents sets the join field of each document of f that names its
+// parent with _parent to {"name": <relation>, "parent": <_parent>}. The
+// relation is the one the document gives as its join value, or else the
+// only child relation of its parent's relation. Parents are looked up
+// among the documents of f, as routing needs them to be.
+func (l *Loader) expandParents(f *indexFixture, join string) error {
+	if !slices.ContainsFunc(f.documents, func(doc Document) bool { return doc.parent != "" }) {
+		return nil
+	}
+
+	byID := make(map[string]int, len(f.documents))
+	for i, doc := range f.documents {
+		if doc.ID != "" {
+			byID[doc.ID] = i
+		}
+	}
+	relations := joinRelations(f.mapping, join)
+
+	var (
+		errs    []error
+		done    = make(map[int]bool) // Documents resolved or failed
+		ok      = make(map[int]bool) // Documents whose join field is complete
+		resolve func(i int, depth int) bool
+	)
+	fail := func(i int, format string, args ...any) bool {
+		doc := f.documents[i]
+		errs = append(errs, fmt.Errorf("index %q: %s: %s", f.name, doc.Location(), fmt.Sprintf(format, args...)))
+		return false
+	}
+	resolve = func(i int, depth int) bool {
+		doc := &f.documents[i]
+		if doc.parent == "" {
+			return true
+		}
+		if done[i] {
+			return ok[i]
+		}
+		done[i] = true
+		switch {
+		case join == "":
+			return fail(i, "document %q sets %s, but the mapping has no join field", doc.ID, parentKey)
+		case depth > len(f.documents):
+			return fail(i, "document %q is its own ancestor through %s", doc.ID, parentKey)
+		}
+
+		p, found := byID[doc.parent]
+		if !found {
+			return fail(i, "%s %q is not a document of the index", parentKey, doc.parent)
+		}
+		done[i] = false
+		if !resolve(p, depth+1) {
+			// The parent's error is reported on its own
+			done[i] = true
+			return false
+		}
+		done[i] = true
+
+		parentRelation := joinRelation(f.documents[p].Source, join)
+		children := relations[parentRelation]
+		switch {
+		case parentRelation == "":
+			return fail(i, "parent %q has no %q relation", doc.parent, join)
+		case len(children) == 0:
+			return fail(i, "parent %q has relation %q, which has no child relations", doc.parent, parentRelation)
+		}
+
+		values, _ := lookupField(doc.Source, []string{join})
+		relation := joinRelation(doc.Source, join)
+		switch {
+		case len(values) > 0 && json.Unmarshal(values[0], new(string)) != nil:
+			return fail(i, "document %q sets %s, so %q must be just the relation name", doc.ID, parentKey, join)
+		case relation == "" && len(children) > 1:
+			return fail(i, "document %q must set %q to one of the child relations %s of %q", doc.ID, join, quoteAll(children), parentRelation)
+		case relation == "":
+			relation = children[0]
+		case !slices.Contains(children, relation):
+			return fail(i, "relation %q of document %q is not a child relation of %q", relation, doc.ID, parentRelation)
+		}
+
+		value := encodeObject([]jsonField{
+			{key: "name", value: json.RawMessage(strconv.Quote(relation))},
+			{key: "parent", value: json.RawMessage(strconv.Quote(doc.parent))},
+		})
+		set := func(json.RawMessage) (json.RawMessage, error) { return value, nil }
+		var err error
+		if len(values) > 0 {
+			doc.Source, err = mapField(doc.Source, []string{join}, set)
+		} else {
+			doc.Source, err = setMissingField(doc.Source, []string{join}, func() (json.RawMessage, error) { return value, nil })
+		}
+		if err != nil {
+			return fail(i, "setting %q: %v", join, err)
+		}
+		ok[i] = true
+		return true
+	}
+	for i := range f.documents {
+		resolve(i, 0)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkRouting fills in the join field of documents that set _parent, then
+// gives each child document of a join field that has no _routing the
+// routing of its parent, since Elasticsearch requires a child to be on its
+// parent's shard. The parent's own routing is used if it has one, so
+// grandchildren follow the root document of their family. It then checks
+// that every document has a routing value where the mapping requires one,
+// so a missing _routing is reported with its file and line rather than as
+// a bulk failure.
+func (l *Loader) checkRouting() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		required, join := parseMappingRouting(f.mapping)
+		if err := l.expandParents(f, join); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if join != "" {
+			routeFamilies(f.documents, join)
+		}
+		if !required {
+			continue
+		}
+		for _, doc := range f.documents {
+			if doc.Routing == "" {
+				errs = append(errs, fmt.Errorf("index %q: %s: document %q has no _routing, which the mapping requires", f.name, doc.Location(), doc.ID))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// routeFamilies sets the routing of the child documents of a join field in
+// docs, by following parents within docs to the root of each family.
+func routeFamilies(docs []Document, join string) {
+	byID := make(map[string]int, len(docs))
+	for i, doc := range docs {
+		if doc.ID != "" {
+			byID[doc.ID] = i
+		}
+	}
+
+	var routing func(i int, depth int) string
+	routing = func(i int, depth int) string {
+		doc := &docs[i]
+		if doc.Routing != "" {
+			return doc.Routing
+		}
+		parent, ok := joinParent(doc.Source, join)
+		if !ok {
+			return ""
+		}
+		doc.Routing = parent
+		if j, ok := byID[parent]; ok && depth < len(docs) {
+			if r := routing(j, depth+1); r != "" {
+				doc.Routing = r
+			}
+		}
+		return doc.Routing
+	}
+	for i := range docs {
+		routing(i, 0)
+	}
+}
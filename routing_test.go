@@ -0,0 +1,188 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNew_JoinChildrenRouting(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"qa/_mapping.json": `{"properties":{"relation":{"type":"join","relations":{"question":"answer","answer":"comment"}}}}`,
+		"qa/documents.yml": `- _id: "1"
+  relation: question
+- _id: "2"
+  relation: {name: answer, parent: "1"}
+- _id: "3"
+  relation: {name: comment, parent: "2"}
+- _id: "4"
+  _routing: explicit
+  relation: {name: answer, parent: "1"}
+- _id: "5"
+  relation: {name: answer, parent: 9}
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	want := map[string]string{"1": "", "2": "1", "3": "1", "4": "explicit", "5": "9"}
+	for _, doc := range loader.fixture("qa").documents {
+		if doc.Routing != want[doc.ID] {
+			t.Errorf("document %q: expected routing %q, got %q", doc.ID, want[doc.ID], doc.Routing)
+		}
+	}
+}
+
+func TestNew_JoinParent(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"qa/_mapping.json": `{"properties":{"relation":{"type":"join","relations":{"question":["answer","note"],"answer":"comment"}}}}`,
+		"qa/documents.yml": `- _id: c1
+  _parent: a1
+  text: Thanks
+- _id: q1
+  relation: question
+- _id: a1
+  _parent: q1
+  relation: answer
+- _id: n1
+  _parent: q1
+  relation: note
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	want := map[string]struct{ routing, source string }{
+		"c1": {"q1", `{"text":"Thanks","relation":{"name":"comment","parent":"a1"}}`},
+		"q1": {"", `{"relation":"question"}`},
+		"a1": {"q1", `{"relation":{"name":"answer","parent":"q1"}}`},
+		"n1": {"q1", `{"relation":{"name":"note","parent":"q1"}}`},
+	}
+	for _, doc := range loader.fixture("qa").documents {
+		w := want[doc.ID]
+		if doc.Routing != w.routing {
+			t.Errorf("document %q: expected routing %q, got %q", doc.ID, w.routing, doc.Routing)
+		}
+		if string(doc.Source) != w.source {
+			t.Errorf("document %q: expected source %s, got %s", doc.ID, w.source, doc.Source)
+		}
+	}
+}
+
+func TestNew_JoinParentErrors(t *testing.T) {
+	const mapping = `{"properties":{"relation":{"type":"join","relations":{"question":["answer","note"],"answer":"comment"}}}}`
+	tests := []struct {
+		name      string
+		mapping   string
+		documents string
+		wantErr   string
+	}{
+		{
+			name:      "no join field",
+			mapping:   `{"properties":{"title":{"type":"text"}}}`,
+			documents: "- _id: q1\n- _id: a1\n  _parent: q1\n",
+			wantErr:   `qa/documents.yml:2: document "a1" sets _parent, but the mapping has no join field`,
+		},
+		{
+			name:      "unknown parent",
+			documents: "- _id: a1\n  _parent: q9\n",
+			wantErr:   `qa/documents.yml:1: _parent "q9" is not a document of the index`,
+		},
+		{
+			name:      "ambiguous relation",
+			documents: "- _id: q1\n  relation: question\n- _id: a1\n  _parent: q1\n",
+			wantErr:   `qa/documents.yml:3: document "a1" must set "relation" to one of the child relations "answer", "note" of "question"`,
+		},
+		{
+			name:      "not a child relation",
+			documents: "- _id: q1\n  relation: question\n- _id: a1\n  _parent: q1\n  relation: comment\n",
+			wantErr:   `qa/documents.yml:3: relation "comment" of document "a1" is not a child relation of "question"`,
+		},
+		{
+			name:      "join object",
+			documents: "- _id: q1\n  relation: question\n- _id: a1\n  _parent: q1\n  relation: {name: answer, parent: q1}\n",
+			wantErr:   `qa/documents.yml:3: document "a1" sets _parent, so "relation" must be just the relation name`,
+		},
+		{
+			name:      "parent without relation",
+			documents: "- _id: q1\n- _id: a1\n  _parent: q1\n",
+			wantErr:   `qa/documents.yml:2: parent "q1" has no "relation" relation`,
+		},
+		{
+			name:      "cycle",
+			documents: "- _id: a1\n  _parent: c1\n- _id: c1\n  _parent: a1\n",
+			wantErr:   `is its own ancestor through _parent`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mapping == "" {
+				tt.mapping = mapping
+			}
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{
+				"qa/_mapping.json": tt.mapping,
+				"qa/documents.yml": tt.documents,
+			})
+
+			_, err := New(newOfflineClient(t), Directory(dir))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNew_RoutingRequired(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_mapping.json": `{"_routing":{"required":true}}`,
+		"orders/documents.yml": "- _id: \"1\"\n  _routing: acme\n- _id: \"2\"\n  total: 5\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil || !strings.Contains(err.Error(), `index "orders": orders/documents.yml:3: document "2" has no _routing, which the mapping requires`) {
+		t.Errorf("expected a missing routing error, got %v", err)
+	}
+}
+
+func TestLoad_SendsRouting(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"qa/_mapping.json": `{"properties":{"relation":{"type":"join","relations":{"question":"answer"}}}}`,
+		"qa/documents.yml": "- _id: q1\n  relation: question\n- _id: a1\n  relation: {name: answer, parent: q1}\n",
+	})
+
+	var body string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"q1","status":201}},{"index":{"_id":"a1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	for _, want := range []string{`{"index":{"_id":"q1"}}`, `{"index":{"_id":"a1","routing":"q1"}}`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the bulk request, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// mergeRuntimeMappings adds runtime field definitions to the "runtime"
+// section of a mapping. Fields already defined in the mapping's own runtime
+// section are reported as conflicts rather than silently overridden.
+func mergeRuntimeMappings(mapping, runtime json.RawMessage) (json.RawMessage, []string, error) {
+	runtimeFields, err := decodeObject(runtime)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s must contain a JSON object: %w", runtimeMappingsFile, err)
+	}
+
+	names := make([]string, 0, len(runtimeFields))
+	for _, f := range runtimeFields {
+		names = append(names, f.key)
+	}
+
+	if mapping == nil {
+		return encodeObject([]jsonField{{key: "runtime", value: runtime}}), names, nil
+	}
+
+	fields, err := decodeObject(mapping)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s must contain a JSON object: %w", mappingFile, err)
+	}
+
+	for i, f := range fields {
+		if f.key != "runtime" {
+			continue
+		}
+
+		existing, err := decodeObject(f.value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("runtime section of %s must be a JSON object: %w", mappingFile, err)
+		}
+		defined := make(map[string]bool, len(existing))
+		for _, e := range existing {
+			defined[e.key] = true
+		}
+		for _, rf := range runtimeFields {
+			if defined[rf.key] {
+				return nil, nil, fmt.Errorf("runtime field %q is defined in both %s and %s", rf.key, mappingFile, runtimeMappingsFile)
+			}
+		}
+
+		fields[i].value = encodeObject(append(existing, runtimeFields...))
+		return encodeObject(fields), names, nil
+	}
+
+	return encodeObject(append(fields, jsonField{key: "runtime", value: runtime})), names, nil
+}
+
+// checkRuntimeFields runs a search that computes the given runtime fields on
+// a document, so script compilation or execution errors surface at load time
+// instead of in the first test that queries them.
+func checkRuntimeFields(ctx context.Context, client *elasticsearch.Client, index string, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"size":    1,
+		"_source": false,
+		"fields":  fields,
+	})
+	if err != nil {
+		return fmt.Errorf("building runtime field query: %w", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("checking runtime fields of %q: %w", index, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("checking runtime fields of %q: %w", index, err)
+	}
+
+	var result struct {
+		Shards struct {
+			Failed   int `json:"failed"`
+			Failures []struct {
+				Reason struct {
+					Type     string `json:"type"`
+					Reason   string `json:"reason"`
+					CausedBy struct {
+						Reason string `json:"reason"`
+					} `json:"caused_by"`
+				} `json:"reason"`
+			} `json:"failures"`
+		} `json:"_shards"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding runtime field query response: %w", err)
+	}
+
+	if result.Shards.Failed > 0 {
+		var reasons []string
+		for _, f := range result.Shards.Failures {
+			reason := f.Reason.Type + ": " + f.Reason.Reason
+			if f.Reason.CausedBy.Reason != "" {
+				reason += " (" + f.Reason.CausedBy.Reason + ")"
+			}
+			reasons = append(reasons, reason)
+		}
+		return fmt.Errorf("runtime fields of %q failed on %d shards: %s", index, result.Shards.Failed, strings.Join(reasons, "; "))
+	}
+
+	return nil
+}
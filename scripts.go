@@ -0,0 +1,156 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// scriptsDir is the top-level directory holding stored scripts, installed by
+// Load before any document is sent. A <id>.json file is a script in the
+// format of the Create Stored Script API, and a file with an extension of
+// scriptLanguages is the source of a script in that language.
+const scriptsDir = "_scripts"
+
+// scriptLanguages maps the extensions of script source files to the
+// language they are stored with. Mustache scripts are search templates,
+// run by the search template API.
+var scriptLanguages = map[string]string{
+	".painless": "painless",
+	".mustache": "mustache",
+}
+
+// storedScript is a script of the _scripts directory.
+type storedScript struct {
+	id   string
+	body json.RawMessage
+}
+
+// readStoredScripts reads the scripts of the _scripts directory, ordered by
+// id. A missing directory means there are none.
+func readStoredScripts(fsys fs.FS, dir string) ([]storedScript, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, scriptsDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", scriptsDir, err)
+	}
+
+	var scripts []storedScript
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := path.Ext(name)
+		if _, ok := scriptLanguages[ext]; entry.IsDir() || (ext != ".json" && !ok) {
+			continue
+		}
+		id := strings.TrimSuffix(name, ext)
+		if other, ok := seen[id]; ok {
+			return nil, fmt.Errorf("%s: script %q is defined by both %s and %s", scriptsDir, id, other, name)
+		}
+		seen[id] = name
+
+		body, err := readStoredScript(fsys, path.Join(dir, scriptsDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", scriptsDir, name, err)
+		}
+		scripts = append(scripts, storedScript{id: id, body: body})
+	}
+
+	return scripts, nil
+}
+
+// readStoredScript returns the Create Stored Script request body of a file
+// of _scripts, wrapping the contents of a source file.
+func readStoredScript(fsys fs.FS, name string) (json.RawMessage, error) {
+	if lang, ok := scriptLanguages[path.Ext(name)]; ok {
+		source, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(source)) == 0 {
+			return nil, errors.New("script source is empty")
+		}
+		return marshalUnescaped(map[string]any{
+			"script": map[string]string{"lang": lang, "source": string(source)},
+		})
+	}
+
+	body, err := readJSONFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var def struct {
+		Script json.RawMessage `json:"script"`
+	}
+	if !isJSONObject(body) || json.Unmarshal(body, &def) != nil || !isJSONObject(def.Script) {
+		return nil, errors.New(`script must be an object of the form {"script": {"lang": ..., "source": ...}}`)
+	}
+	return body, nil
+}
+
+// putStoredScripts installs the scripts of _scripts, replacing any of the
+// same id. Ids are used as they are, since queries refer to them by id.
+func (l *Loader) putStoredScripts(ctx context.Context) error {
+	for _, s := range l.storedScripts {
+		if err := putStoredScript(ctx, l.client, s.id, s.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteStoredScripts removes the scripts of _scripts and returns the
+// errors of the deletions that failed.
+func (l *Loader) deleteStoredScripts(ctx context.Context) []error {
+	var errs []error
+	for _, s := range l.storedScripts {
+		if err := deleteStoredScript(ctx, l.client, s.id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// putStoredScript creates or replaces the stored script id.
+func putStoredScript(ctx context.Context, client *elasticsearch.Client, id string, body json.RawMessage) error {
+	res, err := client.PutScript(id, bytes.NewReader(body), client.PutScript.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating stored script %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating stored script %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// deleteStoredScript deletes the stored script id if it exists.
+func deleteStoredScript(ctx context.Context, client *elasticsearch.Client, id string) error {
+	res, err := client.DeleteScript(id, client.DeleteScript.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting stored script %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The script may already be gone, which is fine
+	if res.StatusCode == 404 {
+		return nil
+	}
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting stored script %q: %w", id, err)
+	}
+
+	return nil
+}
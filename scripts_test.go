@@ -0,0 +1,113 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadStoredScripts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_scripts/boost.painless":  {Data: []byte("doc['rank'].value * params.factor\n")},
+		"_scripts/search.json":     {Data: []byte(`{"script": {"lang": "mustache", "source": {"query": {"match": {"title": "{{q}}"}}}}}`)},
+		"_scripts/by_tag.mustache": {Data: []byte(`{"query": {"terms": {"tags": {{#toJson}}tags{{/toJson}}}}}`)},
+		"_scripts/README.md":       {Data: []byte("notes")},
+	}
+
+	scripts, err := readStoredScripts(fsys, ".")
+	if err != nil {
+		t.Fatalf("readStoredScripts() error: %v", err)
+	}
+	if len(scripts) != 3 || scripts[0].id != "boost" || scripts[1].id != "by_tag" || scripts[2].id != "search" {
+		t.Fatalf("unexpected scripts: %+v", scripts)
+	}
+	want := `{"script":{"lang":"painless","source":"doc['rank'].value * params.factor\n"}}`
+	if got := string(scripts[0].body); got != want {
+		t.Errorf("expected painless script body %s, got %s", want, got)
+	}
+	want = `{"script":{"lang":"mustache","source":"{\"query\": {\"terms\": {\"tags\": {{#toJson}}tags{{/toJson}}}}}"}}`
+	if got := string(scripts[1].body); got != want {
+		t.Errorf("expected mustache script body %s, got %s", want, got)
+	}
+
+	if scripts, err := readStoredScripts(fstest.MapFS{}, "."); scripts != nil || err != nil {
+		t.Errorf("expected no scripts for a missing directory, got %v, %v", scripts, err)
+	}
+}
+
+func TestReadStoredScripts_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{name: "no script", files: map[string]string{"boost.json": `{"lang": "painless"}`}, want: "_scripts/boost.json: script must be an object"},
+		{name: "empty source", files: map[string]string{"boost.painless": "  \n"}, want: "_scripts/boost.painless: script source is empty"},
+		{name: "duplicate", files: map[string]string{"boost.json": `{"script": {}}`, "boost.painless": "1"}, want: `script "boost" is defined by both boost.json and boost.painless`},
+		{name: "duplicate source", files: map[string]string{"boost.mustache": "{}", "boost.painless": "1"}, want: `script "boost" is defined by both boost.mustache and boost.painless`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys["_scripts/"+name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			_, err := readStoredScripts(fsys, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_StoredScripts(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_scripts/boost.painless": "doc['rank'].value * 2",
+		"posts/documents.yml":     "- _id: \"1\"\n  rank: 3\n",
+	})
+
+	var requests []string
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	put := slices.Index(requests, "PUT /_scripts/boost")
+	bulk := slices.Index(requests, "POST /ci_posts/_bulk")
+	if put < 0 || bulk < 0 || put > bulk {
+		t.Fatalf("expected the script to be installed before documents are sent, got %v", requests)
+	}
+	want := `{"script":{"lang":"painless","source":"doc['rank'].value * 2"}}`
+	if got := bodies["/_scripts/boost"]; got != want {
+		t.Errorf("expected script body %s, got %s", want, got)
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if !slices.Contains(requests, "DELETE /_scripts/boost") {
+		t.Errorf("expected Clean to delete the script, got %v", requests)
+	}
+}
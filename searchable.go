@@ -0,0 +1,108 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Polling intervals of AwaitSearchable, doubled after each count that falls
+// short up to the maximum.
+const (
+	searchablePollMin = 10 * time.Millisecond
+	searchablePollMax = time.Second
+)
+
+// AwaitSearchable waits until searches of a fixture index, an alias fixture,
+// or an index made by ShrinkIndex, SplitIndex, or CloneIndex see exactly
+// expectedCount documents, polling the Count API until they do or ctx is
+// done. It covers the cases where the refresh after Load is not enough for
+// a test to read its own writes, such as searches served by replicas that
+// have not caught up, or documents the code under test writes afterwards
+// into an index that refreshes on its own schedule. A target that does not
+// exist yet counts as empty.
+func (l *Loader) AwaitSearchable(ctx context.Context, index string, expectedCount int) error {
+	name, err := l.searchTarget(index)
+	if err != nil {
+		return err
+	}
+
+	interval := searchablePollMin
+	for {
+		n, err := countVisible(ctx, l.client, name)
+		if err != nil {
+			return fmt.Errorf("testfixtures: waiting for %q to be searchable: %w", index, err)
+		}
+		if n == expectedCount {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("testfixtures: waiting for %q to be searchable: %d of %d documents visible: %w", index, n, expectedCount, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval = min(2*interval, searchablePollMax)
+	}
+}
+
+// Count returns the number of documents searches of a fixture index, an
+// alias fixture, or an index made by ShrinkIndex, SplitIndex, or CloneIndex
+// see now, without waiting for any to become visible. A target that does
+// not exist yet counts as empty.
+func (l *Loader) Count(ctx context.Context, index string) (int, error) {
+	name, err := l.searchTarget(index)
+	if err != nil {
+		return 0, err
+	}
+	n, err := countVisible(ctx, l.client, name)
+	if err != nil {
+		return 0, fmt.Errorf("testfixtures: %q: %w", index, err)
+	}
+	return n, nil
+}
+
+// searchTarget returns the name in the cluster of a fixture index, alias
+// fixture, or derived index.
+func (l *Loader) searchTarget(index string) (string, error) {
+	name := l.IndexName(index)
+	switch f := l.fixture(index); {
+	case f != nil && f.isAlias():
+		return l.aliasName(index), nil
+	case f == nil && !slices.Contains(l.derived, name):
+		return "", fmt.Errorf("testfixtures: %q is not a fixture index or alias", index)
+	}
+	return name, nil
+}
+
+// countVisible returns the number of documents searches of name see, which
+// is zero for an index or alias that does not exist.
+func countVisible(ctx context.Context, client *elasticsearch.Client, name string) (int, error) {
+	res, err := client.Count(
+		client.Count.WithContext(ctx),
+		client.Count.WithIndex(name),
+		client.Count.WithIgnoreUnavailable(true),
+		client.Count.WithAllowNoIndices(true),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("counting documents: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return 0, fmt.Errorf("counting documents: %w", err)
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decoding count response: %w", err)
+	}
+
+	return result.Count, nil
+}
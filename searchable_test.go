@@ -0,0 +1,113 @@
+package testfixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAwaitSearchable(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_config.yml":        "alias:\n  indices: [orders_2023, orders_2024]\n",
+		"orders_2023/documents.yml": "- _id: 1\n",
+		"orders_2024/documents.yml": "- _id: 2\n",
+	})
+
+	var paths []string
+	counts := []int{0, 1, 2}
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path)
+		if q := req.URL.Query(); q.Get("ignore_unavailable") != "true" || q.Get("allow_no_indices") != "true" {
+			t.Errorf("expected missing targets to count as empty, got %s", req.URL.RawQuery)
+		}
+		n := counts[0]
+		if len(counts) > 1 {
+			counts = counts[1:]
+		}
+		return jsonResponse(200, fmt.Sprintf(`{"count":%d}`, n)), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.AwaitSearchable(t.Context(), "orders", 2); err != nil {
+		t.Fatalf("AwaitSearchable() error: %v", err)
+	}
+	if len(paths) != 3 || paths[0] != "/ci_orders/_count" {
+		t.Errorf("expected three counts of /ci_orders, got %v", paths)
+	}
+
+	if err := loader.AwaitSearchable(t.Context(), "customers", 0); err == nil {
+		t.Error("expected an error for an index that is not a fixture")
+	}
+}
+
+func TestAwaitSearchable_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": "- _id: 1\n- _id: 2\n"})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"count":1}`), nil
+	}))
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	err = loader.AwaitSearchable(ctx, "users", 2)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AwaitSearchable() error = %v, want a deadline error", err)
+	}
+	if want := `testfixtures: waiting for "users" to be searchable: 1 of 2 documents visible: context deadline exceeded`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestAwaitSearchable_CountError(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": "- _id: 1\n"})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(403, `{"error":{"type":"security_exception","reason":"unauthorized"},"status":403}`), nil
+	}))
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.AwaitSearchable(t.Context(), "users", 1); err == nil {
+		t.Error("expected a failed count to end the wait")
+	}
+}
+
+func TestCount(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{"users/documents.yml": "- _id: 1\n- _id: 2\n"})
+
+	var path string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		path = req.URL.Path
+		return jsonResponse(200, `{"count":1}`), nil
+	}))
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	n, err := loader.Count(t.Context(), "users")
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	if n != 1 || path != "/ci_users/_count" {
+		t.Errorf("expected 1 document counted at /ci_users/_count, got %d at %s", n, path)
+	}
+	if _, err := loader.Count(t.Context(), "customers"); err == nil {
+		t.Error("expected an error for an index that is not a fixture")
+	}
+}
@@ -0,0 +1,208 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// sourceFilter is the _source section of a mapping, which decides the fields
+// of each document Elasticsearch stores in _source and so returns in search
+// hits. Fields it leaves out are still indexed and searchable.
+type sourceFilter struct {
+	Enabled  *bool    `json:"enabled"`
+	Includes []string `json:"includes"`
+	Excludes []string `json:"excludes"`
+}
+
+// parseSourceFilter returns the _source filter of a mapping, or nil if the
+// mapping keeps whole documents.
+func parseSourceFilter(mapping json.RawMessage) *sourceFilter {
+	if mapping == nil {
+		return nil
+	}
+	var m struct {
+		Source *sourceFilter `json:"_source"`
+	}
+	if err := json.Unmarshal(mapping, &m); err != nil || m.Source == nil {
+		return nil
+	}
+	if f := m.Source; f.disabled() || len(f.Includes) > 0 || len(f.Excludes) > 0 {
+		return f
+	}
+	return nil
+}
+
+// disabled reports whether the mapping stores no _source at all.
+func (f *sourceFilter) disabled() bool {
+	return f.Enabled != nil && !*f.Enabled
+}
+
+// apply returns source as Elasticsearch stores it, along with the dotted
+// paths of the fields left out.
+func (f *sourceFilter) apply(source json.RawMessage) (json.RawMessage, []string, error) {
+	fields, err := decodeObject(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.disabled() {
+		dropped := make([]string, len(fields))
+		for i, field := range fields {
+			dropped[i] = field.key
+		}
+		return json.RawMessage(`{}`), dropped, nil
+	}
+
+	kept, dropped, err := f.filterObject(fields, "", len(f.Includes) == 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeObject(kept), dropped, nil
+}
+
+// filterObject filters the fields of an object at prefix. included is true
+// when an include pattern already matched the object or one of its parents.
+func (f *sourceFilter) filterObject(fields []jsonField, prefix string, included bool) ([]jsonField, []string, error) {
+	var (
+		kept    []jsonField
+		dropped []string
+	)
+	for _, field := range fields {
+		path := field.key
+		if prefix != "" {
+			path = prefix + "." + field.key
+		}
+		if matchesAny(f.Excludes, path) {
+			dropped = append(dropped, path)
+			continue
+		}
+
+		value, ok, d, err := f.filterValue(field.value, path, included || matchesAny(f.Includes, path))
+		if err != nil {
+			return nil, nil, err
+		}
+		dropped = append(dropped, d...)
+		if !ok {
+			if len(d) == 0 {
+				dropped = append(dropped, path)
+			}
+			continue
+		}
+		kept = append(kept, jsonField{key: field.key, value: value})
+	}
+	return kept, dropped, nil
+}
+
+// filterValue filters the value of the field at path. ok is false if nothing
+// of the value is kept.
+func (f *sourceFilter) filterValue(value json.RawMessage, path string, included bool) (json.RawMessage, bool, []string, error) {
+	switch {
+	case isJSONObject(value):
+		fields, err := decodeObject(value)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		kept, dropped, err := f.filterObject(fields, path, included)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		if len(kept) == 0 && !included {
+			return nil, false, dropped, nil
+		}
+		return encodeObject(kept), true, dropped, nil
+
+	case isJSONArray(value):
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, false, nil, err
+		}
+		var (
+			kept    []json.RawMessage
+			dropped []string
+		)
+		for _, item := range items {
+			v, ok, d, err := f.filterValue(item, path, included)
+			if err != nil {
+				return nil, false, nil, err
+			}
+			dropped = append(dropped, d...)
+			if ok {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 && !included {
+			return nil, false, dropped, nil
+		}
+		encoded, err := json.Marshal(kept)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		if kept == nil {
+			encoded = []byte(`[]`)
+		}
+		return encoded, true, dropped, nil
+	}
+
+	return value, included, nil, nil
+}
+
+// matchesAny reports whether path matches one of the _source patterns, in
+// which * stands for any run of characters, dots included.
+func matchesAny(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if wildcardMatch(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch reports whether s matches pattern, in which * stands for any
+// run of characters.
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// checkSourceFilters records a warning for each field that fixture
+// documents set but the mapping leaves out of _source, since search hits,
+// FetchAll, and golden files never show it.
+func (l *Loader) checkSourceFilters() error {
+	for _, f := range l.fixtures {
+		filter := parseSourceFilter(f.mapping)
+		if filter == nil {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, doc := range f.documents {
+			_, dropped, err := filter.apply(doc.Source)
+			if err != nil {
+				return fmt.Errorf("index %q: %s: applying _source filter: %w", f.name, doc.Location(), err)
+			}
+			for _, path := range dropped {
+				if seen[path] {
+					continue
+				}
+				seen[path] = true
+				l.warnings = append(l.warnings, fmt.Sprintf("index %q: %s: field %q is left out of _source by the mapping, so search hits, FetchAll, and golden files do not include it; assert on it with a query instead", f.name, doc.Location(), path))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package testfixtures
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSourceFilter_Apply(t *testing.T) {
+	source := `{"name":"Alice","password":"x","profile":{"bio":"hi","secret":{"token":"t"}},"tags":["a"],"addresses":[{"city":"Tokyo","geo":1}]}`
+	disabled := false
+
+	tests := []struct {
+		name    string
+		filter  sourceFilter
+		want    string
+		dropped []string
+	}{
+		{
+			name:    "excludes",
+			filter:  sourceFilter{Excludes: []string{"password", "profile.secret", "addresses.geo"}},
+			want:    `{"name":"Alice","profile":{"bio":"hi"},"tags":["a"],"addresses":[{"city":"Tokyo"}]}`,
+			dropped: []string{"password", "profile.secret", "addresses.geo"},
+		},
+		{
+			name:    "wildcards",
+			filter:  sourceFilter{Excludes: []string{"pass*", "*.token"}},
+			want:    `{"name":"Alice","profile":{"bio":"hi","secret":{}},"tags":["a"],"addresses":[{"city":"Tokyo","geo":1}]}`,
+			dropped: []string{"password", "profile.secret.token"},
+		},
+		{
+			name:    "includes",
+			filter:  sourceFilter{Includes: []string{"name", "profile.*"}, Excludes: []string{"profile.secret"}},
+			want:    `{"name":"Alice","profile":{"bio":"hi"}}`,
+			dropped: []string{"password", "profile.secret", "tags", "addresses.city", "addresses.geo"},
+		},
+		{
+			name:    "disabled",
+			filter:  sourceFilter{Enabled: &disabled},
+			want:    `{}`,
+			dropped: []string{"name", "password", "profile", "tags", "addresses"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped, err := tt.filter.apply([]byte(source))
+			if err != nil {
+				t.Fatalf("apply() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if !slices.Equal(dropped, tt.dropped) {
+				t.Errorf("expected %v dropped, got %v", tt.dropped, dropped)
+			}
+		})
+	}
+}
+
+func TestNew_SourceFilterWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json": `{"_source":{"excludes":["password"]},"properties":{"name":{"type":"text"}}}`,
+		"users/documents.yml": "- _id: 1\n  name: Alice\n- _id: 2\n  name: Bob\n  password: x\n- _id: 3\n  password: y\n",
+		"plain/documents.yml": "- _id: 1\n  password: x\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	warnings := loader.Warnings()
+	want := `index "users": users/documents.yml:3: field "password" is left out of _source by the mapping`
+	if len(warnings) != 1 || !containsWarning(warnings, want) {
+		t.Errorf("expected one warning containing %q, got %q", want, warnings)
+	}
+}
+
+func TestAssertGoldenSearch_SourceExcludes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	// Written before password was excluded from _source
+	golden := `[{"_index":"users","_id":"1","_source":{"name":"Alice","password":"x"}}]`
+	if err := os.WriteFile(path, []byte(golden), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/users/_search":
+			return jsonResponse(200, `{"hits":{"hits":[{"_index":"users","_id":"1","_source":{"name":"Alice"}}]}}`), nil
+		case "/users/_mapping":
+			return jsonResponse(200, `{"users":{"mappings":{"_source":{"excludes":["password"]}}}}`), nil
+		}
+		return jsonResponse(404, `{}`), nil
+	}))
+
+	AssertGoldenSearch(t, client, "users", `{"query":{"match_all":{}}}`, path)
+}
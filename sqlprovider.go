@@ -0,0 +1,45 @@
+package testfixtures
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+// SQLProvider returns a DocumentProvider that runs query against db on every
+// Load and converts each result row into a document with transform.
+//
+// This keeps search tests consistent with a database seeded by another
+// fixtures tool: both stores are filled from the same rows.
+//
+//	SQLProvider(db, "SELECT id, name FROM users", func(rows *sql.Rows) (Document, error) {
+//		var id, name string
+//		if err := rows.Scan(&id, &name); err != nil {
+//			return Document{}, err
+//		}
+//		source, err := json.Marshal(map[string]string{"name": name})
+//		return Document{ID: id, Source: source}, err
+//	})
+func SQLProvider(db *sql.DB, query string, transform func(*sql.Rows) (Document, error)) DocumentProvider {
+	return DocumentProviderFunc(func(ctx context.Context, _ string) (iter.Seq2[Document, error], error) {
+		return func(yield func(Document, error) bool) {
+			rows, err := db.QueryContext(ctx, query)
+			if err != nil {
+				yield(Document{}, err)
+				return
+			}
+			defer func() { _ = rows.Close() }()
+
+			for rows.Next() {
+				doc, err := transform(rows)
+				if !yield(doc, err) || err != nil {
+					return
+				}
+			}
+
+			if err := rows.Err(); err != nil {
+				yield(Document{}, err)
+			}
+		}, nil
+	})
+}
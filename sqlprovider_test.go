@@ -0,0 +1,87 @@
+package testfixtures
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+// fakeDriver serves a fixed result set for any query.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{data: [][]driver.Value{{"1", "Alice"}, {"2", "Bob"}}}, nil
+}
+
+type fakeRows struct {
+	data [][]driver.Value
+	pos  int
+}
+
+func (*fakeRows) Columns() []string { return []string{"id", "name"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.pos])
+	r.pos++
+	return nil
+}
+
+func init() {
+	sql.Register("testfixtures_fake", fakeDriver{})
+}
+
+func TestSQLProvider(t *testing.T) {
+	db, err := sql.Open("testfixtures_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	p := SQLProvider(db, "SELECT id, name FROM users", func(rows *sql.Rows) (Document, error) {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return Document{}, err
+		}
+		source, err := json.Marshal(map[string]string{"name": name})
+		return Document{ID: id, Source: source}, err
+	})
+
+	docs, err := p.Documents(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("Documents() error: %v", err)
+	}
+
+	var got []Document
+	for doc, err := range docs {
+		if err != nil {
+			t.Fatalf("iterating documents: %v", err)
+		}
+		got = append(got, doc)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(got))
+	}
+	if got[1].ID != "2" || string(got[1].Source) != `{"name":"Bob"}` {
+		t.Errorf("unexpected second document: %s %s", got[1].ID, got[1].Source)
+	}
+}
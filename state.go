@@ -0,0 +1,70 @@
+package testfixtures
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Index states that _config.yml can request with the state key. They are
+// applied once the index is loaded, so tests can exercise the error paths of
+// indices that exist but cannot be used normally.
+const (
+	stateOpen     = "open"      // Default: the index is left as loaded
+	stateClosed   = "closed"    // The index is closed, rejecting searches and writes
+	stateReadOnly = "read_only" // A write block rejects document writes; the index stays searchable and deletable
+)
+
+// checkIndexState validates the state key of _config.yml.
+func checkIndexState(state string) error {
+	switch state {
+	case "", stateOpen, stateClosed, stateReadOnly:
+		return nil
+	case "frozen":
+		return fmt.Errorf("%s: state frozen is not supported: Elasticsearch 8 removed frozen indices; use %s for an index that rejects writes", configFile, stateReadOnly)
+	}
+	return fmt.Errorf("%s: unknown state %q (want %s, %s, or %s)", configFile, state, stateOpen, stateClosed, stateReadOnly)
+}
+
+// applyIndexState puts a loaded index into the given state.
+func applyIndexState(ctx context.Context, client *elasticsearch.Client, name, state string) error {
+	switch state {
+	case stateClosed:
+		return closeIndex(ctx, client, name)
+	case stateReadOnly:
+		return blockWrites(ctx, client, name)
+	}
+	return nil
+}
+
+// closeIndex closes an index.
+func closeIndex(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.Indices.Close([]string{name}, client.Indices.Close.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("closing index %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("closing index %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// blockWrites adds a write block to an index. Unlike the read_only block, it
+// still allows the index to be deleted by the next Load or Clean.
+func blockWrites(ctx context.Context, client *elasticsearch.Client, name string) error {
+	res, err := client.Indices.AddBlock([]string{name}, "write", client.Indices.AddBlock.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("blocking writes to index %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("blocking writes to index %q: %w", name, err)
+	}
+
+	return nil
+}
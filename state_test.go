@@ -0,0 +1,77 @@
+package testfixtures
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseFixtures_IndexState(t *testing.T) {
+	tests := []struct {
+		state   string
+		wantErr string
+	}{
+		{state: "closed"},
+		{state: "read_only"},
+		{state: "frozen", wantErr: "removed frozen indices"},
+		{state: "archived", wantErr: `unknown state "archived"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{
+				"orders/_config.yml":   "state: " + tt.state + "\n",
+				"orders/documents.yml": "- _id: 1\n",
+			})
+
+			fixtures, err := parseFixtures(os.DirFS(dir), ".")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFixtures() error: %v", err)
+			}
+			if fixtures[0].config.State != tt.state {
+				t.Errorf("State = %q, want %q", fixtures[0].config.State, tt.state)
+			}
+		})
+	}
+}
+
+func TestLoad_IndexState(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"archive/_config.yml":   "state: closed\n",
+		"archive/documents.yml": "- _id: 1\n",
+		"ledger/_config.yml":    "state: read_only\n",
+		"ledger/documents.yml":  "- _id: 1\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	got := strings.Join(requests, "\n")
+	for _, want := range []string{"POST /archive/_refresh\nPOST /archive/_close", "POST /ledger/_refresh\nPUT /ledger/_block/write"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected requests to contain %q after loading, got:\n%s", want, got)
+		}
+	}
+}
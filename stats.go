@@ -0,0 +1,116 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// IndexStats summarizes the storage statistics of an index.
+// Counts and sizes cover primary shards, so they do not depend on the
+// number of replicas allocated in the test cluster.
+type IndexStats struct {
+	DocCount     int64 // Live documents
+	DeletedDocs  int64 // Deleted documents not yet merged away
+	StoreBytes   int64 // Size of the primary shards on disk
+	SegmentCount int64 // Number of Lucene segments across primary shards
+}
+
+// IndexStats returns storage statistics for a fixture index, or an index
+// made from one by ShrinkIndex, SplitIndex, or CloneIndex, useful for
+// validating force-merge behavior, compression settings, or unexpected
+// index bloat.
+func (l *Loader) IndexStats(index string) (IndexStats, error) {
+	name := l.IndexName(index)
+	if l.fixture(index) == nil && !slices.Contains(l.derived, name) {
+		return IndexStats{}, fmt.Errorf("testfixtures: %q is not a fixture index", index)
+	}
+
+	res, err := l.client.Indices.Stats(
+		l.client.Indices.Stats.WithContext(l.ctx),
+		l.client.Indices.Stats.WithIndex(name),
+		l.client.Indices.Stats.WithMetric("docs", "store", "segments"),
+	)
+	if err != nil {
+		return IndexStats{}, fmt.Errorf("testfixtures: getting stats for %q: %w", index, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return IndexStats{}, fmt.Errorf("testfixtures: getting stats for %q: %w", index, err)
+	}
+
+	var result struct {
+		Indices map[string]struct {
+			Primaries struct {
+				Docs struct {
+					Count   int64 `json:"count"`
+					Deleted int64 `json:"deleted"`
+				} `json:"docs"`
+				Store struct {
+					SizeInBytes int64 `json:"size_in_bytes"`
+				} `json:"store"`
+				Segments struct {
+					Count int64 `json:"count"`
+				} `json:"segments"`
+			} `json:"primaries"`
+		} `json:"indices"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return IndexStats{}, fmt.Errorf("testfixtures: decoding stats for %q: %w", index, err)
+	}
+
+	var stats IndexStats
+	for _, s := range result.Indices {
+		stats.DocCount += s.Primaries.Docs.Count
+		stats.DeletedDocs += s.Primaries.Docs.Deleted
+		stats.StoreBytes += s.Primaries.Store.SizeInBytes
+		stats.SegmentCount += s.Primaries.Segments.Count
+	}
+
+	return stats, nil
+}
+
+// AssertDocCount fails the test unless the fixture index holds exactly want live documents.
+func (l *Loader) AssertDocCount(t testing.TB, index string, want int64) {
+	t.Helper()
+
+	stats := l.mustIndexStats(t, index)
+	if stats.DocCount != want {
+		t.Errorf("index %q: expected %d documents, got %d", index, want, stats.DocCount)
+	}
+}
+
+// AssertMaxSegments fails the test if the fixture index has more than limit
+// segments, e.g. to check that a force merge took effect.
+func (l *Loader) AssertMaxSegments(t testing.TB, index string, limit int64) {
+	t.Helper()
+
+	stats := l.mustIndexStats(t, index)
+	if stats.SegmentCount > limit {
+		t.Errorf("index %q: expected at most %d segments, got %d", index, limit, stats.SegmentCount)
+	}
+}
+
+// AssertMaxStoreSize fails the test if the primary shards of the fixture
+// index take more than maxBytes on disk.
+func (l *Loader) AssertMaxStoreSize(t testing.TB, index string, maxBytes int64) {
+	t.Helper()
+
+	stats := l.mustIndexStats(t, index)
+	if stats.StoreBytes > maxBytes {
+		t.Errorf("index %q: expected store size of at most %d bytes, got %d", index, maxBytes, stats.StoreBytes)
+	}
+}
+
+func (l *Loader) mustIndexStats(t testing.TB, index string) IndexStats {
+	t.Helper()
+
+	stats, err := l.IndexStats(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stats
+}
@@ -0,0 +1,117 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Strategy is how Load prepares a fixture index before inserting its
+// documents. WithStrategy sets it for every index, and the strategy key of
+// an index's _config.yml overrides it.
+type Strategy string
+
+const (
+	// StrategyRecreate deletes the index and creates it again with the
+	// mapping and settings of the fixture. It is the default.
+	StrategyRecreate Strategy = "recreate"
+
+	// StrategyTruncate keeps an existing index and deletes its documents,
+	// which is faster than recreating an index with many shards or a large
+	// mapping. The index is created only if it does not exist, so changes to
+	// its mapping or settings take effect only once it is recreated.
+	StrategyTruncate Strategy = "truncate"
+
+	// StrategySync keeps an existing index and compares its documents with
+	// those of the fixture by _id and a hash of their content, sending only
+	// the index and delete actions that make the index hold the fixture, so
+	// loading a large, mostly unchanged dataset again costs little more than
+	// reading it. Documents without _id are matched by content. As with
+	// StrategyTruncate, the index is created only if it does not exist.
+	StrategySync Strategy = "sync"
+)
+
+// checkStrategy reports an unknown strategy. The empty strategy stands for
+// the default.
+func checkStrategy(s Strategy) error {
+	switch s {
+	case "", StrategyRecreate, StrategyTruncate, StrategySync:
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %q (want %s, %s, or %s)", s, StrategyRecreate, StrategyTruncate, StrategySync)
+}
+
+// checkIndexStrategy checks the strategy of an index's _config.yml. An index
+// left closed or read-only cannot have its documents deleted, so it cannot
+// be truncated or synced.
+func checkIndexStrategy(cfg indexConfig) error {
+	s := Strategy(cfg.Strategy)
+	if err := checkStrategy(s); err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+	if (s == StrategyTruncate || s == StrategySync) && cfg.State != "" && cfg.State != stateOpen {
+		return fmt.Errorf("%s: strategy %s cannot be used with state %s, which leaves the index unwritable", configFile, s, cfg.State)
+	}
+	return nil
+}
+
+// indexStrategy returns the strategy Load uses for f: that of its
+// _config.yml, or else that of WithStrategy. Indices left closed or
+// read-only are always recreated.
+func (l *Loader) indexStrategy(f *indexFixture) Strategy {
+	switch {
+	case f.config.Strategy != "":
+		return Strategy(f.config.Strategy)
+	case f.config.State != "" && f.config.State != stateOpen:
+		return StrategyRecreate
+	case l.strategy != "":
+		return l.strategy
+	}
+	return StrategyRecreate
+}
+
+// truncateIndex deletes every document of the index name, returning how many
+// were deleted. The last result is false if the index does not exist.
+func truncateIndex(ctx context.Context, client *elasticsearch.Client, name string) (int, bool, error) {
+	exists, err := indexOrAliasExists(ctx, client, name)
+	if err != nil || !exists {
+		return 0, false, err
+	}
+
+	res, err := client.DeleteByQuery(
+		[]string{name},
+		bytes.NewReader([]byte(`{"query":{"match_all":{}}}`)),
+		client.DeleteByQuery.WithContext(ctx),
+		client.DeleteByQuery.WithConflicts("proceed"),
+		client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return 0, true, fmt.Errorf("truncating index %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return 0, true, fmt.Errorf("truncating index %q: %w", name, err)
+	}
+
+	var result struct {
+		Deleted  int               `json:"deleted"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, true, fmt.Errorf("truncating index %q: decoding response: %w", name, err)
+	}
+	if len(result.Failures) > 0 {
+		failures := make([]string, len(result.Failures))
+		for i, f := range result.Failures {
+			failures[i] = string(f)
+		}
+		return result.Deleted, true, fmt.Errorf("truncating index %q: %d failures: %s", name, len(result.Failures), strings.Join(failures, "; "))
+	}
+
+	return result.Deleted, true, nil
+}
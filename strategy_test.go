@@ -0,0 +1,142 @@
+package testfixtures
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Strategy(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"catalog/_config.yml":   "strategy: truncate\n",
+		"catalog/documents.yml": "- _id: 1\n",
+		"fresh/_config.yml":     "strategy: truncate\n",
+		"fresh/documents.yml":   "- _id: 1\n",
+		"users/documents.yml":   "- _id: 1\n",
+		"archive/_config.yml":   "state: closed\n",
+		"archive/documents.yml": "- _id: 1\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case req.Method == http.MethodHead && req.URL.Path == "/fresh":
+			return jsonResponse(404, `{}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_delete_by_query"):
+			return jsonResponse(200, `{"deleted":3,"failures":[]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	var truncated []IndexTruncated
+	loader, err := New(client, Directory(dir), WithStrategy(StrategyTruncate), WithEventHandler(func(e Event) {
+		if e, ok := e.(IndexTruncated); ok {
+			truncated = append(truncated, e)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	got := strings.Join(requests, "\n")
+	for _, want := range []string{
+		"HEAD /catalog\nPOST /catalog/_delete_by_query\nPOST /catalog/_bulk",
+		"HEAD /users\nPOST /users/_delete_by_query\nPOST /users/_bulk",
+		"HEAD /fresh\nDELETE /fresh\nPUT /fresh",
+		"DELETE /archive\nPUT /archive",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected requests to contain %q, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"DELETE /catalog", "PUT /catalog", "HEAD /archive"} {
+		if strings.Contains(got, unwanted+"\n") {
+			t.Errorf("expected no %q request, got:\n%s", unwanted, got)
+		}
+	}
+	want := []IndexTruncated{{Index: "catalog", Deleted: 3}, {Index: "users", Deleted: 3}}
+	if !slices.Equal(truncated, want) {
+		t.Errorf("expected IndexTruncated events %+v, got %+v", want, truncated)
+	}
+}
+
+func TestLoad_StrategyOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"catalog/_config.yml":   "strategy: truncate\n",
+		"catalog/documents.yml": "- _id: 1\n",
+		"users/documents.yml":   "- _id: 1\n",
+	})
+
+	var requests []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_delete_by_query"):
+			return jsonResponse(200, `{"deleted":0}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	got := strings.Join(requests, "\n")
+	if !strings.Contains(got, "POST /catalog/_delete_by_query") || strings.Contains(got, "DELETE /catalog\n") {
+		t.Errorf("expected catalog to be truncated, got:\n%s", got)
+	}
+	if !strings.Contains(got, "DELETE /users\nPUT /users") || strings.Contains(got, "/users/_delete_by_query") {
+		t.Errorf("expected users to be recreated, got:\n%s", got)
+	}
+}
+
+func TestParseFixtures_Strategy(t *testing.T) {
+	tests := []struct {
+		config  string
+		wantErr string
+	}{
+		{config: "strategy: recreate\n"},
+		{config: "strategy: truncate\nstate: open\n"},
+		{config: "strategy: reuse\n", wantErr: `_config.yml: unknown strategy "reuse" (want recreate, truncate, or sync)`},
+		{config: "strategy: truncate\nstate: read_only\n", wantErr: "_config.yml: strategy truncate cannot be used with state read_only"},
+		{config: "strategy: sync\n"},
+		{config: "strategy: sync\nstate: closed\n", wantErr: "_config.yml: strategy sync cannot be used with state closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.config, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{
+				"orders/_config.yml":   tt.config,
+				"orders/documents.yml": "- _id: 1\n",
+			})
+
+			_, err := parseFixtures(os.DirFS(dir), ".")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("parseFixtures() error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := New(newOfflineClient(t), Directory(t.TempDir()), WithStrategy("reuse")); err == nil {
+		t.Error("expected WithStrategy to reject an unknown strategy")
+	}
+}
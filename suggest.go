@@ -0,0 +1,303 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// completionField is a field mapped with the completion type.
+type completionField struct {
+	path     string   // Dotted path of the field
+	contexts []string // Names of the contexts declared by the mapping
+}
+
+// completionFields returns the completion fields of a mapping, in path order.
+// Multi-fields are skipped, since their value is that of the parent field.
+func completionFields(mapping json.RawMessage) []completionField {
+	var fields []completionField
+	var walk func(node json.RawMessage, prefix string)
+	walk = func(node json.RawMessage, prefix string) {
+		var obj struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+		if err := json.Unmarshal(node, &obj); err != nil {
+			return
+		}
+		for name, def := range obj.Properties {
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+
+			var field struct {
+				Type     string `json:"type"`
+				Contexts []struct {
+					Name string `json:"name"`
+				} `json:"contexts"`
+			}
+			if err := json.Unmarshal(def, &field); err != nil {
+				continue
+			}
+			if field.Type != "completion" {
+				walk(def, path)
+				continue
+			}
+
+			f := completionField{path: path}
+			for _, c := range field.Contexts {
+				f.contexts = append(f.contexts, c.Name)
+			}
+			fields = append(fields, f)
+		}
+	}
+	if mapping != nil {
+		walk(mapping, "")
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].path < fields[j].path })
+	return fields
+}
+
+// checkCompletionValue validates a document value for a completion field: a
+// string, or an object with a string or array of strings under input, an
+// optional non-negative integer weight, and optional contexts naming only
+// contexts the mapping declares. Arrays of such values are flattened by the
+// caller.
+func checkCompletionValue(field completionField, value json.RawMessage) []error {
+	v, err := decodeValue(value)
+	if err != nil {
+		return []error{err}
+	}
+
+	switch v := v.(type) {
+	case nil, string:
+		return nil
+	case map[string]any:
+		return checkCompletionObject(field, v)
+	}
+	return []error{fmt.Errorf("must be a string or an object with input, got %s", value)}
+}
+
+func checkCompletionObject(field completionField, obj map[string]any) []error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	if _, ok := obj["input"]; !ok {
+		errs = append(errs, errors.New("input is required"))
+	}
+	for _, k := range keys {
+		switch v := obj[k]; k {
+		case "input":
+			if !isCompletionInput(v) {
+				errs = append(errs, errors.New("input must be a string or a non-empty array of strings"))
+			}
+		case "weight":
+			if !isCompletionWeight(v) {
+				errs = append(errs, fmt.Errorf("weight must be a non-negative integer, got %v", v))
+			}
+		case "contexts":
+			contexts, ok := v.(map[string]any)
+			if !ok {
+				errs = append(errs, errors.New("contexts must be a mapping of context names to values"))
+				continue
+			}
+			names := make([]string, 0, len(contexts))
+			for name := range contexts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if !slices.Contains(field.contexts, name) {
+					errs = append(errs, fmt.Errorf("context %q is not declared in the mapping", name))
+				}
+			}
+		default:
+			errs = append(errs, fmt.Errorf("unknown key %q (want input, weight, or contexts)", k))
+		}
+	}
+
+	return errs
+}
+
+func isCompletionInput(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return true
+	case []any:
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return len(v) > 0
+	}
+	return false
+}
+
+func isCompletionWeight(v any) bool {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return false
+	}
+	n, err := strconv.ParseInt(s, 10, 32)
+	return err == nil && n >= 0
+}
+
+// checkCompletionValues validates the values of every completion field in
+// the parsed documents of f, since a malformed suggestion otherwise only
+// surfaces as a failed bulk item.
+func checkCompletionValues(f *indexFixture) error {
+	fields := completionFields(f.mapping)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, doc := range f.documents {
+		for _, field := range fields {
+			values, err := lookupField(doc.Source, strings.Split(field.path, "."))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: reading completion field %q: %w", doc.Location(), field.path, err))
+				continue
+			}
+			for _, v := range values {
+				for _, err := range checkCompletionValue(field, v) {
+					errs = append(errs, fmt.Errorf("%s: completion field %q: %w", doc.Location(), field.path, err))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkCompletionFields runs checkCompletionValues on every fixture.
+func (l *Loader) checkCompletionFields() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		if err := checkCompletionValues(f); err != nil {
+			errs = append(errs, fmt.Errorf("index %q: %w", f.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SuggestRequest describes a completion suggester query.
+type SuggestRequest struct {
+	Field    string              // Completion field to query (required)
+	Prefix   string              // Text typed so far
+	Contexts map[string][]string // Context values to filter by, for context suggesters (optional)
+	Size     int                 // Maximum number of options (default 5)
+}
+
+// SuggestOption is a single option returned by a completion suggester.
+type SuggestOption struct {
+	Text  string  // Matching input of the suggestion
+	ID    string  // ID of the document holding the suggestion
+	Score float64 // Weight of the suggestion
+}
+
+// Suggest runs a completion suggester query against index and returns its
+// options in the order Elasticsearch ranks them.
+func Suggest(ctx context.Context, client *elasticsearch.Client, index string, req SuggestRequest) ([]SuggestOption, error) {
+	if req.Field == "" {
+		return nil, errors.New("testfixtures: SuggestRequest.Field is required")
+	}
+
+	completion := map[string]any{"field": req.Field}
+	if req.Size > 0 {
+		completion["size"] = req.Size
+	}
+	if len(req.Contexts) > 0 {
+		completion["contexts"] = req.Contexts
+	}
+	body, err := json.Marshal(map[string]any{
+		"_source": false,
+		"suggest": map[string]any{
+			"fixture": map[string]any{"prefix": req.Prefix, "completion": completion},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: encoding suggest query: %w", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("testfixtures: suggesting on %q: %w", index, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, fmt.Errorf("testfixtures: suggesting on %q: %w", index, err)
+	}
+
+	var result struct {
+		Suggest map[string][]struct {
+			Options []struct {
+				Text  string  `json:"text"`
+				ID    string  `json:"_id"`
+				Score float64 `json:"_score"`
+			} `json:"options"`
+		} `json:"suggest"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("testfixtures: decoding suggestions from %q: %w", index, err)
+	}
+
+	var options []SuggestOption
+	for _, entry := range result.Suggest["fixture"] {
+		for _, o := range entry.Options {
+			options = append(options, SuggestOption{Text: o.Text, ID: o.ID, Score: o.Score})
+		}
+	}
+
+	return options, nil
+}
+
+// AssertSuggestions fails the test unless the completion suggester query
+// returns exactly the suggestions want, in order. Each wanted suggestion is
+// matched against the option's text.
+func AssertSuggestions(t testing.TB, client *elasticsearch.Client, index string, req SuggestRequest, want ...string) {
+	t.Helper()
+
+	options, err := Suggest(context.Background(), client, index, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, len(options))
+	for i, o := range options {
+		got[i] = o.Text
+	}
+	if !slices.Equal(got, want) {
+		var desc []string
+		for _, o := range options {
+			desc = append(desc, fmt.Sprintf("%q (_id %s, weight %g)", o.Text, o.ID, o.Score))
+		}
+		t.Errorf("suggestions for %q on %s.%s = [%s], want %q", req.Prefix, index, req.Field, strings.Join(desc, ", "), want)
+	}
+}
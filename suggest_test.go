@@ -0,0 +1,107 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNew_CompletionFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"songs/_mapping.json": `{"properties":{
+			"title":{"type":"text","fields":{"suggest":{"type":"completion"}}},
+			"suggest":{"type":"completion","contexts":[{"name":"genre","type":"category"}]},
+			"artist":{"properties":{"suggest":{"type":"completion"}}}
+		}}`,
+		"songs/documents.yml": `- _id: 1
+  suggest: Nevermind
+  artist:
+    suggest: [Nirvana, Kurt]
+- _id: 2
+  suggest:
+    input: [Bad, Beat It]
+    weight: 10
+    contexts:
+      genre: pop
+- _id: 3
+  suggest:
+    - input: Thriller
+      weight: "3"
+    - Off the Wall
+- _id: 4
+  suggest:
+    weight: -1
+- _id: 5
+  suggest:
+    input: [Imagine, 7]
+    contexts:
+      mood: calm
+    boost: 2
+- _id: 6
+  artist:
+    suggest: 42
+`,
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	if err == nil {
+		t.Fatal("expected New to report invalid completion values")
+	}
+	for _, want := range []string{
+		`songs/documents.yml:16: completion field "suggest": input is required`,
+		`songs/documents.yml:16: completion field "suggest": weight must be a non-negative integer, got -1`,
+		`songs/documents.yml:19: completion field "suggest": input must be a string or a non-empty array of strings`,
+		`songs/documents.yml:19: completion field "suggest": unknown key "boost"`,
+		`songs/documents.yml:19: completion field "suggest": context "mood" is not declared in the mapping`,
+		`songs/documents.yml:25: completion field "artist.suggest": must be a string or an object with input, got 42`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got:\n%v", want, err)
+		}
+	}
+	for _, line := range []string{"documents.yml:1:", "documents.yml:5:", "documents.yml:11:"} {
+		if strings.Contains(err.Error(), line) {
+			t.Errorf("expected the valid document at %s to pass, got:\n%v", line, err)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var query map[string]any
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/songs/_search" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(body, &query); err != nil {
+			t.Errorf("decoding query: %v", err)
+		}
+		return jsonResponse(200, `{"suggest":{"fixture":[{"text":"be","offset":0,"length":2,"options":[
+			{"text":"Beat It","_id":"2","_score":10},
+			{"text":"Bad","_id":"2","_score":10}
+		]}]}}`), nil
+	}))
+
+	req := SuggestRequest{Field: "suggest", Prefix: "be", Contexts: map[string][]string{"genre": {"pop"}}}
+	AssertSuggestions(t, client, "songs", req, "Beat It", "Bad")
+
+	completion := query["suggest"].(map[string]any)["fixture"].(map[string]any)["completion"].(map[string]any)
+	if completion["field"] != "suggest" || completion["contexts"] == nil {
+		t.Errorf("unexpected completion query %v", completion)
+	}
+
+	options, err := Suggest(t.Context(), client, "songs", req)
+	if err != nil {
+		t.Fatalf("Suggest() error: %v", err)
+	}
+	if len(options) != 2 || options[0].ID != "2" || options[0].Score != 10 {
+		t.Errorf("unexpected options %+v", options)
+	}
+
+	if _, err := Suggest(t.Context(), client, "songs", SuggestRequest{Prefix: "be"}); err == nil {
+		t.Error("expected an error without a field")
+	}
+}
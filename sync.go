@@ -0,0 +1,110 @@
+package testfixtures
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// syncIndex sends to the existing index indexName only the bulk actions
+// that make its documents those Load would send for f, under StrategySync.
+// The stored documents are streamed through a syncPlanner, so only their
+// _id and routing are kept, and only for those to delete.
+func (l *Loader) syncIndex(ctx context.Context, f *indexFixture, indexName string, cfg bulkConfig, run *indexLoad) error {
+	want, unkeyed, err := l.expectedDocuments(f)
+	if err != nil {
+		return err
+	}
+	planner := newSyncPlanner(want, unkeyed)
+	if err := scrollDocuments(ctx, l.client, indexName, planner.add); err != nil {
+		return fmt.Errorf("syncing index %q: fetching documents: %w", indexName, err)
+	}
+
+	plan := planner.plan()
+	l.events.emit(IndexSynced{Index: indexName, Indexed: plan.indexed, Deleted: plan.deleted, Unchanged: plan.unchanged})
+
+	return bulkInsertDocuments(ctx, l.client, indexName, cfg, plan.actions, l.docConcurrency, countDocuments(nil, &run.docs))
+}
+
+// syncPlan is the bulk actions that bring stored documents in line with
+// those of a fixture.
+type syncPlan struct {
+	actions   []Document // Deletes, then documents to index
+	indexed   int
+	deleted   int
+	unchanged int
+}
+
+// syncPlanner compares the stored documents of an index, given one at a
+// time to add, with want, the documents the fixture leaves by _id, and
+// unkeyed, those without _id by canonical _source. A stored document is
+// kept if its routing and content hash match those of its _id in want, or
+// if it matches an unkeyed document by content; it is deleted if neither
+// has it, or if its routing changed, since the document is then at another
+// shard. Documents of want that are not kept, and unkeyed ones no stored
+// document matches, are indexed.
+type syncPlanner struct {
+	want      map[string]Document
+	unkeyed   map[string][]Document
+	kept      map[string]bool
+	deletes   []Document // _id and routing only
+	unchanged int
+}
+
+func newSyncPlanner(want map[string]Document, unkeyed map[string][]Document) *syncPlanner {
+	return &syncPlanner{want: want, unkeyed: unkeyed, kept: make(map[string]bool, len(want))}
+}
+
+// add compares the stored document doc, whose _source is not kept.
+func (s *syncPlanner) add(doc Document) error {
+	if expected, ok := s.want[doc.ID]; ok {
+		if expected.Routing == doc.Routing && contentHash(expected.Source) == contentHash(doc.Source) {
+			s.kept[doc.ID] = true
+			s.unchanged++
+			return nil
+		}
+		if expected.Routing == doc.Routing {
+			return nil
+		}
+	} else if key := canonicalJSON(doc.Source); len(s.unkeyed[key]) > 0 {
+		s.unkeyed[key] = s.unkeyed[key][1:]
+		s.unchanged++
+		return nil
+	}
+	s.deletes = append(s.deletes, Document{ID: doc.ID, Routing: doc.Routing, Source: json.RawMessage(`{}`), action: actionDelete})
+	return nil
+}
+
+// plan returns the bulk actions for the documents given to add so far:
+// deletes by _id, then the documents to index by _id, then the unkeyed
+// ones.
+func (s *syncPlanner) plan() syncPlan {
+	byID := func(a, b Document) int { return cmp.Compare(a.ID, b.ID) }
+	plan := syncPlan{deleted: len(s.deletes), unchanged: s.unchanged}
+	plan.actions = slices.SortedFunc(slices.Values(s.deletes), byID)
+
+	var missing []Document
+	for _, doc := range s.want {
+		if !s.kept[doc.ID] {
+			missing = append(missing, doc)
+		}
+	}
+	slices.SortFunc(missing, byID)
+	for _, key := range slices.Sorted(maps.Keys(s.unkeyed)) {
+		missing = append(missing, s.unkeyed[key]...)
+	}
+	plan.actions = append(plan.actions, missing...)
+	plan.indexed = len(missing)
+
+	return plan
+}
+
+// contentHash returns the SHA-256 of the canonical JSON of source, so equal
+// JSON values have equal hashes whatever their key order.
+func contentHash(source json.RawMessage) [sha256.Size]byte {
+	return sha256.Sum256([]byte(canonicalJSON(source)))
+}
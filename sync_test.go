@@ -0,0 +1,116 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSyncPlanner(t *testing.T) {
+	want := map[string]Document{
+		"same":    {ID: "same", Source: json.RawMessage(`{"a":1,"b":{"c":2}}`)},
+		"changed": {ID: "changed", Source: json.RawMessage(`{"a":2}`)},
+		"moved":   {ID: "moved", Routing: "eu", Source: json.RawMessage(`{"a":1}`)},
+		"new":     {ID: "new", Source: json.RawMessage(`{"a":1}`)},
+	}
+	unkeyed := map[string][]Document{
+		`{"log":"x"}`: {{Source: json.RawMessage(`{"log":"x"}`)}, {Source: json.RawMessage(`{"log":"x"}`)}},
+	}
+	// In scroll order, not by _id
+	stored := []Document{
+		{ID: "moved", Routing: "us", Source: json.RawMessage(`{"a":1}`)},
+		{ID: "x1", Source: json.RawMessage(`{"log":"x"}`)},
+		{ID: "changed", Source: json.RawMessage(`{"a":1}`)},
+		{ID: "same", Source: json.RawMessage(`{"b":{"c":2},"a":1}`)},
+		{ID: "gone", Routing: "us", Source: json.RawMessage(`{}`)},
+	}
+
+	planner := newSyncPlanner(want, unkeyed)
+	for _, doc := range stored {
+		if err := planner.add(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := planner.plan()
+
+	var got []string
+	for _, doc := range plan.actions {
+		got = append(got, doc.Action()+" "+doc.ID+"/"+doc.Routing)
+	}
+	wantActions := []string{"delete gone/us", "delete moved/us", "index changed/", "index moved/eu", "index new/", "index /"}
+	if strings.Join(got, ", ") != strings.Join(wantActions, ", ") {
+		t.Errorf("expected actions %v, got %v", wantActions, got)
+	}
+	if plan.indexed != 4 || plan.deleted != 2 || plan.unchanged != 2 {
+		t.Errorf("expected 4 indexed, 2 deleted, 2 unchanged, got %+v", plan)
+	}
+}
+
+func TestLoad_StrategySync(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"catalog/_config.yml":   "strategy: sync\n",
+		"catalog/documents.yml": "- {_id: \"1\", name: Pen}\n- {_id: \"2\", name: Ink}\n",
+		"fresh/_config.yml":     "strategy: sync\n",
+		"fresh/documents.yml":   "- {_id: \"1\", name: Pen}\n",
+	})
+
+	var (
+		requests []string
+		bulk     string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case req.Method == http.MethodHead && req.URL.Path == "/fresh":
+			return jsonResponse(404, `{}`), nil
+		case req.URL.Path == "/catalog/_search":
+			return jsonResponse(200, `{"hits":{"hits":[
+				{"_id":"1","_source":{"name":"Pen"}},
+				{"_id":"3","_source":{"name":"Old"}}
+			]}}`), nil
+		case req.URL.Path == "/catalog/_bulk":
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bulk = string(body)
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	var synced []IndexSynced
+	loader, err := New(client, Directory(dir), WithEventHandler(func(e Event) {
+		if e, ok := e.(IndexSynced); ok {
+			synced = append(synced, e)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	got := strings.Join(requests, "\n")
+	if strings.Contains(got, "DELETE /catalog\n") || strings.Contains(got, "PUT /catalog\n") {
+		t.Errorf("expected catalog to be kept, got:\n%s", got)
+	}
+	if !strings.Contains(got, "HEAD /fresh\nDELETE /fresh\nPUT /fresh") {
+		t.Errorf("expected the missing index to be created, got:\n%s", got)
+	}
+	if want := `{"delete":{"_id":"3"}}` + "\n" + `{"index":{"_id":"2"}}` + "\n" + `{"name":"Ink"}` + "\n"; bulk != want {
+		t.Errorf("expected bulk body\n%s\ngot\n%s", want, bulk)
+	}
+	if len(synced) != 1 || synced[0] != (IndexSynced{Index: "catalog", Indexed: 1, Deleted: 1, Unchanged: 1}) {
+		t.Errorf("expected one IndexSynced event for catalog, got %+v", synced)
+	}
+	if r := loader.Results(); r[0].Documents != 2 {
+		t.Errorf("expected 2 documents sent to catalog, got %+v", r[0])
+	}
+}
@@ -0,0 +1,232 @@
+package testfixtures
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// synonymsDir is the top-level directory holding synonym sets, created by
+// Load through the synonyms API before any index. A <id>.json file is a set
+// in the format of the Create Synonym Set API, and a <id>.txt file holds one
+// rule per line in the Solr format, such as "tv, television" or
+// "ipod => i-pod", with blank lines and # comments ignored.
+const synonymsDir = "_synonyms"
+
+// synonymSet is a set of the _synonyms directory.
+type synonymSet struct {
+	id   string
+	body json.RawMessage
+}
+
+// readSynonymSets reads the sets of the _synonyms directory, ordered by id.
+// A missing directory means there are none.
+func readSynonymSets(fsys fs.FS, dir string) ([]synonymSet, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, synonymsDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", synonymsDir, err)
+	}
+
+	var sets []synonymSet
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := path.Ext(name)
+		if entry.IsDir() || (ext != ".json" && ext != ".txt") {
+			continue
+		}
+		id := strings.TrimSuffix(name, ext)
+		if other, ok := seen[id]; ok {
+			return nil, fmt.Errorf("%s: synonym set %q is defined by both %s and %s", synonymsDir, id, other, name)
+		}
+		seen[id] = name
+
+		body, err := readSynonymSet(fsys, path.Join(dir, synonymsDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", synonymsDir, name, err)
+		}
+		sets = append(sets, synonymSet{id: id, body: body})
+	}
+
+	return sets, nil
+}
+
+// readSynonymSet returns the Create Synonym Set request body of a file of
+// _synonyms, turning each rule of a .txt file into an entry of the set.
+func readSynonymSet(fsys fs.FS, name string) (json.RawMessage, error) {
+	if path.Ext(name) == ".txt" {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		type rule struct {
+			Synonyms string `json:"synonyms"`
+		}
+		rules := []rule{}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			rules = append(rules, rule{Synonyms: line})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return marshalUnescaped(map[string][]rule{"synonyms_set": rules})
+	}
+
+	body, err := readJSONFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var def struct {
+		Rules json.RawMessage `json:"synonyms_set"`
+	}
+	if !isJSONObject(body) || json.Unmarshal(body, &def) != nil || len(def.Rules) == 0 || def.Rules[0] != '[' {
+		return nil, errors.New(`synonym set must be an object of the form {"synonyms_set": [{"synonyms": ...}]}`)
+	}
+	return body, nil
+}
+
+// synonymSetRefs returns the ids of the synonym sets that the token filters
+// in settings name in synonyms_set, in nested or flattened form, ordered
+// and without duplicates.
+func synonymSetRefs(settings json.RawMessage) []string {
+	var ids []string
+	var walk func(obj json.RawMessage, prefix string)
+	walk = func(obj json.RawMessage, prefix string) {
+		fields, err := decodeObject(obj)
+		if err != nil {
+			return
+		}
+		for _, f := range fields {
+			key := f.key
+			if prefix != "" {
+				key = prefix + "." + f.key
+			}
+			var id string
+			if key == "synonyms_set" || strings.HasSuffix(key, ".synonyms_set") {
+				if json.Unmarshal(f.value, &id) == nil && id != "" {
+					ids = append(ids, id)
+				}
+				continue
+			}
+			if isJSONObject(f.value) {
+				walk(f.value, key)
+			}
+		}
+	}
+	if settings != nil {
+		walk(settings, "")
+	}
+
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// withSynonymUsers adds to the fixture names the other fixtures whose
+// analyzers use a set of _synonyms that one of the named fixtures uses.
+// Replacing a set reloads only search analyzers, so documents indexed
+// through one that uses the set would otherwise keep the terms of the
+// previous version.
+func (l *Loader) withSynonymUsers(names []string) []string {
+	managed := make(map[string]bool, len(l.synonymSets))
+	for _, s := range l.synonymSets {
+		managed[s.id] = true
+	}
+
+	used := make(map[string]bool)
+	for _, name := range names {
+		if f := l.fixture(name); f != nil {
+			for _, id := range synonymSetRefs(f.settings) {
+				if managed[id] {
+					used[id] = true
+				}
+			}
+		}
+	}
+	if len(used) == 0 {
+		return names
+	}
+
+	all := slices.Clone(names)
+	for _, f := range l.fixtures {
+		if !slices.Contains(all, f.name) && slices.ContainsFunc(synonymSetRefs(f.settings), func(id string) bool { return used[id] }) {
+			all = append(all, f.name)
+		}
+	}
+	return all
+}
+
+// putSynonymSets creates the sets of _synonyms, replacing any of the same
+// id. Ids are used as they are, since settings refer to them by id.
+func (l *Loader) putSynonymSets(ctx context.Context) error {
+	for _, s := range l.synonymSets {
+		if err := putSynonymSet(ctx, l.client, s.id, s.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteSynonymSets removes the sets of _synonyms and returns the errors of
+// the deletions that failed. Elasticsearch refuses to delete a set that
+// indices still use, so this runs after the indices are gone.
+func (l *Loader) deleteSynonymSets(ctx context.Context) []error {
+	var errs []error
+	for _, s := range l.synonymSets {
+		if err := deleteSynonymSet(ctx, l.client, s.id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// putSynonymSet creates or replaces the synonym set id.
+func putSynonymSet(ctx context.Context, client *elasticsearch.Client, id string, body json.RawMessage) error {
+	res, err := client.SynonymsPutSynonym(id, bytes.NewReader(body), client.SynonymsPutSynonym.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating synonym set %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating synonym set %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// deleteSynonymSet deletes the synonym set id if it exists.
+func deleteSynonymSet(ctx context.Context, client *elasticsearch.Client, id string) error {
+	res, err := client.SynonymsDeleteSynonym(id, client.SynonymsDeleteSynonym.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting synonym set %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The set may already be gone, which is fine
+	if res.StatusCode == 404 {
+		return nil
+	}
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting synonym set %q: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadSynonymSets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_synonyms/products.txt": {Data: []byte("# Product names\ntv, television\n\nipod => i-pod\n")},
+		"_synonyms/places.json":  {Data: []byte(`{"synonyms_set": [{"id": "ny", "synonyms": "ny, new york"}]}`)},
+		"_synonyms/README.md":    {Data: []byte("notes")},
+	}
+
+	sets, err := readSynonymSets(fsys, ".")
+	if err != nil {
+		t.Fatalf("readSynonymSets() error: %v", err)
+	}
+	if len(sets) != 2 || sets[0].id != "places" || sets[1].id != "products" {
+		t.Fatalf("unexpected sets: %+v", sets)
+	}
+	want := `{"synonyms_set":[{"synonyms":"tv, television"},{"synonyms":"ipod => i-pod"}]}`
+	if got := string(sets[1].body); got != want {
+		t.Errorf("expected set body %s, got %s", want, got)
+	}
+
+	if sets, err := readSynonymSets(fstest.MapFS{}, "."); sets != nil || err != nil {
+		t.Errorf("expected no sets for a missing directory, got %v, %v", sets, err)
+	}
+}
+
+func TestReadSynonymSets_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{name: "no rules", files: map[string]string{"products.json": `{"synonyms": "tv, television"}`}, want: "_synonyms/products.json: synonym set must be an object"},
+		{name: "rules not a list", files: map[string]string{"products.json": `{"synonyms_set": {}}`}, want: "_synonyms/products.json: synonym set must be an object"},
+		{name: "duplicate", files: map[string]string{"products.json": `{"synonyms_set": []}`, "products.txt": "tv, television"}, want: `synonym set "products" is defined by both products.json and products.txt`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys["_synonyms/"+name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			_, err := readSynonymSets(fsys, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSynonymSetRefs(t *testing.T) {
+	nested := `{"analysis": {"filter": {"syn": {"type": "synonym_graph", "synonyms_set": "products"}, "other": {"type": "synonym", "synonyms_set": "places"}}, "analyzer": {"a": {"tokenizer": "standard", "filter": ["syn"]}}}}`
+	if got := synonymSetRefs([]byte(nested)); !slices.Equal(got, []string{"places", "products"}) {
+		t.Errorf("synonymSetRefs(nested) = %v", got)
+	}
+	flat := `{"index.analysis.filter.syn.type": "synonym", "index.analysis.filter.syn.synonyms_set": "products", "index": {"analysis.filter.again.synonyms_set": "products"}}`
+	if got := synonymSetRefs([]byte(flat)); !slices.Equal(got, []string{"products"}) {
+		t.Errorf("synonymSetRefs(flat) = %v", got)
+	}
+	if got := synonymSetRefs(nil); got != nil {
+		t.Errorf("synonymSetRefs(nil) = %v", got)
+	}
+}
+
+func TestLoad_SynonymSets(t *testing.T) {
+	settings := `{"analysis": {"filter": {"syn": {"type": "synonym_graph", "synonyms_set": "products", "updateable": true}}}}`
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_synonyms/products.txt": "tv, television\n",
+		"items/_settings.json":   settings,
+		"items/documents.yml":    "- _id: \"1\"\n  name: tv\n",
+		"offers/_settings.json":  settings,
+		"offers/documents.yml":   "- _id: \"1\"\n  name: television\n",
+		"users/documents.yml":    "- _id: \"1\"\n  name: Alice\n",
+	})
+
+	var requests []string
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+		}
+		if strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	put := slices.Index(requests, "PUT /_synonyms/products")
+	create := slices.Index(requests, "PUT /ci_items")
+	if put < 0 || create < 0 || put > create {
+		t.Fatalf("expected the synonym set to be created before the index, got %v", requests)
+	}
+	if want := `{"synonyms_set":[{"synonyms":"tv, television"}]}`; bodies["/_synonyms/products"] != want {
+		t.Errorf("expected set body %s, got %s", want, bodies["/_synonyms/products"])
+	}
+
+	requests = nil
+	if err := loader.LoadIndices("items"); err != nil {
+		t.Fatalf("LoadIndices() error: %v", err)
+	}
+	if !slices.Contains(requests, "PUT /ci_offers") || slices.Contains(requests, "PUT /ci_users") {
+		t.Errorf("expected LoadIndices to recreate the other index using the set, and only it, got %v", requests)
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	if !slices.Contains(requests, "DELETE /_synonyms/products") {
+		t.Errorf("expected Clean to delete the set, got %v", requests)
+	}
+}
@@ -0,0 +1,123 @@
+package testfixtures
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs returns the functions available to document templates, with
+// now fixed to the given time so that every file rendered by one Loader sees
+// the same instant.
+func templateFuncs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		// now returns the current time in RFC 3339 format, optionally moved
+		// by an offset such as "-72h".
+		"now": func(offset ...string) (string, error) {
+			if len(offset) > 1 {
+				return "", errors.New("now takes at most one offset")
+			}
+			t := now
+			if len(offset) == 1 {
+				d, err := time.ParseDuration(offset[0])
+				if err != nil {
+					return "", fmt.Errorf("now: %w", err)
+				}
+				t = t.Add(d)
+			}
+			return t.UTC().Format(time.RFC3339Nano), nil
+		},
+		"uuid":    newUUID,
+		"randInt": randInt,
+		"env":     os.Getenv,
+		// seq leaves the {{seq}} placeholder of _repeat entries in place.
+		"seq": func() string { return seqPlaceholder },
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("uuid: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// randInt returns a random integer in [lo, hi).
+func randInt(lo, hi int) (int, error) {
+	if hi <= lo {
+		return 0, fmt.Errorf("randInt: max %d must be greater than min %d", hi, lo)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(hi-lo)))
+	if err != nil {
+		return 0, fmt.Errorf("randInt: %w", err)
+	}
+	return lo + int(n.Int64()), nil
+}
+
+// templateFS renders the YAML document files of the fixtures directory root
+// through text/template when they are read with fs.ReadFile. Other files,
+// such as _config.yml, _traits.yml, or files under _expectations/, are read
+// as they are.
+type templateFS struct {
+	fs.FS
+	root  string
+	funcs template.FuncMap
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (t templateFS) ReadFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(t.FS, name)
+	if err != nil || !isDocumentFile(t.root, name) {
+		return data, err
+	}
+
+	tmpl, err := template.New(path.Base(name)).Funcs(t.funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isDocumentFile reports whether name is a document file: a *.yml, *.yaml,
+// or *.json file in an index directory of the fixtures directory root, no
+// part of whose path below root starts with "_".
+func isDocumentFile(root, name string) bool {
+	if !isDocumentFileName(name) {
+		return false
+	}
+	rel, ok := fixturePath(root, name)
+	if !ok || !strings.Contains(rel, "/") {
+		return false
+	}
+	for elem := range strings.SplitSeq(rel, "/") {
+		if strings.HasPrefix(elem, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+// fixturePath returns name relative to the fixtures directory root, and
+// whether name is inside it.
+func fixturePath(root, name string) (string, bool) {
+	if root == "." {
+		return name, true
+	}
+	return strings.CutPrefix(name, root+"/")
+}
@@ -0,0 +1,86 @@
+package testfixtures
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestWithTemplates(t *testing.T) {
+	t.Setenv("FIXTURE_REGION", "eu-west-1")
+
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_traits.yml":         "tagged:\n  tag: \"{{ literal }}\"\n",
+		"users/_config.yml":   "# {{ not rendered }}\n",
+		"users/documents.yml": "{{ range $i := list 1 2 }}\n- _id: \"{{ uuid }}\"\n  created_at: \"{{ now \"-24h\" }}\"\n  score: {{ randInt 10 20 }}\n  region: {{ env \"FIXTURE_REGION\" }}\n  _traits: tagged\n{{ end }}",
+	})
+
+	list := func(items ...int) []int { return items }
+	loader, err := New(newOfflineClient(t), Directory(dir), WithTemplates(template.FuncMap{"list": list}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	docs := loader.fixtures[0].documents
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, doc := range docs {
+		if !uuid.MatchString(doc.ID) {
+			t.Errorf("expected a UUID _id, got %q", doc.ID)
+		}
+
+		var src struct {
+			CreatedAt time.Time `json:"created_at"`
+			Score     int       `json:"score"`
+			Region    string    `json:"region"`
+			Tag       string    `json:"tag"`
+		}
+		if err := doc.Decode(&src); err != nil {
+			t.Fatalf("decoding %s: %v", doc.Source, err)
+		}
+		if ago := time.Since(src.CreatedAt); ago < 24*time.Hour || ago > 25*time.Hour {
+			t.Errorf("expected created_at a day before New, got %v", src.CreatedAt)
+		}
+		if src.Score < 10 || src.Score >= 20 {
+			t.Errorf("expected a score in [10, 20), got %d", src.Score)
+		}
+		if src.Region != "eu-west-1" {
+			t.Errorf("expected the region from the environment, got %q", src.Region)
+		}
+		if src.Tag != "{{ literal }}" {
+			t.Errorf("expected _traits.yml not to be rendered, got %q", src.Tag)
+		}
+	}
+	if docs[0].ID == docs[1].ID {
+		t.Error("expected each document to get its own UUID")
+	}
+}
+
+func TestWithTemplates_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  score: {{ randInt 5 5 }}\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir), WithTemplates(nil))
+	if err == nil || !strings.Contains(err.Error(), "documents.yml") || !strings.Contains(err.Error(), "max 5 must be greater than min 5") {
+		t.Errorf("expected the template error with its file, got %v", err)
+	}
+
+	// Without WithTemplates, braces are plain YAML flow mappings or strings.
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  note: \"{{ uuid }}\"\n",
+	})
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if src := string(loader.fixtures[0].documents[0].Source); src != `{"note":"{{ uuid }}"}` {
+		t.Errorf("expected the document as written, got %s", src)
+	}
+}
@@ -0,0 +1,173 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// maxTenants is the most tenants per index that WithTenantAliases creates
+// aliases for.
+const maxTenants = 1000
+
+// tenantConfig holds the settings of WithTenantField and WithTenantAliases.
+type tenantConfig struct {
+	field   string // Dotted path of the tenant discriminator
+	aliases bool   // Create a filtered alias per tenant after loading
+}
+
+// addTenantMappings maps the tenant field as a keyword in every fixture
+// index whose mapping does not define it, so that tenants are matched
+// exactly and can be aggregated for WithTenantAliases.
+func (l *Loader) addTenantMappings() error {
+	if l.tenant == nil {
+		return nil
+	}
+
+	for _, f := range l.fixtures {
+		if f.isAlias() {
+			continue
+		}
+		if _, _, ok := mappedField(f.mapping, l.tenant.field); ok {
+			continue
+		}
+		mapping, err := addKeywordMapping(f.mapping, strings.Split(l.tenant.field, "."))
+		if err != nil {
+			return fmt.Errorf("index %q: mapping tenant field %q: %w", f.name, l.tenant.field, err)
+		}
+		f.mapping = mapping
+	}
+
+	return nil
+}
+
+// addKeywordMapping returns mapping with a keyword field at path, creating
+// object properties along the way.
+func addKeywordMapping(mapping json.RawMessage, path []string) (json.RawMessage, error) {
+	root := map[string]any{}
+	if mapping != nil {
+		v, err := decodeValue(mapping)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if root, ok = v.(map[string]any); !ok {
+			return nil, fmt.Errorf("%s must hold an object", mappingFile)
+		}
+	}
+
+	node := root
+	for i, name := range path {
+		props, _ := node["properties"].(map[string]any)
+		if props == nil {
+			if t, ok := node["type"]; ok && t != "object" && t != "nested" {
+				return nil, fmt.Errorf("%q is mapped as %v, which cannot hold fields", strings.Join(path[:i], "."), t)
+			}
+			props = map[string]any{}
+			node["properties"] = props
+		}
+		if i == len(path)-1 {
+			props[name] = map[string]any{"type": "keyword"}
+			break
+		}
+		child, _ := props[name].(map[string]any)
+		if child == nil {
+			child = map[string]any{}
+			props[name] = child
+		}
+		node = child
+	}
+
+	return json.Marshal(root)
+}
+
+// TenantAlias returns the name of the filtered alias that WithTenantAliases
+// creates for tenant on the index of the named fixture: the fixture name and
+// the tenant joined by an underscore, with the prefix and suffix of
+// WithIndexPrefix and WithIndexSuffix.
+func (l *Loader) TenantAlias(fixture, tenant string) string {
+	return l.IndexName(fixture + "_" + tenant)
+}
+
+// createTenantAliases creates a filtered alias on a loaded fixture index for
+// each tenant found in its documents, from any source.
+func (l *Loader) createTenantAliases(ctx context.Context, f *indexFixture) error {
+	index := l.IndexName(f.name)
+	tenants, err := tenantValues(ctx, l.client, index, l.tenant.field)
+	if err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if err := checkIndexAffix(tenant); err != nil {
+			return fmt.Errorf("tenant %q cannot be used in an alias name: %w", tenant, err)
+		}
+		body, err := json.Marshal(map[string]any{
+			"filter": map[string]any{"term": map[string]any{l.tenant.field: tenant}},
+		})
+		if err != nil {
+			return fmt.Errorf("encoding alias filter for tenant %q: %w", tenant, err)
+		}
+		if err := putAlias(ctx, l.client, []string{index}, l.TenantAlias(f.name, tenant), body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tenantValues returns the distinct values of the tenant field in an index.
+func tenantValues(ctx context.Context, client *elasticsearch.Client, index, field string) ([]string, error) {
+	body, err := json.Marshal(map[string]any{
+		"size": 0,
+		"aggs": map[string]any{
+			"tenants": map[string]any{"terms": map[string]any{"field": field, "size": maxTenants}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building tenant aggregation: %w", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing tenants of %q: %w", index, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return nil, fmt.Errorf("listing tenants of %q: %w", index, err)
+	}
+
+	var result struct {
+		Aggregations struct {
+			Tenants struct {
+				Buckets []struct {
+					Key         any    `json:"key"`
+					KeyAsString string `json:"key_as_string"`
+				} `json:"buckets"`
+			} `json:"tenants"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding tenants of %q: %w", index, err)
+	}
+
+	tenants := make([]string, 0, len(result.Aggregations.Tenants.Buckets))
+	for _, b := range result.Aggregations.Tenants.Buckets {
+		key := b.KeyAsString
+		if key == "" {
+			key = fmt.Sprint(b.Key)
+		}
+		tenants = append(tenants, key)
+	}
+
+	return tenants, nil
+}
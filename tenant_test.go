@@ -0,0 +1,100 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWithTenantField(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/_mapping.json": `{"properties":{"name":{"type":"keyword"},"org":{"properties":{"name":{"type":"text"}}}}}`,
+		"users/documents.yml": "- _id: 1\n  name: Alice\n- _id: 2\n  name: Bob\n  org: {id: globex}\n",
+	})
+
+	var (
+		mapping string
+		bulk    string
+		aliases []string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			bulk = string(body)
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":201}}]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_search"):
+			return jsonResponse(200, `{"aggregations":{"tenants":{"buckets":[{"key":"acme","doc_count":1},{"key":"globex","doc_count":1}]}}}`), nil
+		case strings.Contains(req.URL.Path, "/_aliases/"):
+			aliases = append(aliases, req.URL.Path+" "+string(body))
+		case req.Method == http.MethodPut:
+			mapping = string(body)
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithTenantField("org.id", "acme"), WithTenantAliases())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	var created struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(mapping), &created); err != nil {
+		t.Fatalf("decoding create index body %q: %v", mapping, err)
+	}
+	if typ, _, _ := mappedField(created.Mappings, "org.id"); typ != "keyword" {
+		t.Errorf("expected org.id to be mapped as keyword, got %s", created.Mappings)
+	}
+	if typ, _, _ := mappedField(created.Mappings, "org.name"); typ != "text" {
+		t.Errorf("expected the existing mapping to be kept, got %s", created.Mappings)
+	}
+
+	if !strings.Contains(bulk, `"name":"Alice","org":{"id":"acme"}`) || !strings.Contains(bulk, `"org":{"id":"globex"}`) {
+		t.Errorf("expected the tenant to fill in only missing values, got %s", bulk)
+	}
+
+	want := []string{
+		`/users/_aliases/users_acme {"filter":{"term":{"org.id":"acme"}}}`,
+		`/users/_aliases/users_globex {"filter":{"term":{"org.id":"globex"}}}`,
+	}
+	if strings.Join(aliases, "\n") != strings.Join(want, "\n") {
+		t.Errorf("aliases = %q, want %q", aliases, want)
+	}
+	if got := loader.TenantAlias("users", "acme"); got != "users_acme" {
+		t.Errorf("TenantAlias() = %q, want users_acme", got)
+	}
+}
+
+func TestWithTenantAliases_RequiresField(t *testing.T) {
+	if _, err := New(newOfflineClient(t), Directory(t.TempDir()), WithTenantAliases()); err == nil {
+		t.Error("expected WithTenantAliases without WithTenantField to be rejected")
+	}
+	if _, err := New(newOfflineClient(t), Directory(t.TempDir()), WithTenantField("tenant", "a"), WithTenantField("org", "b")); err == nil {
+		t.Error("expected a second tenant field to be rejected")
+	}
+}
+
+func TestAddKeywordMapping(t *testing.T) {
+	got, err := addKeywordMapping(nil, []string{"tenant_id"})
+	if err != nil {
+		t.Fatalf("addKeywordMapping() error: %v", err)
+	}
+	if string(got) != `{"properties":{"tenant_id":{"type":"keyword"}}}` {
+		t.Errorf("unexpected mapping %s", got)
+	}
+
+	if _, err := addKeywordMapping(json.RawMessage(`{"properties":{"org":{"type":"keyword"}}}`), []string{"org", "id"}); err == nil {
+		t.Error("expected an error for a field under a keyword")
+	}
+}
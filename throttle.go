@@ -0,0 +1,334 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Retries of requests rejected by an Elasticsearch circuit breaker. The wait
+// doubles on each attempt, starting at circuitBreakerBackoff.
+const (
+	circuitBreakerRetries = 5
+	circuitBreakerBackoff = 100 * time.Millisecond
+)
+
+// rejectionTypes are the errors of bulk items Elasticsearch rejected for
+// lack of resources, which succeed when sent again more slowly.
+var rejectionTypes = []string{"circuit_breaking_exception", "es_rejected_execution_exception"}
+
+// throttleTransport limits the total size of request bodies in flight at
+// once. When Elasticsearch rejects a request because a circuit breaker
+// tripped, or rejects items of a bulk request for lack of resources, it
+// halves the limit and retries what was rejected after a backoff.
+type throttleTransport struct {
+	next esapi.Transport
+
+	mu       sync.Mutex
+	changed  chan struct{} // Closed when inFlight or limit changes
+	limit    int64         // Current limit, lowered by rejections
+	inFlight int64
+}
+
+func newThrottleTransport(next esapi.Transport, limit int64) *throttleTransport {
+	return &throttleTransport{next: next, limit: limit, changed: make(chan struct{})}
+}
+
+func (t *throttleTransport) Perform(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		body = data
+	}
+	size := int64(len(body))
+
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if err := t.acquire(req.Context(), size); err != nil {
+			return nil, err
+		}
+		res, err := t.next.Perform(req)
+		t.release(size)
+		if err == nil && res.StatusCode == http.StatusOK && strings.HasSuffix(req.URL.Path, "/_bulk") {
+			return t.retryRejectedItems(req, body, res)
+		}
+		if err != nil || res.StatusCode != http.StatusTooManyRequests || attempt == circuitBreakerRetries {
+			return res, err
+		}
+
+		data, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading response body: %w", err)
+		}
+		res.Body = io.NopCloser(bytes.NewReader(data))
+		if !bytes.Contains(data, []byte("circuit_breaking_exception")) {
+			return res, nil
+		}
+
+		t.slowDown()
+		select {
+		case <-req.Context().Done():
+			return res, nil
+		case <-time.After(circuitBreakerBackoff << attempt):
+		}
+	}
+}
+
+// retryRejectedItems resends, with backoff and a halved limit, the items of
+// the bulk request body that res reports as rejected for lack of resources,
+// and returns res with the results of the last attempt in their place. The
+// bulk indexer matches results to documents by position, so the items keep
+// their order. A response that cannot be read as a bulk response is
+// returned as it is.
+func (t *throttleTransport) retryRejectedItems(req *http.Request, body []byte, res *http.Response) (*http.Response, error) {
+	data, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	if !slices.ContainsFunc(rejectionTypes, func(typ string) bool { return bytes.Contains(data, []byte(typ)) }) {
+		return res, nil
+	}
+	fields, items, err := decodeBulkItems(data)
+	if err != nil {
+		return res, nil
+	}
+	entries := splitBulkBody(body)
+	if len(entries) != len(items) {
+		return res, nil
+	}
+
+	rejected := rejectedItems(items)
+	for attempt := 0; len(rejected) > 0 && attempt < circuitBreakerRetries; attempt++ {
+		t.slowDown()
+		select {
+		case <-req.Context().Done():
+			return bulkResponse(res, fields, items), nil
+		case <-time.After(circuitBreakerBackoff << attempt):
+		}
+
+		var retry []byte
+		for _, i := range rejected {
+			retry = append(retry, entries[i]...)
+		}
+		retried, err := t.sendBulk(req, retry)
+		if err != nil || len(retried) != len(rejected) {
+			break
+		}
+		var still []int
+		for j, i := range rejected {
+			items[i] = retried[j]
+			if isRejectedItem(retried[j]) {
+				still = append(still, i)
+			}
+		}
+		rejected = still
+	}
+
+	return bulkResponse(res, fields, items), nil
+}
+
+// sendBulk sends body as a bulk request like req, returning the items of
+// its response. A request failing as a whole returns an error.
+func (t *throttleTransport) sendBulk(req *http.Request, body []byte) ([]json.RawMessage, error) {
+	retry := req.Clone(req.Context())
+	retry.Body = io.NopCloser(bytes.NewReader(body))
+	retry.ContentLength = int64(len(body))
+
+	size := int64(len(body))
+	if err := t.acquire(req.Context(), size); err != nil {
+		return nil, err
+	}
+	res, err := t.next.Perform(retry)
+	t.release(size)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bulk retry failed with status %d", res.StatusCode)
+	}
+	_, items, err := decodeBulkItems(data)
+	return items, err
+}
+
+// decodeBulkItems splits a bulk response into its fields and its items.
+func decodeBulkItems(data []byte) ([]jsonField, []json.RawMessage, error) {
+	fields, err := decodeObject(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, f := range fields {
+		if f.key == "items" {
+			var items []json.RawMessage
+			if err := json.Unmarshal(f.value, &items); err != nil {
+				return nil, nil, err
+			}
+			return fields, items, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("bulk response has no items")
+}
+
+// bulkResponse returns res with a body made of fields and items, its errors
+// field telling whether any item failed.
+func bulkResponse(res *http.Response, fields []jsonField, items []json.RawMessage) *http.Response {
+	failed := false
+	for _, item := range items {
+		if status, _ := bulkItemResult(item); status >= 300 {
+			failed = true
+		}
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return res
+	}
+	out := make([]jsonField, 0, len(fields))
+	for _, f := range fields {
+		switch f.key {
+		case "items":
+			f.value = encoded
+		case "errors":
+			f.value = json.RawMessage(strconv.FormatBool(failed))
+		}
+		out = append(out, f)
+	}
+
+	body := encodeObject(out)
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.Header.Del("Content-Length")
+	return res
+}
+
+// splitBulkBody splits a bulk request body into its items: an action line
+// and, for all actions but delete, the line of the document that follows.
+func splitBulkBody(body []byte) [][]byte {
+	var (
+		entries [][]byte
+		entry   []byte
+	)
+	lines := bytes.SplitAfter(body, []byte("\n"))
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry = append([]byte(nil), line...)
+		fields, err := decodeObject(bytes.TrimSpace(line))
+		if err != nil || len(fields) != 1 {
+			return nil
+		}
+		if fields[0].key != "delete" && i+1 < len(lines) {
+			i++
+			entry = append(entry, lines[i]...)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// rejectedItems returns the positions of the items rejected for lack of
+// resources.
+func rejectedItems(items []json.RawMessage) []int {
+	var rejected []int
+	for i, item := range items {
+		if isRejectedItem(item) {
+			rejected = append(rejected, i)
+		}
+	}
+	return rejected
+}
+
+// isRejectedItem reports whether a bulk response item was rejected for lack
+// of resources, and may succeed when sent again.
+func isRejectedItem(item json.RawMessage) bool {
+	status, errType := bulkItemResult(item)
+	return status == http.StatusTooManyRequests || slices.Contains(rejectionTypes, errType)
+}
+
+// bulkItemResult returns the status and error type of a bulk response item,
+// an object holding the result under the name of its action.
+func bulkItemResult(item json.RawMessage) (int, string) {
+	var results map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(item, &results); err != nil {
+		return 0, ""
+	}
+	for _, r := range results {
+		return r.Status, r.Error.Type
+	}
+	return 0, ""
+}
+
+// acquire waits until n more bytes fit under the limit, or ctx is done. A
+// request larger than the limit is let through once nothing else is in
+// flight.
+func (t *throttleTransport) acquire(ctx context.Context, n int64) error {
+	for {
+		t.mu.Lock()
+		if t.inFlight == 0 || t.inFlight+n <= t.limit {
+			t.inFlight += n
+			t.mu.Unlock()
+			return nil
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (t *throttleTransport) release(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight -= n
+	t.notify()
+}
+
+// slowDown halves the limit after a rejection.
+func (t *throttleTransport) slowDown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = max(t.limit/2, 1)
+	t.notify()
+}
+
+// notify wakes the requests waiting in acquire. t.mu must be held.
+func (t *throttleTransport) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
@@ -0,0 +1,205 @@
+package testfixtures
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// performFunc adapts a function to esapi.Transport.
+type performFunc func(*http.Request) (*http.Response, error)
+
+func (f performFunc) Perform(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestThrottleTransport_LimitsInFlightBytes(t *testing.T) {
+	var current, peak atomic.Int64
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		n := req.ContentLength
+		v := current.Add(n)
+		for {
+			p := peak.Load()
+			if v <= p || peak.CompareAndSwap(p, v) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		current.Add(-n)
+		return jsonResponse(200, `{}`), nil
+	})
+	tr := newThrottleTransport(next, 250)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodPost, "/_bulk", strings.NewReader(strings.Repeat("x", 100)))
+			if _, err := tr.Perform(req); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 250 {
+		t.Errorf("peak in-flight bytes = %d, want at most 250", got)
+	}
+}
+
+func TestThrottleTransport_RetriesCircuitBreaker(t *testing.T) {
+	var calls int
+	var bodies []string
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		if calls == 1 {
+			return jsonResponse(429, `{"error":{"type":"circuit_breaking_exception","reason":"[parent] Data too large"},"status":429}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	})
+	tr := newThrottleTransport(next, 1000)
+
+	req, _ := http.NewRequest(http.MethodPost, "/_bulk", strings.NewReader(`{"index":{}}`))
+	res, err := tr.Perform(req)
+	if err != nil {
+		t.Fatalf("Perform() error: %v", err)
+	}
+	if res.StatusCode != 200 || calls != 2 {
+		t.Errorf("expected a successful retry, got status %d after %d calls", res.StatusCode, calls)
+	}
+	if bodies[1] != `{"index":{}}` {
+		t.Errorf("expected the body to be resent, got %q", bodies[1])
+	}
+	if tr.limit != 500 {
+		t.Errorf("expected limit to be halved to 500, got %d", tr.limit)
+	}
+}
+
+func TestThrottleTransport_PassesOtherRejections(t *testing.T) {
+	calls := 0
+	next := performFunc(func(*http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(429, `{"error":"too many requests"}`), nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	res, err := newThrottleTransport(next, 10).Perform(req)
+	if err != nil || res.StatusCode != 429 || calls != 1 {
+		t.Errorf("expected the rejection to be returned without retry, got %v, %v after %d calls", res, err, calls)
+	}
+	if body, _ := io.ReadAll(res.Body); string(body) != `{"error":"too many requests"}` {
+		t.Errorf("expected the response body to be preserved, got %q", body)
+	}
+}
+
+func TestThrottleTransport_RetriesRejectedItems(t *testing.T) {
+	var bodies []string
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		switch len(bodies) {
+		case 1:
+			return jsonResponse(200, `{"took":3,"errors":true,"items":[`+
+				`{"index":{"_id":"1","status":201}},`+
+				`{"delete":{"_id":"2","status":429,"error":{"type":"es_rejected_execution_exception"}}},`+
+				`{"index":{"_id":"3","status":429,"error":{"type":"circuit_breaking_exception"}}},`+
+				`{"index":{"_id":"4","status":400,"error":{"type":"mapper_parsing_exception"}}}]}`), nil
+		case 2:
+			return jsonResponse(200, `{"took":1,"errors":true,"items":[`+
+				`{"delete":{"_id":"2","status":200}},`+
+				`{"index":{"_id":"3","status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`), nil
+		}
+		return jsonResponse(200, `{"took":1,"errors":false,"items":[{"index":{"_id":"3","status":201}}]}`), nil
+	})
+	tr := newThrottleTransport(next, 1000)
+
+	body := `{"index":{"_id":"1"}}` + "\n" + `{"a":1}` + "\n" +
+		`{"delete":{"_id":"2"}}` + "\n" +
+		`{"index":{"_id":"3"}}` + "\n" + `{"a":3}` + "\n" +
+		`{"index":{"_id":"4"}}` + "\n" + `{"a":"x"}` + "\n"
+	req, _ := http.NewRequest(http.MethodPost, "/users/_bulk", strings.NewReader(body))
+	res, err := tr.Perform(req)
+	if err != nil {
+		t.Fatalf("Perform() error: %v", err)
+	}
+
+	wantBodies := []string{
+		body,
+		`{"delete":{"_id":"2"}}` + "\n" + `{"index":{"_id":"3"}}` + "\n" + `{"a":3}` + "\n",
+		`{"index":{"_id":"3"}}` + "\n" + `{"a":3}` + "\n",
+	}
+	if strings.Join(bodies, "---\n") != strings.Join(wantBodies, "---\n") {
+		t.Errorf("expected only the rejected items to be resent, got bodies:\n%s", strings.Join(bodies, "---\n"))
+	}
+	got, _ := io.ReadAll(res.Body)
+	want := `{"took":3,"errors":true,"items":[` +
+		`{"index":{"_id":"1","status":201}},` +
+		`{"delete":{"_id":"2","status":200}},` +
+		`{"index":{"_id":"3","status":201}},` +
+		`{"index":{"_id":"4","status":400,"error":{"type":"mapper_parsing_exception"}}}]}`
+	if string(got) != want {
+		t.Errorf("expected the retried results in place\n%s\ngot\n%s", want, got)
+	}
+	if tr.limit != 250 {
+		t.Errorf("expected limit to be halved twice to 250, got %d", tr.limit)
+	}
+}
+
+func TestThrottleTransport_AcquireHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	next := performFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		return jsonResponse(200, `{}`), nil
+	})
+	tr := newThrottleTransport(next, 100)
+	defer close(release)
+
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "/_bulk", strings.NewReader(strings.Repeat("x", 100)))
+		_, _ = tr.Perform(req)
+	}()
+	for {
+		tr.mu.Lock()
+		n := tr.inFlight
+		tr.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/_bulk", strings.NewReader(strings.Repeat("x", 100)))
+	done := make(chan error, 1)
+	go func() {
+		_, err := tr.Perform(req)
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the waiting request to fail with its context, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a cancelled request to stop waiting for the limit")
+	}
+}
+
+func TestDescribeFlushError(t *testing.T) {
+	err := errors.New(`flush: [429 Too Many Requests] {"error":{"type":"circuit_breaking_exception"}}`)
+	if got := describeFlushError(err); !strings.Contains(got, "WithMaxInFlightBytes") {
+		t.Errorf("expected circuit breaker hint, got %q", got)
+	}
+	if got := describeFlushError(errors.New("flush: EOF")); got != "flush: EOF" {
+		t.Errorf("expected other errors unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,78 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// traitsFile is the optional top-level file defining named document fragments.
+const traitsFile = "_traits.yml"
+
+// traitsKey is the document key listing the traits mixed into a document,
+// e.g. _traits: [premium_user, eu_region].
+const traitsKey = "_traits"
+
+// readTraits reads _traits.yml, a YAML mapping of trait names to document
+// fragments. A missing file yields no traits.
+func readTraits(fsys fs.FS, name string) (map[string]json.RawMessage, error) {
+	traits, err := readYAMLValues(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", traitsFile, err)
+	}
+
+	for name, fragment := range traits {
+		if _, err := decodeObject(fragment); err != nil {
+			return nil, fmt.Errorf("%s: trait %q must be a mapping", traitsFile, name)
+		}
+	}
+
+	return traits, nil
+}
+
+// traitNames returns the trait names listed by a _traits value, which may be
+// a single name or a sequence of names.
+func traitNames(node *yaml.Node) ([]string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return []string{node.Value}, nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: %s must be a trait name or a list of names", node.Line, traitsKey)
+	}
+
+	names := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: %s must be a trait name or a list of names", item.Line, traitsKey)
+		}
+		names = append(names, item.Value)
+	}
+
+	return names, nil
+}
+
+// applyTraits deep-merges the named traits under body. Traits are applied in
+// the order listed, so later traits override earlier ones, and the document's
+// own fields override them all.
+func applyTraits(body json.RawMessage, names []string, traits map[string]json.RawMessage) (json.RawMessage, error) {
+	var merged json.RawMessage
+	for _, name := range names {
+		fragment, ok := traits[name]
+		if !ok {
+			return nil, fmt.Errorf("undefined trait %q (define it in %s)", name, traitsFile)
+		}
+
+		var err error
+		if merged, err = mergeJSONObjects(merged, fragment); err != nil {
+			return nil, fmt.Errorf("trait %q: %w", name, err)
+		}
+	}
+
+	return mergeJSONObjects(merged, body)
+}
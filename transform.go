@@ -0,0 +1,151 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// fieldNormalizer rewrites the value of a single document field.
+type fieldNormalizer struct {
+	field string
+	fn    func(any) any
+}
+
+// apply runs the normalizer on body. Array values are normalized element by
+// element, since Elasticsearch treats them as multi-valued fields.
+func (n fieldNormalizer) apply(body json.RawMessage) (json.RawMessage, error) {
+	return mapField(body, strings.Split(n.field, "."), func(value json.RawMessage) (json.RawMessage, error) {
+		if !isJSONArray(value) {
+			return n.normalize(value)
+		}
+
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			normalized, err := n.normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = normalized
+		}
+		return json.Marshal(items)
+	})
+}
+
+func (n fieldNormalizer) normalize(value json.RawMessage) (json.RawMessage, error) {
+	v, err := decodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(n.fn(v))
+}
+
+// fieldGenerator supplies a value for a document field when it is missing.
+type fieldGenerator struct {
+	field string
+	fn    func() any
+}
+
+// apply adds the generated value to body if the field is absent.
+func (g fieldGenerator) apply(body json.RawMessage) (json.RawMessage, error) {
+	return setMissingField(body, strings.Split(g.field, "."), func() (json.RawMessage, error) {
+		return json.Marshal(g.fn())
+	})
+}
+
+// transformDocument applies the Loader's static document transforms to doc.
+// These run once per document when the Loader is constructed.
+func (l *Loader) transformDocument(doc Document) (Document, error) {
+	for _, n := range l.normalizers {
+		body, err := n.apply(doc.Source)
+		if err != nil {
+			return Document{}, fmt.Errorf("normalizing %q: %w", n.field, err)
+		}
+		doc.Source = body
+	}
+
+	return doc, nil
+}
+
+// generateFields fills in generated fields on doc. Unlike transformDocument,
+// it runs on every Load so that volatile values such as timestamps are fresh.
+// Updates and deletes are left alone, since the fields a partial update
+// omits keep the values of the document it applies to.
+func (l *Loader) generateFields(doc Document) (Document, error) {
+	if doc.partial() {
+		return doc, nil
+	}
+	for _, g := range l.generators {
+		body, err := g.apply(doc.Source)
+		if err != nil {
+			return Document{}, fmt.Errorf("generating %q: %w", g.field, err)
+		}
+		doc.Source = body
+	}
+
+	return doc, nil
+}
+
+// transformFixtures applies the static document transforms to all parsed documents.
+func (l *Loader) transformFixtures() error {
+	if len(l.normalizers) == 0 {
+		return nil
+	}
+
+	for _, f := range l.fixtures {
+		for i, doc := range f.documents {
+			transformed, err := l.transformDocument(doc)
+			if err != nil {
+				return fmt.Errorf("index %q: document %d: %w", f.name, i, err)
+			}
+			f.documents[i] = transformed
+		}
+	}
+
+	return nil
+}
+
+// documentTransform returns the transform applied to parsed documents at
+// load time, or nil if there is nothing to apply.
+func (l *Loader) documentTransform() func(Document) (Document, error) {
+	if len(l.generators) == 0 {
+		return nil
+	}
+	return l.generateFields
+}
+
+// streamTransform returns the transform applied to streamed NDJSON lines and
+// provider documents, or nil if there is nothing to apply. These are never
+// parsed ahead of time, so they get both the static and the load-time transforms.
+func (l *Loader) streamTransform() func(Document) (Document, error) {
+	if len(l.normalizers) == 0 && len(l.generators) == 0 {
+		return nil
+	}
+	return func(doc Document) (Document, error) {
+		doc, err := l.transformDocument(doc)
+		if err != nil {
+			return Document{}, err
+		}
+		return l.generateFields(doc)
+	}
+}
+
+// countDocuments wraps transform so that each document passing through it
+// increments n. A nil transform is treated as the identity.
+func countDocuments(transform func(Document) (Document, error), n *atomic.Int64) func(Document) (Document, error) {
+	return func(doc Document) (Document, error) {
+		if transform != nil {
+			var err error
+			if doc, err = transform(doc); err != nil {
+				return Document{}, err
+			}
+		}
+		n.Add(1)
+		return doc, nil
+	}
+}
@@ -0,0 +1,110 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// newOfflineClient returns a client for constructing Loaders in unit tests.
+// No request is sent until Load or Clean is called.
+func newOfflineClient(t *testing.T) *elasticsearch.Client {
+	t.Helper()
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{})
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	return client
+}
+
+func TestWithFieldNormalizer(t *testing.T) {
+	lower := func(v any) any {
+		if s, ok := v.(string); ok {
+			return strings.ToLower(s)
+		}
+		return v
+	}
+
+	loader, err := New(newOfflineClient(t),
+		Directory("testdata/fixtures"),
+		WithFieldNormalizer("email", lower),
+		WithFieldNormalizer("email", func(v any) any { return strings.TrimSuffix(v.(string), ".com") }),
+	)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	for _, f := range loader.fixtures {
+		if f.name != "users" {
+			continue
+		}
+		if got := decodeBody(t, f.documents[0])["email"]; got != "alice@example" {
+			t.Errorf("expected normalized email 'alice@example', got %v", got)
+		}
+	}
+}
+
+func TestFieldNormalizer_NestedAndArrays(t *testing.T) {
+	n := fieldNormalizer{
+		field: "tags.name",
+		fn: func(v any) any {
+			return strings.TrimSpace(v.(string))
+		},
+	}
+
+	body := json.RawMessage(`{"id":12345678901234567890,"tags":[{"name":" a "},{"name":[" b "," c"]}]}`)
+	got, err := n.apply(body)
+	if err != nil {
+		t.Fatalf("apply() error: %v", err)
+	}
+
+	want := `{"id":12345678901234567890,"tags":[{"name":"a"},{"name":["b","c"]}]}`
+	if string(got) != want {
+		t.Errorf("unexpected body:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestWithFieldNormalizer_Invalid(t *testing.T) {
+	if _, err := New(newOfflineClient(t), Directory("testdata/fixtures"), WithFieldNormalizer("", func(v any) any { return v })); err == nil {
+		t.Error("expected error for empty field name")
+	}
+	if _, err := New(newOfflineClient(t), Directory("testdata/fixtures"), WithFieldNormalizer("email", nil)); err == nil {
+		t.Error("expected error for nil normalizer")
+	}
+}
+
+func TestFieldGenerator(t *testing.T) {
+	calls := 0
+	g := fieldGenerator{
+		field: "meta.created_at",
+		fn: func() any {
+			calls++
+			return "2024-01-01T00:00:00Z"
+		},
+	}
+
+	got, err := g.apply(json.RawMessage(`{"name":"a"}`))
+	if err != nil {
+		t.Fatalf("apply() error: %v", err)
+	}
+	if want := `{"name":"a","meta":{"created_at":"2024-01-01T00:00:00Z"}}`; string(got) != want {
+		t.Errorf("unexpected body:\n got: %s\nwant: %s", got, want)
+	}
+
+	// Fields present in the fixture are left alone
+	body := json.RawMessage(`{"meta":{"created_at":"fixed","v":1}}`)
+	got, err = g.apply(body)
+	if err != nil {
+		t.Fatalf("apply() error: %v", err)
+	}
+	if string(got) != string(body) {
+		t.Errorf("expected body to be unchanged, got %s", got)
+	}
+	if calls != 1 {
+		t.Errorf("expected generator to be called once, got %d", calls)
+	}
+}
@@ -0,0 +1,380 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// transformsDir is the top-level directory holding transforms, one
+// <id>.json file per transform in the format of the Create Transform API.
+// Load creates each transform once its source indices are loaded.
+const transformsDir = "_transforms"
+
+// Polling intervals while waiting for a transform checkpoint, doubled after
+// each poll up to the maximum.
+const (
+	transformPollMin = 50 * time.Millisecond
+	transformPollMax = 2 * time.Second
+)
+
+// esTransform is a transform of the _transforms directory.
+type esTransform struct {
+	id      string
+	config  []jsonField // The definition, without start
+	sources []string    // Source fixture indices
+	dest    string      // Destination index, named like a fixture index
+	start   bool        // Whether Load starts it and waits for a checkpoint
+}
+
+// readTransforms reads the transforms of the _transforms directory, ordered
+// by id. A missing directory means there are none.
+func readTransforms(fsys fs.FS, dir string) ([]esTransform, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, transformsDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", transformsDir, err)
+	}
+
+	var transforms []esTransform
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		file := transformsDir + "/" + name
+		body, err := readJSONFile(fsys, path.Join(dir, transformsDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		t, err := parseTransform(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		t.id = strings.TrimSuffix(name, ".json")
+		transforms = append(transforms, t)
+	}
+
+	return transforms, nil
+}
+
+// parseTransform parses a transform definition: a Create Transform body
+// whose source indices are a fixture index or a list of them, with an
+// optional "start" flag of its own.
+func parseTransform(body json.RawMessage) (esTransform, error) {
+	if !isJSONObject(body) {
+		return esTransform{}, errors.New("transform must be an object")
+	}
+	fields, err := decodeObject(body)
+	if err != nil {
+		return esTransform{}, err
+	}
+
+	var t esTransform
+	for _, f := range fields {
+		switch f.key {
+		case "start":
+			if err := json.Unmarshal(f.value, &t.start); err != nil {
+				return esTransform{}, errors.New("start must be true or false")
+			}
+			continue
+		case "source":
+			var source struct {
+				Index json.RawMessage `json:"index"`
+			}
+			if !isJSONObject(f.value) || json.Unmarshal(f.value, &source) != nil {
+				return esTransform{}, errors.New("source must be an object")
+			}
+			if json.Unmarshal(source.Index, &t.sources) != nil {
+				var index string
+				if json.Unmarshal(source.Index, &index) != nil {
+					return esTransform{}, errors.New("source.index must be a fixture index or a list of them")
+				}
+				t.sources = []string{index}
+			}
+		case "dest":
+			var dest struct {
+				Index string `json:"index"`
+			}
+			if !isJSONObject(f.value) || json.Unmarshal(f.value, &dest) != nil {
+				return esTransform{}, errors.New("dest must be an object")
+			}
+			t.dest = dest.Index
+		}
+		t.config = append(t.config, f)
+	}
+	if len(t.sources) == 0 {
+		return esTransform{}, errors.New("source.index must not be empty")
+	}
+	if t.dest == "" {
+		return esTransform{}, errors.New("dest.index must be set")
+	}
+
+	return t, nil
+}
+
+// checkTransforms checks that the source indices of every transform are
+// fixture indices, and that no transform writes into a fixture index or
+// into the destination of another.
+func (l *Loader) checkTransforms() error {
+	var errs []error
+	dests := make(map[string]string)
+	for _, t := range l.transforms {
+		file := transformsDir + "/" + t.id + ".json"
+		for _, index := range t.sources {
+			if f := l.fixture(index); f == nil || f.isAlias() {
+				errs = append(errs, fmt.Errorf("%s: source index %q is not a fixture index", file, index))
+			}
+		}
+		if l.fixture(t.dest) != nil {
+			errs = append(errs, fmt.Errorf("%s: destination index %q is a fixture", file, t.dest))
+		}
+		if other, ok := dests[t.dest]; ok {
+			errs = append(errs, fmt.Errorf("%s: destination index %q is also the destination of transform %q", file, t.dest, other))
+		}
+		dests[t.dest] = t.id
+	}
+	return errors.Join(errs...)
+}
+
+// transformBody returns the Create Transform body of t, with its source and
+// destination indices named as they are in the cluster.
+func (l *Loader) transformBody(t esTransform) (json.RawMessage, error) {
+	sources := make([]string, len(t.sources))
+	for i, index := range t.sources {
+		sources[i] = l.IndexName(index)
+	}
+
+	fields := slices.Clone(t.config)
+	for i, f := range fields {
+		var err error
+		switch f.key {
+		case "source":
+			fields[i].value, err = replaceField(f.value, "index", sources)
+		case "dest":
+			fields[i].value, err = replaceField(f.value, "index", l.IndexName(t.dest))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encodeObject(fields), nil
+}
+
+// replaceField returns the JSON object obj with its key field set to value.
+func replaceField(obj json.RawMessage, key string, value any) (json.RawMessage, error) {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range fields {
+		if f.key == key {
+			fields[i].value = v
+		}
+	}
+	return encodeObject(fields), nil
+}
+
+// runTransforms creates the transforms whose last source index among
+// fixtures is f, now that their source data is loaded. Transforms cannot be
+// replaced, so each is deleted first together with its destination index.
+// Those with start set are then started, and Load waits until they have
+// completed their first checkpoint. Nothing is created if _optional.yml
+// lists transforms and the cluster cannot run them.
+func (l *Loader) runTransforms(ctx context.Context, f *indexFixture, fixtures []*indexFixture) error {
+	if skip, err := l.skipsComponent(ctx, "transforms"); err != nil || skip {
+		return err
+	}
+	for _, t := range l.transforms {
+		if lastSource(t.sources, fixtures) != f {
+			continue
+		}
+
+		if err := l.deleteTransform(ctx, t); err != nil {
+			return err
+		}
+		body, err := l.transformBody(t)
+		if err != nil {
+			return fmt.Errorf("transform %q: %w", t.id, err)
+		}
+		if err := putTransform(ctx, l.client, t.id, body); err != nil {
+			return err
+		}
+		if !t.start {
+			continue
+		}
+		if err := startTransform(ctx, l.client, t.id); err != nil {
+			return err
+		}
+		if err := awaitTransformCheckpoint(ctx, l.client, t.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lastSource returns the fixture of indices that comes last in fixtures, or
+// nil if none of them is there.
+func lastSource(indices []string, fixtures []*indexFixture) *indexFixture {
+	var last *indexFixture
+	for _, f := range fixtures {
+		if slices.Contains(indices, f.name) {
+			last = f
+		}
+	}
+	return last
+}
+
+// deleteTransforms removes the transforms of _transforms and their
+// destination indices, and returns the errors of the deletions that failed.
+func (l *Loader) deleteTransforms(ctx context.Context) []error {
+	if skip, err := l.skipsComponent(ctx, "transforms"); err != nil {
+		return []error{err}
+	} else if skip {
+		return nil
+	}
+	var errs []error
+	for _, t := range l.transforms {
+		if err := l.deleteTransform(ctx, t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// deleteTransform stops and deletes the transform t if it exists, and then
+// its destination index, which may outlive a transform deleted by hand.
+func (l *Loader) deleteTransform(ctx context.Context, t esTransform) error {
+	res, err := l.client.TransformDeleteTransform(t.id,
+		l.client.TransformDeleteTransform.WithForce(true),
+		l.client.TransformDeleteTransform.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("deleting transform %q: %w", t.id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// The transform may already be gone, which is fine
+	if res.StatusCode != 404 {
+		if err := checkResponse(res); err != nil {
+			return fmt.Errorf("deleting transform %q: %w", t.id, err)
+		}
+	}
+
+	name := l.IndexName(t.dest)
+	if err := deleteIndex(ctx, l.client, name); err != nil {
+		return err
+	}
+	l.events.emit(IndexDeleted{Index: name})
+
+	return nil
+}
+
+// putTransform creates the transform id.
+func putTransform(ctx context.Context, client *elasticsearch.Client, id string, body json.RawMessage) error {
+	res, err := client.TransformPutTransform(bytes.NewReader(body), id, client.TransformPutTransform.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating transform %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("creating transform %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// startTransform starts the transform id.
+func startTransform(ctx context.Context, client *elasticsearch.Client, id string) error {
+	res, err := client.TransformStartTransform(id, client.TransformStartTransform.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("starting transform %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("starting transform %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// awaitTransformCheckpoint polls the stats of the transform id until it has
+// completed a checkpoint, which a batch transform does once it has
+// processed all of its source data. It fails if the transform fails.
+func awaitTransformCheckpoint(ctx context.Context, client *elasticsearch.Client, id string) error {
+	interval := transformPollMin
+	for {
+		stats, err := transformStats(ctx, client, id)
+		if err != nil {
+			return err
+		}
+		if stats.State == "failed" {
+			return fmt.Errorf("transform %q failed: %s", id, stats.Reason)
+		}
+		if stats.Checkpointing.Last.Checkpoint > 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for transform %q to complete a checkpoint: %w", id, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval = min(2*interval, transformPollMax)
+	}
+}
+
+// transformState is the part of the transform stats that
+// awaitTransformCheckpoint looks at.
+type transformState struct {
+	State         string `json:"state"`
+	Reason        string `json:"reason"`
+	Checkpointing struct {
+		Last struct {
+			Checkpoint int64 `json:"checkpoint"`
+		} `json:"last"`
+	} `json:"checkpointing"`
+}
+
+// transformStats returns the state of the transform id.
+func transformStats(ctx context.Context, client *elasticsearch.Client, id string) (transformState, error) {
+	res, err := client.TransformGetTransformStats(id, client.TransformGetTransformStats.WithContext(ctx))
+	if err != nil {
+		return transformState{}, fmt.Errorf("getting stats of transform %q: %w", id, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return transformState{}, fmt.Errorf("getting stats of transform %q: %w", id, err)
+	}
+
+	var result struct {
+		Transforms []transformState `json:"transforms"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return transformState{}, fmt.Errorf("decoding stats of transform %q: %w", id, err)
+	}
+	if len(result.Transforms) == 0 {
+		return transformState{}, fmt.Errorf("getting stats of transform %q: no stats returned", id)
+	}
+
+	return result.Transforms[0], nil
+}
@@ -0,0 +1,197 @@
+package testfixtures
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadTransforms(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_transforms/daily-totals.json": {Data: []byte(`{"source": {"index": ["orders", "refunds"]}, "dest": {"index": "daily_totals"}, "pivot": {"group_by": {}}, "start": true}`)},
+		"_transforms/by-user.json":      {Data: []byte(`{"source": {"index": "orders"}, "dest": {"index": "user_totals"}, "latest": {}}`)},
+		"_transforms/README.md":         {Data: []byte("notes")},
+	}
+
+	transforms, err := readTransforms(fsys, ".")
+	if err != nil {
+		t.Fatalf("readTransforms() error: %v", err)
+	}
+	if len(transforms) != 2 {
+		t.Fatalf("expected 2 transforms, got %d", len(transforms))
+	}
+	if tr := transforms[0]; tr.id != "by-user" || tr.start || tr.dest != "user_totals" || !slices.Equal(tr.sources, []string{"orders"}) {
+		t.Errorf("unexpected first transform: %+v", tr)
+	}
+	if tr := transforms[1]; tr.id != "daily-totals" || !tr.start || !slices.Equal(tr.sources, []string{"orders", "refunds"}) {
+		t.Errorf("unexpected second transform: %+v", tr)
+	}
+	if got := string(encodeObject(transforms[1].config)); strings.Contains(got, "start") {
+		t.Errorf("expected start to be left out of the definition, got %s", got)
+	}
+
+	if transforms, err := readTransforms(fstest.MapFS{}, "."); transforms != nil || err != nil {
+		t.Errorf("expected no transforms for a missing directory, got %v, %v", transforms, err)
+	}
+}
+
+func TestReadTransforms_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not an object", data: `[]`, want: "_transforms/totals.json: transform must be an object"},
+		{name: "no source", data: `{"dest": {"index": "totals"}}`, want: "source.index must not be empty"},
+		{name: "bad source", data: `{"source": {"index": 1}, "dest": {"index": "totals"}}`, want: "source.index must be a fixture index or a list of them"},
+		{name: "no dest", data: `{"source": {"index": "orders"}}`, want: "dest.index must be set"},
+		{name: "bad start", data: `{"source": {"index": "orders"}, "dest": {"index": "totals"}, "start": "yes"}`, want: "start must be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"_transforms/totals.json": {Data: []byte(tt.data)}}
+			_, err := readTransforms(fsys, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNew_TransformIndicesChecked(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_transforms/a.json":   `{"source": {"index": ["orders", "users"]}, "dest": {"index": "orders"}}`,
+		"_transforms/b.json":   `{"source": {"index": "orders"}, "dest": {"index": "totals"}}`,
+		"_transforms/c.json":   `{"source": {"index": "orders"}, "dest": {"index": "totals"}}`,
+		"orders/documents.yml": "- _id: \"1\"\n",
+	})
+
+	_, err := New(newOfflineClient(t), Directory(dir))
+	for _, want := range []string{
+		`_transforms/a.json: source index "users" is not a fixture index`,
+		`_transforms/a.json: destination index "orders" is a fixture`,
+		`_transforms/c.json: destination index "totals" is also the destination of transform "b"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestLoad_Transforms(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_transforms/totals.json": `{"source": {"index": ["orders", "refunds"]}, "dest": {"index": "totals"}, "pivot": {"group_by":{"user":{"terms":{"field":"user"}}},"aggregations":{"n":{"value_count":{"field":"user"}}}}, "start": true}`,
+		"orders/documents.yml":    "- _id: \"1\"\n  user: alice\n",
+		"refunds/documents.yml":   "- _id: \"1\"\n  user: alice\n",
+	})
+
+	var (
+		requests []string
+		polls    int
+	)
+	bodies := make(map[string]string)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			bodies[req.URL.Path] = string(data)
+		}
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/_transform/"):
+			if req.URL.Query().Get("force") != "true" {
+				t.Errorf("expected the transform to be deleted with force, got %s", req.URL.RawQuery)
+			}
+			return jsonResponse(404, `{}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_stats"):
+			polls++
+			if polls == 1 {
+				return jsonResponse(200, `{"transforms":[{"id":"totals","state":"indexing","checkpointing":{"last":{"checkpoint":0}}}]}`), nil
+			}
+			return jsonResponse(200, `{"transforms":[{"id":"totals","state":"stopped","checkpointing":{"last":{"checkpoint":1}}}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithIndexPrefix("ci_"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	order := []string{
+		"POST /ci_refunds/_bulk",
+		"DELETE /_transform/totals",
+		"DELETE /ci_totals",
+		"PUT /_transform/totals",
+		"POST /_transform/totals/_start",
+		"GET /_transform/totals/_stats",
+	}
+	last := -1
+	for _, want := range order {
+		i := slices.Index(requests, want)
+		if i <= last {
+			t.Fatalf("expected requests in the order %v, got %v", order, requests)
+		}
+		last = i
+	}
+	if polls != 2 {
+		t.Errorf("expected the stats to be polled until a checkpoint, got %d polls", polls)
+	}
+	want := `{"source":{"index":["ci_orders","ci_refunds"]},"dest":{"index":"ci_totals"},"pivot":{"group_by":{"user":{"terms":{"field":"user"}}},"aggregations":{"n":{"value_count":{"field":"user"}}}}}`
+	if got := bodies["/_transform/totals"]; got != want {
+		t.Errorf("expected transform body %s, got %s", want, got)
+	}
+
+	requests = nil
+	if err := loader.Clean(); err != nil {
+		t.Fatalf("Clean() error: %v", err)
+	}
+	transform := slices.Index(requests, "DELETE /_transform/totals")
+	dest := slices.Index(requests, "DELETE /ci_totals")
+	source := slices.Index(requests, "DELETE /ci_orders")
+	if transform < 0 || dest < transform || source < dest {
+		t.Errorf("expected Clean to delete the transform and its destination before the sources, got %v", requests)
+	}
+}
+
+func TestLoad_TransformFailed(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"_transforms/totals.json": `{"source": {"index": "orders"}, "dest": {"index": "totals"}, "latest": {}, "start": true}`,
+		"orders/documents.yml":    "- _id: \"1\"\n",
+	})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_stats"):
+			return jsonResponse(200, `{"transforms":[{"id":"totals","state":"failed","reason":"field [user] not found"}]}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	err = loader.Load()
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Stage != StageTransform {
+		t.Fatalf("expected a LoadError at the transform stage, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `transform "totals" failed: field [user] not found`) {
+		t.Errorf("expected the failure reason, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package testfixtures
+
+import (
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// wrapClient returns a client whose requests pass through the transport
+// returned by wrap before reaching client. The returned client shares
+// client's connection pool and configuration.
+func wrapClient(client *elasticsearch.Client, wrap func(next esapi.Transport) esapi.Transport) *elasticsearch.Client {
+	transport := wrap(clientTransport{client})
+
+	return &elasticsearch.Client{
+		BaseClient: elasticsearch.BaseClient{Transport: transport},
+		API:        esapi.New(transport),
+	}
+}
+
+// clientTransport performs requests with an existing client.
+type clientTransport struct {
+	client *elasticsearch.Client
+}
+
+func (t clientTransport) Perform(req *http.Request) (*http.Response, error) {
+	// The wrapping client's BaseClient adds its own meta header for callers
+	// that use Perform directly (such as the bulk indexer); client adds it again.
+	req.Header.Del(elasticsearch.HeaderClientMeta)
+	return t.client.Perform(req)
+}
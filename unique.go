@@ -0,0 +1,136 @@
+package testfixtures
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// ErrAliasInUse is returned by Load under WithUniqueIndices when the alias
+// for a fixture already points to an index this Loader does not own, such
+// as one loaded by a parallel test.
+var ErrAliasInUse = errors.New("testfixtures: alias is in use by another loader")
+
+// newUniqueSuffix returns a random suffix for physical index names.
+func newUniqueSuffix() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating unique index suffix: %w", err)
+	}
+	return "_" + hex.EncodeToString(b), nil
+}
+
+// aliasName returns the name application code uses for the named fixture:
+// the fixture name with the prefix and suffix of WithIndexPrefix and
+// WithIndexSuffix, but without the random suffix of WithUniqueIndices.
+func (l *Loader) aliasName(fixture string) string {
+	return l.indexPrefix + fixture + l.indexSuffix
+}
+
+// claimAlias points the alias name at indices, failing with ErrAliasInUse if
+// it already points anywhere else. The alias is checked again once added, so
+// when two loaders race for it, neither keeps it.
+func claimAlias(ctx context.Context, client *elasticsearch.Client, indices []string, name string, body json.RawMessage) error {
+	if err := checkAliasOwners(ctx, client, indices, name); err != nil {
+		return err
+	}
+
+	if err := putAlias(ctx, client, indices, name, body); err != nil {
+		return err
+	}
+
+	if err := checkAliasOwners(ctx, client, indices, name); err != nil {
+		if rmErr := deleteAlias(ctx, client, indices, name); rmErr != nil {
+			return errors.Join(err, rmErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkAliasOwners reports an error if the alias name points to an index
+// other than indices, or if name is taken by an index.
+func checkAliasOwners(ctx context.Context, client *elasticsearch.Client, indices []string, name string) error {
+	owners, err := aliasIndices(ctx, client, name)
+	if err != nil {
+		return err
+	}
+
+	var foreign []string
+	for _, owner := range owners {
+		if !slices.Contains(indices, owner) {
+			foreign = append(foreign, owner)
+		}
+	}
+	if len(foreign) > 0 {
+		slices.Sort(foreign)
+		return fmt.Errorf("%w: %q points to %s; if no test is using it, delete that index", ErrAliasInUse, name, strings.Join(foreign, ", "))
+	}
+
+	if len(owners) == 0 {
+		exists, err := indexOrAliasExists(ctx, client, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("an index named %q exists, so it cannot be used as an alias; delete it to load with unique indices", name)
+		}
+	}
+
+	return nil
+}
+
+// aliasIndices returns the indices the alias name points to.
+func aliasIndices(ctx context.Context, client *elasticsearch.Client, name string) ([]string, error) {
+	res, err := client.Indices.GetAlias(
+		client.Indices.GetAlias.WithName(name),
+		client.Indices.GetAlias.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting alias %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, fmt.Errorf("getting alias %q: %w", name, err)
+	}
+
+	var result map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding alias %q: %w", name, err)
+	}
+
+	indices := make([]string, 0, len(result))
+	for index := range result {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+
+	return indices, nil
+}
+
+// deleteAlias removes the alias name from indices.
+func deleteAlias(ctx context.Context, client *elasticsearch.Client, indices []string, name string) error {
+	res, err := client.Indices.DeleteAlias(indices, []string{name}, client.Indices.DeleteAlias.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting alias %q: %w", name, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("deleting alias %q: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package testfixtures
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithUniqueIndices(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: 1\n  name: Alice\n",
+	})
+
+	// owners answers GET /_alias/users for each call in turn.
+	var (
+		owners   []string
+		requests []string
+	)
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		case req.Method == http.MethodGet && req.URL.Path == "/_alias/users":
+			if len(owners) == 0 {
+				return jsonResponse(404, `{"error":"alias [users] missing","status":404}`), nil
+			}
+			body := owners[0]
+			owners = owners[1:]
+			if body == "" {
+				return jsonResponse(404, `{"error":"alias [users] missing","status":404}`), nil
+			}
+			return jsonResponse(200, body), nil
+		case req.Method == http.MethodHead:
+			return jsonResponse(404, ``), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WithUniqueIndices())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	index := loader.IndexName("users")
+	if !strings.HasPrefix(index, "users_") || len(index) != len("users_")+8 {
+		t.Fatalf("expected a unique index name for users, got %q", index)
+	}
+	other, err := New(client, Directory(dir), WithUniqueIndices())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if other.IndexName("users") == index {
+		t.Errorf("expected loaders to get different index names, both got %q", index)
+	}
+
+	owners = []string{"", `{"` + index + `":{"aliases":{"users":{}}}}`}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	for _, want := range []string{"PUT /" + index, "PUT /" + index + "/_aliases/users"} {
+		if !slices.Contains(requests, want) {
+			t.Errorf("expected request %q, got %v", want, requests)
+		}
+	}
+
+	// The alias already belongs to another loader's index.
+	requests = nil
+	owners = []string{`{"users_0000beef":{"aliases":{"users":{}}}}`}
+	err = loader.Load()
+	if !errors.Is(err, ErrAliasInUse) || !strings.Contains(err.Error(), "users_0000beef") {
+		t.Errorf("expected ErrAliasInUse naming the owner, got %v", err)
+	}
+	if slices.Contains(requests, "PUT /"+index+"/_aliases/users") {
+		t.Error("expected the alias not to be added while in use")
+	}
+
+	// Another loader claimed the alias at the same time.
+	requests = nil
+	owners = []string{"", `{"` + index + `":{},"users_0000beef":{}}`}
+	if err := loader.Load(); !errors.Is(err, ErrAliasInUse) {
+		t.Errorf("expected ErrAliasInUse after a race, got %v", err)
+	}
+	if !slices.Contains(requests, "DELETE /"+index+"/_aliases/users") {
+		t.Errorf("expected the contested alias to be removed, got %v", requests)
+	}
+}
@@ -0,0 +1,90 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks the parsed fixtures for problems that Elasticsearch would
+// not report, without contacting the cluster:
+//
+//   - every field declared under references in _config.yml holds IDs that
+//     exist among the referenced fixture index's documents.
+//
+// Documents from NDJSON files and providers are not parsed ahead of time and
+// are therefore not checked.
+func (l *Loader) Validate() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		errs = append(errs, l.validateReferences(f)...)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("testfixtures: validating fixtures: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// validateReferences checks the cross-index references declared for f.
+func (l *Loader) validateReferences(f *indexFixture) []error {
+	fields := make([]string, 0, len(f.config.References))
+	for field := range f.config.References {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var errs []error
+	for _, field := range fields {
+		target := f.config.References[field]
+		tf := l.fixture(target)
+		if tf == nil {
+			errs = append(errs, fmt.Errorf("index %q: field %q references unknown fixture index %q", f.name, field, target))
+			continue
+		}
+
+		ids := make(map[string]bool, len(tf.documents))
+		for _, doc := range tf.documents {
+			ids[doc.ID] = doc.action != actionDelete
+		}
+
+		for _, doc := range f.documents {
+			values, err := lookupField(doc.Source, strings.Split(field, "."))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("index %q: %s: reading field %q: %w", f.name, doc.Location(), field, err))
+				continue
+			}
+
+			for _, v := range values {
+				id, ok := referenceID(v)
+				if !ok || ids[id] {
+					continue
+				}
+				errs = append(errs, fmt.Errorf("index %q: %s: field %q references missing %s document %q", f.name, doc.Location(), field, target, id))
+			}
+		}
+	}
+
+	return errs
+}
+
+// referenceID converts a scalar JSON value to the document ID it refers to.
+// Null values are not references.
+func referenceID(v json.RawMessage) (string, bool) {
+	value, err := decodeValue(v)
+	if err != nil {
+		return "", false
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	}
+
+	return "", false
+}
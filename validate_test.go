@@ -0,0 +1,83 @@
+package testfixtures
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFixtureFiles creates files under dir from a map of relative paths to contents.
+func writeFixtureFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidate_References(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml": "- _id: \"1\"\n  name: Alice\n- _id: \"2\"\n  name: Bob\n",
+		"orders/_config.yml":  "references:\n  user_id: users\n  reviewers.user_id: users\n",
+		"orders/documents.yml": `- _id: o1
+  user_id: "1"
+  reviewers: [{user_id: 2}]
+- _id: o2
+  user_id: "3"
+- _id: o3
+  user_id: null
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	err = loader.Validate()
+	if err == nil {
+		t.Fatal("expected Validate() to report the missing user")
+	}
+	if msg := err.Error(); !strings.Contains(msg, `missing users document "3"`) || !strings.Contains(msg, "orders/documents.yml:4") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if strings.Count(err.Error(), "references missing") != 1 {
+		t.Errorf("expected exactly one broken reference, got: %v", err)
+	}
+}
+
+func TestValidate_UnknownReferencedIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_config.yml":   "references:\n  user_id: customers\n",
+		"orders/documents.yml": "- user_id: \"1\"\n",
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := loader.Validate(); err == nil || !strings.Contains(err.Error(), `unknown fixture index "customers"`) {
+		t.Errorf("expected unknown index error, got %v", err)
+	}
+}
+
+func TestParseFixtures_UnknownConfigKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"orders/_config.yml": "referencez:\n  user_id: users\n",
+	})
+
+	if _, err := parseFixtures(os.DirFS(dir), "."); err == nil {
+		t.Fatal("expected error for unknown _config.yml key")
+	}
+}
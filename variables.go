@@ -0,0 +1,79 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// variablesFile is the optional top-level file defining values that index
+// settings can reference.
+const variablesFile = "_variables.yml"
+
+// variableKey marks a settings node that is replaced by a variable:
+//
+//	{"filter": {"$var": "folding_filters"}}
+const variableKey = "$var"
+
+// readVariables reads _variables.yml, a YAML mapping of variable names to
+// arbitrary values, returning each value encoded as JSON. A missing file
+// yields no variables.
+func readVariables(fsys fs.FS, name string) (map[string]json.RawMessage, error) {
+	vars, err := readYAMLValues(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", variablesFile, err)
+	}
+
+	return vars, nil
+}
+
+// expandVariables replaces every {"$var": name} object in data with the value
+// of the named variable. Variables are not expanded recursively.
+func expandVariables(data json.RawMessage, vars map[string]json.RawMessage) (json.RawMessage, error) {
+	if isJSONArray(data) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			expanded, err := expandVariables(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = expanded
+		}
+		return json.Marshal(items)
+	}
+
+	fields, err := decodeObject(data)
+	if err != nil {
+		// Scalars contain no variables
+		return data, nil
+	}
+
+	if len(fields) == 1 && fields[0].key == variableKey {
+		var name string
+		if err := json.Unmarshal(fields[0].value, &name); err != nil {
+			return nil, fmt.Errorf("%s must name a variable", variableKey)
+		}
+		value, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable %q (define it in %s)", name, variablesFile)
+		}
+		return value, nil
+	}
+
+	for i, f := range fields {
+		expanded, err := expandVariables(f.value, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.key, err)
+		}
+		fields[i].value = expanded
+	}
+
+	return encodeObject(fields), nil
+}
@@ -0,0 +1,229 @@
+package testfixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// vectorKey marks a document value of a dense_vector field that is replaced
+// by a vector generated from a seed, sized by the dims of the mapping:
+//
+//	# docs/documents.yml
+//	- _id: doc-1
+//	  embedding: {$vector: doc-1}
+const vectorKey = "$vector"
+
+// vectorField is a dense_vector field of a mapping.
+type vectorField struct {
+	path        string // Dotted path of the field
+	dims        int    // Number of dimensions, or 0 if the mapping sets none
+	elementType string // float, byte, or bit
+}
+
+// denseVectorFields returns the dense_vector fields of a mapping, ordered by
+// path.
+func denseVectorFields(mapping json.RawMessage) []vectorField {
+	var fields []vectorField
+	walkMappedFields(mapping, func(path, source string, def json.RawMessage) {
+		var field struct {
+			Type        string `json:"type"`
+			Dims        int    `json:"dims"`
+			ElementType string `json:"element_type"`
+		}
+		if path != source || json.Unmarshal(def, &field) != nil || field.Type != "dense_vector" {
+			return
+		}
+		if field.ElementType == "" {
+			field.ElementType = "float"
+		}
+		fields = append(fields, vectorField{path: path, dims: field.Dims, elementType: field.ElementType})
+	})
+
+	return fields
+}
+
+// Vector returns the vector that {$vector: seed} stands for in a float
+// dense_vector field of dims dimensions, so a kNN test can search with the
+// vector of a fixture document. The vector has unit length, as the
+// dot_product similarity requires, and depends only on seed and dims.
+func Vector(seed string, dims int) []float32 {
+	r := vectorRand(seed)
+	v := make([]float64, dims)
+	var norm float64
+	for i := range v {
+		v[i] = r.NormFloat64()
+		norm += v[i] * v[i]
+	}
+	norm = math.Sqrt(norm)
+
+	out := make([]float32, dims)
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+	return out
+}
+
+// vectorRand returns the random source of the vectors of seed.
+func vectorRand(seed string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(seed))
+	sum := h.Sum64()
+	return rand.New(rand.NewPCG(sum, sum))
+}
+
+// vectorValue returns the JSON value of the vector of seed for field. Byte
+// vectors scale the unit vector to integers in [-127, 127], and bit vectors
+// are dims/8 random bytes.
+func vectorValue(seed string, field vectorField) (json.RawMessage, error) {
+	var elems []string
+	switch field.elementType {
+	case "float":
+		for _, x := range Vector(seed, field.dims) {
+			elems = append(elems, strconv.FormatFloat(float64(x), 'f', -1, 32))
+		}
+	case "byte":
+		for _, x := range Vector(seed, field.dims) {
+			elems = append(elems, strconv.Itoa(int(math.Round(float64(x)*127))))
+		}
+	case "bit":
+		if field.dims%8 != 0 {
+			return nil, fmt.Errorf("dims %d of a bit vector is not a multiple of 8", field.dims)
+		}
+		r := vectorRand(seed)
+		for range field.dims / 8 {
+			elems = append(elems, strconv.Itoa(r.IntN(256)-128))
+		}
+	default:
+		return nil, fmt.Errorf("unknown element_type %q", field.elementType)
+	}
+	return json.RawMessage("[" + strings.Join(elems, ",") + "]"), nil
+}
+
+// vectorSeed returns the seed of a {"$vector": seed} value, a string or a
+// number taken as written. The last result is false for other values.
+func vectorSeed(value json.RawMessage) (string, bool, error) {
+	if !isJSONObject(value) {
+		return "", false, nil
+	}
+	fields, err := decodeObject(value)
+	if err != nil || len(fields) != 1 || fields[0].key != vectorKey {
+		return "", false, nil
+	}
+
+	raw := bytes.TrimSpace(fields[0].value)
+	var seed string
+	if json.Unmarshal(raw, &seed) == nil {
+		return seed, true, nil
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String(), true, nil
+	}
+	return "", true, fmt.Errorf("%s must be a seed string or number, got %s", vectorKey, raw)
+}
+
+// expandVector returns the vector of value for field if value is a
+// {"$vector": seed} object, or else value itself.
+func expandVector(value json.RawMessage, field vectorField) (json.RawMessage, error) {
+	seed, ok, err := vectorSeed(value)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return value, nil
+	case field.dims == 0:
+		return nil, fmt.Errorf("the mapping sets no dims, so %s cannot size the vector", vectorKey)
+	}
+	return vectorValue(seed, field)
+}
+
+// expandVectors replaces the {"$vector": seed} values of dense_vector fields
+// with generated vectors, the same for the same seed on every run. Such a
+// value anywhere else is an error, as Elasticsearch would index it as an
+// object or reject it.
+func (l *Loader) expandVectors() error {
+	var errs []error
+	for _, f := range l.fixtures {
+		fields := denseVectorFields(f.mapping)
+		for i := range f.documents {
+			doc := &f.documents[i]
+			if !bytes.Contains(doc.Source, []byte(`"`+vectorKey+`"`)) {
+				continue
+			}
+			failed := false
+			fail := func(field, format string, args ...any) {
+				failed = true
+				errs = append(errs, fmt.Errorf("index %q: %s: field %q: %s", f.name, doc.Location(), field, fmt.Sprintf(format, args...)))
+			}
+
+			for _, field := range fields {
+				// Errors are kept out of mapField to report the whole path
+				var fieldErr error
+				source, err := mapField(doc.Source, strings.Split(field.path, "."), func(v json.RawMessage) (json.RawMessage, error) {
+					vector, err := expandVector(v, field)
+					if err != nil {
+						fieldErr = err
+						return v, nil
+					}
+					return vector, nil
+				})
+				if err == nil {
+					err = fieldErr
+				}
+				if err != nil {
+					fail(field.path, "%v", err)
+					continue
+				}
+				doc.Source = source
+			}
+
+			if path, ok := findVectorDirective(doc.Source, ""); ok && !failed {
+				fail(path, "%s is only valid in fields mapped as dense_vector", vectorKey)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// findVectorDirective returns the dotted path of the first {"$vector": ...}
+// value left in body, which prefix leads to.
+func findVectorDirective(body json.RawMessage, prefix string) (string, bool) {
+	if isJSONArray(body) {
+		var items []json.RawMessage
+		if json.Unmarshal(body, &items) != nil {
+			return "", false
+		}
+		for _, item := range items {
+			if path, ok := findVectorDirective(item, prefix); ok {
+				return path, true
+			}
+		}
+		return "", false
+	}
+
+	fields, err := decodeObject(body)
+	if err != nil {
+		return "", false
+	}
+	for _, f := range fields {
+		if f.key == vectorKey {
+			return prefix, true
+		}
+		path := f.key
+		if prefix != "" {
+			path = prefix + "." + f.key
+		}
+		if path, ok := findVectorDirective(f.value, path); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
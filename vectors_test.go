@@ -0,0 +1,152 @@
+package testfixtures
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVector(t *testing.T) {
+	v := Vector("doc-1", 8)
+	if len(v) != 8 {
+		t.Fatalf("expected 8 dimensions, got %d", len(v))
+	}
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected a unit vector, got squared length %v", norm)
+	}
+	if !slices.Equal(v, Vector("doc-1", 8)) {
+		t.Error("expected the same vector for the same seed")
+	}
+	if slices.Equal(v, Vector("doc-2", 8)) {
+		t.Error("expected different vectors for different seeds")
+	}
+}
+
+func TestNew_VectorDirective(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"docs/_mapping.json": `{"properties":{
+			"embedding":{"type":"dense_vector","dims":4},
+			"codes":{"type":"dense_vector","dims":3,"element_type":"byte"},
+			"bits":{"type":"dense_vector","dims":16,"element_type":"bit"},
+			"chunks":{"type":"nested","properties":{"vector":{"type":"dense_vector","dims":2}}}
+		}}`,
+		"docs/documents.yml": `- _id: a
+  embedding: {$vector: a}
+  codes: {$vector: 7}
+  bits: {$vector: a}
+  chunks:
+    - vector: {$vector: a-1}
+    - vector: [0.5, 0.5]
+- _generate:
+    count: 2
+    template:
+      _id: {$fake: seq, format: "g%d"}
+      embedding: {$vector: {$fake: seq, format: "g%d"}}
+`,
+	})
+
+	loader, err := New(newOfflineClient(t), Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	vectors := make(map[string]map[string]json.RawMessage)
+	for _, doc := range loader.fixture("docs").documents {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(doc.Source, &fields); err != nil {
+			t.Fatalf("document %q: %v", doc.ID, err)
+		}
+		vectors[doc.ID] = fields
+	}
+
+	decode := func(raw json.RawMessage) []float32 {
+		t.Helper()
+		var v []float32
+		if err := json.Unmarshal(raw, &v); err != nil {
+			t.Fatalf("decoding %s: %v", raw, err)
+		}
+		return v
+	}
+	if got := decode(vectors["a"]["embedding"]); !slices.Equal(got, Vector("a", 4)) {
+		t.Errorf("expected embedding %v, got %v", Vector("a", 4), got)
+	}
+	for _, id := range []string{"g1", "g2"} {
+		if got := decode(vectors[id]["embedding"]); !slices.Equal(got, Vector(id, 4)) {
+			t.Errorf("document %q: expected embedding %v, got %v", id, Vector(id, 4), got)
+		}
+	}
+
+	var codes []int
+	if err := json.Unmarshal(vectors["a"]["codes"], &codes); err != nil || len(codes) != 3 {
+		t.Fatalf("expected 3 byte values, got %s", vectors["a"]["codes"])
+	}
+	for _, c := range codes {
+		if c < -127 || c > 127 {
+			t.Errorf("byte value %d out of range", c)
+		}
+	}
+
+	var bits []int
+	if err := json.Unmarshal(vectors["a"]["bits"], &bits); err != nil || len(bits) != 2 {
+		t.Errorf("expected 2 bytes for 16 bits, got %s", vectors["a"]["bits"])
+	}
+
+	var chunks []struct {
+		Vector []float32 `json:"vector"`
+	}
+	if err := json.Unmarshal(vectors["a"]["chunks"], &chunks); err != nil {
+		t.Fatalf("decoding chunks: %v", err)
+	}
+	if !slices.Equal(chunks[0].Vector, Vector("a-1", 2)) || !slices.Equal(chunks[1].Vector, []float32{0.5, 0.5}) {
+		t.Errorf("expected the first chunk vector generated and the second kept, got %v", chunks)
+	}
+}
+
+func TestNew_VectorDirectiveErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		mapping   string
+		documents string
+		wantErr   string
+	}{
+		{
+			name:      "no dims",
+			mapping:   `{"properties":{"embedding":{"type":"dense_vector"}}}`,
+			documents: "- _id: a\n  embedding: {$vector: a}\n",
+			wantErr:   `index "docs": docs/documents.yml:1: field "embedding": the mapping sets no dims, so $vector cannot size the vector`,
+		},
+		{
+			name:      "not a dense_vector",
+			mapping:   `{"properties":{"title":{"type":"text"}}}`,
+			documents: "- _id: a\n  title: {$vector: a}\n",
+			wantErr:   `index "docs": docs/documents.yml:1: field "title": $vector is only valid in fields mapped as dense_vector`,
+		},
+		{
+			name:      "bad seed",
+			mapping:   `{"properties":{"embedding":{"type":"dense_vector","dims":4}}}`,
+			documents: "- _id: a\n  embedding: {$vector: [1, 2]}\n",
+			wantErr:   `field "embedding": $vector must be a seed string or number, got [1,2]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtureFiles(t, dir, map[string]string{
+				"docs/_mapping.json": tt.mapping,
+				"docs/documents.yml": tt.documents,
+			})
+
+			_, err := New(newOfflineClient(t), Directory(dir))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
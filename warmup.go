@@ -0,0 +1,78 @@
+package testfixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// warmupQuery is a query declared for a fixture, run once after loading
+// under WarmupQueries.
+type warmupQuery struct {
+	label string // Where the query is declared, for error messages
+	query json.RawMessage
+}
+
+// warmupQueries returns the queries declared in the _expectations directory
+// and _expect.yml of f, in that order, each distinct query once.
+func (f *indexFixture) warmupQueries() []warmupQuery {
+	var (
+		queries []warmupQuery
+		seen    = make(map[string]bool)
+	)
+	add := func(label string, query json.RawMessage) {
+		if !seen[string(query)] {
+			seen[string(query)] = true
+			queries = append(queries, warmupQuery{label: label, query: query})
+		}
+	}
+	for _, e := range f.expectations {
+		add(fmt.Sprintf("expectation %q (%s)", e.name, e.file), e.query)
+	}
+	if f.counts != nil {
+		for _, q := range f.counts.queries {
+			add(fmt.Sprintf("%s: query %q", expectFile, q.name), q.query)
+		}
+	}
+	return queries
+}
+
+// warmUp runs each query declared for f once against the loaded index, so
+// caches are filled and lazily built structures, such as global ordinals,
+// exist before the first test measures a search.
+func (l *Loader) warmUp(ctx context.Context, f *indexFixture) error {
+	index := l.IndexName(f.name)
+	for _, q := range f.warmupQueries() {
+		if err := runQuery(ctx, l.client, index, q.query); err != nil {
+			return fmt.Errorf("warming up %s: %w", q.label, err)
+		}
+	}
+	return nil
+}
+
+// runQuery searches index with query, discarding the hits.
+func runQuery(ctx context.Context, client *elasticsearch.Client, index string, query json.RawMessage) error {
+	body, err := json.Marshal(map[string]json.RawMessage{"query": query})
+	if err != nil {
+		return fmt.Errorf("building search body: %w", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(index),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("searching: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("searching: %w", err)
+	}
+
+	return nil
+}
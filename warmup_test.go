@@ -0,0 +1,90 @@
+package testfixtures
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad_WarmupQueries(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml":          "- _id: \"1\"\n  age: 35\n",
+		"users/_expectations/ages.yml": "adults:\n  query: {range: {age: {gte: 30}}}\n  ids: [\"1\"]\n",
+		"users/_expect.yml":            "queries:\n  adults:\n    query: {range: {age: {gte: 30}}}\n    count: 1\n  all:\n    query: {match_all: {}}\n    count: 1\n",
+		"products/documents.yml":       "- _id: \"1\"\n",
+	})
+
+	var searches []string
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_count"):
+			return jsonResponse(200, `{"count":1}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_search"):
+			data, _ := io.ReadAll(req.Body)
+			searches = append(searches, req.URL.Path+" "+string(data))
+			return jsonResponse(200, `{"hits":{"hits":[]}}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(searches) != 0 {
+		t.Errorf("expected no searches without WarmupQueries, got %v", searches)
+	}
+
+	loader, err = New(client, Directory(dir), WarmupQueries())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := []string{
+		`/users/_search {"query":{"range":{"age":{"gte":30}}}}`,
+		`/users/_search {"query":{"match_all":{}}}`,
+	}
+	if !slices.Equal(searches, want) {
+		t.Errorf("expected searches\n%v\ngot\n%v", want, searches)
+	}
+}
+
+func TestLoad_WarmupQueriesFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureFiles(t, dir, map[string]string{
+		"users/documents.yml":         "- _id: \"1\"\n",
+		"users/_expectations/bad.yml": "broken:\n  query: {nope: {}}\n  ids: []\n",
+	})
+
+	client := newFakeClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/_bulk"):
+			return jsonResponse(200, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/_search"):
+			return jsonResponse(400, `{"error":{"type":"parsing_exception","reason":"unknown query [nope]"},"status":400}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	}))
+
+	loader, err := New(client, Directory(dir), WarmupQueries())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	var loadErr *LoadError
+	err = loader.Load()
+	if !errors.As(err, &loadErr) || loadErr.Stage != StageWarmup || !strings.Contains(err.Error(), `warming up expectation "broken" (bad.yml)`) {
+		t.Errorf("expected a warmup LoadError, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package testfixtures
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentsFile is the name of the document file written for each index.
+const documentsFile = "documents.yml"
+
+// writtenDocumentsFile matches the names of the document files written for
+// an index, documents.yml or the numbered files of DocsPerFile.
+var writtenDocumentsFile = regexp.MustCompile(`^documents(-[0-9]+)?\.yml$`)
+
+// WriteOption configures how fixture files are written out.
+type WriteOption func(*writeConfig)
+
+// writeConfig holds the settings of the WriteOptions given to one call.
+type writeConfig struct {
+	docsPerFile int
+}
+
+// DocsPerFile splits the documents of an index with more than n of them
+// across numbered files of at most n documents each, documents-01.yml,
+// documents-02.yml, and so on, which load in order. Large fixtures stay
+// reviewable, and a change touches only the files holding the documents it
+// changes. A zero or negative n writes every document to documents.yml.
+func DocsPerFile(n int) WriteOption {
+	return func(c *writeConfig) {
+		c.docsPerFile = n
+	}
+}
+
+// writeDocumentFiles writes docs to dir as documents.yml, or as numbered
+// files of at most cfg.docsPerFile documents each. Document files left in
+// dir by an earlier write are removed first, so documents never appear in
+// two files.
+func writeDocumentFiles(dir string, docs []Document, cfg writeConfig) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && writtenDocumentsFile.MatchString(entry.Name()) {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	n := cfg.docsPerFile
+	if n <= 0 || len(docs) <= n {
+		return writeDocumentsFile(filepath.Join(dir, documentsFile), docs)
+	}
+
+	files := (len(docs) + n - 1) / n
+	width := max(2, len(strconv.Itoa(files)))
+	for i := range files {
+		name := fmt.Sprintf("documents-%0*d.yml", width, i+1)
+		if err := writeDocumentsFile(filepath.Join(dir, name), docs[i*n:min((i+1)*n, len(docs))]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeDocumentsFile writes docs to path as a YAML document file in the
+// format read by parseYAMLDocuments, with _id (and _routing, if set) as the
+// first keys of each entry.
+func writeDocumentsFile(path string, docs []Document) error {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, doc := range docs {
+		body, err := jsonToYAML(doc.Source)
+		if err != nil {
+			return fmt.Errorf("converting document %q to YAML: %w", doc.ID, err)
+		}
+		if body.Kind != yaml.MappingNode {
+			return fmt.Errorf("document %q is not a JSON object", doc.ID)
+		}
+
+		var meta []*yaml.Node
+		if doc.ID != "" {
+			meta = append(meta,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "_id"},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: doc.ID, Style: yaml.DoubleQuotedStyle},
+			)
+		}
+		if doc.Routing != "" {
+			meta = append(meta,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: routingKey},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: doc.Routing, Style: yaml.DoubleQuotedStyle},
+			)
+		}
+		body.Content = append(meta, body.Content...)
+		seq.Content = append(seq.Content, body)
+	}
+
+	var buf bytes.Buffer
+	if len(docs) > 0 {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(seq); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+	} else {
+		buf.WriteString("[]\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating directory for %q: %w", path, err)
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,345 @@
+package testfixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonNumber matches number literals that are valid JSON as written.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// yamlToJSON encodes a YAML node as JSON.
+//
+// Unlike decoding into map[string]interface{} and re-marshaling, mapping keys
+// keep their order from the file and numbers keep their literal text, so large
+// integers (IDs, epoch millis) are never rounded through float64.
+func yamlToJSON(node *yaml.Node) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if err := writeYAMLAsJSON(&buf, node); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeYAMLAsJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeYAMLAsJSON(buf, node.Content[0])
+
+	case yaml.AliasNode:
+		return writeYAMLAsJSON(buf, node.Alias)
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLAsJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case yaml.MappingNode:
+		pairs, err := mappingPairs(node)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte('{')
+		for i, p := range pairs {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(p.key)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeYAMLAsJSON(buf, p.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case yaml.ScalarNode:
+		return writeYAMLScalar(buf, node)
+	}
+
+	return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+func writeYAMLScalar(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+		return nil
+
+	case "!!int", "!!float":
+		if jsonNumber.MatchString(node.Value) {
+			buf.WriteString(node.Value)
+			return nil
+		}
+
+	case "!!str", "!!timestamp", "!!binary":
+		// Timestamps keep the text the fixture author wrote
+		data, _ := json.Marshal(node.Value)
+		buf.Write(data)
+		return nil
+	}
+
+	// Booleans and numbers in non-JSON notation (0x1F, 1_000, .inf)
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("line %d: encoding %q as JSON: %w", node.Line, node.Value, err)
+	}
+	buf.Write(data)
+	return nil
+}
+
+// readYAMLValues reads a YAML file holding a mapping of names to arbitrary
+// values and returns each value encoded as JSON. Errors from reading the file
+// are returned unwrapped so callers can check for fs.ErrNotExist.
+func readYAMLValues(fsys fs.FS, name string) (map[string]json.RawMessage, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("unmarshaling YAML: %w", err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("expected a mapping of names to values")
+	}
+
+	pairs, err := mappingPairs(root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]json.RawMessage, len(pairs))
+	for _, p := range pairs {
+		value, err := yamlToJSON(p.value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", p.key, err)
+		}
+		values[p.key] = value
+	}
+
+	return values, nil
+}
+
+// yamlPair is a single key/value entry of a YAML mapping.
+type yamlPair struct {
+	key   string
+	value *yaml.Node
+}
+
+// mappingPairs returns the entries of a mapping node in file order, with
+// merge keys ("<<") resolved. Explicit keys take precedence over merged ones.
+func mappingPairs(node *yaml.Node) ([]yamlPair, error) {
+	explicit := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k := node.Content[i]
+		if isMergeKey(k) {
+			continue
+		}
+		if explicit[k.Value] {
+			return nil, fmt.Errorf("line %d: mapping key %q already defined", k.Line, k.Value)
+		}
+		explicit[k.Value] = true
+	}
+
+	var pairs []yamlPair
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if !isMergeKey(k) {
+			if k.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: mapping keys must be scalars", k.Line)
+			}
+			pairs = append(pairs, yamlPair{key: k.Value, value: v})
+			seen[k.Value] = true
+			continue
+		}
+
+		merged, err := mergeSources(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range merged {
+			mp, err := mappingPairs(m)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range mp {
+				if explicit[p.key] || seen[p.key] {
+					continue
+				}
+				pairs = append(pairs, p)
+				seen[p.key] = true
+			}
+		}
+	}
+
+	return pairs, nil
+}
+
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Value == "<<" && node.ShortTag() == "!!merge"
+}
+
+// mergeSources resolves the value of a merge key to the mappings it merges.
+func mergeSources(node *yaml.Node) ([]*yaml.Node, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		return []*yaml.Node{node}, nil
+	case yaml.SequenceNode:
+		var sources []*yaml.Node
+		for _, item := range node.Content {
+			if item.Kind == yaml.AliasNode {
+				item = item.Alias
+			}
+			if item.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: merge sequence must contain only mappings", item.Line)
+			}
+			sources = append(sources, item)
+		}
+		return sources, nil
+	}
+
+	return nil, fmt.Errorf("line %d: merge value must be a mapping or sequence of mappings", node.Line)
+}
+
+// jsonToYAML converts a JSON value into a YAML node, keeping object key order
+// and number literals, so that fixtures written from JSON read like
+// hand-written ones. Nodes record the line they start on, as parsed YAML
+// does, and syntax errors report the line they are on.
+func jsonToYAML(data json.RawMessage) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	lines := &jsonLines{data: data, line: 1}
+
+	node, err := decodeYAMLNode(dec, lines)
+	if err == nil {
+		if _, extra := dec.Token(); !errors.Is(extra, io.EOF) {
+			err = errors.New("unexpected data after the JSON value")
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return nil, fmt.Errorf("line %d: %w", lines.at(syntaxErr.Offset), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// jsonLines finds the lines of offsets into a JSON document, which must be
+// asked for in increasing order.
+type jsonLines struct {
+	data []byte
+	off  int64 // Offset counted up to
+	line int   // Line at off
+}
+
+// at returns the line of the first token at or after off.
+func (l *jsonLines) at(off int64) int {
+	for off < int64(len(l.data)) && bytes.IndexByte([]byte(" \t\r\n,:"), l.data[off]) >= 0 {
+		off++
+	}
+	if off > int64(len(l.data)) {
+		off = int64(len(l.data))
+	}
+	if off > l.off {
+		l.line += bytes.Count(l.data[l.off:off], []byte("\n"))
+		l.off = off
+	}
+	return l.line
+}
+
+func decodeYAMLNode(dec *json.Decoder, lines *jsonLines) (*yaml.Node, error) {
+	line := lines.at(dec.InputOffset())
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '[' {
+			seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line}
+			for dec.More() {
+				item, err := decodeYAMLNode(dec, lines)
+				if err != nil {
+					return nil, err
+				}
+				seq.Content = append(seq.Content, item)
+			}
+			_, err := dec.Token() // closing ']'
+			return seq, err
+		}
+
+		m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line}
+		for dec.More() {
+			keyLine := lines.at(dec.InputOffset())
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+
+			value, err := decodeYAMLNode(dec, lines)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, Line: keyLine}, value)
+		}
+		_, err := dec.Token() // closing '}'
+		return m, err
+
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v, Line: line}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String(), Line: line}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v), Line: line}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null", Line: line}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}